@@ -0,0 +1,57 @@
+package main
+
+import "time"
+
+const (
+	// accelInterval is the longest gap between movement events that still counts as a held key.
+	// Terminals repeat held keys every 30-50ms, after a longer initial delay.
+	accelInterval = 80 * time.Millisecond
+
+	// accelRamp is how many repeats it takes for each step up in speed.
+	accelRamp = 5
+)
+
+// accelerator speeds up cursor movement while a movement key is held, for -accel.
+type accelerator struct {
+	// max is the most cells a single event moves; 1 or less disables acceleration.
+	max int
+
+	// dx and dy are the direction of the last movement, and last when it happened.
+	dx, dy int
+	last   time.Time
+
+	// repeats counts the movements in the same direction, each within accelInterval of the one before.
+	repeats int
+}
+
+// accel is the accelerator for movement keys; runEditor sets its max from -accel.
+var accel accelerator
+
+// step returns how many cells a movement in direction (dx, dy) at the given time moves.
+// Movements repeated quickly in the same direction speed up to max; a pause, or changing direction, starts over at one.
+func (a *accelerator) step(dx, dy int, at time.Time) int {
+	if a.max <= 1 {
+		return 1
+	}
+
+	if dx == a.dx && dy == a.dy && !a.last.IsZero() && at.Sub(a.last) <= accelInterval {
+		a.repeats++
+	} else {
+		a.repeats = 0
+	}
+	a.dx, a.dy, a.last = dx, dy, at
+
+	return min(a.max, 1+a.repeats/accelRamp)
+}
+
+// moveCursor moves the cursor one cell or line in the given direction, or several while the key is held with -accel.
+// Replayed macros move exactly as recorded, however fast they're replayed.
+func moveCursor(dx, dy int) {
+	n := 1
+	if !rec.replaying {
+		n = accel.step(dx, dy, time.Now())
+	}
+	for range n {
+		e.MoveCursor(dx, dy)
+	}
+}
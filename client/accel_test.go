@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestAccelerator_Step(t *testing.T) {
+	a := accelerator{max: 3}
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	// A held key repeats every 30ms: each accelRamp repeats add a cell, up to max.
+	var got []int
+	for i := range 3*accelRamp + 2 {
+		got = append(got, a.step(1, 0, at(30*i)))
+	}
+	want := []int{1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 3, 3, 3, 3, 3, 3, 3}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, expected %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, expected %v", got, want)
+		}
+	}
+
+	// Releasing the key, or turning, starts over at one cell.
+	last := 30 * (3*accelRamp + 1)
+	if got := a.step(1, 0, at(last+200)); got != 1 {
+		t.Errorf("after a pause: got step %d, expected 1", got)
+	}
+	for i := 1; i <= accelRamp; i++ {
+		a.step(1, 0, at(last+200+30*i))
+	}
+	if got := a.step(0, 1, at(last+200+30*(accelRamp+1))); got != 1 {
+		t.Errorf("after turning: got step %d, expected 1", got)
+	}
+}
+
+func TestAccelerator_Disabled(t *testing.T) {
+	for _, limit := range []int{0, 1} {
+		a := accelerator{max: limit}
+		start := time.Now()
+		for i := range 20 {
+			if got := a.step(-1, 0, start.Add(time.Duration(i)*time.Millisecond)); got != 1 {
+				t.Fatalf("max %d: got step %d, expected 1", limit, got)
+			}
+		}
+	}
+}
+
+func TestMoveCursor_Accelerated(t *testing.T) {
+	resetState(t)
+	accel = accelerator{max: 4}
+	e.SetText(strings.Repeat("x", 100))
+
+	// Events arrive back to back, as from a held key.
+	for range accelRamp + 1 {
+		_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyArrowRight}, nil)
+	}
+	if want := accelRamp + 2; e.Cursor != want {
+		t.Errorf("got cursor %d, expected %d", e.Cursor, want)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// userSites holds the site IDs of the room's users, in the same order as the editor's user list.
+	userSites []string
+
+	// guestWrite is the write access this client, as a room owner, last gave single guests, by site ID.
+	// Guests missing from it have the access last given to all guests.
+	guestWrite = map[string]bool{}
+)
+
+// guest is another user in the room, named by their site ID as well as their username, which may be shared.
+type guest struct {
+	name string
+	site string
+}
+
+// guests returns the room's users other than this client.
+func guests() []guest {
+	e.StatusMu.Lock()
+	users := e.Users
+	e.StatusMu.Unlock()
+
+	self := strconv.Itoa(doc.SiteID)
+	var list []guest
+	for i, name := range users {
+		if i >= len(userSites) || userSites[i] == "" || userSites[i] == self {
+			continue
+		}
+		list = append(list, guest{name: name, site: userSites[i]})
+	}
+	return list
+}
+
+// promptWriteAccess lets a room owner toggle write access for all guests, or for one picked by number.
+func promptWriteAccess(conn *websocket.Conn) {
+	if !isOwner {
+		e.StatusChan <- "only the room owner can change write access"
+		return
+	}
+
+	list := guests()
+	var label strings.Builder
+	label.WriteString("Toggle write access: Enter for all guests")
+	for i, g := range list {
+		fmt.Fprintf(&label, ", %d) %s (site %s, %s)", i+1, g.name, g.site, accessName(guestCanWrite(g.site)))
+	}
+	label.WriteString(": ")
+
+	openPrompt(label.String(), func(input string, conn *websocket.Conn) {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			toggleGuestWrite(conn)
+			return
+		}
+
+		n, err := strconv.Atoi(input)
+		if err != nil || n < 1 || n > len(list) {
+			e.StatusChan <- fmt.Sprintf("No guest %q", input)
+			return
+		}
+		toggleSiteWrite(conn, list[n-1])
+	})
+}
+
+// guestCanWrite reports the write access last given to the guest with the given site ID.
+func guestCanWrite(site string) bool {
+	if canWrite, ok := guestWrite[site]; ok {
+		return canWrite
+	}
+	return guestsCanWrite
+}
+
+// accessName names a write access the way the server's permission messages do.
+func accessName(canWrite bool) string {
+	if canWrite {
+		return "write"
+	}
+	return "read"
+}
+
+// toggleSiteWrite grants or revokes write access for one guest, picked by site ID so namesakes keep theirs.
+func toggleSiteWrite(conn *websocket.Conn, g guest) {
+	canWrite := !guestCanWrite(g.site)
+	msg := commons.Message{Type: commons.PermissionMessage, Text: accessName(canWrite), Site: g.site}
+	if err := conn.WriteJSON(&msg); err != nil {
+		logger.Errorf("failed to change permissions, err: %v\n", err)
+		e.StatusChan <- "failed to change write access!"
+		return
+	}
+
+	guestWrite[g.site] = canWrite
+	e.StatusChan <- fmt.Sprintf("%s (site %s) can write: %v", g.name, g.site, canWrite)
+}
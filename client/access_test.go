@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestPromptWriteAccess(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+	oldOwner, oldGuestsCanWrite := isOwner, guestsCanWrite
+	defer func() { isOwner, guestsCanWrite = oldOwner, oldGuestsCanWrite }()
+	guestsCanWrite = false
+
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, conn)
+	handleMsg(commons.Message{Type: commons.UsersMessage, Text: "me,guest,guest,", Site: "1,5,9,"}, conn)
+
+	// Guests can't change write access.
+	isOwner = false
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlG}, conn)
+	if activePrompt != nil {
+		t.Fatalf("expected no prompt for a guest")
+	}
+
+	isOwner = true
+	choose := func(input string) commons.Message {
+		t.Helper()
+		_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlG}, conn)
+		for _, ch := range input {
+			_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+		}
+		_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyEnter}, conn)
+		return <-received
+	}
+
+	// Guests sharing a name are told apart by site ID; the owner isn't listed.
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlG}, conn)
+	if label := activePrompt.label; !strings.Contains(label, "1) guest (site 5, read), 2) guest (site 9, read)") || strings.Contains(label, "me") {
+		t.Errorf("got prompt %q, expected both guests with their site IDs", label)
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyEsc}, conn)
+
+	tests := []struct {
+		input    string
+		wantText string
+		wantSite string
+	}{
+		{"2", "write", "9"},
+		{"2", "read", "9"},
+		{"1", "write", "5"},
+		// Enter alone changes every guest's access.
+		{"", "write", ""},
+	}
+	for _, tc := range tests {
+		msg := choose(tc.input)
+		if msg.Type != commons.PermissionMessage || msg.Text != tc.wantText || msg.Site != tc.wantSite {
+			t.Errorf("(%q) sent %+v, expected %q for site %q", tc.input, msg, tc.wantText, tc.wantSite)
+		}
+	}
+
+	// After changing every guest's access, a single guest's toggle starts from it.
+	if msg := choose("1"); msg.Text != "read" || msg.Site != "5" {
+		t.Errorf("sent %+v, expected to revoke site 5's write access", msg)
+	}
+}
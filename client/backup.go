@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// backupName returns the name of the nth most recent backup of path, e.g. file.txt.~1~.
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.~%d~", path, n)
+}
+
+// rotateBackups keeps up to count previous versions of path before it's overwritten.
+// Existing backups shift up by one, dropping the oldest, and path is copied to the newest, .~1~.
+// The newest backup keeps the modification time of the version it holds.
+func rotateBackups(path string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Nothing to back up yet.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(backupName(path, count)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for n := count - 1; n >= 1; n-- {
+		if err := os.Rename(backupName(path, n), backupName(path, n+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	newest := backupName(path, 1)
+	if err := os.WriteFile(newest, content, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(newest, info.ModTime(), info.ModTime())
+}
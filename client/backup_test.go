@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestRotateBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+
+	// Nothing to back up before the first save.
+	if err := rotateBackups(path, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(backupName(path, 1)); !os.IsNotExist(err) {
+		t.Errorf("expected no backup of a missing file, got err = %v", err)
+	}
+
+	// Save versions v1 through v4, backing up before each save.
+	for _, version := range []string{"v1", "v2", "v3", "v4"} {
+		if err := rotateBackups(path, 2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(version), 0644); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+	}
+
+	want := map[string]string{path: "v4", backupName(path, 1): "v3", backupName(path, 2): "v2"}
+	for name, content := range want {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		if string(got) != content {
+			t.Errorf("%s: got %q, expected %q", filepath.Base(name), got, content)
+		}
+	}
+
+	// Only count backups are kept.
+	if _, err := os.Stat(backupName(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("expected no third backup, got err = %v", err)
+	}
+}
+
+func TestRotateBackups_Disabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	if err := rotateBackups(path, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(backupName(path, 1)); !os.IsNotExist(err) {
+		t.Errorf("expected no backup, got err = %v", err)
+	}
+}
+
+func TestSave_BackupFailureStillSaves(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	// A non-empty directory where the oldest backup goes can't be replaced.
+	if err := os.MkdirAll(filepath.Join(backupName(path, 1), "blocker"), 0755); err != nil {
+		t.Fatalf("mkdir error: %v", err)
+	}
+
+	oldFileName, oldBackups := fileName, flags.Backups
+	fileName, flags.Backups = path, 1
+	defer func() { fileName, flags.Backups = oldFileName, oldBackups }()
+
+	_ = handleTermboxEvent(termbox.Event{Ch: 'n'}, nil)
+	if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlS}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if string(got) != "n" {
+		t.Errorf("got %q, expected %q", got, "n")
+	}
+	if msg := <-e.StatusChan; !strings.Contains(msg, "backup skipped") {
+		t.Errorf("got status %q, expected it to mention the skipped backup", msg)
+	}
+}
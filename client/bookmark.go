@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// bookmarks maps bookmark names to the ID of the character before the bookmarked position, or "start".
+// Like collaborators' cursors, they're anchored to characters, so they stay put as the text around them is edited.
+var bookmarks = map[string]string{}
+
+// promptBookmark asks for a name to bookmark the cursor's position under.
+func promptBookmark() {
+	openPrompt("Bookmark name: ", func(input string, conn *websocket.Conn) {
+		setBookmark(strings.TrimSpace(input))
+	})
+}
+
+// setBookmark bookmarks the cursor's position under name, replacing any bookmark of that name.
+func setBookmark(name string) {
+	if name == "" {
+		e.StatusChan <- "no bookmark name given"
+		return
+	}
+	bookmarks[name] = cursorCharID(e.Cursor)
+	e.StatusChan <- fmt.Sprintf("Bookmarked %q (Ctrl+Q jumps to it)", name)
+}
+
+// promptJump asks which bookmark to jump to, listing them.
+func promptJump() {
+	names := bookmarkNames()
+	if len(names) == 0 {
+		e.StatusChan <- "No bookmarks: Ctrl+\\ bookmarks the cursor's position"
+		return
+	}
+	openPrompt(fmt.Sprintf("Jump to bookmark (%s): ", strings.Join(names, ", ")), func(input string, conn *websocket.Conn) {
+		jumpToBookmark(strings.TrimSpace(input))
+	})
+}
+
+// jumpToBookmark moves the cursor to the named bookmark, if its position is still in the document.
+func jumpToBookmark(name string) {
+	charID, ok := bookmarks[name]
+	if !ok {
+		e.StatusChan <- fmt.Sprintf("No bookmark named %q", name)
+		return
+	}
+
+	pos := cursorPosition(charID)
+	if pos < 0 {
+		delete(bookmarks, name)
+		e.StatusChan <- fmt.Sprintf("Bookmark %q was removed: its text was deleted", name)
+		return
+	}
+	e.SetX(min(pos, len(e.Text)))
+	e.MoveCursor(0, 0)
+	e.StatusChan <- fmt.Sprintf("Jumped to %q", name)
+}
+
+// bookmarkNames returns the names of the bookmarks still in the document, sorted,
+// pruning those whose character was deleted.
+func bookmarkNames() []string {
+	names := make([]string, 0, len(bookmarks))
+	for name, charID := range bookmarks {
+		if cursorPosition(charID) < 0 {
+			delete(bookmarks, name)
+			continue
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
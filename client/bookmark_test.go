@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestBookmark_StableAcrossEdits(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	typeText(t, "hello world")
+
+	// Bookmark the start of "world", and the start of the document.
+	e.SetX(6)
+	setBookmark("w")
+	e.SetX(0)
+	setBookmark("top")
+
+	// Text typed before the bookmark pushes it along.
+	for _, ch := range ">> " {
+		performOperation(OperationInsert, termbox.Event{Ch: ch}, nil)
+	}
+	jumpToBookmark("w")
+	if got := string(e.Text[e.Cursor:]); got != "world" {
+		t.Errorf("after inserting before it: cursor at %d, before %q, expected before %q", e.Cursor, got, "world")
+	}
+
+	// Deleting text before it pulls it back.
+	e.SetX(3)
+	performOperation(OperationDelete, termbox.Event{}, nil)
+	performOperation(OperationDelete, termbox.Event{}, nil)
+	jumpToBookmark("w")
+	if got := string(e.Text[e.Cursor:]); got != "world" {
+		t.Errorf("after deleting before it: cursor at %d, before %q, expected before %q", e.Cursor, got, "world")
+	}
+
+	// Text typed after it leaves it alone.
+	e.SetX(len(e.Text))
+	performOperation(OperationInsert, termbox.Event{Ch: '!'}, nil)
+	jumpToBookmark("w")
+	if got := string(e.Text[e.Cursor:]); got != "world!" {
+		t.Errorf("after inserting after it: cursor before %q, expected before %q", got, "world!")
+	}
+
+	jumpToBookmark("top")
+	if e.Cursor != 0 {
+		t.Errorf("start bookmark: cursor at %d, expected 0", e.Cursor)
+	}
+}
+
+func TestBookmark_DeletedAnchorIsPruned(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	typeText(t, "abc")
+
+	e.SetX(2)
+	setBookmark("b")
+	e.SetX(3)
+	setBookmark("c")
+
+	// Deleting the character before the bookmark removes it.
+	e.SetX(2)
+	performOperation(OperationDelete, termbox.Event{}, nil)
+
+	if got := bookmarkNames(); len(got) != 1 || got[0] != "c" {
+		t.Errorf("got bookmarks %q, expected only %q", got, "c")
+	}
+	cursor := e.Cursor
+	jumpToBookmark("b")
+	if e.Cursor != cursor {
+		t.Errorf("jumping to a removed bookmark moved the cursor from %d to %d", cursor, e.Cursor)
+	}
+	if _, ok := bookmarks["b"]; ok {
+		t.Errorf("expected the bookmark to be removed")
+	}
+}
+
+func TestBookmark_Keys(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	typeText(t, "one\ntwo")
+
+	press := func(key termbox.Key, answer string) {
+		t.Helper()
+		_ = handleTermboxEvent(termbox.Event{Key: key}, nil)
+		for _, ch := range answer {
+			_ = handleTermboxEvent(termbox.Event{Ch: ch}, nil)
+		}
+		_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyEnter}, nil)
+	}
+
+	e.SetX(4)
+	press(termbox.KeyCtrlBackslash, "two")
+	e.SetX(0)
+	press(termbox.KeyCtrlQ, "two")
+	if e.Cursor != 4 {
+		t.Errorf("cursor at %d, expected 4", e.Cursor)
+	}
+}
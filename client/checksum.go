@@ -0,0 +1,32 @@
+package main
+
+import (
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+// checksumInterval is how often this client sends the room its document's checksum, so a desync is noticed.
+const checksumInterval = 30 * time.Second
+
+// desyncStatus is shown when the server reports that this client's document differs from the room's.
+const desyncStatus = "Out of sync with the room: Ctrl+R resyncs"
+
+// sendChecksum sends the room a checksum of this client's document.
+// It's skipped while the document is known to be catching up with the room, as it would differ for good reason.
+func sendChecksum(conn *websocket.Conn) {
+	if conn == nil || !e.IsConnected || detached || resyncing || pendingRun != nil || doc.SiteID == 0 {
+		return
+	}
+	if err := conn.WriteJSON(commons.Message{Type: commons.ChecksumMessage, Text: doc.Checksum()}); err != nil {
+		logger.Errorf("failed to send checksum, err: %v\n", err)
+	}
+}
+
+// handleChecksumMsg tells the user that the server found this client's document out of sync with the room's.
+func handleChecksumMsg(msg commons.Message) {
+	logger.Infof("document checksum %s differs from the room's %s\n", doc.Checksum(), msg.Text)
+	e.StatusChan <- desyncStatus
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestSendChecksum(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+	e.IsConnected = true
+	doc.SiteID = 1
+
+	_ = handleTermboxEvent(termbox.Event{Ch: 'a'}, conn)
+	<-received
+
+	sendChecksum(conn)
+	if msg := <-received; msg.Type != commons.ChecksumMessage || msg.Text != doc.Checksum() {
+		t.Errorf("got %+v, expected the document's checksum %q", msg, doc.Checksum())
+	}
+
+	// While resyncing, the document is expected to differ from the room's.
+	resyncing = true
+	sendChecksum(conn)
+	resyncing = false
+	sendChecksum(conn)
+	if msg := <-received; msg.Type != commons.ChecksumMessage {
+		t.Errorf("got %+v, expected only the checksum sent after resyncing", msg)
+	}
+	if len(received) > 0 {
+		t.Errorf("got %d unexpected messages", len(received))
+	}
+}
+
+func TestHandleMsg_Checksum(t *testing.T) {
+	resetState(t)
+	for len(e.StatusChan) > 0 {
+		<-e.StatusChan
+	}
+
+	handleMsg(commons.Message{Type: commons.ChecksumMessage, Text: "abc"}, nil)
+	if msg := <-e.StatusChan; msg != desyncStatus {
+		t.Errorf("got status %q, expected %q", msg, desyncStatus)
+	}
+}
+
+func TestHandleMsg_InsertMissingNeighbors(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 2
+	for len(e.StatusChan) > 0 {
+		<-e.StatusChan
+	}
+
+	// The character was generated after one this document never received.
+	char := crdt.Character{ID: "12", Visible: true, Value: "b", IDPrevious: "11", IDNext: "end"}
+	op := commons.Operation{Type: "insert", Position: 2, Value: "b", Character: &char}
+	handleMsg(commons.Message{Type: "operation", Username: "peer", Operation: op}, nil)
+
+	if msg := <-e.StatusChan; msg != desyncStatus {
+		t.Errorf("got status %q, expected %q", msg, desyncStatus)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/Pallinder/go-randomdata"
+)
+
+// command is a client subcommand, which parses its own flags from args.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the client's subcommands. The first one runs when no subcommand is given.
+var commands = []command{
+	{"join", "Join a collaborative editing session on a server", runJoin},
+	{"edit", "Edit a file offline, without a server", runEdit},
+	{"export", "Write a room's document to a file or stdout", runExport},
+	{"replay", "Type a file's contents into a room, as a headless user", runReplay},
+	{"view", "View a file too big to edit, read-only, loading only the part in view", runView},
+}
+
+var (
+	// errUnknownCommand is returned for subcommands that don't exist.
+	errUnknownCommand = errors.New("unknown command")
+
+	// errFlagParse is returned when a subcommand's flags can't be parsed.
+	errFlagParse = errors.New("invalid flags")
+)
+
+// dispatch runs the subcommand named by the first argument with the remaining arguments.
+// Without a subcommand, e.g. when the arguments start with a flag, it runs the default one,
+// so invocations from before subcommands existed keep working.
+func dispatch(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commands[0].run(args)
+	}
+
+	if args[0] == "help" {
+		fmt.Print(usage())
+		return nil
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
+	}
+
+	return fmt.Errorf("%w %q\n\n%s", errUnknownCommand, args[0], usage())
+}
+
+// usage lists the subcommands.
+func usage() string {
+	var b strings.Builder
+	b.WriteString("Usage: edito <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	b.WriteString("\nRun edito <command> -h for a command's flags.\n")
+	return b.String()
+}
+
+// parseArgs parses a subcommand's flags, rejecting leftover arguments.
+// Flags default to the values in the config file, if there is one, so only the command line overrides it.
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	fs.String("config", "", "The JSON config file to read flag defaults from (defaults to ~/.edito/config.json)")
+	if err := applyConfig(fs, args); err != nil {
+		return err
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return fmt.Errorf("%w: %v", errFlagParse, err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%s: unexpected arguments: %s", fs.Name(), strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// runEdit edits a file offline. Nothing is shared, so the file is the only place edits are kept.
+func runEdit(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	addEditorFlags(fs, &flags)
+	addLogFlags(fs, &flags)
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	// No server assigns a site ID offline, and there are no other sites to collide with.
+	doc.SiteID = 1
+
+	return runEditor(nil)
+}
+
+// runExport writes a room's document, as served by the server's HTTP API, to a file or stdout.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.StringVar(&flags.Server, "server", "localhost:8080", "The network address of the server")
+	fs.StringVar(&flags.Room, "room", "", "The room to export")
+	apiToken := fs.String("api-token", "", "Token required by the server's HTTP API")
+	output := fs.String("o", "", "The file to write to (defaults to stdout)")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	u := url.URL{Scheme: "http", Host: flags.Server, Path: "/doc", RawQuery: url.Values{"room": {flags.Room}}.Encode()}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if *apiToken != "" {
+		req.Header.Set("Authorization", "Bearer "+*apiToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("export failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// runReplay joins a room without the editor and types a file's contents at the end of its document.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	addConnFlags(fs, &flags)
+	fs.StringVar(&flags.File, "file", "", "The file to replay")
+	delay := fs.Duration("delay", 50*time.Millisecond, "Delay between typed characters")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if flags.File == "" {
+		return errors.New("replay: -file is required")
+	}
+
+	content, err := os.ReadFile(flags.File)
+	if err != nil {
+		return err
+	}
+
+	conn, resp, err := connect(flags)
+	if err != nil {
+		return fmt.Errorf("%s Exiting.", classifyConnError(err, resp))
+	}
+	defer conn.Close()
+
+	username = randomdata.SillyName()
+	if err := conn.WriteJSON(commons.Message{Username: username, Text: "is replaying " + flags.File, Type: commons.JoinMessage}); err != nil {
+		return err
+	}
+
+	// The server sends the room's document to this client alone on joining, after its permission and site ID.
+	var replayDoc crdt.Document
+	siteID := 0
+	for replayDoc.Characters == nil {
+		var msg commons.Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			return err
+		}
+
+		switch {
+		case msg.Type == commons.PermissionMessage && msg.Text == "read":
+			return errors.New("replay: guests can't edit this room; ask its owner for write access (Ctrl+G) and try again")
+		case msg.Type == commons.SiteIDMessage:
+			if siteID, err = strconv.Atoi(msg.Text); err != nil {
+				return err
+			}
+		case msg.Type == commons.DocSyncMessage && siteID != 0 && msg.Document != nil && msg.Document.Validate() == nil:
+			replayDoc = *msg.Document
+		}
+	}
+	replayDoc.SiteID = siteID
+
+	// Keep reading so the server never blocks on this client, but leave others' edits alone.
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	position := len([]rune(crdt.Content(replayDoc))) + 1
+	for _, r := range string(content) {
+		char, err := replayDoc.GenerateCharacter(position, string(r))
+		if err != nil {
+			return err
+		}
+		op := commons.Operation{Type: "insert", Position: position, Value: string(r), Character: &char}
+		if err := conn.WriteJSON(commons.Message{Type: "operation", Operation: op}); err != nil {
+			return err
+		}
+
+		position++
+		time.Sleep(*delay)
+	}
+
+	fmt.Printf("Replayed %d characters from %s into the room.\n", len([]rune(string(content))), flags.File)
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+)
+
+func TestDispatch_Routing(t *testing.T) {
+	oldCommands := commands
+	defer func() { commands = oldCommands }()
+
+	// Record which command ran with which arguments.
+	var gotName string
+	var gotArgs []string
+	fake := func(name string) func([]string) error {
+		return func(args []string) error {
+			gotName, gotArgs = name, args
+			return nil
+		}
+	}
+	commands = []command{{"join", "", fake("join")}, {"edit", "", fake("edit")}, {"export", "", fake("export")}}
+
+	tests := []struct {
+		description string
+		args        []string
+		wantName    string
+		wantArgs    []string
+	}{
+		{"no arguments", nil, "join", nil},
+		{"flags only", []string{"-server", "example:8080"}, "join", []string{"-server", "example:8080"}},
+		{"explicit default", []string{"join", "-room", "r"}, "join", []string{"-room", "r"}},
+		{"subcommand", []string{"edit", "-file", "a.txt"}, "edit", []string{"-file", "a.txt"}},
+		{"subcommand without flags", []string{"export"}, "export", []string{}},
+	}
+
+	for _, tc := range tests {
+		gotName, gotArgs = "", nil
+		if err := dispatch(tc.args); err != nil {
+			t.Fatalf("(%s) error: %v\n", tc.description, err)
+		}
+		if gotName != tc.wantName {
+			t.Errorf("(%s) got != want; ran = %q, expected = %q\n", tc.description, gotName, tc.wantName)
+		}
+		if !cmp.Equal(gotArgs, tc.wantArgs) {
+			t.Errorf("(%s) got != want; diff = %v\n", tc.description, cmp.Diff(gotArgs, tc.wantArgs))
+		}
+	}
+}
+
+func TestDispatch_UnknownCommand(t *testing.T) {
+	err := dispatch([]string{"frobnicate", "-x"})
+	if !errors.Is(err, errUnknownCommand) {
+		t.Fatalf("got != want; err = %v, expected = %v\n", err, errUnknownCommand)
+	}
+
+	// The error lists the available commands.
+	for _, cmd := range commands {
+		if !strings.Contains(err.Error(), cmd.name) {
+			t.Errorf("error doesn't mention %q: %v\n", cmd.name, err)
+		}
+	}
+}
+
+func TestDispatch_InvalidFlags(t *testing.T) {
+	oldFlags := flags
+	defer func() { flags = oldFlags }()
+
+	for _, args := range [][]string{
+		{"export", "-no-such-flag"},
+		{"edit", "-scroll=maybe"},
+	} {
+		if err := dispatch(args); !errors.Is(err, errFlagParse) {
+			t.Errorf("(%v) got != want; err = %v, expected = %v\n", args, err, errFlagParse)
+		}
+	}
+
+	if err := dispatch([]string{"export", "extra"}); err == nil {
+		t.Errorf("expected an error for unexpected arguments\n")
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	oldFlags := flags
+	defer func() { flags = oldFlags }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/doc" || r.URL.Query().Get("room") != "notes" {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("hello\nworld"))
+	}))
+	defer srv.Close()
+
+	server := strings.TrimPrefix(srv.URL, "http://")
+	output := filepath.Join(t.TempDir(), "out.txt")
+
+	if err := dispatch([]string{"export", "-server", server, "-room", "notes", "-api-token", "secret", "-o", output}); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if string(got) != "hello\nworld" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "hello\nworld")
+	}
+
+	// Server errors are reported with their status.
+	err = dispatch([]string{"export", "-server", server, "-room", "notes", "-o", output})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("got = %v, expected an unauthorized error\n", err)
+	}
+}
+
+// newReplayServer starts a server that greets a joining client like a room whose document holds text,
+// granting it the given permission, and sends what the client sends after joining on the returned channel.
+func newReplayServer(t *testing.T, permission, text string) (string, chan commons.Message) {
+	t.Helper()
+
+	received := make(chan commons.Message, 100)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		defer close(received)
+
+		var msg commons.Message
+		if err := conn.ReadJSON(&msg); err != nil || msg.Type != commons.HelloMessage {
+			return
+		}
+		_ = conn.WriteJSON(commons.Message{Type: commons.WelcomeMessage, Text: fmt.Sprint(commons.ProtocolVersion)})
+		if err := conn.ReadJSON(&msg); err != nil || msg.Type != commons.JoinMessage {
+			return
+		}
+
+		doc := crdt.New()
+		doc.SiteID = 1
+		for i, r := range text {
+			if _, err := doc.Insert(i+1, string(r)); err != nil {
+				return
+			}
+		}
+		_ = conn.WriteJSON(commons.Message{Type: commons.PermissionMessage, Text: permission})
+		_ = conn.WriteJSON(commons.Message{Type: commons.SiteIDMessage, Text: "7"})
+		_ = conn.WriteJSON(commons.Message{Type: commons.DocSyncMessage, Document: &doc})
+
+		for {
+			var msg commons.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			received <- msg
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://"), received
+}
+
+func TestRunReplay(t *testing.T) {
+	oldFlags, oldUsername := flags, username
+	defer func() { flags, username = oldFlags, oldUsername }()
+
+	path := filepath.Join(t.TempDir(), "replay.txt")
+	if err := os.WriteFile(path, []byte("cd"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server, received := newReplayServer(t, "write", "ab")
+
+	if err := dispatch([]string{"replay", "-server", server, "-file", path, "-delay", "0"}); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	// The replay is typed after the document the server sent on joining, which isn't asked for again:
+	// a DocSync would be merged and sent to everyone in the room.
+	var typed []string
+	for msg := range received {
+		if msg.Type != "operation" {
+			t.Errorf("got a %q message, expected only operations", msg.Type)
+			continue
+		}
+		typed = append(typed, fmt.Sprintf("%s@%d", msg.Operation.Value, msg.Operation.Position))
+	}
+	if want := []string{"c@3", "d@4"}; !cmp.Equal(typed, want) {
+		t.Errorf("typed (-want +got)\n%s", cmp.Diff(want, typed))
+	}
+}
+
+func TestRunReplay_ReadOnly(t *testing.T) {
+	oldFlags, oldUsername := flags, username
+	defer func() { flags, username = oldFlags, oldUsername }()
+
+	path := filepath.Join(t.TempDir(), "replay.txt")
+	if err := os.WriteFile(path, []byte("cd"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server, _ := newReplayServer(t, "read", "ab")
+
+	err := dispatch([]string{"replay", "-server", server, "-file", path, "-delay", "0"})
+	if err == nil || !strings.Contains(err.Error(), "write access") {
+		t.Errorf("got %v, expected an error asking for write access", err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// commentPrefixes maps file extensions to their line comment prefix.
+var commentPrefixes = map[string]string{
+	".go":    "//",
+	".c":     "//",
+	".h":     "//",
+	".cpp":   "//",
+	".hpp":   "//",
+	".cs":    "//",
+	".java":  "//",
+	".js":    "//",
+	".ts":    "//",
+	".rs":    "//",
+	".swift": "//",
+	".kt":    "//",
+	".py":    "#",
+	".sh":    "#",
+	".rb":    "#",
+	".pl":    "#",
+	".r":     "#",
+	".yaml":  "#",
+	".yml":   "#",
+	".toml":  "#",
+	".conf":  "#",
+	".sql":   "--",
+	".lua":   "--",
+	".hs":    "--",
+	".lisp":  ";",
+	".el":    ";",
+	".clj":   ";",
+	".ini":   ";",
+}
+
+// commentPrefix returns the line comment prefix for a file, based on its extension.
+func commentPrefix(name string) (string, bool) {
+	prefix, ok := commentPrefixes[strings.ToLower(filepath.Ext(name))]
+	return prefix, ok
+}
+
+// toggleComment comments or uncomments the line the cursor is on.
+func toggleComment(conn *websocket.Conn) {
+	prefix, ok := commentPrefix(fileName)
+	if !ok {
+		e.StatusChan <- fmt.Sprintf("No comment syntax known for %q", fileName)
+		return
+	}
+
+	line := lineAt(e.Text, e.Cursor)
+	toggleCommentLines(line, line, prefix, conn)
+}
+
+// lineAt returns the 0-based line number of the given position in text.
+func lineAt(text []rune, pos int) int {
+	pos = max(0, min(pos, len(text)))
+	return strings.Count(string(text[:pos]), "\n")
+}
+
+// lineRange describes where a line's content starts after its indentation, and where it ends.
+type lineRange struct {
+	content, end int
+}
+
+// lineRanges returns the ranges of lines first through last of text.
+func lineRanges(text []rune, first, last int) []lineRange {
+	var ranges []lineRange
+
+	line, start := 0, 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\n' {
+			continue
+		}
+		if line >= first && line <= last {
+			content := start
+			for content < i && (text[content] == ' ' || text[content] == '\t') {
+				content++
+			}
+			ranges = append(ranges, lineRange{content: content, end: i})
+		}
+		line, start = line+1, i+1
+	}
+
+	return ranges
+}
+
+// toggleCommentLines comments out lines first through last, or uncomments them if they're all commented.
+// The prefix goes after each line's indentation; blank lines are left alone.
+func toggleCommentLines(first, last int, prefix string, conn *websocket.Conn) {
+	ranges := lineRanges(e.Text, first, last)
+
+	// Uncomment only if every non-blank line is commented.
+	uncomment, blank := true, true
+	for _, r := range ranges {
+		if r.content == r.end {
+			continue
+		}
+		blank = false
+		if !strings.HasPrefix(string(e.Text[r.content:r.end]), prefix) {
+			uncomment = false
+		}
+	}
+	if blank {
+		return
+	}
+
+	var edits []textEdit
+	for _, r := range ranges {
+		if r.content == r.end {
+			continue
+		}
+
+		if !uncomment {
+			edits = append(edits, textEdit{pos: r.content, ins: prefix + " "})
+			continue
+		}
+
+		// Remove the prefix and the space after it, if any.
+		n := len([]rune(prefix))
+		if r.content+n < r.end && e.Text[r.content+n] == ' ' {
+			n++
+		}
+		edits = append(edits, textEdit{pos: r.content, del: n})
+	}
+
+	applyEdits(edits, conn)
+}
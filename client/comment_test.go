@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+)
+
+func TestCommentPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		ok     bool
+	}{
+		{"main.go", "//", true},
+		{"script.PY", "#", true},
+		{"query.sql", "--", true},
+		{"notes.txt", "", false},
+		{"", "", false},
+	}
+
+	for _, tc := range tests {
+		prefix, ok := commentPrefix(tc.name)
+		if prefix != tc.prefix || ok != tc.ok {
+			t.Errorf("%q: got (%q, %v), expected (%q, %v)", tc.name, prefix, ok, tc.prefix, tc.ok)
+		}
+	}
+}
+
+func TestToggleCommentLines(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	text := "func f() {\n\tx := 1\n\n\treturn\n}"
+	for i, ch := range []rune(text) {
+		doc.GenerateInsert(i+1, string(ch))
+	}
+	e.SetText(text)
+	e.Cursor = 13 // After "\tx" on the second line.
+
+	commented := "func f() {\n\t// x := 1\n\n\t// return\n}"
+	toggleCommentLines(1, 3, "//", nil)
+	if got := crdt.Content(doc); got != commented {
+		t.Errorf("toggle on: got %q, expected %q", got, commented)
+	}
+	if got := string(e.Text); got != commented {
+		t.Errorf("toggle on: editor text got %q, expected %q", got, commented)
+	}
+	if e.Cursor != 16 {
+		t.Errorf("toggle on: got cursor %d, expected %d", e.Cursor, 16)
+	}
+
+	toggleCommentLines(1, 3, "//", nil)
+	if got := crdt.Content(doc); got != text {
+		t.Errorf("toggle off: got %q, expected %q", got, text)
+	}
+	if e.Cursor != 13 {
+		t.Errorf("toggle off: got cursor %d, expected %d", e.Cursor, 13)
+	}
+}
+
+func TestToggleCommentLines_MixedCommentsAddPrefix(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	text := "# a\nb"
+	for i, ch := range []rune(text) {
+		doc.GenerateInsert(i+1, string(ch))
+	}
+	e.SetText(text)
+
+	want := "# # a\n# b"
+	toggleCommentLines(0, 1, "#", nil)
+	if got := crdt.Content(doc); got != want {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Config holds flag defaults read from a config file, keyed by flag name.
+// Values are kept as the strings the flag package parses, so any flag can be configured.
+type Config map[string]string
+
+// defaultConfigPath returns where the config file is looked for without -config, alongside the logs in ~/.edito.
+func defaultConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".edito", "config.json"), nil
+}
+
+// LoadConfig reads a JSON config file: an object mapping flag names, without dashes, to strings, numbers or booleans.
+// For example, {"server": "example.com:8080", "scroll": false, "backups": 5, "connect-timeout": "30s"}.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	conf := Config{}
+	for name, value := range raw {
+		switch v := value.(type) {
+		case string:
+			conf[name] = v
+		case bool:
+			conf[name] = strconv.FormatBool(v)
+		case json.Number:
+			conf[name] = v.String()
+		default:
+			return nil, fmt.Errorf("invalid config file %s: %q must be a string, number or boolean", path, name)
+		}
+	}
+	return conf, nil
+}
+
+// apply sets fs's flags from the config. Flags parsed afterwards override them, so the command line wins.
+// Settings for flags fs doesn't have, e.g. another command's, are ignored.
+func (c Config) apply(fs *flag.FlagSet) error {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if name == "config" || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, c[name]); err != nil {
+			return fmt.Errorf("invalid config value for %s: %w", name, err)
+		}
+	}
+
+	// Flags that collect values from each use, like -file, start over on the command line.
+	fs.VisitAll(func(f *flag.Flag) {
+		if d, ok := f.Value.(interface{ markDefault() }); ok {
+			d.markDefault()
+		}
+	})
+	return nil
+}
+
+// applyConfig sets fs's flags from the config file named by -config in args, or from the default one if it exists.
+func applyConfig(fs *flag.FlagSet, args []string) error {
+	path, explicit := configArg(args)
+	if !explicit {
+		var err error
+		if path, err = defaultConfigPath(); err != nil {
+			return nil
+		}
+	}
+
+	conf, err := LoadConfig(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return conf.apply(fs)
+}
+
+// configArg returns the value of the -config flag in args, and whether it was given.
+// It's looked up before the flags are parsed, so the config can set their defaults.
+func configArg(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes a config file with the given contents, returning its path.
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	return path
+}
+
+// newJoinFlags returns the join command's flag set, parsing into f.
+func newJoinFlags(f *Flags) *flag.FlagSet {
+	fs := flag.NewFlagSet("join", flag.ContinueOnError)
+	addConnFlags(fs, f)
+	addEditorFlags(fs, f)
+	addLogFlags(fs, f)
+	return fs
+}
+
+func TestParseArgs_ConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `{
+		"server": "config.example:9000",
+		"room": "from-config",
+		"scroll": false,
+		"backups": 7,
+		"connect-timeout": "30s",
+		"api-token": "another command's flag"
+	}`)
+
+	var f Flags
+	fs := newJoinFlags(&f)
+	if err := parseArgs(fs, []string{"-room", "from-flag", "-config", path, "-backups=1"}); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	// The command line wins over the config, which wins over the defaults.
+	checks := []struct {
+		name      string
+		got, want any
+	}{
+		{"server", f.Server, "config.example:9000"},
+		{"room", f.Room, "from-flag"},
+		{"scroll", f.Scroll, false},
+		{"backups", f.Backups, 1},
+		{"connect-timeout", f.ConnectTimeout, 30 * time.Second},
+		{"connect-retries", f.ConnectRetries, 3},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, expected %v\n", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestParseArgs_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		description string
+		contents    string
+		want        string
+	}{
+		{"malformed JSON", `{"server": `, "invalid config file"},
+		{"not an object", `["server"]`, "invalid config file"},
+		{"nested value", `{"server": {"host": "x"}}`, `"server" must be a string, number or boolean`},
+		{"wrong type for the flag", `{"backups": "many"}`, "invalid config value for backups"},
+	}
+
+	for _, tc := range tests {
+		path := writeConfig(t, tc.contents)
+
+		var f Flags
+		err := parseArgs(newJoinFlags(&f), []string{"-config=" + path})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("(%s) got error %v, expected one mentioning %q\n", tc.description, err, tc.want)
+		}
+	}
+
+	// A config file that was asked for must exist.
+	var f Flags
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if err := parseArgs(newJoinFlags(&f), []string{"-config", missing}); err == nil {
+		t.Errorf("missing config file: got no error\n")
+	}
+}
+
+func TestConfigArg(t *testing.T) {
+	tests := []struct {
+		args   []string
+		want   string
+		wantOK bool
+	}{
+		{nil, "", false},
+		{[]string{"-room", "r"}, "", false},
+		{[]string{"-config", "a.json"}, "a.json", true},
+		{[]string{"--config=b.json", "-room", "r"}, "b.json", true},
+		{[]string{"-room", "r", "-config", "c.json"}, "c.json", true},
+		{[]string{"--", "-config", "d.json"}, "", false},
+	}
+
+	for _, tc := range tests {
+		got, ok := configArg(tc.args)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("%q: got (%q, %v), expected (%q, %v)\n", tc.args, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+// diffContext is how many unchanged lines are shown around each change in the diff panel.
+const diffContext = 2
+
+// showDiff opens a panel with the line diff between the file on disk and the document; any key dismisses it.
+// A file that doesn't exist yet counts as empty, so every line shows as added.
+func showDiff() {
+	if fileName == "" {
+		e.StatusChan <- "No file to compare with!"
+		return
+	}
+
+	saved, err := os.ReadFile(fileName)
+	missing := errors.Is(err, fs.ErrNotExist)
+	if err != nil && !missing {
+		e.StatusChan <- fmt.Sprintf("Failed to read %s: %v", fileName, err)
+		return
+	}
+
+	hunks := crdt.DiffLines(string(saved), crdt.Content(doc))
+
+	header := fmt.Sprintf("Changes since %s was saved (any key to close)", fileName)
+	switch {
+	case missing:
+		header = fmt.Sprintf("%s isn't on disk yet, so every line is new (any key to close)", fileName)
+	case len(hunks) <= 1 && (len(hunks) == 0 || hunks[0].Op == crdt.DiffEqual):
+		e.StatusChan <- fmt.Sprintf("No changes since %s was saved", fileName)
+		return
+	}
+
+	lines, colors := diffPanel(hunks, diffContext)
+	e.SetColoredPanel(append([]string{header}, lines...), append([]termbox.Attribute{termbox.ColorDefault}, colors...))
+}
+
+// diffPanel formats hunks as panel lines, prefixing removed lines with "-" in red and added ones with "+" in green.
+// Only context unchanged lines are kept on either side of a change; longer runs are cut to "...".
+func diffPanel(hunks []crdt.DiffHunk, context int) ([]string, []termbox.Attribute) {
+	var lines []string
+	var colors []termbox.Attribute
+	add := func(prefix, line string, color termbox.Attribute) {
+		lines = append(lines, prefix+line)
+		colors = append(colors, color)
+	}
+
+	for i, h := range hunks {
+		switch h.Op {
+		case crdt.DiffDelete:
+			for _, line := range h.Lines {
+				add("- ", line, termbox.ColorRed)
+			}
+		case crdt.DiffInsert:
+			for _, line := range h.Lines {
+				add("+ ", line, termbox.ColorGreen)
+			}
+		case crdt.DiffEqual:
+			// Keep the lines after the previous change and before the next one.
+			head, tail := 0, 0
+			if i > 0 {
+				head = context
+			}
+			if i < len(hunks)-1 {
+				tail = context
+			}
+
+			if head+tail >= len(h.Lines) {
+				for _, line := range h.Lines {
+					add("  ", line, termbox.ColorDefault)
+				}
+				continue
+			}
+			for _, line := range h.Lines[:head] {
+				add("  ", line, termbox.ColorDefault)
+			}
+			add("", "...", termbox.ColorDefault)
+			for _, line := range h.Lines[len(h.Lines)-tail:] {
+				add("  ", line, termbox.ColorDefault)
+			}
+		}
+	}
+
+	return lines, colors
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nsf/termbox-go"
+)
+
+func TestDiffPanel(t *testing.T) {
+	hunks := crdt.DiffLines("1\n2\n3\n4\n5\n6\n7\n8\n", "1\n2\n3\n4\nfour\n5\n6\n7\n")
+
+	lines, colors := diffPanel(hunks, 1)
+
+	wantLines := []string{"...", "  4", "+ four", "  5", "...", "  7", "- 8"}
+	if !cmp.Equal(lines, wantLines) {
+		t.Errorf("lines (-want +got)\n%s", cmp.Diff(wantLines, lines))
+	}
+
+	d := termbox.ColorDefault
+	wantColors := []termbox.Attribute{d, d, termbox.ColorGreen, d, d, d, termbox.ColorRed}
+	if !cmp.Equal(colors, wantColors) {
+		t.Errorf("colors (-want +got)\n%s", cmp.Diff(wantColors, colors))
+	}
+}
+
+func TestShowDiff_MissingFile(t *testing.T) {
+	resetState(t)
+	typeText(t, "a\nb")
+
+	oldName := fileName
+	fileName = filepath.Join(t.TempDir(), "new.txt")
+	defer func() { fileName = oldName }()
+
+	showDiff()
+
+	want := []string{"+ a", "+ b"}
+	if got := e.GetPanel(); len(got) != 3 || !cmp.Equal(got[1:], want) {
+		t.Errorf("got panel %q, expected a header and %q\n", got, want)
+	}
+
+	// Once saved, there's nothing to show.
+	if err := os.WriteFile(fileName, []byte("a\nb"), 0600); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	e.SetPanel(nil)
+	showDiff()
+	if got := e.GetPanel(); got != nil {
+		t.Errorf("got panel %q for an unchanged file, expected none\n", got)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+
+	"text-editor/crdt"
+)
+
+// checkDrift verifies that the editor's text is the document's content, as every edit is meant to keep it.
+// The two are updated separately, so a bug in either path would otherwise go unnoticed until the display
+// no longer matched what peers see. A mismatch is logged loudly and repaired by showing the document again.
+// It reports whether the two had drifted apart. after describes what was just handled, for the log.
+func checkDrift(after string) bool {
+	content := crdt.Content(doc)
+	text := string(e.GetText())
+	if text == content {
+		return false
+	}
+
+	// Point at where they first differ, since the texts can be long.
+	at := 0
+	for at < len(text) && at < len(content) && text[at] == content[at] {
+		at++
+	}
+	logger.Errorf("DRIFT after %s: editor text (%d bytes) differs from the document (%d bytes) from byte %d: editor %q, document %q",
+		after, len(text), len(content), at, excerpt(text, at), excerpt(content, at))
+	e.StatusChan <- fmt.Sprintf("Editor text drifted from the document after %s, see the log; redrawn", after)
+
+	e.SetText(content)
+	e.SetX(min(e.Cursor, len(e.Text)))
+	return true
+}
+
+// excerpt returns up to 20 bytes of s starting at byte i.
+func excerpt(s string, i int) string {
+	return s[i:min(len(s), i+20)]
+}
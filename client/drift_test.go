@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestCheckDrift(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+
+	// Local and remote edits keep the editor's text and the document in step.
+	for _, ch := range "hello" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, nil)
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyBackspace2}, nil)
+	peer := crdt.New()
+	peer.SiteID = 2
+	char, err := peer.GenerateCharacter(1, ">")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handleMsg(commons.Message{Type: "operation", Username: "peer", Operation: commons.Operation{Type: "insert", Position: 1, Value: ">", Character: &char}}, nil)
+	if checkDrift("the edits") {
+		t.Fatalf("editor text %q drifted from the document %q", string(e.Text), crdt.Content(doc))
+	}
+
+	// Text changed behind the document's back is caught, reported and repaired.
+	want := crdt.Content(doc)
+	e.SetText(want + "x")
+	if !checkDrift("a bad edit") {
+		t.Fatalf("drift wasn't detected")
+	}
+	if msg := <-e.StatusChan; !strings.Contains(msg, "drifted") {
+		t.Errorf("got status %q, expected a drift warning", msg)
+	}
+	if got := string(e.Text); got != want {
+		t.Errorf("got editor text %q, expected it repaired to %q", got, want)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"text-editor/client/editor"
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+)
+
+// dumbHelp lists the line mode's commands.
+const dumbHelp = `Line mode: type a line and press Enter to append it to the document.
+  :p  print the document      :w  save it
+  :q  quit (:q! discards unsaved changes)
+  :h  show this help`
+
+// chooseUI reports whether the full-screen editor can be used, and if not, why.
+// The line mode is used when asked for, when TERM says the terminal can't move the cursor,
+// or when init, which sets the terminal up like termbox.Init, fails, e.g. without a terminal in CI.
+// init is only called if the full-screen editor is still an option; it's up to the caller to close it then.
+func chooseUI(forceDumb bool, term string, init func() error) (bool, string) {
+	switch {
+	case forceDumb:
+		return false, "-dumb was given"
+	case term == "dumb":
+		return false, "TERM is dumb"
+	}
+	if err := init(); err != nil {
+		return false, fmt.Sprintf("the terminal couldn't be set up for the full-screen editor: %v", err)
+	}
+	return true, ""
+}
+
+// dumbUI runs the line mode, a plain fallback for terminals the full-screen editor can't use:
+// the document is printed on request, and lines read from in are appended to it.
+// It ends when the user quits or in ends.
+func dumbUI(conn *websocket.Conn, conf UIConfig, reason string, in io.Reader, out io.Writer) error {
+	e = editor.NewEditor(conf.EditorConfig)
+	e.SetText(crdt.Content(doc))
+	e.SetFileName(fileName)
+	e.IsConnected = conn != nil
+
+	fmt.Fprintf(out, "Using line mode: %s.\n%s\n", reason, dumbHelp)
+	printDocument(out)
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		s := bufio.NewScanner(in)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+
+	var msgChan chan commons.Message
+	if conn != nil {
+		startHeartbeat(conn)
+		msgChan = getMsgChan(conn)
+	}
+
+	// changed is set once the document was edited by others, until it's printed again, so the notice isn't repeated.
+	changed := false
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if dirty {
+					fmt.Fprintln(out, "-- end of input: exiting without saving")
+				}
+				flushOnExit(conn)
+				return errExiting
+			}
+			if cmd := strings.TrimSpace(line); cmd == ":p" || cmd == ":print" {
+				changed = false
+			}
+			quit, err := dumbCommand(line, conn, out)
+			printStatus(out)
+			if quit {
+				flushOnExit(conn)
+				return err
+			}
+		case msg, ok := <-msgChan:
+			if !ok {
+				// Line mode doesn't reconnect; edits are still kept for saving.
+				msgChan = nil
+				fmt.Fprintln(out, "-- lost connection: editing offline, :w saves")
+				continue
+			}
+			handleMsg(msg, conn)
+			if (msg.Type == "operation" || msg.Type == commons.DocSyncMessage) && !changed {
+				changed = true
+				fmt.Fprintln(out, "-- the document changed: :p prints it")
+			}
+		case <-runFlush:
+			if err := flushRun(conn); err != nil {
+				unsent = true
+				fmt.Fprintln(out, "-- lost connection!")
+			}
+		case status := <-e.StatusChan:
+			fmt.Fprintf(out, "-- %s\n", status)
+		case <-e.DrawChan:
+			// There's no screen to redraw.
+		}
+	}
+}
+
+// dumbCommand runs a line typed in line mode: a command, or a line to append.
+// It reports whether to quit.
+func dumbCommand(line string, conn *websocket.Conn, out io.Writer) (bool, error) {
+	switch strings.TrimSpace(line) {
+	case ":p", ":print":
+		printDocument(out)
+	case ":w", ":write":
+		if fileName == "" {
+			fileName = "editor-content.txt"
+			e.SetFileName(fileName)
+		}
+		// A failed save is reported like in the full-screen editor, but line mode carries on.
+		_ = saveTo(fileName)
+	case ":q", ":quit":
+		if dirty {
+			fmt.Fprintln(out, "-- unsaved changes: :w saves them, :q! quits anyway")
+			return false, nil
+		}
+		return true, errExiting
+	case ":q!":
+		return true, errExiting
+	case ":h", ":help":
+		fmt.Fprintln(out, dumbHelp)
+	default:
+		appendLine(line, conn, out)
+	}
+	return false, nil
+}
+
+// appendLine adds line to the end of the document, as a line of its own.
+func appendLine(line string, conn *websocket.Conn, out io.Writer) {
+	if reason := editBlocked(); reason != "" {
+		fmt.Fprintf(out, "-- %s\n", reason)
+		return
+	}
+	if doc.SiteID == 0 {
+		fmt.Fprintln(out, "-- connecting...")
+		return
+	}
+
+	// The line goes after the last one, or on a new line if the document doesn't end with a newline.
+	text := line + "\n"
+	if n := len(e.Text); n > 0 && e.Text[n-1] != '\n' {
+		text = "\n" + line
+	}
+	e.SetX(len(e.Text))
+	if _, err := insertFrom(strings.NewReader(text), conn); err != nil {
+		fmt.Fprintf(out, "-- failed to append the line: %v\n", err)
+	}
+}
+
+// printDocument writes the document to out with line numbers, between rules so empty documents show too.
+func printDocument(out io.Writer) {
+	name := fileName
+	if name == "" {
+		name = "[no file]"
+	}
+	fmt.Fprintf(out, "---- %s ----\n", name)
+
+	text := string(e.GetText())
+	if text != "" {
+		for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+			fmt.Fprintf(out, "%4d  %s\n", i+1, line)
+		}
+	}
+	fmt.Fprintln(out, "----")
+}
+
+// printStatus writes out the status messages waiting to be shown.
+func printStatus(out io.Writer) {
+	for {
+		select {
+		case status := <-e.StatusChan:
+			fmt.Fprintf(out, "-- %s\n", status)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"text-editor/crdt"
+)
+
+func TestChooseUI(t *testing.T) {
+	initErr := errors.New("open /dev/tty: no such device or address")
+	tests := []struct {
+		description string
+		forceDumb   bool
+		term        string
+		initErr     error
+		wantFull    bool
+		wantInit    bool
+		wantReason  string
+	}{
+		{"terminal works", false, "xterm-256color", nil, true, true, ""},
+		{"terminal setup fails", false, "xterm", initErr, false, true, "couldn't be set up"},
+		{"no TERM, setup fails", false, "", initErr, false, true, "couldn't be set up"},
+		{"dumb terminal", false, "dumb", nil, false, false, "TERM is dumb"},
+		{"asked for", true, "xterm", nil, false, false, "-dumb"},
+	}
+
+	for _, tc := range tests {
+		initCalled := false
+		full, reason := chooseUI(tc.forceDumb, tc.term, func() error {
+			initCalled = true
+			return tc.initErr
+		})
+		if full != tc.wantFull || initCalled != tc.wantInit || !strings.Contains(reason, tc.wantReason) {
+			t.Errorf("(%s) got full = %v, init called = %v, reason %q; expected %v, %v and a reason containing %q",
+				tc.description, full, initCalled, reason, tc.wantFull, tc.wantInit, tc.wantReason)
+		}
+	}
+}
+
+func TestDumbUI_ViewAndAppend(t *testing.T) {
+	resetState(t)
+	oldFileName, oldFlags := fileName, flags
+	defer func() { fileName, flags = oldFileName, oldFlags }()
+	fileName, flags.Backups = filepath.Join(t.TempDir(), "notes.txt"), 0
+
+	doc = crdt.New()
+	doc.SiteID = 1
+	if err := doc.ReplaceContent("first\n"); err != nil {
+		t.Fatalf("replace error: %v", err)
+	}
+
+	in := strings.NewReader("second\n:q\nthird\n:p\n:w\n:q\n")
+	var out strings.Builder
+	if err := dumbUI(nil, UIConfig{}, "testing", in, &out); !errors.Is(err, errExiting) {
+		t.Fatalf("got error %v, expected to exit", err)
+	}
+
+	if got, want := crdt.Content(doc), "first\nsecond\nthird\n"; got != want {
+		t.Errorf("got document %q, expected %q", got, want)
+	}
+	saved, err := os.ReadFile(fileName)
+	if err != nil || string(saved) != "first\nsecond\nthird\n" {
+		t.Errorf("got %q saved (err: %v), expected the document", saved, err)
+	}
+
+	// The document is printed on start and with :p, and quitting with unsaved changes is refused.
+	for _, want := range []string{"Using line mode: testing.", "   1  first\n----", "unsaved changes", "   3  third\n----"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected the output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"slices"
+
+	"text-editor/commons"
+)
+
+// unacked holds the clocks of operations sent with -echo that the server hasn't echoed back yet, oldest first.
+// It's only touched from the main loop, so it needs no locking.
+var unacked []uint64
+
+// awaitEcho records a sent operation as unacknowledged, if the server was asked to echo operations.
+func awaitEcho(op commons.Operation) {
+	if flags.Echo {
+		unacked = append(unacked, op.Clock)
+	}
+}
+
+// acknowledge clears an echoed operation from unacked. Its edit was already applied locally when it was made.
+func acknowledge(op commons.Operation) {
+	if i := slices.Index(unacked, op.Clock); i >= 0 {
+		unacked = slices.Delete(unacked, i, i+1)
+		return
+	}
+	logger.Warnf("echo of unknown operation at clock %d\n", op.Clock)
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+func TestHandleMsg_EchoAcknowledges(t *testing.T) {
+	resetState(t)
+	oldEcho := flags.Echo
+	flags.Echo = true
+	defer func() { flags.Echo = oldEcho }()
+
+	clientID = uuid.New()
+	doc.SiteID = 1
+	typeText(t, "a")
+
+	sent := []commons.Operation{{Type: "insert", Value: "x", Clock: 1}, {Type: "insert", Value: "y", Clock: 2}}
+	for _, op := range sent {
+		awaitEcho(op)
+	}
+
+	// The echo of the second operation clears it, without applying it again.
+	handleMsg(commons.Message{Type: "operation", ID: clientID, Username: "me", Operation: sent[1]}, nil)
+	if want := []uint64{1}; !cmp.Equal(unacked, want) {
+		t.Errorf("unacked (-want +got)\n%s", cmp.Diff(want, unacked))
+	}
+	if got := crdt.Content(doc); got != "a" {
+		t.Errorf("echo was applied; got = %q, expected = %q\n", got, "a")
+	}
+
+	// Others' operations aren't acknowledgements.
+	handleMsg(commons.Message{Type: "operation", ID: uuid.New(), Username: "peer", Operation: commons.Operation{Type: "insert", Position: 2, Value: "b", Clock: 1}}, nil)
+	if want := []uint64{1}; !cmp.Equal(unacked, want) {
+		t.Errorf("unacked after a peer's operation (-want +got)\n%s", cmp.Diff(want, unacked))
+	}
+
+	handleMsg(commons.Message{Type: "operation", ID: clientID, Username: "me", Operation: sent[0]}, nil)
+	if len(unacked) != 0 {
+		t.Errorf("got unacked = %v, expected none\n", unacked)
+	}
+}
@@ -0,0 +1,82 @@
+package editor
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// benchSizes are the text sizes benchmarks are run against.
+var benchSizes = []int{100, 1000, 10000}
+
+// benchAlphabet is what random text is made of. The newline gives it realistic lines.
+const benchAlphabet = "abcdefghijklmnopqrstuvwxyz     \n"
+
+// newBenchEditor returns an editor holding n random characters, with a fixed seed so runs are comparable.
+func newBenchEditor(n int) *Editor {
+	r := rand.New(rand.NewSource(int64(n)))
+
+	text := make([]rune, n)
+	for i := range text {
+		text[i] = rune(benchAlphabet[r.Intn(len(benchAlphabet))])
+	}
+
+	e := NewEditor(EditorConfig{ScrollEnabled: true})
+	e.Text = text
+	e.Width, e.Height = 80, 24
+	return e
+}
+
+func BenchmarkCalcXY(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			e := newBenchEditor(n)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = e.calcXY(n)
+			}
+		})
+	}
+}
+
+func BenchmarkMoveCursor(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			e := newBenchEditor(n)
+			e.Cursor = n / 2
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Alternate directions so the cursor stays in the middle of the text.
+				if i%2 == 0 {
+					e.MoveCursor(0, -1)
+				} else {
+					e.MoveCursor(0, 1)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDrawText(b *testing.B) {
+	// A 100k-line document, viewed from the middle.
+	const lines = 100000
+	text := []rune(strings.Repeat("the quick brown fox jumps over the lazy dog\n", lines))
+
+	e := NewEditor(EditorConfig{ScrollEnabled: true})
+	e.Text = text
+	e.Width, e.Height = 80, 24
+	e.RowOff = lines / 2
+	e.Cursor = len(text) / 2
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e.drawText(-1, DefaultTheme)
+		_, _ = e.calcXY(e.Cursor)
+	}
+}
@@ -0,0 +1,49 @@
+package editor
+
+import (
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+// controlGlyph returns what's drawn in place of a control character, or "" if r isn't one.
+// Terminals act on control characters, e.g. beeping or moving the cursor, rather than drawing them,
+// so they're shown in caret notation: ^@ to ^_ for the C0 controls and ^? for DEL.
+// The C1 controls have no caret notation and are shown as the replacement character.
+// Newlines end lines rather than being drawn, so they're not included.
+func controlGlyph(r rune) string {
+	switch {
+	case r == '\n':
+		return ""
+	case r < 0x20:
+		return string([]rune{'^', r + 0x40})
+	case r == 0x7f:
+		return "^?"
+	case r >= 0x80 && r < 0xa0:
+		return "�"
+	}
+	return ""
+}
+
+// runeWidth returns how many columns r takes on screen, with control characters in their drawn form.
+func runeWidth(r rune) int {
+	if glyph := controlGlyph(r); glyph != "" {
+		return runewidth.StringWidth(glyph)
+	}
+	return runewidth.RuneWidth(r)
+}
+
+// drawRune draws r with its left edge at column x of row y, as runeWidth measures it, and returns its width.
+func (e *Editor) drawRune(x, y int, r rune, fg, bg termbox.Attribute) int {
+	glyph := controlGlyph(r)
+	if glyph == "" {
+		e.screen.SetCell(x, y, r, fg, bg)
+		return runewidth.RuneWidth(r)
+	}
+
+	w := 0
+	for _, g := range glyph {
+		e.screen.SetCell(x+w, y, g, fg, bg)
+		w += runewidth.RuneWidth(g)
+	}
+	return w
+}
@@ -2,7 +2,13 @@ package editor
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
 	"github.com/nsf/termbox-go"
@@ -10,6 +16,27 @@ import (
 
 type EditorConfig struct {
 	ScrollEnabled bool
+
+	// ScrollMargin is how many lines are kept visible above and below the cursor when scrolling.
+	ScrollMargin int
+
+	// SideScrollMargin is how many columns are kept visible left and right of the cursor when scrolling.
+	SideScrollMargin int
+
+	// LongLineLimit, if positive, highlights the current line when it's wider than this many columns.
+	LongLineLimit int
+
+	// DebugBar shows cursor and length details in the info bar instead of the file name.
+	DebugBar bool
+
+	// Theme is the colors the editor is drawn in; nil uses DefaultTheme.
+	Theme *Theme
+
+	// Screen is what the editor is drawn on; nil draws on the terminal through termbox.
+	Screen Screen
+
+	// StatusTemplate lays out the info bar, e.g. "{file} {pos}"; "" uses DefaultStatusTemplate.
+	StatusTemplate string
 }
 
 // Editor encapsulates the core structure of the text editor.
@@ -41,6 +68,9 @@ type Editor struct {
 	// StatusMsg contains the text to be shown in the status bar.
 	StatusMsg string
 
+	// Prompt, if set, replaces the status bar while the user types a response to it.
+	Prompt string
+
 	// StatusChan facilitates communication of status messages.
 	StatusChan chan string
 
@@ -50,9 +80,67 @@ type Editor struct {
 	// Users maintains a list of connected users for display.
 	Users []string
 
+	// LockHolder names the user holding the write lock, if any.
+	LockHolder string
+
+	// typing maps collaborators to the time of their last edit, guarded by StatusMu.
+	typing map[string]time.Time
+
+	// latency is the last round-trip time to the server, or 0 if none was measured; guarded by StatusMu.
+	latency time.Duration
+
 	// ScrollEnabled determines if scrolling beyond the initial view is allowed.
 	ScrollEnabled bool
 
+	// ScrollMargin is how many lines are kept visible above and below the cursor when scrolling.
+	ScrollMargin int
+
+	// SideScrollMargin is how many columns are kept visible left and right of the cursor when scrolling.
+	SideScrollMargin int
+
+	// LongLineLimit is the width beyond which the current line is flagged; 0 disables the warning.
+	LongLineLimit int
+
+	// DebugBar shows cursor and length details in the info bar instead of the file name.
+	DebugBar bool
+
+	// FileName is the file being edited, shown in the info bar; guarded by StatusMu.
+	FileName string
+
+	// Panel, if set, is shown over the text area instead of the document; guarded by StatusMu.
+	Panel []string
+
+	// PanelColors optionally sets the foreground color of each line of Panel; guarded by StatusMu.
+	PanelColors []termbox.Attribute
+
+	// SelectionStart is where the selection was started and SelectionEnd where it was extended to, following the cursor,
+	// so either may come first. Nothing is selected while they're equal; both are guarded by mu.
+	SelectionStart, SelectionEnd int
+
+	// Selecting is set while a selection is being made, even before it covers any text; guarded by mu.
+	Selecting bool
+
+	// Mode, if set, names the input mode of a modal editor in the info bar; guarded by StatusMu.
+	Mode string
+
+	// Title is the room's title, shown in the info bar; guarded by StatusMu.
+	Title string
+
+	// Overwrite is set while typing replaces characters instead of inserting them; guarded by StatusMu.
+	Overwrite bool
+
+	// Minimap is set while a zoomed-out view of the document is shown at the right of the text area; guarded by StatusMu.
+	Minimap bool
+
+	// Theme is the colors the editor is drawn in; guarded by StatusMu.
+	Theme Theme
+
+	// StatusTemplate lays out the info bar, with DefaultStatusTemplate used while it's ""; guarded by StatusMu.
+	StatusTemplate string
+
+	// screen is what the editor is drawn on.
+	screen Screen
+
 	// IsConnected indicates the current server connection status.
 	IsConnected bool
 
@@ -61,27 +149,45 @@ type Editor struct {
 
 	// mu ensures thread-safe access to the editor's state.
 	mu sync.RWMutex
+
+	// lines caches where each line of Text starts, so rendering and cursor math needn't scan the whole text.
+	lines lineIndex
+
+	// linesMu guards lines, which is rebuilt lazily under a read lock on mu.
+	linesMu sync.Mutex
 }
 
-var userColors = []termbox.Attribute{
-	termbox.ColorGreen,
-	termbox.ColorYellow,
-	termbox.ColorBlue,
-	termbox.ColorMagenta,
-	termbox.ColorCyan,
-	termbox.ColorLightYellow,
-	termbox.ColorLightMagenta,
-	termbox.ColorLightGreen,
-	termbox.ColorLightRed,
-	termbox.ColorRed,
+// lineIndex records the offsets at which each line of a text starts.
+type lineIndex struct {
+	// starts holds the offset of each line's first rune; the first line starts at 0.
+	starts []int
+
+	// data and length identify the text the index was built for, so replacing Text invalidates it.
+	data   *rune
+	length int
 }
 
 // NewEditor initializes and returns a fresh editor instance.
 func NewEditor(conf EditorConfig) *Editor {
+	theme := DefaultTheme
+	if conf.Theme != nil {
+		theme = *conf.Theme
+	}
+	var screen Screen = TermboxScreen{}
+	if conf.Screen != nil {
+		screen = conf.Screen
+	}
 	return &Editor{
-		ScrollEnabled: conf.ScrollEnabled,
-		StatusChan:    make(chan string, 100),
-		DrawChan:      make(chan int, 10000),
+		ScrollEnabled:    conf.ScrollEnabled,
+		ScrollMargin:     conf.ScrollMargin,
+		SideScrollMargin: conf.SideScrollMargin,
+		LongLineLimit:    conf.LongLineLimit,
+		DebugBar:         conf.DebugBar,
+		Theme:            theme,
+		screen:           screen,
+		StatusTemplate:   conf.StatusTemplate,
+		StatusChan:       make(chan string, 100),
+		DrawChan:         make(chan int, 10000),
 	}
 }
 
@@ -96,9 +202,51 @@ func (e *Editor) GetText() []rune {
 func (e *Editor) SetText(text string) {
 	e.mu.Lock()
 	e.Text = []rune(text)
+	e.invalidateLines()
 	e.mu.Unlock()
 }
 
+// ApplyInsert inserts s at the given index of the editor's content.
+// It mirrors a single CRDT insert without rebuilding the whole text; use SetText for full resyncs.
+func (e *Editor) ApplyInsert(pos int, s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pos = max(0, min(pos, len(e.Text)))
+	e.Text = slices.Insert(e.Text, pos, []rune(s)...)
+	e.invalidateLines()
+
+	// Keep the selection on the same text when text is inserted before it.
+	n := utf8.RuneCountInString(s)
+	for _, p := range []*int{&e.SelectionStart, &e.SelectionEnd} {
+		if *p > pos {
+			*p += n
+		}
+	}
+}
+
+// ApplyDelete removes n runes starting at the given index of the editor's content.
+// It mirrors CRDT deletes without rebuilding the whole text; use SetText for full resyncs.
+func (e *Editor) ApplyDelete(pos, n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	start := max(0, min(pos, len(e.Text)))
+	end := max(start, min(pos+n, len(e.Text)))
+	e.Text = slices.Delete(e.Text, start, end)
+	e.invalidateLines()
+
+	// Keep the selection on the same text, shrinking it by what was deleted from it.
+	for _, p := range []*int{&e.SelectionStart, &e.SelectionEnd} {
+		switch {
+		case *p >= end:
+			*p -= end - start
+		case *p > start:
+			*p = start
+		}
+	}
+}
+
 // GetX retrieves the horizontal component of the cursor's position.
 func (e *Editor) GetX() int {
 	x, _ := e.calcXY(e.Cursor)
@@ -159,113 +307,417 @@ func (e *Editor) SendDraw() {
 
 // Draw refreshes the UI by populating cells with the editor's content.
 func (e *Editor) Draw() {
-	_ = termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	theme := e.GetTheme()
+	e.screen.Clear(theme.Text.Fg, theme.Text.Bg)
+
+	// A panel replaces the document until it's dismissed.
+	if panel := e.GetPanel(); panel != nil {
+		e.StatusMu.Lock()
+		colors := e.PanelColors
+		e.StatusMu.Unlock()
+
+		e.drawPanel(panel, colors, theme)
+		e.DrawStatusBar()
+		e.screen.Flush()
+		return
+	}
 
 	e.mu.RLock()
 	cursor := e.Cursor
 	e.mu.RUnlock()
 
-	cx, cy := e.calcXY(cursor)
+	e.screen.SetCursor(e.screenXY(cursor))
+
+	// Highlight the current line if it's too long.
+	longLine := e.LongLineLimit > 0 && e.CurrentLineLength() > e.LongLineLimit
+	_, lineY := e.calcXY(cursor)
 
-	// Adjust cursor x position for horizontal scroll
-	if cx-e.GetColOff() > 0 {
-		cx -= e.GetColOff()
+	highlight := -1
+	if longLine {
+		highlight = lineY - 1
 	}
+	e.drawText(highlight, theme)
 
-	// Adjust cursor y position for vertical scroll
-	if cy-e.GetRowOff() > 0 {
-		cy -= e.GetRowOff()
+	if e.IsMinimap() {
+		starts := e.lineStarts()
+		e.mu.RLock()
+		length := len(e.Text)
+		e.mu.RUnlock()
+		e.drawMinimap(starts, length, theme)
 	}
 
-	termbox.SetCursor(cx-1, cy-1)
+	e.DrawStatusBar()
+
+	// Apply changes to display
+	e.screen.Flush()
+}
+
+// drawText draws the lines of text in view in theme's colors, marking line highlight (0-based) as too long if it's in view,
+// and the selected text as selected.
+// Only the visible lines are visited, so drawing costs the same however long the document is.
+func (e *Editor) drawText(highlight int, theme Theme) {
+	starts := e.lineStarts()
+	yStart, yEnd := e.visibleLines(len(starts))
+	cols := e.textCols()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
 
-	// Determine visible area boundaries
-	yStart := e.GetRowOff()
-	yEnd := yStart + e.GetHeight() - 1 // Account for status bar
 	xStart := e.GetColOff()
+	selStart, selEnd := e.selection()
 
-	x, y := 0, 0
-	for i := 0; i < len(e.Text) && y < yEnd; i++ {
-		if e.Text[i] == rune('\n') {
-			x = 0
-			y++
-		} else {
-			// Render visible content
-			setY := y - yStart
-			setX := x - xStart
-			termbox.SetCell(setX, setY, e.Text[i], termbox.ColorDefault, termbox.ColorDefault)
-
-			// Advance horizontal position
-			x = x + runewidth.RuneWidth(e.Text[i])
+	for y := yStart; y < yEnd; y++ {
+		style := theme.Text
+		if y == highlight {
+			style = theme.LongLine
+		}
+
+		x := 0
+		for i := starts[y]; i < len(e.Text) && e.Text[i] != '\n'; i++ {
+			// Stop once the rest of the line is off screen.
+			if x-xStart >= cols {
+				break
+			}
+			cell := style
+			if i >= selStart && i < selEnd {
+				cell = theme.Selection
+			}
+			// Draw the character and advance past it; control characters take more than one cell.
+			x += e.drawRune(x-xStart, y-yStart, e.Text[i], cell.Fg, cell.Bg)
 		}
 	}
+}
 
-	e.DrawStatusBar()
+// textRows returns how many screen rows show text: all but the last, which holds the status bar.
+func (e *Editor) textRows() int {
+	return max(0, e.GetHeight()-1)
+}
 
-	// Apply changes to display
-	termbox.Flush()
+// visibleLines returns the range [start, end) of the document's lines that are in view,
+// for a document of the given number of lines. Line start is drawn on screen row 0,
+// and at most textRows lines are drawn, so the status bar's row is never written to.
+func (e *Editor) visibleLines(lines int) (int, int) {
+	start := max(0, e.GetRowOff())
+	end := min(lines, start+e.textRows())
+	return start, max(start, end)
+}
+
+// drawPanel draws the lines of a panel over the text area in the given colors, hiding the cursor.
+// Lines without a color use theme's text colors.
+func (e *Editor) drawPanel(lines []string, colors []termbox.Attribute, theme Theme) {
+	e.screen.HideCursor()
+
+	for y, line := range lines {
+		if y >= e.textRows() {
+			break
+		}
+		fg := theme.Text.Fg
+		if y < len(colors) {
+			fg = colors[y]
+		}
+		x := 0
+		for _, r := range line {
+			x += e.drawRune(x, y, r, fg, theme.Text.Bg)
+		}
+	}
+}
+
+// SetPanel shows lines over the text area; nil dismisses the panel.
+func (e *Editor) SetPanel(lines []string) {
+	e.SetColoredPanel(lines, nil)
+}
+
+// SetColoredPanel shows lines over the text area, each in the foreground color at the same index of colors.
+func (e *Editor) SetColoredPanel(lines []string, colors []termbox.Attribute) {
+	e.StatusMu.Lock()
+	e.Panel = lines
+	e.PanelColors = colors
+	e.StatusMu.Unlock()
+}
+
+// GetPanel returns the panel shown over the text area, if any.
+func (e *Editor) GetPanel() []string {
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
+	return e.Panel
+}
+
+// screenXY converts a cursor position to the 0-based terminal cell it's drawn in.
+// calcXY is 1-based and ignores scrolling, so this subtracts one and the scroll offsets.
+// A cursor outside the view is kept at its nearest edge rather than drawn elsewhere.
+func (e *Editor) screenXY(cursor int) (int, int) {
+	cx, cy := e.calcXY(cursor)
+
+	x := cx - 1 - e.GetColOff()
+	y := cy - 1 - e.GetRowOff()
+
+	// The last row holds the status bar.
+	x = max(0, min(x, e.textCols()-1))
+	y = max(0, min(y, e.textRows()-1))
+	return x, y
 }
 
 // DrawStatusBar renders status and debug information at the bottom of the editor.
 func (e *Editor) DrawStatusBar() {
 	e.StatusMu.Lock()
 	showMsg := e.ShowMsg
+	prompt := e.Prompt
+	theme := e.Theme
 	e.StatusMu.Unlock()
-	if showMsg {
+
+	// The bar's background spans the whole row, however much it shows.
+	for x := 0; x < e.Width; x++ {
+		e.screen.SetCell(x, e.Height-1, ' ', theme.Status.Fg, theme.Status.Bg)
+	}
+
+	if prompt != "" {
+		e.DrawPrompt(prompt)
+	} else if showMsg {
 		e.DrawStatusMsg()
 	} else {
 		e.DrawInfoBar()
 	}
 
 	// Display connection status indicator
+	indicator := theme.Disconnected
 	if e.IsConnected {
-		termbox.SetBg(e.Width-1, e.Height-1, termbox.ColorGreen)
-	} else {
-		termbox.SetBg(e.Width-1, e.Height-1, termbox.ColorRed)
+		indicator = theme.Connected
 	}
+	e.screen.SetCell(e.Width-1, e.Height-1, ' ', theme.Status.Fg, indicator)
 }
 
 // DrawStatusMsg displays the current status message at the bottom of the editor.
 func (e *Editor) DrawStatusMsg() {
 	e.StatusMu.Lock()
 	statusMsg := e.StatusMsg
+	status := e.Theme.Status
 	e.StatusMu.Unlock()
-	for i, r := range []rune(statusMsg) {
-		termbox.SetCell(i, e.Height-1, r, termbox.ColorDefault, termbox.ColorDefault)
+
+	// Leave the last column to the connection indicator.
+	x := 0
+	for _, r := range truncateWidth(statusMsg, e.Width-1) {
+		e.screen.SetCell(x, e.Height-1, r, status.Fg, status.Bg)
+		x += runewidth.RuneWidth(r)
+	}
+}
+
+// truncateWidth shortens s to fit in width terminal columns, ending it with an ellipsis if anything was cut.
+// Wide characters count as two columns and are never split.
+func truncateWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+
+	const ellipsis = "…"
+	var b strings.Builder
+	used := runewidth.StringWidth(ellipsis)
+	for _, r := range s {
+		w := runewidth.RuneWidth(r)
+		if used+w > width {
+			break
+		}
+		b.WriteRune(r)
+		used += w
 	}
+	return b.String() + ellipsis
 }
 
-// DrawInfoBar presents debug information and active user list at the bottom of the editor.
+// DrawPrompt displays a prompt and its input at the bottom of the editor, with the cursor after it.
+func (e *Editor) DrawPrompt(prompt string) {
+	status := e.GetTheme().Status
+	x := 0
+	for _, r := range prompt {
+		e.screen.SetCell(x, e.Height-1, r, status.Fg, status.Bg)
+		x += runewidth.RuneWidth(r)
+	}
+	e.screen.SetCursor(x, e.Height-1)
+}
+
+// DrawInfoBar presents debug information and active user list at the bottom of the editor,
+// laid out by the status template and cut short to leave the last column to the connection indicator.
 func (e *Editor) DrawInfoBar() {
+	theme := e.GetTheme()
+
+	x := 0
+	for _, run := range truncateRuns(e.statusRuns(theme), e.Width-1) {
+		for _, r := range run.text {
+			e.screen.SetCell(x, e.Height-1, r, run.fg, theme.Status.Bg)
+			x += runewidth.RuneWidth(r)
+		}
+	}
+}
+
+// SetLatency records the last round-trip time to the server; 0 means it's unknown.
+func (e *Editor) SetLatency(rtt time.Duration) {
+	e.StatusMu.Lock()
+	e.latency = rtt
+	e.StatusMu.Unlock()
+}
+
+// latencyText returns the info bar's round-trip time, or a placeholder until one is measured.
+// It's empty while disconnected.
+func (e *Editor) latencyText() string {
+	if !e.IsConnected {
+		return ""
+	}
+
 	e.StatusMu.Lock()
-	users := e.Users
+	rtt := e.latency
 	e.StatusMu.Unlock()
 
+	if rtt <= 0 {
+		return "rtt=--"
+	}
+	return fmt.Sprintf("rtt=%dms", max(1, rtt.Round(time.Millisecond).Milliseconds()))
+}
+
+// infoText returns the info bar's details: the cursor position and text length with DebugBar set,
+// or else the name of the file being edited.
+func (e *Editor) infoText(length int) string {
+	if e.DebugBar {
+		e.mu.RLock()
+		cursor := e.Cursor
+		e.mu.RUnlock()
+
+		cx, cy := e.calcXY(cursor)
+		return fmt.Sprintf("x=%d, y=%d, cursor=%d, len(text)=%d", cx, cy, cursor, length)
+	}
+
+	e.StatusMu.Lock()
+	name := e.FileName
+	e.StatusMu.Unlock()
+
+	if name == "" {
+		name = "[no file]"
+	}
+	return name
+}
+
+// SetTitle updates the room title shown in the info bar; "" hides it.
+func (e *Editor) SetTitle(title string) {
+	e.StatusMu.Lock()
+	e.Title = title
+	e.StatusMu.Unlock()
+}
+
+// titleText returns the info bar's room title, or "" if the room has none.
+func titleText(title string) string {
+	if title == "" {
+		return ""
+	}
+	return fmt.Sprintf("[%s]", title)
+}
+
+// SetOverwrite sets whether typing replaces characters, as shown in the info bar.
+func (e *Editor) SetOverwrite(overwrite bool) {
+	e.StatusMu.Lock()
+	e.Overwrite = overwrite
+	e.StatusMu.Unlock()
+}
+
+// IsOverwrite reports whether typing replaces characters instead of inserting them.
+func (e *Editor) IsOverwrite() bool {
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
+	return e.Overwrite
+}
+
+// overwriteText returns the info bar's note of whether typing inserts or overwrites.
+func (e *Editor) overwriteText() string {
+	if e.IsOverwrite() {
+		return "OVR"
+	}
+	return "INS"
+}
+
+// SetMode updates the input mode shown in the info bar.
+func (e *Editor) SetMode(mode string) {
+	e.StatusMu.Lock()
+	e.Mode = mode
+	e.StatusMu.Unlock()
+}
+
+// SetFileName updates the file name shown in the info bar.
+func (e *Editor) SetFileName(name string) {
+	e.StatusMu.Lock()
+	e.FileName = name
+	e.StatusMu.Unlock()
+}
+
+// CurrentLineLength returns the width of the line the cursor is on, in terminal columns.
+// Wide characters, such as CJK, count as two columns.
+func (e *Editor) CurrentLineLength() int {
 	e.mu.RLock()
-	length := len(e.Text)
-	e.mu.RUnlock()
+	defer e.mu.RUnlock()
 
-	x := 0
-	for i, user := range users {
-		for _, r := range user {
-			colorIdx := i % len(userColors)
-			termbox.SetCell(x, e.Height-1, r, userColors[colorIdx], termbox.ColorDefault)
-			x++
+	cursor := max(0, min(e.Cursor, len(e.Text)))
+
+	start := cursor
+	for start > 0 && e.Text[start-1] != '\n' {
+		start--
+	}
+
+	end := cursor
+	for end < len(e.Text) && e.Text[end] != '\n' {
+		end++
+	}
+
+	width := 0
+	for _, r := range e.Text[start:end] {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// TypingTimeout is how long a collaborator is shown as typing after their last edit.
+const TypingTimeout = 2 * time.Second
+
+// MarkTyping records that a collaborator edited the document at the given time.
+func (e *Editor) MarkTyping(user string, at time.Time) {
+	if user == "" {
+		return
+	}
+
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
+
+	if e.typing == nil {
+		e.typing = make(map[string]time.Time)
+	}
+	e.typing[user] = at
+}
+
+// Typing returns the collaborators who edited within TypingTimeout of now, sorted.
+// Collaborators who have gone quiet are forgotten.
+func (e *Editor) Typing(now time.Time) []string {
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
+
+	var users []string
+	for user, at := range e.typing {
+		if now.Sub(at) >= TypingTimeout {
+			delete(e.typing, user)
+			continue
 		}
-		termbox.SetCell(x, e.Height-1, ' ', termbox.ColorDefault, termbox.ColorDefault)
-		x++
+		users = append(users, user)
 	}
 
-	e.mu.RLock()
-	cursor := e.Cursor
-	e.mu.RUnlock()
+	sort.Strings(users)
+	return users
+}
 
-	cx, cy := e.calcXY(cursor)
-	debugInfo := fmt.Sprintf(" x=%d, y=%d, cursor=%d, len(text)=%d", cx, cy, e.Cursor, length)
+// PruneTyping forgets collaborators who are no longer connected.
+func (e *Editor) PruneTyping(connected []string) {
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
 
-	for _, r := range debugInfo {
-		termbox.SetCell(x, e.Height-1, r, termbox.ColorDefault, termbox.ColorDefault)
-		x++
+	for user := range e.typing {
+		if !slices.Contains(connected, user) {
+			delete(e.typing, user)
+		}
 	}
 }
 
@@ -291,23 +743,32 @@ func (e *Editor) MoveCursor(x, y int) {
 	if e.ScrollEnabled {
 		cx, cy := e.calcXY(newCursor)
 
-		// Adjust view window based on cursor movement
-		rowStart := e.GetRowOff()
-		rowEnd := e.GetRowOff() + e.GetHeight() - 1
+		// Adjust view window based on cursor movement, keeping the margin in view where the document allows.
+		// The margin can't exceed half the view, or the cursor would have nowhere to go.
+		rows := e.textRows()
+		margin := max(0, min(e.ScrollMargin, (rows-1)/2))
+		rowStart := e.GetRowOff() + margin
+		rowEnd := e.GetRowOff() + rows - margin
 
 		if cy <= rowStart { // Scroll up
-			e.IncRowOff(cy - rowStart - 1)
+			e.IncRowOff(max(cy-rowStart-1, -e.GetRowOff()))
 		}
 
 		if cy > rowEnd { // Scroll down
-			e.IncRowOff(cy - rowEnd)
+			// Don't scroll past the last line to make room for the margin.
+			maxRowOff := max(e.GetRowOff(), e.lineCount()-rows)
+			e.IncRowOff(min(cy-rowEnd, maxRowOff-e.GetRowOff()))
 		}
 
-		colStart := e.GetColOff()
-		colEnd := e.GetColOff() + e.GetWidth()
+		// Columns colOff+1 through colOff+textCols are visible, as cx is 1-based.
+		// The margin shrinks that window, like the vertical one.
+		cols := e.textCols()
+		sideMargin := max(0, min(e.SideScrollMargin, (cols-1)/2))
+		colStart := e.GetColOff() + sideMargin
+		colEnd := e.GetColOff() + cols - sideMargin
 
 		if cx <= colStart { // Scroll left
-			e.IncColOff(cx - (colStart + 1))
+			e.IncColOff(max(cx-(colStart+1), -e.GetColOff()))
 		}
 
 		if cx > colEnd { // Scroll right
@@ -328,6 +789,174 @@ func (e *Editor) MoveCursor(x, y int) {
 	e.mu.Unlock()
 }
 
+// ScrollToEnd moves the cursor to the end of the text, scrolling so the last line is at the bottom of the view
+// and the end of it is in view. Text that fits in the view isn't scrolled.
+func (e *Editor) ScrollToEnd() {
+	e.mu.Lock()
+	e.Cursor = len(e.Text)
+	cursor := e.Cursor
+	e.mu.Unlock()
+
+	if !e.ScrollEnabled {
+		return
+	}
+
+	cx, _ := e.calcXY(cursor)
+	e.RowOff = max(0, e.lineCount()-e.textRows())
+	e.ColOff = max(0, cx-e.textCols())
+}
+
+// GotoLine moves the cursor to the given column of the given line, both counted from 1, scrolling it into view.
+// Columns count characters. Lines and columns past the end go to the last line and to the end of the line.
+func (e *Editor) GotoLine(line, col int) {
+	starts := e.lineStarts()
+
+	e.mu.Lock()
+	y := max(0, min(line-1, len(starts)-1))
+	end := len(e.Text)
+	if y+1 < len(starts) {
+		// Stop before the newline ending the line.
+		end = starts[y+1] - 1
+	}
+	e.Cursor = starts[y] + max(0, min(col-1, end-starts[y]))
+	e.mu.Unlock()
+
+	e.MoveCursor(0, 0)
+}
+
+// GotoPercent moves the cursor to the start of the line p percent of the way through the text, like less's 50%,
+// scrolling it into view. p is clamped to [0, 100]: 0 is the first line and 100 the last.
+func (e *Editor) GotoPercent(p float64) {
+	e.GotoLine(percentLine(p, e.lineCount())+1, 1)
+}
+
+// percentLine returns the line, counted from 0, that's p percent of the way through a text of the given number of lines.
+func percentLine(p float64, lines int) int {
+	if lines <= 1 || math.IsNaN(p) {
+		return 0
+	}
+	p = max(0, min(p, 100))
+	return int(math.Round(p / 100 * float64(lines-1)))
+}
+
+// ShowPosition scrolls the view, without moving the cursor, so that position pos of the text is in it,
+// e.g. to follow a collaborator's cursor. The view only moves if pos is outside it.
+func (e *Editor) ShowPosition(pos int) {
+	cx, cy := e.calcXY(pos)
+	e.RowOff, e.ColOff = scrollToShow(e.RowOff, e.ColOff, cx-1, cy-1, e.textRows(), e.textCols())
+}
+
+// scrollToShow returns the row and column offsets of a view of rows by cols cells, scrolled from rowOff and colOff
+// so that it shows column x of line y, both counted from 0. Offsets already showing it are kept;
+// otherwise the view is centered on it, as far as the start of the text allows.
+func scrollToShow(rowOff, colOff, x, y, rows, cols int) (int, int) {
+	if y < rowOff || y >= rowOff+rows {
+		rowOff = max(0, y-rows/2)
+	}
+	if x < colOff || x >= colOff+cols {
+		colOff = max(0, x-cols/2)
+	}
+	return rowOff, colOff
+}
+
+// StartSelection starts selecting text at the cursor. Moving the cursor with ExtendSelection then selects the text it passes.
+func (e *Editor) StartSelection() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.Selecting = true
+	e.SelectionStart, e.SelectionEnd = e.Cursor, e.Cursor
+}
+
+// ExtendSelection moves the end of the selection being made to the cursor.
+func (e *Editor) ExtendSelection() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.Selecting {
+		e.SelectionEnd = e.Cursor
+	}
+}
+
+// ClearSelection stops selecting and deselects any selected text.
+func (e *Editor) ClearSelection() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.Selecting = false
+	e.SelectionStart, e.SelectionEnd = 0, 0
+}
+
+// IsSelecting reports whether a selection is being made.
+func (e *Editor) IsSelecting() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.Selecting
+}
+
+// Selection returns the range [start, end) of the selected text, which is empty if nothing is selected.
+func (e *Editor) Selection() (int, int) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.selection()
+}
+
+// selection returns the range of the selected text, within Text; the caller must hold mu.
+func (e *Editor) selection() (int, int) {
+	start, end := min(e.SelectionStart, e.SelectionEnd), max(e.SelectionStart, e.SelectionEnd)
+	start, end = max(0, min(start, len(e.Text))), max(0, min(end, len(e.Text)))
+	return start, end
+}
+
+// SelectedText returns the selected text, or "" if nothing is selected.
+func (e *Editor) SelectedText() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	start, end := e.selection()
+	return string(e.Text[start:end])
+}
+
+// lineCount returns the number of lines in the editor's content.
+func (e *Editor) lineCount() int {
+	return len(e.lineStarts())
+}
+
+// invalidateLines drops the line index after Text changes; the caller must hold mu.
+func (e *Editor) invalidateLines() {
+	e.linesMu.Lock()
+	e.lines = lineIndex{}
+	e.linesMu.Unlock()
+}
+
+// lineStarts returns the offset at which each line of Text starts, rebuilding the index if Text changed.
+// Text is normally changed through SetText, ApplyInsert and ApplyDelete, which invalidate the index,
+// but replacing it outright is detected too.
+func (e *Editor) lineStarts() []int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	e.linesMu.Lock()
+	defer e.linesMu.Unlock()
+
+	var data *rune
+	if len(e.Text) > 0 {
+		data = &e.Text[0]
+	}
+	if e.lines.starts != nil && e.lines.data == data && e.lines.length == len(e.Text) {
+		return e.lines.starts
+	}
+
+	starts := []int{0}
+	for i, r := range e.Text {
+		if r == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	e.lines = lineIndex{starts: starts, data: data, length: len(e.Text)}
+	return starts
+}
+
 // The calcCursorUp and calcCursorDown functions locate newline characters by scanning backwards and forwards from the current cursor position.
 // These characters define the "start" and "end" of the current line.
 // The cursor's offset from the line start is calculated and used to determine its final position on the target line, considering the target line's length.
@@ -446,24 +1075,20 @@ func (e *Editor) calcXY(index int) (int, int) {
 		return x, y
 	}
 
+	starts := e.lineStarts()
+
 	e.mu.RLock()
-	length := len(e.Text)
-	e.mu.RUnlock()
+	defer e.mu.RUnlock()
 
-	if index > length {
-		index = length
+	if index > len(e.Text) {
+		index = len(e.Text)
 	}
 
-	for i := 0; i < index; i++ {
-		e.mu.RLock()
-		r := e.Text[i]
-		e.mu.RUnlock()
-		if r == rune('\n') {
-			x = 1
-			y++
-		} else {
-			x = x + runewidth.RuneWidth(r)
-		}
+	// Find the line holding index, then measure from its start.
+	line := sort.Search(len(starts), func(i int) bool { return starts[i] > index }) - 1
+	y += line
+	for _, r := range e.Text[starts[line]:index] {
+		x += runeWidth(r)
 	}
 	return x, y
 }
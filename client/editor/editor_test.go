@@ -1,9 +1,14 @@
 package editor
 
 import (
+	"math"
 	"testing"
+	"time"
+
+	"text-editor/crdt"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/mattn/go-runewidth"
 )
 
 func TestEditor_CalcXY(t *testing.T) {
@@ -110,6 +115,8 @@ func TestScroll(t *testing.T) {
 			description    string
 			x              int
 			y              int
+			margin         int
+			sideMargin     int
 			colOff         int
 			expectedColOff int
 			rowOff         int
@@ -159,6 +166,83 @@ func TestScroll(t *testing.T) {
 				rowOff: 0, expectedRowOff: 0,
 				cursor: 9, expectedCursor: 8,
 				text: []rune("abcdefgh\nijk")},
+
+			{description: "no margin",
+				y:      1,
+				colOff: 0, expectedColOff: 0,
+				rowOff: 0, expectedRowOff: 0,
+				cursor: 4, expectedCursor: 6,
+				text: []rune("a\nb\nc\nd\ne\nf\ng")},
+
+			{description: "scroll down with margin",
+				y: 1, margin: 1,
+				colOff: 0, expectedColOff: 0,
+				rowOff: 0, expectedRowOff: 1,
+				cursor: 4, expectedCursor: 6,
+				text: []rune("a\nb\nc\nd\ne\nf\ng")},
+
+			{description: "scroll up with margin",
+				y: -1, margin: 1,
+				colOff: 0, expectedColOff: 0,
+				rowOff: 3, expectedRowOff: 2,
+				cursor: 8, expectedCursor: 6,
+				text: []rune("a\nb\nc\nd\ne\nf\ng")},
+
+			{description: "margin clamped at top",
+				y: -1, margin: 1,
+				colOff: 0, expectedColOff: 0,
+				rowOff: 0, expectedRowOff: 0,
+				cursor: 2, expectedCursor: 0,
+				text: []rune("a\nb\nc\nd\ne\nf\ng")},
+
+			{description: "margin clamped at bottom",
+				y: 1, margin: 1,
+				colOff: 0, expectedColOff: 0,
+				rowOff: 3, expectedRowOff: 3,
+				cursor: 10, expectedCursor: 12,
+				text: []rune("a\nb\nc\nd\ne\nf\ng")},
+
+			{description: "margin larger than half the view",
+				y: 1, margin: 5,
+				colOff: 0, expectedColOff: 0,
+				rowOff: 0, expectedRowOff: 1,
+				cursor: 4, expectedCursor: 6,
+				text: []rune("a\nb\nc\nd\ne\nf\ng")},
+
+			{description: "scroll right with side margin",
+				x: 1, sideMargin: 1,
+				colOff: 0, expectedColOff: 1,
+				rowOff: 0, expectedRowOff: 0,
+				cursor: 3, expectedCursor: 4,
+				text: []rune("abcdefghij")},
+
+			{description: "scroll left with side margin",
+				x: -1, sideMargin: 1,
+				colOff: 3, expectedColOff: 2,
+				rowOff: 0, expectedRowOff: 0,
+				cursor: 4, expectedCursor: 3,
+				text: []rune("abcdefghij")},
+
+			{description: "side margin clamped at line start",
+				x: -1, sideMargin: 1,
+				colOff: 0, expectedColOff: 0,
+				rowOff: 0, expectedRowOff: 0,
+				cursor: 1, expectedCursor: 0,
+				text: []rune("abcdefghij")},
+
+			{description: "side margin larger than half the view",
+				x: 1, sideMargin: 9,
+				colOff: 0, expectedColOff: 2,
+				rowOff: 0, expectedRowOff: 0,
+				cursor: 3, expectedCursor: 4,
+				text: []rune("abcdefghij")},
+
+			{description: "horizontal jump forwards with side margin",
+				x: -1, sideMargin: 1,
+				colOff: 0, expectedColOff: 5,
+				rowOff: 0, expectedRowOff: 0,
+				cursor: 9, expectedCursor: 8,
+				text: []rune("abcdefgh\nijk")},
 		}
 
 		e := NewEditor(EditorConfig{
@@ -172,6 +256,8 @@ func TestScroll(t *testing.T) {
 			e.RowOff = tc.rowOff
 			e.Cursor = tc.cursor
 			e.Text = tc.text
+			e.ScrollMargin = tc.margin
+			e.SideScrollMargin = tc.sideMargin
 
 			e.MoveCursor(tc.x, tc.y)
 
@@ -198,3 +284,663 @@ func TestScroll(t *testing.T) {
 		}
 	}
 }
+
+// Verify that incremental updates keep the editor's text identical to a full rebuild from the CRDT.
+func TestEditor_ApplyInsertDelete(t *testing.T) {
+	tests := []struct {
+		description string
+		insert      bool
+		position    int
+		value       string
+	}{
+		{"insert into empty", true, 1, "a"},
+		{"insert at end", true, 2, "c"},
+		{"insert in middle", true, 2, "b"},
+		{"insert at start", true, 1, "\n"},
+		{"insert wide rune", true, 3, "世"},
+		{"delete in middle", false, 3, ""},
+		{"delete at start", false, 1, ""},
+		{"delete at end", false, 3, ""},
+		{"insert after deletes", true, 3, "d"},
+	}
+
+	doc := crdt.New()
+	e := NewEditor(EditorConfig{})
+
+	for _, tc := range tests {
+		if tc.insert {
+			if _, err := doc.Insert(tc.position, tc.value); err != nil {
+				t.Fatalf("(%s) error: %v\n", tc.description, err)
+			}
+			e.ApplyInsert(tc.position-1, tc.value)
+		} else {
+			doc.Delete(tc.position)
+			e.ApplyDelete(tc.position-1, 1)
+		}
+
+		got := string(e.Text)
+		want := crdt.Content(doc)
+
+		if !cmp.Equal(got, want) {
+			t.Errorf("(%s) got != expected, diff: %v", tc.description, cmp.Diff(got, want))
+		}
+	}
+}
+
+func TestEditor_ApplyOutOfBounds(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	e.SetText("abc")
+
+	e.ApplyInsert(100, "d")
+	e.ApplyInsert(-5, "z")
+	e.ApplyDelete(2, 100)
+	e.ApplyDelete(-1, 1)
+
+	got := string(e.Text)
+	want := "za"
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got != expected, diff: %v", cmp.Diff(got, want))
+	}
+}
+
+func TestEditor_TypingDecay(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	start := time.Now()
+
+	e.MarkTyping("bob", start)
+	e.MarkTyping("alice", start.Add(time.Second))
+	e.MarkTyping("", start)
+
+	if got, want := e.Typing(start.Add(time.Second)), []string{"alice", "bob"}; !cmp.Equal(got, want) {
+		t.Errorf("typing (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	// bob goes quiet first.
+	if got, want := e.Typing(start.Add(TypingTimeout)), []string{"alice"}; !cmp.Equal(got, want) {
+		t.Errorf("typing after bob decays (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	// Typing again brings bob back.
+	e.MarkTyping("bob", start.Add(TypingTimeout))
+	if got, want := e.Typing(start.Add(TypingTimeout+time.Second)), []string{"bob"}; !cmp.Equal(got, want) {
+		t.Errorf("typing after alice decays (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	if got := e.Typing(start.Add(time.Hour)); len(got) != 0 {
+		t.Errorf("expected nobody typing, got %v", got)
+	}
+}
+
+func TestEditor_PruneTyping(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	now := time.Now()
+
+	e.MarkTyping("alice", now)
+	e.MarkTyping("bob", now)
+	e.PruneTyping([]string{"alice", "carol"})
+
+	if got, want := e.Typing(now), []string{"alice"}; !cmp.Equal(got, want) {
+		t.Errorf("typing after bob left (-want +got)\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestEditor_CurrentLineLength(t *testing.T) {
+	tests := []struct {
+		description string
+		text        string
+		cursor      int
+		expected    int
+	}{
+		{"empty", "", 0, 0},
+		{"ascii", "hello\nworld!", 2, 5},
+		{"second line", "hello\nworld!", 8, 6},
+		{"end of text", "hello\nworld!", 12, 6},
+		{"empty line", "a\n\nb", 2, 0},
+		{"wide runes", "ab世界c\nx", 1, 7},
+		{"mixed widths", "x\né世😀", 4, 5},
+		{"out of bounds", "abc", 100, 3},
+	}
+
+	e := NewEditor(EditorConfig{})
+
+	for _, tc := range tests {
+		e.Text = []rune(tc.text)
+		e.Cursor = tc.cursor
+
+		if got := e.CurrentLineLength(); got != tc.expected {
+			t.Errorf("%s: got length %d, expected %d", tc.description, got, tc.expected)
+		}
+	}
+}
+
+func TestEditor_ScreenXY(t *testing.T) {
+	tests := []struct {
+		description string
+		text        string
+		cursor      int
+		colOff      int
+		rowOff      int
+		expectedX   int
+		expectedY   int
+	}{
+		{"origin", "", 0, 0, 0, 0, 0},
+		{"start of text", "abc\ndef", 0, 0, 0, 0, 0},
+		{"end of first line", "abc\ndef", 3, 0, 0, 3, 0},
+		{"start of second line", "abc\ndef", 4, 0, 0, 0, 1},
+		{"scrolled down", "a\nb\nc\nd\ne", 8, 0, 1, 0, 3},
+		{"scrolled down to the cursor's row", "a\nb\nc\nd\ne", 2, 0, 1, 0, 0},
+		{"scrolled right", "abcdefgh", 8, 4, 0, 4, 0},
+		{"scrolled right to the cursor's column", "abcdefgh", 4, 4, 0, 0, 0},
+		{"cursor left of the view", "abcdefgh", 3, 4, 0, 0, 0},
+		{"cursor above the view", "a\nb\nc\nd\ne", 0, 0, 2, 0, 0},
+		{"cursor right of the view", "abcdefgh", 8, 0, 0, 4, 0},
+		{"cursor below the view", "a\nb\nc\nd\ne", 8, 0, 0, 0, 3},
+		{"wide runes", "世界x", 2, 0, 0, 4, 0},
+	}
+
+	e := NewEditor(EditorConfig{})
+	e.Width = 5
+	e.Height = 5
+
+	for _, tc := range tests {
+		e.Text = []rune(tc.text)
+		e.ColOff = tc.colOff
+		e.RowOff = tc.rowOff
+
+		x, y := e.screenXY(tc.cursor)
+		if x != tc.expectedX || y != tc.expectedY {
+			t.Errorf("(%s) got (%d, %d), expected (%d, %d)", tc.description, x, y, tc.expectedX, tc.expectedY)
+		}
+	}
+}
+
+func TestEditor_InfoText(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	e.Text = []rune("ab\ncd")
+	e.Cursor = 4
+
+	if got, want := e.infoText(len(e.Text)), "[no file]"; got != want {
+		t.Errorf("without a file: got %q, expected %q", got, want)
+	}
+
+	e.SetFileName("notes.txt")
+	if got, want := e.infoText(len(e.Text)), "notes.txt"; got != want {
+		t.Errorf("with a file: got %q, expected %q", got, want)
+	}
+
+	e = NewEditor(EditorConfig{DebugBar: true})
+	e.Text = []rune("ab\ncd")
+	e.Cursor = 4
+	e.SetFileName("notes.txt")
+
+	if got, want := e.infoText(len(e.Text)), "x=2, y=2, cursor=4, len(text)=5"; got != want {
+		t.Errorf("with the debug bar: got %q, expected %q", got, want)
+	}
+}
+
+func TestEditor_OverwriteText(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	if got := e.overwriteText(); got != "INS" {
+		t.Errorf("inserting: got %q, expected %q", got, "INS ")
+	}
+
+	e.SetOverwrite(true)
+	if got := e.overwriteText(); got != "OVR" {
+		t.Errorf("overwriting: got %q, expected %q", got, "OVR ")
+	}
+}
+
+func TestEditor_LatencyText(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+
+	if got := e.latencyText(); got != "" {
+		t.Errorf("while disconnected: got %q, expected none", got)
+	}
+
+	e.IsConnected = true
+	if got, want := e.latencyText(), "rtt=--"; got != want {
+		t.Errorf("before a measurement: got %q, expected %q", got, want)
+	}
+
+	e.SetLatency(42*time.Millisecond + 400*time.Microsecond)
+	if got, want := e.latencyText(), "rtt=42ms"; got != want {
+		t.Errorf("after a measurement: got %q, expected %q", got, want)
+	}
+
+	e.SetLatency(200 * time.Microsecond)
+	if got, want := e.latencyText(), "rtt=1ms"; got != want {
+		t.Errorf("under a millisecond: got %q, expected %q", got, want)
+	}
+}
+
+func TestEditor_LineStarts(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+
+	if got, want := e.lineStarts(), []int{0}; !cmp.Equal(got, want) {
+		t.Errorf("empty text (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	e.SetText("ab\nc\n")
+	if got, want := e.lineStarts(), []int{0, 3, 5}; !cmp.Equal(got, want) {
+		t.Errorf("after SetText (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	e.ApplyInsert(0, "\n")
+	if got, want := e.lineStarts(), []int{0, 1, 4, 6}; !cmp.Equal(got, want) {
+		t.Errorf("after ApplyInsert (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	e.ApplyDelete(3, 1)
+	if got, want := e.lineStarts(), []int{0, 1, 5}; !cmp.Equal(got, want) {
+		t.Errorf("after ApplyDelete (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	// Replacing the text outright is detected too.
+	e.Text = []rune("x\ny")
+	if got, want := e.lineStarts(), []int{0, 2}; !cmp.Equal(got, want) {
+		t.Errorf("after replacing Text (-want +got)\n%s", cmp.Diff(want, got))
+	}
+}
+
+func TestEditor_VisibleLines(t *testing.T) {
+	const lines = 10
+
+	e := NewEditor(EditorConfig{})
+
+	for height := 0; height <= 12; height++ {
+		for _, rowOff := range []int{0, 3, 9, 10} {
+			e.Height = height
+			e.RowOff = rowOff
+
+			start, end := e.visibleLines(lines)
+
+			// Exactly Height-1 rows show text, or fewer where the document ends.
+			want := max(0, min(height-1, lines-rowOff))
+			if got := end - start; got != want {
+				t.Errorf("height %d, row offset %d: got %d lines, expected %d", height, rowOff, got, want)
+			}
+			if start != rowOff {
+				t.Errorf("height %d, row offset %d: got first line %d, expected %d", height, rowOff, start, rowOff)
+			}
+
+			// The last line drawn must be above the status bar.
+			if lastRow := end - 1 - start; end > start && lastRow >= height-1 {
+				t.Errorf("height %d, row offset %d: line drawn on row %d, the status bar's", height, rowOff, lastRow)
+			}
+		}
+	}
+}
+
+func TestEditor_ScrollToEnd(t *testing.T) {
+	tests := []struct {
+		description    string
+		text           string
+		wantRowOff     int
+		wantColOff     int
+		wantX, wantY   int
+		scrollDisabled bool
+	}{
+		{"empty", "", 0, 0, 0, 0, false},
+		{"shorter than the view", "a\nb\nc", 0, 0, 1, 2, false},
+		{"exactly fills the view", "1\n2\n3\n4", 0, 0, 1, 3, false},
+		{"longer than the view", "1\n2\n3\n4\n5\n6\n7", 3, 0, 1, 3, false},
+		{"trailing newline", "1\n2\n3\n4\n5\n", 2, 0, 0, 3, false},
+		{"long last line", "1\n2\n3\n4\n5\n0123456789abc", 2, 4, 9, 3, false},
+		{"scrolling disabled", "1\n2\n3\n4\n5\n6\n7", 0, 0, 1, 3, true},
+	}
+
+	for _, tc := range tests {
+		// Four rows of text above the status bar, ten columns wide.
+		e := NewEditor(EditorConfig{ScrollEnabled: !tc.scrollDisabled})
+		e.SetSize(10, 5)
+		e.SetText(tc.text)
+
+		e.ScrollToEnd()
+
+		if e.Cursor != len([]rune(tc.text)) {
+			t.Errorf("(%s) cursor = %d, expected the end, %d", tc.description, e.Cursor, len([]rune(tc.text)))
+		}
+		if e.RowOff != tc.wantRowOff || e.ColOff != tc.wantColOff {
+			t.Errorf("(%s) offsets = (%d, %d), expected (%d, %d)", tc.description, e.RowOff, e.ColOff, tc.wantRowOff, tc.wantColOff)
+		}
+		if x, y := e.screenXY(e.Cursor); x != tc.wantX || y != tc.wantY {
+			t.Errorf("(%s) cursor drawn at (%d, %d), expected (%d, %d)", tc.description, x, y, tc.wantX, tc.wantY)
+		}
+	}
+}
+
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"Saved document to notes.txt", 40, "Saved document to notes.txt"},
+		{"Saved document to notes.txt", 27, "Saved document to notes.txt"},
+		{"Saved document to notes.txt", 26, "Saved document to notes.t…"},
+		{"Saved document to notes.txt", 10, "Saved doc…"},
+		{"Saved document to notes.txt", 2, "S…"},
+		{"Saved document to notes.txt", 1, "…"},
+		{"Saved document to notes.txt", 0, ""},
+		{"Saved document to notes.txt", -1, ""},
+		{"", 5, ""},
+
+		// Wide characters take two columns and aren't split.
+		{"保存しました", 12, "保存しました"},
+		{"保存しました", 11, "保存しまし…"},
+		{"保存しました", 10, "保存しま…"},
+		{"保存しました", 2, "…"},
+	}
+
+	for _, tc := range tests {
+		got := truncateWidth(tc.s, tc.width)
+		if got != tc.want {
+			t.Errorf("(%q, %d) got %q, expected %q", tc.s, tc.width, got, tc.want)
+		}
+		if w := runewidth.StringWidth(got); w > max(0, tc.width) {
+			t.Errorf("(%q, %d) got %q, %d columns wide", tc.s, tc.width, got, w)
+		}
+	}
+}
+
+func TestEditor_SelectionFollowsEdits(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	e.SetText("hello world")
+
+	// Select "world", backwards.
+	e.SetX(11)
+	e.StartSelection()
+	e.SetX(6)
+	e.ExtendSelection()
+
+	if got := e.SelectedText(); got != "world" {
+		t.Fatalf("selected %q, expected %q", got, "world")
+	}
+
+	// A peer's edits before the selection move it; edits inside it change what it covers.
+	e.ApplyInsert(0, ">> ")
+	e.ApplyDelete(10, 2)
+	e.ApplyInsert(14, "!")
+
+	if got := e.SelectedText(); got != "wld" {
+		t.Errorf("selected %q after edits, expected %q", got, "wld")
+	}
+	if start, end := e.Selection(); start != 9 || end != 12 {
+		t.Errorf("selection = [%d, %d), expected [9, 12)", start, end)
+	}
+
+	e.ClearSelection()
+	if e.IsSelecting() || e.SelectedText() != "" {
+		t.Errorf("selection not cleared: selecting = %v, selected %q", e.IsSelecting(), e.SelectedText())
+	}
+}
+
+func TestEditor_GotoLine(t *testing.T) {
+	tests := []struct {
+		description string
+		line, col   int
+		wantCursor  int
+		wantRowOff  int
+	}{
+		{"start", 1, 1, 0, 0},
+		{"line only", 3, 0, 5, 0},
+		{"line and column", 2, 2, 4, 0},
+		{"column past the end of the line", 2, 10, 4, 0},
+		{"scrolls down", 7, 1, 13, 3},
+		{"line past the end", 100, 1, 15, 4},
+		{"line before the start", -3, 2, 1, 0},
+	}
+
+	for _, tc := range tests {
+		// Four rows of text above the status bar.
+		e := NewEditor(EditorConfig{ScrollEnabled: true})
+		e.SetSize(10, 5)
+		e.SetText("ab\nc\nd\ne\nf\ng\nh\ni")
+
+		e.GotoLine(tc.line, tc.col)
+
+		if e.Cursor != tc.wantCursor {
+			t.Errorf("(%s) cursor = %d, expected %d", tc.description, e.Cursor, tc.wantCursor)
+		}
+		if e.RowOff != tc.wantRowOff {
+			t.Errorf("(%s) row offset = %d, expected %d", tc.description, e.RowOff, tc.wantRowOff)
+		}
+	}
+}
+
+func TestPercentLine(t *testing.T) {
+	tests := []struct {
+		p     float64
+		lines int
+		want  int
+	}{
+		{0, 101, 0},
+		{50, 101, 50},
+		{100, 101, 100},
+		{50, 10, 5},
+		{33.3, 4, 1},
+		{150, 10, 9},
+		{-20, 10, 0},
+		{math.NaN(), 10, 0},
+		{50, 1, 0},
+		{50, 0, 0},
+	}
+
+	for _, tc := range tests {
+		if got := percentLine(tc.p, tc.lines); got != tc.want {
+			t.Errorf("percentLine(%v, %d) = %d, expected %d", tc.p, tc.lines, got, tc.want)
+		}
+	}
+}
+
+func TestEditor_GotoPercent(t *testing.T) {
+	tests := []struct {
+		description string
+		text        string
+		p           float64
+		wantCursor  int
+		wantRowOff  int
+	}{
+		{"start", "ab\nc\nd\ne\nf\ng\nh\ni", 0, 0, 0},
+		{"middle", "ab\nc\nd\ne\nf\ng\nh\ni", 50, 9, 1},
+		{"end", "ab\nc\nd\ne\nf\ng\nh\ni", 100, 15, 4},
+		{"past the end", "ab\nc\nd\ne\nf\ng\nh\ni", 200, 15, 4},
+		{"empty document", "", 50, 0, 0},
+	}
+
+	for _, tc := range tests {
+		// Four rows of text above the status bar.
+		e := NewEditor(EditorConfig{ScrollEnabled: true})
+		e.SetSize(10, 5)
+		e.SetText(tc.text)
+		e.SetX(3)
+
+		e.GotoPercent(tc.p)
+
+		if e.Cursor != tc.wantCursor {
+			t.Errorf("(%s) cursor = %d, expected %d", tc.description, e.Cursor, tc.wantCursor)
+		}
+		if e.RowOff != tc.wantRowOff {
+			t.Errorf("(%s) row offset = %d, expected %d", tc.description, e.RowOff, tc.wantRowOff)
+		}
+	}
+}
+
+func TestRuneWidth_ControlCharacters(t *testing.T) {
+	tests := []struct {
+		r         rune
+		wantGlyph string
+		wantWidth int
+	}{
+		{'a', "", 1},
+		{'保', "", 2},
+		{'\n', "", 0},
+		{0x00, "^@", 2},
+		{0x07, "^G", 2},
+		{'\t', "^I", 2},
+		{'\r', "^M", 2},
+		{0x1b, "^[", 2},
+		{0x1f, "^_", 2},
+		{0x7f, "^?", 2},
+		{0x85, "�", 1},
+		{0xa0, "", 1},
+	}
+
+	for _, tc := range tests {
+		if got := controlGlyph(tc.r); got != tc.wantGlyph {
+			t.Errorf("controlGlyph(%U) = %q, expected %q", tc.r, got, tc.wantGlyph)
+		}
+		if got := runeWidth(tc.r); got != tc.wantWidth {
+			t.Errorf("runeWidth(%U) = %d, expected %d", tc.r, got, tc.wantWidth)
+		}
+	}
+}
+
+func TestEditor_CalcXYControlCharacters(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	e.SetText("a\x07b\n\x00保c")
+
+	tests := []struct {
+		index        int
+		wantX, wantY int
+	}{
+		{1, 2, 1},
+		{2, 4, 1},
+		{3, 5, 1},
+		{5, 3, 2},
+		{6, 5, 2},
+		{7, 6, 2},
+	}
+	for _, tc := range tests {
+		if x, y := e.calcXY(tc.index); x != tc.wantX || y != tc.wantY {
+			t.Errorf("calcXY(%d) = (%d, %d), expected (%d, %d)", tc.index, x, y, tc.wantX, tc.wantY)
+		}
+	}
+
+	e.SetX(5)
+	if got := e.CurrentLineLength(); got != 5 {
+		t.Errorf("current line length = %d, expected 5", got)
+	}
+}
+
+func TestScrollToShow(t *testing.T) {
+	tests := []struct {
+		description      string
+		rowOff, colOff   int
+		x, y             int
+		wantRow, wantCol int
+	}{
+		{"already in view", 0, 0, 3, 2, 0, 0},
+		{"in a scrolled view", 10, 5, 7, 12, 10, 5},
+		{"last row of the view", 0, 0, 0, 3, 0, 0},
+		{"below the view", 0, 0, 0, 4, 2, 0},
+		{"far below the view", 0, 0, 0, 50, 48, 0},
+		{"above the view", 20, 0, 0, 5, 3, 0},
+		{"near the start", 20, 0, 0, 1, 0, 0},
+		{"right of the view", 0, 0, 10, 0, 0, 5},
+		{"left of the view", 0, 8, 3, 0, 0, 0},
+		{"both", 0, 0, 30, 30, 28, 25},
+	}
+
+	for _, tc := range tests {
+		// A view of four rows and ten columns.
+		row, col := scrollToShow(tc.rowOff, tc.colOff, tc.x, tc.y, 4, 10)
+		if row != tc.wantRow || col != tc.wantCol {
+			t.Errorf("(%s) offsets = (%d, %d), expected (%d, %d)", tc.description, row, col, tc.wantRow, tc.wantCol)
+		}
+	}
+}
+
+func TestEditor_ShowPosition(t *testing.T) {
+	e := NewEditor(EditorConfig{ScrollEnabled: true})
+	e.SetSize(10, 5)
+	e.SetText("1\n2\n3\n4\n5\n6\n7\n8\n9")
+
+	// Showing the 8th line centers it, leaving the cursor where it was.
+	e.ShowPosition(14)
+	if e.RowOff != 5 || e.Cursor != 0 {
+		t.Errorf("row offset = %d and cursor = %d, expected 5 and 0", e.RowOff, e.Cursor)
+	}
+
+	// A line already in view doesn't scroll.
+	e.ShowPosition(16)
+	if e.RowOff != 5 {
+		t.Errorf("row offset = %d, expected it to stay 5", e.RowOff)
+	}
+}
+
+func TestMinimapRow(t *testing.T) {
+	tests := []struct {
+		description string
+		line, lines int
+		want        int
+	}{
+		{"first line", 0, 10, 0},
+		{"same row as the first", 2, 10, 0},
+		{"next row", 3, 10, 1},
+		{"last line of a short document", 9, 10, 3},
+		{"long document, first row", 19, 100, 0},
+		{"long document, second row", 20, 100, 1},
+		{"long document, last line", 99, 100, 4},
+		{"document that just fits", 14, 15, 4},
+	}
+
+	for _, tc := range tests {
+		// A minimap five rows high.
+		if got := minimapRow(tc.line, tc.lines, 5); got != tc.want {
+			t.Errorf("(%s) row = %d, expected %d", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestMinimapShade(t *testing.T) {
+	tests := []struct {
+		description string
+		chars       int
+		want        rune
+	}{
+		{"empty", 0, ' '},
+		{"a character", 1, '░'},
+		{"half full", 15, '▒'},
+		{"nearly full", 29, '█'},
+		{"full", 30, '█'},
+	}
+
+	for _, tc := range tests {
+		// Rows of three lines of ten columns.
+		if got := minimapShade(tc.chars, 3, 10); got != tc.want {
+			t.Errorf("(%s) shade = %q, expected %q", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestEditor_MinimapNarrowsText(t *testing.T) {
+	e := NewEditor(EditorConfig{ScrollEnabled: true})
+	e.SetSize(10, 5)
+	e.SetText("abcdefghij")
+	e.Cursor = 9
+
+	if got := e.textCols(); got != 10 {
+		t.Errorf("without the minimap: columns = %d, expected 10", got)
+	}
+
+	// The last columns go to the minimap, so the end of the line scrolls into view.
+	e.SetMinimap(true)
+	if got := e.textCols(); got != 10-minimapWidth {
+		t.Errorf("with the minimap: columns = %d, expected %d", got, 10-minimapWidth)
+	}
+	e.MoveCursor(0, 0)
+	if x, _ := e.screenXY(e.Cursor); x >= e.textCols() {
+		t.Errorf("cursor drawn at column %d, under the minimap", x)
+	}
+}
+
+func TestTitleText(t *testing.T) {
+	if got := titleText(""); got != "" {
+		t.Errorf("without a title: got %q, expected none", got)
+	}
+	if got, want := titleText("Release notes"), "[Release notes]"; got != want {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package editor
+
+// minimapWidth is how many columns the minimap takes at the right of the text area.
+const minimapWidth = 2
+
+// minimapLines is the fewest lines a row of the minimap stands for; long documents put more in each row so they fit.
+const minimapLines = 3
+
+// minimapShades are the minimap's cells from empty to full, by how much text the lines they stand for hold.
+var minimapShades = []rune{' ', '░', '▒', '▓', '█'}
+
+// SetMinimap shows or hides the minimap, a zoomed-out view of the whole document along the right of the text area.
+func (e *Editor) SetMinimap(show bool) {
+	e.StatusMu.Lock()
+	e.Minimap = show
+	e.StatusMu.Unlock()
+}
+
+// IsMinimap reports whether the minimap is shown.
+func (e *Editor) IsMinimap() bool {
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
+	return e.Minimap
+}
+
+// textCols returns how many screen columns show text: all but the minimap's, while it's shown.
+func (e *Editor) textCols() int {
+	if e.IsMinimap() {
+		return max(0, e.GetWidth()-minimapWidth)
+	}
+	return e.GetWidth()
+}
+
+// minimapScale returns how many lines each row of a minimap rows high stands for, for a document of the given number of lines.
+func minimapScale(lines, rows int) int {
+	if rows <= 0 {
+		return minimapLines
+	}
+	return max(minimapLines, (lines+rows-1)/rows)
+}
+
+// minimapRow returns the row of a minimap rows high that line, counted from 0, of a document of the given number of lines is in.
+func minimapRow(line, lines, rows int) int {
+	return line / minimapScale(lines, rows)
+}
+
+// minimapShade returns the cell for a minimap row whose lines hold chars characters,
+// out of the scale lines of cols columns it stands for. Any text at all shows.
+func minimapShade(chars, scale, cols int) rune {
+	capacity := scale * max(1, cols)
+	steps := len(minimapShades) - 1
+	level := min(steps, (chars*steps+capacity-1)/capacity)
+	return minimapShades[level]
+}
+
+// drawMinimap draws the minimap along the right of the text area, for a text of length runes whose lines start at starts.
+// Only line lengths are used, which the line index already has, so it costs a pass over the lines rather than the text.
+// It's drawn in theme's colors, with the rows standing for the lines in view highlighted.
+func (e *Editor) drawMinimap(starts []int, length int, theme Theme) {
+	rows, cols := e.textRows(), e.textCols()
+	scale := minimapScale(len(starts), rows)
+
+	viewStart, viewEnd := e.visibleLines(len(starts))
+	firstInView, lastInView := minimapRow(viewStart, len(starts), rows), minimapRow(max(viewStart, viewEnd-1), len(starts), rows)
+
+	for y := 0; y < rows; y++ {
+		chars := 0
+		for line := y * scale; line < min(len(starts), (y+1)*scale); line++ {
+			end := length
+			if line+1 < len(starts) {
+				// Leave out the newline ending the line.
+				end = starts[line+1] - 1
+			}
+			chars += min(cols, end-starts[line])
+		}
+
+		shade := ' '
+		if y*scale < len(starts) {
+			shade = minimapShade(chars, scale, cols)
+		}
+		bg := theme.Minimap.Bg
+		if y >= firstInView && y <= lastInView {
+			bg = theme.MinimapView
+		}
+		for x := cols; x < cols+minimapWidth; x++ {
+			e.screen.SetCell(x, y, shade, theme.Minimap.Fg, bg)
+		}
+	}
+}
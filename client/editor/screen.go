@@ -0,0 +1,120 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Screen is the grid of cells the editor draws itself on, with a cursor.
+// Drawing goes through it rather than straight to termbox, so what would be shown can be checked without a terminal.
+type Screen interface {
+	// Clear fills every cell with a space in the given colors.
+	Clear(fg, bg termbox.Attribute)
+
+	// SetCell draws r at column x of row y; cells off the screen are ignored.
+	SetCell(x, y int, r rune, fg, bg termbox.Attribute)
+
+	// SetCursor shows the cursor at column x of row y.
+	SetCursor(x, y int)
+
+	// HideCursor hides the cursor.
+	HideCursor()
+
+	// Flush shows what was drawn since the last Flush.
+	Flush()
+}
+
+// TermboxScreen draws on the terminal through termbox, which must be initialized first.
+type TermboxScreen struct{}
+
+func (TermboxScreen) Clear(fg, bg termbox.Attribute) {
+	_ = termbox.Clear(fg, bg)
+}
+
+func (TermboxScreen) SetCell(x, y int, r rune, fg, bg termbox.Attribute) {
+	termbox.SetCell(x, y, r, fg, bg)
+}
+
+func (TermboxScreen) SetCursor(x, y int) {
+	termbox.SetCursor(x, y)
+}
+
+func (TermboxScreen) HideCursor() {
+	termbox.HideCursor()
+}
+
+func (TermboxScreen) Flush() {
+	_ = termbox.Flush()
+}
+
+// Cell is what's drawn in one cell of a MemScreen.
+type Cell struct {
+	Ch     rune
+	Fg, Bg termbox.Attribute
+}
+
+// MemScreen keeps what's drawn in memory, e.g. for tests to check what the editor would show.
+type MemScreen struct {
+	// Width and Height are the screen's size in cells.
+	Width, Height int
+
+	// Cells holds the cells row by row.
+	Cells []Cell
+
+	// CursorX and CursorY are where the cursor is, or -1 while it's hidden.
+	CursorX, CursorY int
+
+	// Flushes counts the calls to Flush.
+	Flushes int
+}
+
+// NewMemScreen returns a blank screen of width by height cells, with the cursor hidden.
+func NewMemScreen(width, height int) *MemScreen {
+	s := &MemScreen{Width: width, Height: height, Cells: make([]Cell, width*height)}
+	s.Clear(termbox.ColorDefault, termbox.ColorDefault)
+	s.HideCursor()
+	return s
+}
+
+func (s *MemScreen) Clear(fg, bg termbox.Attribute) {
+	for i := range s.Cells {
+		s.Cells[i] = Cell{' ', fg, bg}
+	}
+}
+
+func (s *MemScreen) SetCell(x, y int, r rune, fg, bg termbox.Attribute) {
+	if x < 0 || x >= s.Width || y < 0 || y >= s.Height {
+		return
+	}
+	s.Cells[y*s.Width+x] = Cell{r, fg, bg}
+}
+
+func (s *MemScreen) SetCursor(x, y int) {
+	s.CursorX, s.CursorY = x, y
+}
+
+func (s *MemScreen) HideCursor() {
+	s.CursorX, s.CursorY = -1, -1
+}
+
+func (s *MemScreen) Flush() {
+	s.Flushes++
+}
+
+// Cell returns the cell at column x of row y, or a zero Cell off the screen.
+func (s *MemScreen) Cell(x, y int) Cell {
+	if x < 0 || x >= s.Width || y < 0 || y >= s.Height {
+		return Cell{}
+	}
+	return s.Cells[y*s.Width+x]
+}
+
+// Row returns the characters of row y, without trailing spaces.
+func (s *MemScreen) Row(y int) string {
+	var b strings.Builder
+	for x := 0; x < s.Width; x++ {
+		b.WriteRune(s.Cell(x, y).Ch)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
@@ -0,0 +1,191 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nsf/termbox-go"
+)
+
+// newScreenEditor returns an editor of width by height cells holding text, drawn on the screen it returns.
+func newScreenEditor(width, height int, text string) (*Editor, *MemScreen) {
+	screen := NewMemScreen(width, height)
+	e := NewEditor(EditorConfig{ScrollEnabled: true, Screen: screen})
+	e.SetSize(width, height)
+	e.SetText(text)
+	return e, screen
+}
+
+// rows returns the first n rows of screen.
+func rows(screen *MemScreen, n int) []string {
+	var got []string
+	for y := 0; y < n; y++ {
+		got = append(got, screen.Row(y))
+	}
+	return got
+}
+
+func TestDraw_Text(t *testing.T) {
+	tests := []struct {
+		description        string
+		text               string
+		rowOff, colOff     int
+		cursor             int
+		want               []string
+		wantCurX, wantCurY int
+	}{
+		{"lines", "hello\nworld", 0, 0, 8, []string{"hello", "world", ""}, 2, 1},
+		{"scrolled down", "one\ntwo\nthree\nfour", 2, 0, 10, []string{"three", "four", ""}, 2, 0},
+		{"scrolled right", "hello\nworld", 0, 2, 4, []string{"llo", "rld", ""}, 2, 0},
+		{"control characters", "a\tb\x7f", 0, 0, 0, []string{"a^Ib^?", "", ""}, 0, 0},
+		{"wide characters", "世界x", 0, 0, 2, []string{"世 界 x", "", ""}, 4, 0},
+	}
+	for _, tc := range tests {
+		e, screen := newScreenEditor(10, 4, tc.text)
+		e.RowOff, e.ColOff = tc.rowOff, tc.colOff
+		e.SetX(tc.cursor)
+
+		e.Draw()
+
+		// Wide characters take two cells, the second of which is left blank.
+		got := rows(screen, 3)
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("(%s) got != want, diff: %v", tc.description, cmp.Diff(got, tc.want))
+		}
+		if screen.CursorX != tc.wantCurX || screen.CursorY != tc.wantCurY {
+			t.Errorf("(%s) got cursor at (%d, %d), want (%d, %d)", tc.description, screen.CursorX, screen.CursorY, tc.wantCurX, tc.wantCurY)
+		}
+		if screen.Flushes != 1 {
+			t.Errorf("(%s) got %d flushes, want 1", tc.description, screen.Flushes)
+		}
+	}
+}
+
+func TestDraw_Selection(t *testing.T) {
+	e, screen := newScreenEditor(10, 3, "abcdef")
+	e.SelectionStart, e.SelectionEnd = 4, 2
+
+	e.Draw()
+
+	for x := 0; x < 6; x++ {
+		want := DefaultTheme.Text
+		if x >= 2 && x < 4 {
+			want = DefaultTheme.Selection
+		}
+		if got := screen.Cell(x, 0); got.Fg != want.Fg || got.Bg != want.Bg {
+			t.Errorf("column %d: got colors %v/%v, want %v/%v", x, got.Fg, got.Bg, want.Fg, want.Bg)
+		}
+	}
+}
+
+func TestDraw_LongLine(t *testing.T) {
+	screen := NewMemScreen(40, 4)
+	e := NewEditor(EditorConfig{LongLineLimit: 3, Screen: screen})
+	e.SetSize(40, 4)
+	e.SetText("ab\nabcdef")
+	e.SetX(5)
+
+	e.Draw()
+
+	if got := screen.Cell(0, 0); got.Fg != DefaultTheme.Text.Fg {
+		t.Errorf("short line: got foreground %v, want %v", got.Fg, DefaultTheme.Text.Fg)
+	}
+	if got := screen.Cell(0, 1); got.Fg != DefaultTheme.LongLine.Fg {
+		t.Errorf("long line: got foreground %v, want %v", got.Fg, DefaultTheme.LongLine.Fg)
+	}
+	if row := screen.Row(3); !strings.Contains(row, "line=6") {
+		t.Errorf("info bar %q doesn't show the line's length", row)
+	}
+}
+
+func TestDraw_Panel(t *testing.T) {
+	e, screen := newScreenEditor(20, 4, "document")
+	e.SetColoredPanel([]string{"help", "more help"}, []termbox.Attribute{termbox.ColorGreen})
+
+	e.Draw()
+
+	if got, want := rows(screen, 3), []string{"help", "more help", ""}; !cmp.Equal(got, want) {
+		t.Errorf("got != want, diff: %v", cmp.Diff(got, want))
+	}
+	if got := screen.Cell(0, 0).Fg; got != termbox.ColorGreen {
+		t.Errorf("first line: got foreground %v, want green", got)
+	}
+	if got := screen.Cell(0, 1).Fg; got != DefaultTheme.Text.Fg {
+		t.Errorf("second line: got foreground %v, want the text's", got)
+	}
+	if screen.CursorX != -1 {
+		t.Errorf("got cursor at (%d, %d), want it hidden", screen.CursorX, screen.CursorY)
+	}
+}
+
+func TestDrawStatusBar(t *testing.T) {
+	tests := []struct {
+		description string
+		prompt      string
+		msg         string
+		want        string
+		wantCurX    int
+	}{
+		{"prompt", "Find: ab", "", "Find: ab", 8},
+		{"message", "", "saved!", "saved!", -1},
+		{"long message", "", "a message far too long for the bar", "a message…", -1},
+	}
+	for _, tc := range tests {
+		e, screen := newScreenEditor(11, 3, "")
+		e.Prompt = tc.prompt
+		e.StatusMsg, e.ShowMsg = tc.msg, tc.msg != ""
+
+		e.DrawStatusBar()
+
+		if got := screen.Row(2); got != tc.want {
+			t.Errorf("(%s) got status bar %q, want %q", tc.description, got, tc.want)
+		}
+		if screen.CursorX != tc.wantCurX {
+			t.Errorf("(%s) got cursor at column %d, want %d", tc.description, screen.CursorX, tc.wantCurX)
+		}
+		if got := screen.Cell(10, 2).Bg; got != DefaultTheme.Disconnected {
+			t.Errorf("(%s) got connection indicator %v, want %v", tc.description, got, DefaultTheme.Disconnected)
+		}
+	}
+}
+
+func TestDraw_Minimap(t *testing.T) {
+	e, screen := newScreenEditor(10, 3, strings.Repeat("xxxxxxxx\n", 3))
+	e.SetMinimap(true)
+
+	e.Draw()
+
+	// The text stops short of the minimap's two columns.
+	if got := screen.Row(0); got != "xxxxxxxx██" {
+		t.Errorf("got row %q, want the text then a full minimap row", got)
+	}
+	if got := screen.Cell(8, 0); got.Bg != DefaultTheme.MinimapView {
+		t.Errorf("minimap row in view: got background %v, want %v", got.Bg, DefaultTheme.MinimapView)
+	}
+}
+
+func TestMemScreen(t *testing.T) {
+	s := NewMemScreen(3, 2)
+
+	// Cells off the screen are ignored, like termbox does.
+	s.SetCell(-1, 0, 'x', termbox.ColorRed, termbox.ColorDefault)
+	s.SetCell(3, 0, 'x', termbox.ColorRed, termbox.ColorDefault)
+	s.SetCell(0, 2, 'x', termbox.ColorRed, termbox.ColorDefault)
+	s.SetCell(1, 1, 'y', termbox.ColorRed, termbox.ColorBlue)
+
+	if got, want := rows(s, 2), []string{"", " y"}; !cmp.Equal(got, want) {
+		t.Errorf("got != want, diff: %v", cmp.Diff(got, want))
+	}
+	if got, want := s.Cell(1, 1), (Cell{'y', termbox.ColorRed, termbox.ColorBlue}); got != want {
+		t.Errorf("got cell %+v, want %+v", got, want)
+	}
+	if got := s.Cell(5, 5); got != (Cell{}) {
+		t.Errorf("got cell %+v off the screen, want a zero cell", got)
+	}
+
+	s.Clear(termbox.ColorWhite, termbox.ColorBlack)
+	if got, want := s.Cell(1, 1), (Cell{' ', termbox.ColorWhite, termbox.ColorBlack}); got != want {
+		t.Errorf("after Clear: got cell %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,262 @@
+package editor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+// DefaultStatusTemplate is the info bar's layout unless another is configured.
+const DefaultStatusTemplate = "{mode} {title} {overwrite} {users} {lock} {typing} {file}, line={line} {latency}"
+
+// statusSegments describes the segments a status template can show, as {name}.
+// Segments with nothing to show, like {title} in a room without one, are left out along with the space after them.
+var statusSegments = map[string]string{
+	"mode":      "the modal editor's mode, e.g. -- NORMAL --",
+	"title":     "the room's title, in brackets",
+	"overwrite": "INS or OVR, as Insert toggles typing over text",
+	"users":     "the users in the room, each in their color",
+	"lock":      "who holds the write lock",
+	"typing":    "who's typing",
+	"file":      "the file's name, or with -debugbar the cursor position and text length",
+	"pos":       "the cursor's line and column",
+	"length":    "how many characters the document has",
+	"line":      "the current line's length, flagged when it's over -long-line",
+	"latency":   "the round-trip time to the server",
+}
+
+// StatusSegments returns the names of the segments a status template can use, sorted.
+func StatusSegments() []string {
+	names := make([]string, 0, len(statusSegments))
+	for name := range statusSegments {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// statusRun is a piece of the info bar drawn in one foreground color.
+type statusRun struct {
+	text string
+	fg   termbox.Attribute
+}
+
+// templatePart is a piece of a status template: literal text, or the name of a segment.
+type templatePart struct {
+	text    string
+	segment bool
+}
+
+// parseStatusTemplate splits a status template into literal text and {segment} names.
+// "{{" stands for a literal brace; braces not around a name are kept as they are.
+func parseStatusTemplate(tmpl string) []templatePart {
+	var parts []templatePart
+	var literal strings.Builder
+	for len(tmpl) > 0 {
+		if strings.HasPrefix(tmpl, "{{") {
+			literal.WriteByte('{')
+			tmpl = tmpl[2:]
+			continue
+		}
+		if tmpl[0] == '{' {
+			if end := strings.IndexByte(tmpl, '}'); end > 1 && isSegmentName(tmpl[1:end]) {
+				if literal.Len() > 0 {
+					parts = append(parts, templatePart{text: literal.String()})
+					literal.Reset()
+				}
+				parts = append(parts, templatePart{text: tmpl[1:end], segment: true})
+				tmpl = tmpl[end+1:]
+				continue
+			}
+		}
+		literal.WriteByte(tmpl[0])
+		tmpl = tmpl[1:]
+	}
+	if literal.Len() > 0 {
+		parts = append(parts, templatePart{text: literal.String()})
+	}
+	return parts
+}
+
+// isSegmentName reports whether s could name a segment: lowercase letters only.
+func isSegmentName(s string) bool {
+	for _, r := range s {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateStatusTemplate returns an error naming the first segment of tmpl that doesn't exist, so a typo is caught at startup.
+func ValidateStatusTemplate(tmpl string) error {
+	for _, part := range parseStatusTemplate(tmpl) {
+		if _, ok := statusSegments[part.text]; part.segment && !ok {
+			return fmt.Errorf("unknown status segment {%s}, expected one of {%s}", part.text, strings.Join(StatusSegments(), "}, {"))
+		}
+	}
+	return nil
+}
+
+// renderStatusTemplate fills in a status template, with segment returning what each segment shows
+// and literal text drawn in fg. An empty segment takes the space after it along, so optional segments leave no gaps.
+// Unknown segments are shown as written.
+func renderStatusTemplate(tmpl string, fg termbox.Attribute, segment func(name string) []statusRun) []statusRun {
+	var runs []statusRun
+	skipSpace := false
+	for _, part := range parseStatusTemplate(tmpl) {
+		if !part.segment {
+			text := part.text
+			if skipSpace {
+				text = strings.TrimPrefix(text, " ")
+			}
+			skipSpace = false
+			if text != "" {
+				runs = append(runs, statusRun{text, fg})
+			}
+			continue
+		}
+
+		if _, ok := statusSegments[part.text]; !ok {
+			runs = append(runs, statusRun{"{" + part.text + "}", fg})
+			skipSpace = false
+			continue
+		}
+		segRuns := segment(part.text)
+		skipSpace = true
+		for _, run := range segRuns {
+			if run.text != "" {
+				runs = append(runs, run)
+				skipSpace = false
+			}
+		}
+	}
+	return runs
+}
+
+// truncateRuns shortens runs to fit in width terminal columns, ending them with an ellipsis if anything was cut,
+// like truncateWidth does for a single string.
+func truncateRuns(runs []statusRun, width int) []statusRun {
+	total := 0
+	for _, run := range runs {
+		total += runewidth.StringWidth(run.text)
+	}
+	if total <= width {
+		return runs
+	}
+	if width <= 0 {
+		return nil
+	}
+
+	const ellipsis = "…"
+	room := width - runewidth.StringWidth(ellipsis)
+	var out []statusRun
+	for _, run := range runs {
+		if room <= 0 {
+			break
+		}
+		var b strings.Builder
+		for _, r := range run.text {
+			w := runewidth.RuneWidth(r)
+			if w > room {
+				room = 0
+				break
+			}
+			b.WriteRune(r)
+			room -= w
+		}
+		if b.Len() > 0 {
+			out = append(out, statusRun{b.String(), run.fg})
+		}
+	}
+	// The ellipsis continues the color of the text it cuts short.
+	fg := runs[0].fg
+	if len(out) > 0 {
+		fg = out[len(out)-1].fg
+	}
+	return append(out, statusRun{ellipsis, fg})
+}
+
+// SetStatusTemplate changes the info bar's layout; "" restores the default.
+func (e *Editor) SetStatusTemplate(tmpl string) {
+	e.StatusMu.Lock()
+	e.StatusTemplate = tmpl
+	e.StatusMu.Unlock()
+}
+
+// statusRuns returns the info bar's content, laid out by the status template, in theme's colors.
+func (e *Editor) statusRuns(theme Theme) []statusRun {
+	e.StatusMu.Lock()
+	users := e.Users
+	lockHolder := e.LockHolder
+	mode := e.Mode
+	title := e.Title
+	tmpl := e.StatusTemplate
+	e.StatusMu.Unlock()
+	if tmpl == "" {
+		tmpl = DefaultStatusTemplate
+	}
+
+	fg := theme.Status.Fg
+	segment := func(name string) []statusRun {
+		switch name {
+		case "mode":
+			if mode == "" {
+				return nil
+			}
+			return []statusRun{{fmt.Sprintf("-- %s --", mode), theme.Mode}}
+		case "title":
+			return []statusRun{{titleText(title), theme.Title}}
+		case "overwrite":
+			// Overwriting stands out, as it's easy to lose text to by accident.
+			if e.IsOverwrite() {
+				return []statusRun{{e.overwriteText(), fg | termbox.AttrBold}}
+			}
+			return []statusRun{{e.overwriteText(), fg}}
+		case "users":
+			var runs []statusRun
+			for i, user := range users {
+				if i > 0 {
+					runs = append(runs, statusRun{" ", fg})
+				}
+				runs = append(runs, statusRun{user, theme.userColor(user)})
+			}
+			return runs
+		case "lock":
+			if lockHolder == "" {
+				return nil
+			}
+			return []statusRun{{fmt.Sprintf("[lock: %s]", lockHolder), theme.Lock}}
+		case "typing":
+			if typing := e.Typing(time.Now()); len(typing) > 0 {
+				return []statusRun{{fmt.Sprintf("%s typing...", strings.Join(typing, ", ")), fg}}
+			}
+			return nil
+		case "file":
+			return []statusRun{{e.infoText(len(e.GetText())), fg}}
+		case "pos":
+			e.mu.RLock()
+			cursor := e.Cursor
+			e.mu.RUnlock()
+			cx, cy := e.calcXY(cursor)
+			return []statusRun{{fmt.Sprintf("%d:%d", cy, cx), fg}}
+		case "length":
+			return []statusRun{{fmt.Sprint(len(e.GetText())), fg}}
+		case "line":
+			// Show the current line's length, in the warning color if it's over the limit.
+			lineLength := e.CurrentLineLength()
+			if e.LongLineLimit > 0 && lineLength > e.LongLineLimit {
+				return []statusRun{{fmt.Sprint(lineLength), theme.Warning}}
+			}
+			return []statusRun{{fmt.Sprint(lineLength), fg}}
+		case "latency":
+			return []statusRun{{e.latencyText(), fg}}
+		}
+		return nil
+	}
+	return renderStatusTemplate(tmpl, fg, segment)
+}
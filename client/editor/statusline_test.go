@@ -0,0 +1,133 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nsf/termbox-go"
+)
+
+// runsText joins the text of runs.
+func runsText(runs []statusRun) string {
+	var b strings.Builder
+	for _, run := range runs {
+		b.WriteString(run.text)
+	}
+	return b.String()
+}
+
+func TestRenderStatusTemplate(t *testing.T) {
+	segments := map[string]string{"file": "notes.txt", "pos": "2:5", "latency": "rtt=42ms"}
+	segment := func(name string) []statusRun {
+		if text := segments[name]; text != "" {
+			return []statusRun{{text, termbox.ColorRed}}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		description string
+		tmpl        string
+		want        string
+	}{
+		{"segments and text", "{file} at {pos}", "notes.txt at 2:5"},
+		{"empty segment takes its space", "{mode} {file} {lock} {latency}", "notes.txt rtt=42ms"},
+		{"empty segment before other text", "{lock} | {file}", "| notes.txt"},
+		{"only literal text", "hello", "hello"},
+		{"escaped brace", "{{file} {file}", "{file} notes.txt"},
+		{"unknown segment", "{nope} {file}", "{nope} notes.txt"},
+		{"not a segment name", "{File} {1}", "{File} {1}"},
+		{"unclosed brace", "{file", "{file"},
+		{"empty", "", ""},
+	}
+	for _, tc := range tests {
+		got := runsText(renderStatusTemplate(tc.tmpl, termbox.ColorWhite, segment))
+		if got != tc.want {
+			t.Errorf("(%s) renderStatusTemplate(%q) = %q, want %q", tc.description, tc.tmpl, got, tc.want)
+		}
+	}
+}
+
+func TestRenderStatusTemplate_Colors(t *testing.T) {
+	segment := func(name string) []statusRun {
+		return []statusRun{{name, termbox.ColorRed}}
+	}
+
+	got := renderStatusTemplate("[{file}]", termbox.ColorWhite, segment)
+	want := []statusRun{{"[", termbox.ColorWhite}, {"file", termbox.ColorRed}, {"]", termbox.ColorWhite}}
+	if !cmp.Equal(got, want, cmp.AllowUnexported(statusRun{})) {
+		t.Errorf("got != want, diff: %v", cmp.Diff(got, want, cmp.AllowUnexported(statusRun{})))
+	}
+}
+
+func TestTruncateRuns(t *testing.T) {
+	runs := []statusRun{{"abc", termbox.ColorRed}, {" ", termbox.ColorWhite}, {"世界", termbox.ColorBlue}}
+
+	tests := []struct {
+		width   int
+		want    string
+		wantLen int
+	}{
+		{8, "abc 世界", 3},
+		{20, "abc 世界", 3},
+		{7, "abc 世…", 4},
+		// A wide character that doesn't fit is left out whole.
+		{6, "abc …", 3},
+		{4, "abc…", 2},
+		{1, "…", 1},
+		{0, "", 0},
+	}
+	for _, tc := range tests {
+		got := truncateRuns(runs, tc.width)
+		if text := runsText(got); text != tc.want || len(got) != tc.wantLen {
+			t.Errorf("truncateRuns(width=%d) = %q in %d runs, want %q in %d", tc.width, text, len(got), tc.want, tc.wantLen)
+		}
+	}
+
+	// The ellipsis takes the color of the text it follows.
+	if got := truncateRuns(runs, 3); got[len(got)-1].fg != termbox.ColorRed {
+		t.Errorf("got ellipsis color %v, want red", got[len(got)-1].fg)
+	}
+}
+
+func TestValidateStatusTemplate(t *testing.T) {
+	for _, tmpl := range []string{"", DefaultStatusTemplate, "{file} {pos} {length} {{users}"} {
+		if err := ValidateStatusTemplate(tmpl); err != nil {
+			t.Errorf("ValidateStatusTemplate(%q) returned %v, want no error", tmpl, err)
+		}
+	}
+	err := ValidateStatusTemplate("{file} {postion}")
+	if err == nil || !strings.Contains(err.Error(), "{postion}") {
+		t.Errorf("got error %v, want one naming {postion}", err)
+	}
+}
+
+func TestDrawInfoBar_Template(t *testing.T) {
+	tests := []struct {
+		description string
+		tmpl        string
+		width       int
+		want        string
+	}{
+		{"default", "", 60, "[Notes] INS ann bob [no file], line=3"},
+		{"custom", "{pos} {length} {file}", 40, "2:3 8 [no file]"},
+		{"title and users", "{title} {users}|", 40, "[Notes] ann bob|"},
+		{"truncated", "{file} {pos} {length}", 10, "[no file…"},
+	}
+	for _, tc := range tests {
+		e, screen := newScreenEditor(tc.width, 3, "abcd\nefg")
+		e.SetStatusTemplate(tc.tmpl)
+		e.SetX(7)
+		e.StatusMu.Lock()
+		e.Title = "Notes"
+		e.Users = []string{"ann", "bob"}
+		e.StatusMu.Unlock()
+
+		e.DrawInfoBar()
+
+		if got := screen.Row(2); got != tc.want {
+			t.Errorf("(%s) got info bar %q, want %q", tc.description, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package editor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/nsf/termbox-go"
+)
+
+// Style is a pair of foreground and background attributes a cell is drawn in.
+type Style struct {
+	Fg, Bg termbox.Attribute
+}
+
+// Theme holds the colors the editor is drawn in.
+type Theme struct {
+	// Text is the document's colors; its background fills the rest of the screen too.
+	Text Style
+
+	// Selection is the colors of selected text.
+	Selection Style
+
+	// LongLine is the colors of the current line while it's longer than LongLineLimit.
+	LongLine Style
+
+	// Status is the colors of the status bar, where messages, prompts and the info bar are shown.
+	Status Style
+
+	// Mode, Title, Lock and Warning are the foregrounds of parts of the info bar:
+	// the modal editor's mode, the room's title, the lock holder and a too long line's length.
+	Mode, Title, Lock, Warning termbox.Attribute
+
+	// Connected and Disconnected are the backgrounds of the connection indicator at the end of the status bar.
+	Connected, Disconnected termbox.Attribute
+
+	// Minimap is the colors of the minimap, and MinimapView the background of its rows standing for the lines in view.
+	Minimap     Style
+	MinimapView termbox.Attribute
+
+	// Users are the colors users are named in, each user keeping the same one; see userColor.
+	Users []termbox.Attribute
+}
+
+// DefaultTheme keeps the terminal's own colors, adding a few for the status bar.
+var DefaultTheme = Theme{
+	Text:         Style{termbox.ColorDefault, termbox.ColorDefault},
+	Selection:    Style{termbox.ColorDefault | termbox.AttrReverse, termbox.ColorDefault},
+	LongLine:     Style{termbox.ColorDefault | termbox.AttrReverse, termbox.ColorDefault},
+	Status:       Style{termbox.ColorDefault, termbox.ColorDefault},
+	Mode:         termbox.ColorDefault | termbox.AttrBold,
+	Title:        termbox.ColorCyan | termbox.AttrBold,
+	Lock:         termbox.ColorRed,
+	Warning:      termbox.ColorRed,
+	Connected:    termbox.ColorGreen,
+	Disconnected: termbox.ColorRed,
+	Minimap:      Style{termbox.ColorDefault, termbox.ColorDefault},
+	MinimapView:  termbox.ColorBlue,
+	Users: []termbox.Attribute{
+		termbox.ColorGreen,
+		termbox.ColorYellow,
+		termbox.ColorBlue,
+		termbox.ColorMagenta,
+		termbox.ColorCyan,
+		termbox.ColorLightYellow,
+		termbox.ColorLightMagenta,
+		termbox.ColorLightGreen,
+		termbox.ColorLightRed,
+		termbox.ColorRed,
+	},
+}
+
+// Themes are the built-in themes, by name.
+var Themes = map[string]Theme{
+	"default": DefaultTheme,
+	"dark": {
+		Text:         Style{termbox.ColorLightGray, termbox.ColorBlack},
+		Selection:    Style{termbox.ColorWhite, termbox.ColorBlue},
+		LongLine:     Style{termbox.ColorLightGray, termbox.ColorRed},
+		Status:       Style{termbox.ColorWhite, termbox.ColorDarkGray},
+		Mode:         termbox.ColorLightYellow | termbox.AttrBold,
+		Title:        termbox.ColorLightCyan | termbox.AttrBold,
+		Lock:         termbox.ColorLightRed,
+		Warning:      termbox.ColorLightRed,
+		Connected:    termbox.ColorGreen,
+		Disconnected: termbox.ColorRed,
+		Minimap:      Style{termbox.ColorDarkGray, termbox.ColorBlack},
+		MinimapView:  termbox.ColorBlue,
+		Users: []termbox.Attribute{
+			termbox.ColorLightGreen,
+			termbox.ColorLightYellow,
+			termbox.ColorLightBlue,
+			termbox.ColorLightMagenta,
+			termbox.ColorLightCyan,
+			termbox.ColorLightRed,
+		},
+	},
+	"light": {
+		Text:         Style{termbox.ColorBlack, termbox.ColorWhite},
+		Selection:    Style{termbox.ColorBlack, termbox.ColorLightCyan},
+		LongLine:     Style{termbox.ColorBlack, termbox.ColorLightYellow},
+		Status:       Style{termbox.ColorBlack, termbox.ColorLightGray},
+		Mode:         termbox.ColorBlue | termbox.AttrBold,
+		Title:        termbox.ColorMagenta | termbox.AttrBold,
+		Lock:         termbox.ColorRed,
+		Warning:      termbox.ColorRed,
+		Connected:    termbox.ColorGreen,
+		Disconnected: termbox.ColorRed,
+		Minimap:      Style{termbox.ColorDarkGray, termbox.ColorWhite},
+		MinimapView:  termbox.ColorLightBlue,
+		Users: []termbox.Attribute{
+			termbox.ColorBlue,
+			termbox.ColorMagenta,
+			termbox.ColorGreen,
+			termbox.ColorRed,
+			termbox.ColorCyan,
+		},
+	},
+}
+
+// colorNames and attributeNames map the words ParseAttribute knows to termbox's attributes.
+var (
+	colorNames = map[string]termbox.Attribute{
+		"default":      termbox.ColorDefault,
+		"black":        termbox.ColorBlack,
+		"red":          termbox.ColorRed,
+		"green":        termbox.ColorGreen,
+		"yellow":       termbox.ColorYellow,
+		"blue":         termbox.ColorBlue,
+		"magenta":      termbox.ColorMagenta,
+		"cyan":         termbox.ColorCyan,
+		"white":        termbox.ColorWhite,
+		"darkgray":     termbox.ColorDarkGray,
+		"lightred":     termbox.ColorLightRed,
+		"lightgreen":   termbox.ColorLightGreen,
+		"lightyellow":  termbox.ColorLightYellow,
+		"lightblue":    termbox.ColorLightBlue,
+		"lightmagenta": termbox.ColorLightMagenta,
+		"lightcyan":    termbox.ColorLightCyan,
+		"lightgray":    termbox.ColorLightGray,
+	}
+	attributeNames = map[string]termbox.Attribute{
+		"bold":      termbox.AttrBold,
+		"underline": termbox.AttrUnderline,
+		"reverse":   termbox.AttrReverse,
+	}
+)
+
+// ParseAttribute reads a color and any attributes, separated by spaces, e.g. "bold red" or "lightblue".
+// Without a color, the terminal's default is used.
+func ParseAttribute(s string) (termbox.Attribute, error) {
+	attr := termbox.ColorDefault
+	color := ""
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		if a, ok := attributeNames[word]; ok {
+			attr |= a
+			continue
+		}
+		c, ok := colorNames[word]
+		if !ok {
+			return 0, fmt.Errorf("unknown color or attribute %q", word)
+		}
+		if color != "" {
+			return 0, fmt.Errorf("more than one color in %q", s)
+		}
+		color = word
+		attr |= c
+	}
+	return attr, nil
+}
+
+// SetTheme changes the colors the editor is drawn in.
+func (e *Editor) SetTheme(theme Theme) {
+	e.StatusMu.Lock()
+	e.Theme = theme
+	e.StatusMu.Unlock()
+}
+
+// GetTheme returns the colors the editor is drawn in.
+func (e *Editor) GetTheme() Theme {
+	e.StatusMu.Lock()
+	defer e.StatusMu.Unlock()
+	return e.Theme
+}
+
+// userColor returns the color the named user is shown in. It's picked by a hash of the name rather than
+// the user's place in the list, so it doesn't change as others join and leave, and every client agrees on it.
+func (t Theme) userColor(name string) termbox.Attribute {
+	if len(t.Users) == 0 {
+		return t.Status.Fg
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return t.Users[h.Sum32()%uint32(len(t.Users))]
+}
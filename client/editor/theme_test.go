@@ -0,0 +1,144 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestTheme_Text(t *testing.T) {
+	screen := NewMemScreen(20, 5)
+	theme := Themes["dark"]
+	e := NewEditor(EditorConfig{Theme: &theme, Screen: screen})
+	e.SetSize(20, 5)
+	e.SetText("abcd\nlong line")
+	e.SelectionStart, e.SelectionEnd = 1, 3
+
+	e.drawText(1, e.GetTheme())
+
+	tests := []struct {
+		description string
+		x, y        int
+		want        Style
+	}{
+		{"plain text", 0, 0, theme.Text},
+		{"selection start", 1, 0, theme.Selection},
+		{"selection end", 2, 0, theme.Selection},
+		{"after the selection", 3, 0, theme.Text},
+		{"long line", 0, 1, theme.LongLine},
+	}
+	for _, tc := range tests {
+		got := screen.Cell(tc.x, tc.y)
+		if got.Fg != tc.want.Fg || got.Bg != tc.want.Bg {
+			t.Errorf("(%s) got colors %v/%v, want %v/%v", tc.description, got.Fg, got.Bg, tc.want.Fg, tc.want.Bg)
+		}
+	}
+}
+
+func TestTheme_StatusBar(t *testing.T) {
+	screen := NewMemScreen(40, 3)
+	theme := Themes["light"]
+	e := NewEditor(EditorConfig{Theme: &theme, Screen: screen})
+	e.SetSize(40, 3)
+	e.Users = []string{"ab", "cd"}
+	e.IsConnected = true
+
+	e.DrawStatusBar()
+
+	// Users are named after the mode and title, so they're found in the row's text.
+	row := screen.Row(2)
+	for _, user := range e.Users {
+		x := strings.Index(row, user+" ")
+		if x < 0 {
+			t.Fatalf("user %q not in the status bar %q", user, row)
+		}
+		if got := screen.Cell(x, 2); got.Fg != theme.userColor(user) || got.Bg != theme.Status.Bg {
+			t.Errorf("user %q: got colors %v/%v, want %v/%v", user, got.Fg, got.Bg, theme.userColor(user), theme.Status.Bg)
+		}
+	}
+	if got := screen.Cell(39, 2); got.Bg != theme.Connected {
+		t.Errorf("connection indicator: got background %v, want %v", got.Bg, theme.Connected)
+	}
+
+	// The status bar's background spans the row, and nothing is drawn above it.
+	for x := 0; x < 39; x++ {
+		if got := screen.Cell(x, 2); got.Bg != theme.Status.Bg {
+			t.Errorf("column %d: got background %v, want %v", x, got.Bg, theme.Status.Bg)
+		}
+	}
+	for y := 0; y < 2; y++ {
+		if row := screen.Row(y); row != "" {
+			t.Errorf("drew %q outside the status bar, on row %d", row, y)
+		}
+	}
+}
+
+func TestTheme_DefaultTheme(t *testing.T) {
+	e := NewEditor(EditorConfig{})
+	if got := e.GetTheme(); got.Selection != DefaultTheme.Selection || len(got.Users) != len(DefaultTheme.Users) {
+		t.Errorf("got theme %+v, want the default", got)
+	}
+}
+
+func TestParseAttribute(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    termbox.Attribute
+		wantErr bool
+	}{
+		{"red", termbox.ColorRed, false},
+		{"Bold LightBlue", termbox.ColorLightBlue | termbox.AttrBold, false},
+		{"underline", termbox.ColorDefault | termbox.AttrUnderline, false},
+		{"", termbox.ColorDefault, false},
+		{"red blue", 0, true},
+		{"purple", 0, true},
+	}
+	for _, tc := range tests {
+		got, err := ParseAttribute(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseAttribute(%q): got err %v, want error: %t", tc.input, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && got != tc.want {
+			t.Errorf("ParseAttribute(%q): got %v, want %v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestTheme_UserColorsStable(t *testing.T) {
+	e, screen := newScreenEditor(60, 3, "")
+	e.SetStatusTemplate("{users}")
+
+	// colors returns the color each user is named in on the info bar.
+	colors := func(users ...string) map[string]termbox.Attribute {
+		t.Helper()
+		e.StatusMu.Lock()
+		e.Users = users
+		e.StatusMu.Unlock()
+		e.DrawInfoBar()
+
+		got := map[string]termbox.Attribute{}
+		x := 0
+		for _, user := range users {
+			got[user] = screen.Cell(x, 2).Fg
+			x += len(user) + 1
+		}
+		return got
+	}
+
+	want := colors("alice", "bob", "carol", "dave")
+
+	// Reordering the list, or others joining and leaving, leaves everyone's color alone.
+	for _, users := range [][]string{
+		{"dave", "carol", "bob", "alice"},
+		{"bob", "dave"},
+		{"erin", "alice", "frank", "carol", "bob", "dave"},
+	} {
+		for user, got := range colors(users...) {
+			if w, ok := want[user]; ok && got != w {
+				t.Errorf("users %v: %q got color %v, want %v as before", users, user, got, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import "fmt"
+
+// maxEdits bounds how many edit locations the edit ring keeps.
+const maxEdits = 50
+
+// editRing keeps the locations of recent edits, oldest first, for jumping between them.
+// Locations are offsets into the editor's text, shifted as text is inserted and deleted before them.
+type editRing struct {
+	positions []int
+
+	// current is the index of the location last jumped to, or len(positions) before any jump.
+	current int
+
+	// limit is the most locations kept.
+	limit int
+}
+
+// newEditRing returns an edit ring that keeps the last limit edit locations.
+func newEditRing(limit int) *editRing {
+	return &editRing{limit: limit}
+}
+
+// record adds an edit location and restarts cycling from the newest one.
+// An edit next to the newest location replaces it, so typing a word is one location rather than one per character.
+func (r *editRing) record(pos int) {
+	defer func() { r.current = len(r.positions) }()
+
+	if r.limit <= 0 {
+		return
+	}
+	if n := len(r.positions); n > 0 && abs(r.positions[n-1]-pos) <= 1 {
+		r.positions[n-1] = pos
+		return
+	}
+
+	r.positions = append(r.positions, pos)
+	if len(r.positions) > r.limit {
+		r.positions = r.positions[len(r.positions)-r.limit:]
+	}
+}
+
+// inserted shifts the locations at or after pos past n inserted runes.
+func (r *editRing) inserted(pos, n int) {
+	for i, p := range r.positions {
+		if p >= pos {
+			r.positions[i] = p + n
+		}
+	}
+}
+
+// deleted shifts the locations after pos back over n deleted runes. Locations in the deleted text move to pos.
+func (r *editRing) deleted(pos, n int) {
+	for i, p := range r.positions {
+		switch {
+		case p >= pos+n:
+			r.positions[i] = p - n
+		case p > pos:
+			r.positions[i] = pos
+		}
+	}
+}
+
+// prev returns the location of the edit before the one last jumped to, and false if there isn't one.
+func (r *editRing) prev() (int, bool) {
+	if r.current == 0 {
+		return 0, false
+	}
+	r.current--
+	return r.positions[r.current], true
+}
+
+// next returns the location of the edit after the one last jumped to, and false if there isn't one.
+func (r *editRing) next() (int, bool) {
+	if r.current >= len(r.positions)-1 {
+		return 0, false
+	}
+	r.current++
+	return r.positions[r.current], true
+}
+
+// abs returns the absolute value of x.
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// edits records where the document was last edited, locally and by peers.
+// It's only touched from the main loop, so it needs no locking.
+var edits = newEditRing(maxEdits)
+
+// noteInsert records n runes inserted at pos of the editor's text.
+func noteInsert(pos, n int) {
+	edits.inserted(pos, n)
+	edits.record(pos + n)
+}
+
+// noteDelete records n runes deleted at pos of the editor's text.
+func noteDelete(pos, n int) {
+	edits.deleted(pos, n)
+	edits.record(pos)
+}
+
+// jumpToEdit moves the cursor to an older edit location, or a newer one if older is false.
+func jumpToEdit(older bool) {
+	var pos int
+	var ok bool
+	if older {
+		pos, ok = edits.prev()
+	} else {
+		pos, ok = edits.next()
+	}
+	if !ok {
+		if len(edits.positions) == 0 {
+			e.StatusChan <- "No edits yet"
+		} else if older {
+			e.StatusChan <- "At the oldest edit"
+		} else {
+			e.StatusChan <- "At the newest edit"
+		}
+		return
+	}
+
+	// Locations can outlive the text they pointed into, e.g. after a resync replaced it.
+	e.SetX(max(0, min(pos, len(e.Text))))
+	e.MoveCursor(0, 0)
+	e.StatusChan <- fmt.Sprintf("Edit %d of %d", edits.current+1, len(edits.positions))
+}
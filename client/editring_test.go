@@ -0,0 +1,140 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nsf/termbox-go"
+)
+
+func TestEditRing_Record(t *testing.T) {
+	r := newEditRing(3)
+
+	// Neighboring edits coalesce into one location.
+	for _, pos := range []int{10, 11, 12, 11} {
+		r.record(pos)
+	}
+	if want := []int{11}; !cmp.Equal(r.positions, want) {
+		t.Errorf("coalescing (-want +got)\n%s", cmp.Diff(want, r.positions))
+	}
+
+	// The oldest locations are dropped past the limit.
+	for _, pos := range []int{20, 30, 40} {
+		r.record(pos)
+	}
+	if want := []int{20, 30, 40}; !cmp.Equal(r.positions, want) {
+		t.Errorf("bounding (-want +got)\n%s", cmp.Diff(want, r.positions))
+	}
+}
+
+func TestEditRing_Cycle(t *testing.T) {
+	r := newEditRing(10)
+	if _, ok := r.prev(); ok {
+		t.Errorf("prev on an empty ring succeeded")
+	}
+	if _, ok := r.next(); ok {
+		t.Errorf("next on an empty ring succeeded")
+	}
+
+	for _, pos := range []int{5, 20, 40} {
+		r.record(pos)
+	}
+
+	var got []int
+	for pos, ok := r.prev(); ok; pos, ok = r.prev() {
+		got = append(got, pos)
+	}
+	for pos, ok := r.next(); ok; pos, ok = r.next() {
+		got = append(got, pos)
+	}
+	if want := []int{40, 20, 5, 20, 40}; !cmp.Equal(got, want) {
+		t.Errorf("cycling (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	// A new edit restarts from the newest location.
+	r.prev()
+	r.record(60)
+	if pos, _ := r.prev(); pos != 60 {
+		t.Errorf("after a new edit, prev = %d, expected 60", pos)
+	}
+}
+
+func TestEditRing_Remap(t *testing.T) {
+	r := newEditRing(10)
+	for _, pos := range []int{3, 10, 20} {
+		r.record(pos)
+	}
+
+	// Text inserted before a location pushes it along; text after it doesn't.
+	r.inserted(10, 4)
+	if want := []int{3, 14, 24}; !cmp.Equal(r.positions, want) {
+		t.Errorf("after inserting (-want +got)\n%s", cmp.Diff(want, r.positions))
+	}
+
+	// Deleting text before a location pulls it back; one inside the deleted text moves to its start.
+	r.deleted(12, 5)
+	if want := []int{3, 12, 19}; !cmp.Equal(r.positions, want) {
+		t.Errorf("after deleting (-want +got)\n%s", cmp.Diff(want, r.positions))
+	}
+}
+
+func TestJumpToEdit_FollowsRemoteEdits(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+	typeText(t, "hello world")
+
+	// Type at the end, then a peer inserts at the start, shifting the local edit along.
+	e.SetX(11)
+	performOperation(OperationInsert, termbox.Event{Ch: '!'}, nil)
+
+	peer := crdt.New()
+	peer.SiteID = 2
+	peer.Characters = append([]crdt.Character(nil), doc.Characters...)
+	char, err := peer.GenerateCharacter(1, ">")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	handleMsg(commons.Message{Type: "operation", Username: "peer", Operation: commons.Operation{Type: "insert", Position: 1, Value: ">", Character: &char}}, nil)
+
+	e.SetX(5)
+	jumpToEdit(true)
+	if e.Cursor != 1 {
+		t.Errorf("jumped to %d, expected the peer's edit at 1", e.Cursor)
+	}
+	jumpToEdit(true)
+	if e.Cursor != 13 {
+		t.Errorf("jumped to %d, expected the local edit, shifted to 13", e.Cursor)
+	}
+	jumpToEdit(false)
+	if e.Cursor != 1 {
+		t.Errorf("jumped to %d, expected to return to 1", e.Cursor)
+	}
+}
+
+func TestJumpToEdit_ReachesOldest(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+	typeText(t, strings.Repeat("x", 40))
+	for _, pos := range []int{10, 20, 30} {
+		edits.record(pos)
+	}
+
+	var got []int
+	for range 4 {
+		jumpToEdit(true)
+		got = append(got, e.Cursor)
+	}
+	for range 3 {
+		jumpToEdit(false)
+		got = append(got, e.Cursor)
+	}
+
+	// Past the oldest edit the cursor stays put, and jumping back goes to the newest.
+	if want := []int{30, 20, 10, 10, 20, 30, 30}; !cmp.Equal(got, want) {
+		t.Errorf("jumping (-want +got)\n%s", cmp.Diff(want, got))
+	}
+}
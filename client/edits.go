@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// textEdit replaces del runes at pos of the editor's text with ins.
+type textEdit struct {
+	pos int
+	del int
+	ins string
+}
+
+// applyEdits makes a batch of edits to the document, keeping the cursor on the same text.
+// Positions refer to the text before any edit, and edits mustn't overlap.
+// Each change goes through performOperation, so peers see ordinary inserts and deletes.
+// It reports whether the edits were made.
+func applyEdits(edits []textEdit, conn *websocket.Conn) bool {
+	if reason := editBlocked(); reason != "" {
+		e.StatusChan <- reason
+		return false
+	}
+	if doc.SiteID == 0 {
+		e.StatusChan <- "connecting..."
+		return false
+	}
+
+	cursor := e.Cursor
+
+	// Edit from the end backwards, so the positions of earlier edits stay valid.
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		ins := []rune(edit.ins)
+
+		e.Cursor = edit.pos + edit.del
+		for j := 0; j < edit.del; j++ {
+			performOperation(OperationDelete, termbox.Event{}, conn)
+		}
+		for _, ch := range ins {
+			performOperation(OperationInsert, termbox.Event{Ch: ch}, conn)
+		}
+
+		// Text inserted at the cursor goes before it; a cursor in deleted text moves to where it was.
+		switch {
+		case cursor >= edit.pos+edit.del && (edit.del > 0 || cursor >= edit.pos):
+			cursor += len(ins) - edit.del
+		case cursor > edit.pos:
+			cursor = edit.pos
+		}
+	}
+
+	e.Cursor = max(0, min(cursor, len(e.Text)))
+	return true
+}
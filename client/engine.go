@@ -3,13 +3,16 @@ package main
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"text-editor/commons"
 	"text-editor/crdt"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/nsf/termbox-go"
 	"github.com/sirupsen/logrus"
@@ -18,70 +21,197 @@ import (
 // handleTermboxEvent processes keyboard input, updates the local CRDT document,
 // and transmits a message via WebSocket.
 func handleTermboxEvent(ev termbox.Event, conn *websocket.Conn) error {
+	recordEvent(ev)
+
+	// While quitting with unsaved changes is being confirmed, the next key answers.
+	if handled, err := handleQuitConfirmEvent(ev); handled {
+		e.SendDraw()
+		return err
+	}
+
+	// An open panel is read-only; any key dismisses it.
+	if ev.Type == termbox.EventKey && e.GetPanel() != nil {
+		e.SetPanel(nil)
+		e.SendDraw()
+		return nil
+	}
+
+	// An open prompt takes all key events until it's submitted or cancelled.
+	if ev.Type == termbox.EventKey && activePrompt != nil {
+		handlePromptEvent(ev, conn)
+		e.SendDraw()
+		return nil
+	}
+
+	// Ctrl+Space, Ctrl+X and Ctrl+V select, copy and paste text.
+	if handleSelectionEvent(ev, conn) {
+		e.SendDraw()
+		return nil
+	}
+
+	// In normal mode, letters move the cursor instead of being inserted.
+	if handleModalEvent(ev, conn) {
+		updateSelection(ev)
+		e.SendDraw()
+		return nil
+	}
+
 	// Focus on termbox key events (EventKey) exclusively.
 	if ev.Type == termbox.EventKey {
 		switch ev.Key {
 
 		// Esc and Ctrl+C serve as the standard session termination keys.
+		// With unsaved changes, they ask for confirmation first.
 		case termbox.KeyEsc, termbox.KeyCtrlC:
-			// Generate an error with the "editor" prefix for exit handling.
-			return errors.New("editor: exiting")
+			if err := requestQuit(); err != nil {
+				return err
+			}
 
 		// Ctrl+S is designated as the default key for content preservation.
 		case termbox.KeyCtrlS:
 			// Assign a default filename if none is provided.
 			if fileName == "" {
 				fileName = "editor-content.txt"
+				e.SetFileName(fileName)
 			}
 
-			// Persist the CRDT to a file.
-			err := crdt.Save(fileName, &doc)
-			if err != nil {
-				logrus.Errorf("Failed to save to %s", fileName)
-				e.StatusChan <- fmt.Sprintf("Failed to save to %s", fileName)
+			if err := saveFile(); err != nil {
 				return err
 			}
 
-			// Update the status bar.
-			e.StatusChan <- fmt.Sprintf("Saved document to %s", fileName)
+		// F6 saves the document to a new file, which later saves go to.
+		// Terminals send Ctrl+Shift+S as Ctrl+S, so it can't be used.
+		case termbox.KeyF6:
+			openPrompt("Save as: ", saveAs)
 
 		// Ctrl+L is set as the default key for file content retrieval.
 		case termbox.KeyCtrlL:
 			if fileName != "" {
-				logger.Log(logrus.InfoLevel, "LOADING DOCUMENT")
-				newDoc, err := crdt.Load(fileName)
-				if err != nil {
-					logrus.Errorf("failed to load file %s", fileName)
-					e.StatusChan <- fmt.Sprintf("Failed to load %s", fileName)
+				if err := loadFile(conn); err != nil {
 					return err
 				}
-				e.StatusChan <- fmt.Sprintf("Loading %s", fileName)
-				doc = newDoc
-				e.SetX(0)
-				e.SetText(crdt.Content(doc))
-
-				logger.Log(logrus.InfoLevel, "SENDING DOCUMENT")
-				docMsg := commons.Message{Type: commons.DocSyncMessage, Document: doc}
-				_ = conn.WriteJSON(&docMsg)
 			} else {
 				e.StatusChan <- "No file to load!"
 			}
 
+		// Ctrl+D prompts for a file and inserts its contents at the cursor.
+		// Ctrl+I can't be told apart from Tab in a terminal, so it isn't used.
+		case termbox.KeyCtrlD:
+			openPrompt("Insert file: ", insertFile)
+
+		// Ctrl+O opens a picker of recently used files.
+		case termbox.KeyCtrlO:
+			openRecentPicker()
+
+		// Ctrl+G lets a room owner toggle write access for all guests, or for one.
+		case termbox.KeyCtrlG:
+			promptWriteAccess(conn)
+
+		// Ctrl+J lets a room owner set the room's title, shown to everyone.
+		case termbox.KeyCtrlJ:
+			promptTitle(conn)
+
+		// Ctrl+\ bookmarks the cursor's position under a name, and Ctrl+Q jumps to a bookmark.
+		case termbox.KeyCtrlBackslash:
+			promptBookmark()
+		case termbox.KeyCtrlQ:
+			promptJump()
+
+		// Ctrl+U prompts for a hex code point and inserts that character.
+		case termbox.KeyCtrlU:
+			openPrompt("Insert code point (hex): ", insertCodePoint)
+
+		// Ctrl+K takes or releases the write lock in single-writer rooms.
+		case termbox.KeyCtrlK:
+			toggleLock(conn)
+
+		// Ctrl+T starts or stops recording a macro.
+		case termbox.KeyCtrlT:
+			toggleRecording()
+
+		// Ctrl+Y replays the recorded macro.
+		case termbox.KeyCtrlY:
+			if !rec.replaying {
+				replayMacro(conn)
+			}
+
+		// Ctrl+/ comments or uncomments the current line.
+		case termbox.KeyCtrlSlash:
+			toggleComment(conn)
+
+		// Ctrl+W converts the document's indentation between tabs and spaces.
+		case termbox.KeyCtrlW:
+			openPrompt("Convert indentation to (spaces/tabs): ", convertIndentation)
+
+		// F2 shows what changed since the file was saved.
+		case termbox.KeyF2:
+			showDiff()
+
+		// F3 and F4 jump to the previous and next of the recent edit locations.
+		case termbox.KeyF3:
+			jumpToEdit(true)
+		case termbox.KeyF4:
+			jumpToEdit(false)
+
+		// F5 starts a new, empty document.
+		case termbox.KeyF5:
+			newDocument(conn)
+
+		// F7 follows another user's view, or stops following it.
+		case termbox.KeyF7:
+			toggleFollow()
+
+		// F8 inserts the current date and time.
+		case termbox.KeyF8:
+			insertTimestamp(conn)
+
+		// F9 inserts a snippet, chosen by name.
+		case termbox.KeyF9:
+			promptSnippet()
+
+		// F10 exports the changes since the file was saved as a patch of operations, which F11 applies.
+		case termbox.KeyF10:
+			openPrompt("Export patch to: ", exportPatch)
+		case termbox.KeyF11:
+			openPrompt("Apply patch: ", importPatch)
+
+		// F1 jumps to a percentage through the document, like less's 50%.
+		case termbox.KeyF1:
+			promptPercent()
+
+		// F12 opens the next of the files given with -file.
+		case termbox.KeyF12:
+			nextFile(conn)
+
+		// Ctrl+A shows or hides the minimap, an overview of the whole document.
+		case termbox.KeyCtrlA:
+			e.SetMinimap(!e.IsMinimap())
+			// The text area changed width, so make sure the cursor is still in view.
+			e.MoveCursor(0, 0)
+
+		// Ctrl+E lists the most recent operations.
+		case termbox.KeyCtrlE:
+			showHistory()
+
+		// Ctrl+R replaces the local document with a fresh copy from a peer.
+		case termbox.KeyCtrlR:
+			requestResync(conn)
+
 		// Left arrow and Ctrl+B are configured for leftward cursor movement.
 		case termbox.KeyArrowLeft, termbox.KeyCtrlB:
-			e.MoveCursor(-1, 0)
+			moveCursor(-1, 0)
 
 		// Right arrow and Ctrl+F facilitate rightward cursor movement.
 		case termbox.KeyArrowRight, termbox.KeyCtrlF:
-			e.MoveCursor(1, 0)
+			moveCursor(1, 0)
 
 		// Up arrow and Ctrl+P enable upward cursor movement.
 		case termbox.KeyArrowUp, termbox.KeyCtrlP:
-			e.MoveCursor(0, -1)
+			moveCursor(0, -1)
 
 		// Down arrow and Ctrl+N allow downward cursor movement.
 		case termbox.KeyArrowDown, termbox.KeyCtrlN:
-			e.MoveCursor(0, 1)
+			moveCursor(0, 1)
 
 		// Home key repositions the cursor to the line's start (X=0).
 		case termbox.KeyHome:
@@ -97,12 +227,9 @@ func handleTermboxEvent(ev termbox.Event, conn *websocket.Conn) error {
 		case termbox.KeyDelete:
 			performOperation(OperationDelete, ev, conn)
 
-		// Tab key inserts 4 spaces to emulate a tab character.
+		// Tab key inserts spaces to emulate a tab character.
 		case termbox.KeyTab:
-			for i := 0; i < 4; i++ {
-				ev.Ch = ' '
-				performOperation(OperationInsert, ev, conn)
-			}
+			insertIndent(ev, conn)
 
 		// Enter key adds a newline character to the content.
 		case termbox.KeyEnter:
@@ -112,30 +239,177 @@ func handleTermboxEvent(ev termbox.Event, conn *websocket.Conn) error {
 		// Space key introduces a space character to the content.
 		case termbox.KeySpace:
 			ev.Ch = ' '
-			performOperation(OperationInsert, ev, conn)
+			typeChar(ev, conn)
+
+		// Insert switches between inserting and overwriting typed characters.
+		case termbox.KeyInsert:
+			toggleOverwrite()
 
 		// Any other key is considered for insertion.
 		default:
 			if ev.Ch != 0 {
-				performOperation(OperationInsert, ev, conn)
+				typeChar(ev, conn)
 			}
 		}
+		updateSelection(ev)
 	}
 
 	e.SendDraw()
 	return nil
 }
 
+// loadFile replaces the document's content with the contents of fileName and shares it with peers.
+// The old characters are deleted rather than the document replaced, so peers merging the result
+// see the file's content, and edits they made concurrently against the old text still integrate.
+// Loading runs on the main loop, like handleMsg, so no remote operation is applied halfway through.
+func loadFile(conn *websocket.Conn) error {
+	logger.Log(logrus.InfoLevel, "LOADING DOCUMENT")
+	newDoc, err := crdt.Load(fileName)
+	if err != nil {
+		logrus.Errorf("failed to load file %s", fileName)
+		e.StatusChan <- fmt.Sprintf("Failed to load %s", fileName)
+		return err
+	}
+	noteWritable(fileName)
+
+	// With -no-broadcast-load, the file is only shown here and the room's document is left alone.
+	if flags.NoBroadcastLoad && conn != nil {
+		adoptDoc(newDoc)
+		detached = true
+		e.SetText(crdt.Content(doc))
+		positionOnLoad()
+		rememberFile(fileName)
+		dirty = false
+		e.StatusChan <- fmt.Sprintf("Loaded %s locally; Ctrl+R returns to the shared document", fileName)
+		return nil
+	}
+
+	// Don't broadcast a document the server would reject the edits of.
+	if reason := roomEditBlocked(); reason != "" {
+		e.StatusChan <- reason
+		return nil
+	}
+	if doc.SiteID == 0 {
+		e.StatusChan <- "connecting..."
+		return nil
+	}
+
+	e.StatusChan <- fmt.Sprintf("Loading %s", fileName)
+	if err := doc.ReplaceContent(crdt.Content(newDoc)); err != nil {
+		logger.Errorf("failed to replace content, err: %v", err)
+	}
+	e.SetText(crdt.Content(doc))
+	positionOnLoad()
+	rememberFile(fileName)
+	dirty = false
+
+	if conn != nil {
+		logger.Log(logrus.InfoLevel, "SENDING DOCUMENT")
+		docMsg := commons.Message{Type: commons.DocSyncMessage, Document: &doc}
+		_ = conn.WriteJSON(&docMsg)
+	}
+	return nil
+}
+
+// positionAtStart puts the cursor where -file said to in the document the editor starts with, or where loading it would.
+func positionAtStart() {
+	if startLine > 0 {
+		e.GotoLine(startLine, startCol)
+		return
+	}
+	positionOnLoad()
+}
+
+// positionOnLoad puts the cursor at the start of a freshly loaded document, or at its end with -tail.
+func positionOnLoad() {
+	if flags.Tail {
+		e.ScrollToEnd()
+		return
+	}
+	e.SetX(0)
+}
+
 const (
 	OperationInsert = iota
 	OperationDelete
 )
 
+// pendingOp is a local edit deferred until the server assigns a site ID.
+type pendingOp struct {
+	opType int
+	ev     termbox.Event
+}
+
+// pendingOps holds local edits made before the site ID was assigned.
+// Generating CRDT IDs with the unassigned site ID (0) would collide with other clients.
+var pendingOps []pendingOp
+
+// lamport orders this client's operations relative to everyone else's.
+var lamport commons.LamportClock
+
+// editBlocked explains why edits are refused, because the open file can't be saved or the server wouldn't accept them,
+// or returns "" if they aren't.
+func editBlocked() string {
+	if fileReadOnly {
+		return fmt.Sprintf("read-only: %s can't be written, F6 saves a copy", fileName)
+	}
+	return roomEditBlocked()
+}
+
+// roomEditBlocked explains why the room won't accept this client's edits, or returns "" if it will.
+func roomEditBlocked() string {
+	if readOnly {
+		return "read-only: ask the room owner for write access"
+	}
+	if lockHolderID != uuid.Nil && lockHolderID != clientID {
+		return fmt.Sprintf("%s holds the write lock", lockHolder)
+	}
+	return ""
+}
+
 // performOperation executes a CRDT insert or delete action on the local document
 // and dispatches a message via WebSocket.
 func performOperation(opType int, ev termbox.Event, conn *websocket.Conn) {
+	// Defer the edit until the server has assigned a site ID.
+	if doc.SiteID == 0 {
+		pendingOps = append(pendingOps, pendingOp{opType: opType, ev: ev})
+		if len(pendingOps) == 1 {
+			e.StatusChan <- "connecting..."
+		}
+		return
+	}
+
+	// The server drops edits from read-only clients, so reject them before they diverge the local document.
+	if reason := editBlocked(); reason != "" {
+		e.StatusChan <- reason
+		return
+	}
+
+	// A resync can leave the cursor past the end of a shorter text, e.g. after a peer deleted everything,
+	// where the CRDT would reject inserts, so keep it within the text.
+	e.SetX(max(0, min(e.Cursor, len(e.GetText()))))
+
+	// Let hooks inspect, change or veto the edit before it's applied anywhere.
+	proposed := commons.Operation{Type: "insert", Position: e.Cursor + 1, Value: string(ev.Ch)}
+	if opType == OperationDelete {
+		// At the start of the text, or in a document with only tombstones left, there's nothing to delete or send.
+		char := crdt.IthVisible(doc, e.Cursor)
+		if char.ID == "-1" {
+			return
+		}
+		proposed = commons.Operation{Type: "delete", Position: e.Cursor, ID: char.ID, Value: char.Value}
+	}
+	if !runOpHooks(&proposed) {
+		logger.Debugf("hook blocked the %s of %q at %d", proposed.Type, proposed.Value, proposed.Position)
+		return
+	}
+
 	// Retrieve position and value.
-	ch := string(ev.Ch)
+	ch := proposed.Value
+	if opType == OperationInsert && utf8.RuneCountInString(ch) != 1 {
+		logger.Errorf("hook changed the inserted character to %q, which isn't one character", ch)
+		return
+	}
 
 	var msg commons.Message
 
@@ -144,37 +418,199 @@ func performOperation(opType int, ev termbox.Event, conn *websocket.Conn) {
 	case OperationInsert:
 		logger.Infof("LOCAL INSERT: %s at cursor position %v\n", ch, e.Cursor)
 
-		text, err := doc.Insert(e.Cursor+1, ch)
+		// Mirror the insert into the editor, falling back to a full resync if the CRDT rejected it.
+		// A rejected character isn't part of the local document, so it must not reach peers either.
+		char, err := doc.GenerateCharacter(e.Cursor+1, ch)
 		if err != nil {
-			e.SetText(text)
 			logger.Errorf("CRDT error: %v\n", err)
+			e.SetText(crdt.Content(doc))
+			return
 		}
-		e.SetText(text)
+		e.ApplyInsert(e.Cursor, ch)
+		noteInsert(e.Cursor, 1)
+		dirty = true
+		recordOp(time.Now(), "insert", e.Cursor+1, ch, username)
 
 		e.MoveCursor(1, 0)
-		msg = commons.Message{Type: "operation", Operation: commons.Operation{Type: "insert", Position: e.Cursor, Value: ch}}
+		msg = commons.Message{Type: "operation", Operation: commons.Operation{Type: "insert", Position: e.Cursor, Value: ch, Character: &char}}
 
 	case OperationDelete:
 		logger.Infof("LOCAL DELETE: cursor position %v\n", e.Cursor)
 
-		if e.Cursor-1 < 0 {
-			e.Cursor = 0
-		}
-
-		text := doc.Delete(e.Cursor)
-		e.SetText(text)
+		// Peers delete by ID, so look up the character before removing it.
+		char := crdt.IthVisible(doc, e.Cursor)
+		doc.IntegrateDelete(char)
+		e.ApplyDelete(e.Cursor-1, 1)
+		noteDelete(e.Cursor-1, 1)
+		dirty = true
+		recordOp(time.Now(), "delete", e.Cursor, char.Value, username)
 
-		msg = commons.Message{Type: "operation", Operation: commons.Operation{Type: "delete", Position: e.Cursor}}
+		msg = commons.Message{Type: "operation", Operation: commons.Operation{Type: "delete", Position: e.Cursor, ID: char.ID}}
 		e.MoveCursor(-1, 0)
 	}
 
-	// Transmit the message.
-	if e.IsConnected {
-		err := conn.WriteJSON(msg)
-		if err != nil {
+	// Stamp the operation so peers can order and date it.
+	msg.Operation.Clock = lamport.Tick()
+	msg.Operation.Timestamp = time.Now().UnixMilli()
+
+	// Transmit the message, unless the local document isn't the room's.
+	if e.IsConnected && !detached {
+		if err := sendOperation(msg, conn); err != nil {
 			e.IsConnected = false
+			unsent = true
 			e.StatusChan <- "lost connection!"
 		}
+	} else if conn != nil && !detached {
+		// Rejoining sends it along with the rest of the document.
+		unsent = true
+	}
+}
+
+// adoptDoc replaces the local document, keeping this client's site ID and clock
+// so characters inserted afterwards don't reuse identifiers.
+func adoptDoc(newDoc crdt.Document) {
+	newDoc.SiteID = doc.SiteID
+	newDoc.Clock = max(doc.Clock, newDoc.Clock)
+	doc = newDoc
+}
+
+var (
+	// readOnly is set while the server rejects this client's edits.
+	readOnly bool
+
+	// isOwner is set if this client owns its room.
+	isOwner bool
+
+	// guestsCanWrite is the guest write access last set by this client, as a room owner.
+	guestsCanWrite bool
+)
+
+// toggleGuestWrite grants or revokes write access for all guests, if this client owns the room.
+func toggleGuestWrite(conn *websocket.Conn) {
+	if !isOwner {
+		e.StatusChan <- "only the room owner can change write access"
+		return
+	}
+
+	text := "write"
+	if guestsCanWrite {
+		text = "read"
+	}
+
+	msg := commons.Message{Type: commons.PermissionMessage, Text: text}
+	if err := conn.WriteJSON(&msg); err != nil {
+		logger.Errorf("failed to change permissions, err: %v\n", err)
+		e.StatusChan <- "failed to change write access!"
+		return
+	}
+
+	guestsCanWrite = !guestsCanWrite
+	guestWrite = map[string]bool{}
+	e.StatusChan <- fmt.Sprintf("guests can write: %v", guestsCanWrite)
+}
+
+var (
+	// clientID identifies this connection on the server.
+	clientID uuid.UUID
+
+	// lockHolder and lockHolderID identify the write lock holder in single-writer rooms, if any.
+	lockHolder   string
+	lockHolderID uuid.UUID
+)
+
+// toggleLock asks the server for the write lock, or releases it if this client holds it.
+func toggleLock(conn *websocket.Conn) {
+	if !e.IsConnected {
+		e.StatusChan <- "can't change the write lock while disconnected"
+		return
+	}
+
+	text := "acquire"
+	if lockHolderID != uuid.Nil && lockHolderID == clientID {
+		text = "release"
+	}
+
+	msg := commons.Message{Type: commons.LockMessage, Text: text}
+	if err := conn.WriteJSON(&msg); err != nil {
+		logger.Errorf("failed to %s the write lock, err: %v\n", text, err)
+		e.StatusChan <- "failed to change the write lock!"
+	}
+}
+
+// resyncing is set while waiting for the document requested by requestResync.
+var resyncing bool
+
+// detached is set after a local-only load (-no-broadcast-load), while the local document isn't the room's.
+// The two documents share no characters, so edits can't flow between them:
+// local edits aren't sent, and the room's edits and documents are ignored until a resync (Ctrl+R).
+var detached bool
+
+// requestResync asks for a fresh copy of the shared document, which replaces the local one when it arrives.
+func requestResync(conn *websocket.Conn) {
+	if !e.IsConnected {
+		e.StatusChan <- "can't resync while disconnected"
+		return
+	}
+
+	msg := commons.Message{Type: commons.DocReqMessage}
+	if err := conn.WriteJSON(&msg); err != nil {
+		logger.Errorf("failed to request resync, err: %v\n", err)
+		e.StatusChan <- "resync failed!"
+		return
+	}
+
+	resyncing = true
+	e.StatusChan <- "resyncing..."
+}
+
+// claimLoadedChars regenerates the characters loaded before the server assigned a site ID, e.g. from -file,
+// under the assigned site. They were made under site 0, like every other client's, so two clients' files
+// would share IDs and merging would mistake one's characters for the other's.
+// Local edits wait for the site ID, so the document holds nothing else yet. It reports whether there were any.
+func claimLoadedChars() bool {
+	if crdt.Content(doc) == "" {
+		return false
+	}
+
+	claimed := crdt.New()
+	claimed.SiteID = doc.SiteID
+	if err := claimed.ReplaceContent(crdt.Content(doc)); err != nil {
+		logger.Errorf("failed to claim loaded characters, err: %v\n", err)
+		return false
+	}
+	doc = claimed
+	return true
+}
+
+// shareStartFile sends the file the editor started with to the room, which merges it and passes it on,
+// so peers have the characters this client's edits are anchored to.
+// A client that can't edit the room keeps the file to itself, as after a local-only load.
+func shareStartFile(conn *websocket.Conn) {
+	if conn == nil {
+		return
+	}
+	if reason := roomEditBlocked(); reason != "" {
+		detached = true
+		e.StatusChan <- fmt.Sprintf("%s; %s is shown locally, Ctrl+R returns to the shared document", reason, fileName)
+		return
+	}
+
+	docMsg := commons.Message{Type: commons.DocSyncMessage, Document: &doc}
+	if err := conn.WriteJSON(&docMsg); err != nil {
+		logger.Errorf("failed to send %s, err: %v\n", fileName, err)
+	}
+}
+
+// flushPendingOps applies the edits deferred while waiting for a site ID.
+func flushPendingOps(conn *websocket.Conn) {
+	if doc.SiteID == 0 {
+		return
+	}
+
+	ops := pendingOps
+	pendingOps = nil
+	for _, op := range ops {
+		performOperation(op.opType, op.ev, conn)
 	}
 }
 
@@ -193,57 +629,215 @@ func getTermboxChan() chan termbox.Event {
 
 // handleMsg refreshes the CRDT document with the message contents.
 func handleMsg(msg commons.Message, conn *websocket.Conn) {
+	// A detached client neither applies the room's edits nor offers its own document as the room's.
+	if detached && (msg.Type == "operation" || msg.Type == commons.DocReqMessage || (msg.Type == commons.DocSyncMessage && !resyncing)) {
+		logger.Infof("detached, ignoring %s message\n", msg.Type)
+		return
+	}
+
+	// A run of repeated inserts is applied as the inserts it stands for.
+	if msg.Type == "operation" && msg.Operation.Repeat > 1 {
+		for _, op := range msg.Operation.Expand() {
+			one := msg
+			one.Operation = op
+			handleMsg(one, conn)
+		}
+		return
+	}
+
 	switch msg.Type {
 	case commons.DocSyncMessage:
 		logger.Infof("DOCSYNC RECEIVED, updating local doc %+v\n", msg.Document)
 
-		doc = msg.Document
+		// A malformed document would leave the local one without its sentinels, breaking every later edit.
+		if msg.Document == nil {
+			logger.Errorf("ignoring DocSync without a document\n")
+			break
+		}
+		if err := msg.Document.Validate(); err != nil {
+			logger.Errorf("ignoring invalid document, err: %v\n", err)
+			e.StatusChan <- "ignored an invalid document"
+			break
+		}
+
+		if resyncing {
+			// A requested resync replaces the local document, discarding any divergence.
+			resyncing = false
+			detached = false
+			adoptDoc(*msg.Document)
+			e.SetText(crdt.Content(doc))
+			e.SetX(min(e.Cursor, len(e.Text)))
+			e.StatusChan <- "resynced"
+			break
+		}
+
+		// Merge rather than replace, so local edits the sender hasn't seen survive.
+		if _, err := doc.Merge(*msg.Document); err != nil {
+			logger.Errorf("failed to merge document, err: %v\n", err)
+		}
 		e.SetText(crdt.Content(doc))
+		e.SetX(min(e.Cursor, len(e.Text)))
 
 	case commons.DocReqMessage:
 		logger.Infof("DOCREQ RECEIVED, sending local document to %v\n", msg.ID)
 
-		docMsg := commons.Message{Type: commons.DocSyncMessage, Document: doc, ID: msg.ID}
+		docMsg := commons.Message{Type: commons.DocSyncMessage, Document: &doc, ID: msg.ID}
 		_ = conn.WriteJSON(&docMsg)
 
 	case commons.SiteIDMessage:
+		// The server identifies this connection by ID, e.g. as the write lock holder.
+		clientID = msg.ID
+
+		// Keep the original site across reconnects, so this client's characters stay attributable to one site.
+		// The server picks site IDs at random, so a restarted one won't give this site to a newcomer too.
+		if doc.SiteID != 0 {
+			logger.Infof("SITE ID %v kept on reconnect, ignoring %v", doc.SiteID, msg.Text)
+			break
+		}
+
 		siteID, err := strconv.Atoi(msg.Text)
 		if err != nil {
 			logger.Errorf("failed to set siteID, err: %v\n", err)
 		}
 
-		crdt.SiteID = siteID
-		logger.Infof("SITE ID %v, INTENDED SITE ID: %v", crdt.SiteID, siteID)
+		doc.SiteID = siteID
+		logger.Infof("SITE ID %v, INTENDED SITE ID: %v", doc.SiteID, siteID)
+
+		if claimLoadedChars() {
+			shareStartFile(conn)
+		}
+		flushPendingOps(conn)
 
 	case commons.JoinMessage:
 		e.StatusChan <- fmt.Sprintf("%s has joined the session!", msg.Username)
 
+	case commons.LockMessage:
+		lockHolder, lockHolderID = msg.Text, msg.ID
+		e.StatusMu.Lock()
+		e.LockHolder = lockHolder
+		e.StatusMu.Unlock()
+
+		switch {
+		case lockHolderID == uuid.Nil:
+			e.StatusChan <- "write lock is free"
+		case lockHolderID == clientID:
+			e.StatusChan <- "you hold the write lock (Ctrl+K releases it)"
+		default:
+			e.StatusChan <- fmt.Sprintf("%s holds the write lock", lockHolder)
+		}
+
+	case commons.PermissionMessage:
+		isOwner = msg.Text == "owner"
+		readOnly = msg.Text == "read"
+		switch msg.Text {
+		case "owner":
+			e.StatusChan <- "you own this room (Ctrl+G changes guests' write access)"
+		case "write":
+			e.StatusChan <- "you can edit"
+		default:
+			e.StatusChan <- "read-only: ask the room owner for write access"
+		}
+
 	case commons.UsersMessage:
+		users := strings.Split(msg.Text, ",")
 		e.StatusMu.Lock()
-		e.Users = strings.Split(msg.Text, ",")
+		e.Users = users
 		e.StatusMu.Unlock()
+		userSites = strings.Split(msg.Site, ",")
+		e.PruneTyping(users)
+		pruneCursors(users)
+
+	case commons.CursorMessage:
+		handleCursorMsg(msg)
+
+	case commons.ChecksumMessage:
+		handleChecksumMsg(msg)
+
+	case commons.TitleMessage:
+		handleTitleMsg(msg)
 
 	default:
+		// In echo mode, this client's own operations come back once the server has relayed them.
+		if msg.Type == "operation" && clientID != uuid.Nil && msg.ID == clientID {
+			acknowledge(msg.Operation)
+			break
+		}
+
+		// When the operation was made, falling back to now for clients that don't stamp operations.
+		at := time.Now()
+		if msg.Type == "operation" {
+			e.MarkTyping(msg.Username, at)
+			lamport.Observe(msg.Operation.Clock)
+			if msg.Operation.Timestamp != 0 {
+				at = time.UnixMilli(msg.Operation.Timestamp)
+			}
+		}
+
 		switch msg.Operation.Type {
 		case "insert":
-			_, err := doc.Insert(msg.Operation.Position, msg.Operation.Value)
+			char := msg.Operation.Character
+
+			// Characters that were already integrated must not be mirrored twice.
+			if char != nil && doc.Contains(char.ID) {
+				logger.Infof("REMOTE INSERT: %s already integrated\n", char.ID)
+				break
+			}
+
+			var err error
+			position := msg.Operation.Position
+			if char != nil {
+				// Integrate between the exact neighbors the character was generated with.
+				_, err = doc.IntegrateRemoteInsert(*char)
+				position = doc.VisiblePosition(char.ID)
+			} else {
+				_, err = doc.GenerateInsert(position, msg.Operation.Value)
+			}
 			if err != nil {
 				logger.Errorf("failed to insert, err: %v\n", err)
+
+				// Missing neighbors mean this document missed operations the sender's had.
+				if errors.Is(err, crdt.ErrBoundsNotPresent) {
+					e.StatusChan <- desyncStatus
+				}
 			}
 
-			e.SetText(crdt.Content(doc))
-			if msg.Operation.Position-1 <= e.Cursor {
-				e.MoveCursor(len(msg.Operation.Value), 0)
+			// Positions outside the editor's text can't be mirrored incrementally, so resync instead.
+			if err != nil || position < 1 || position > len(e.Text)+1 {
+				e.SetText(crdt.Content(doc))
+			} else {
+				e.ApplyInsert(position-1, msg.Operation.Value)
+				noteInsert(position-1, len([]rune(msg.Operation.Value)))
+			}
+			if position-1 <= e.Cursor {
+				e.MoveCursor(len([]rune(msg.Operation.Value)), 0)
+			}
+			if err == nil {
+				recordOp(at, "insert", position, msg.Operation.Value, msg.Username)
 			}
-			logger.Infof("REMOTE INSERT: %s at position %v\n", msg.Operation.Value, msg.Operation.Position)
+			logger.Infof("REMOTE INSERT: %s at position %v by %s\n", msg.Operation.Value, position, msg.Username)
 
 		case "delete":
-			_ = doc.Delete(msg.Operation.Position)
-			e.SetText(crdt.Content(doc))
-			if msg.Operation.Position-1 <= e.Cursor {
-				e.MoveCursor(-len(msg.Operation.Value), 0)
+			// Delete by ID when the character is known, since positions shift under concurrent edits.
+			position := -1
+			var value string
+			if msg.Operation.ID != "" && doc.Contains(msg.Operation.ID) {
+				value = doc.Find(msg.Operation.ID).Value
+				position = doc.DeleteByID(msg.Operation.ID)
+			} else if char := crdt.IthVisible(doc, msg.Operation.Position); char.ID != "-1" {
+				value = char.Value
+				position = msg.Operation.Position
+				doc.GenerateDelete(position)
+			}
+
+			if position >= 1 {
+				recordOp(at, "delete", position, value, msg.Username)
+				e.ApplyDelete(position-1, 1)
+				noteDelete(position-1, 1)
+				if position <= e.Cursor {
+					e.MoveCursor(-1, 0)
+				}
 			}
-			logger.Infof("REMOTE DELETE: position %v\n", msg.Operation.Position)
+			logger.Infof("REMOTE DELETE: position %v by %s\n", position, msg.Username)
 		}
 	}
 
@@ -261,6 +855,9 @@ func handleMsg(msg commons.Message, conn *websocket.Conn) {
 func getMsgChan(conn *websocket.Conn) chan commons.Message {
 	messageChan := make(chan commons.Message)
 	go func() {
+		// Closing the channel tells the main loop to reconnect.
+		defer close(messageChan)
+
 		for {
 			var msg commons.Message
 
@@ -285,6 +882,50 @@ func getMsgChan(conn *websocket.Conn) chan commons.Message {
 	return messageChan
 }
 
+// reconnectInterval is the delay between reconnection attempts.
+var reconnectInterval = 2 * time.Second
+
+// reconnect keeps trying to reach the server until it succeeds, then hands over the new connection.
+func reconnect(connChan chan<- *websocket.Conn) {
+	e.StatusChan <- "reconnecting..."
+
+	// Retry here rather than in createConn, which reports attempts on stdout and would garble the UI.
+	conf := flags
+	conf.ConnectRetries = 0
+
+	for {
+		conn, resp, err := connect(conf)
+		if err == nil {
+			connChan <- conn
+			return
+		}
+
+		logger.Errorf("reconnect failed: %s", classifyConnError(err, resp))
+		time.Sleep(reconnectInterval)
+	}
+}
+
+// rejoin announces the user on a new connection and sends the local document,
+// so edits made while disconnected are merged into the shared document.
+func rejoin(conn *websocket.Conn) error {
+	// The document sent below carries any operations the old connection didn't acknowledge, or didn't send.
+	unacked = nil
+	pendingRun, runFlush = nil, nil
+
+	msg := commons.Message{Username: username, Text: "has rejoined the session.", Type: commons.JoinMessage}
+	if err := conn.WriteJSON(msg); err != nil {
+		return err
+	}
+
+	// A detached document isn't the room's, so it mustn't be merged into it.
+	if detached {
+		return nil
+	}
+
+	docMsg := commons.Message{Type: commons.DocSyncMessage, Document: &doc}
+	return conn.WriteJSON(&docMsg)
+}
+
 // handleStatusMsg asynchronously waits for messages from e.StatusChan and
 // renders the message upon arrival.
 func handleStatusMsg() {
@@ -308,6 +949,19 @@ func handleStatusMsg() {
 
 }
 
+// typingLoop redraws the editor when the set of typing collaborators changes, so the indicator decays.
+// Redrawing only on changes keeps the status bar from flickering.
+func typingLoop() {
+	var last []string
+	for range time.Tick(250 * time.Millisecond) {
+		typing := e.Typing(time.Now())
+		if !slices.Equal(typing, last) {
+			e.SendDraw()
+		}
+		last = typing
+	}
+}
+
 func drawLoop() {
 	for {
 		<-e.DrawChan
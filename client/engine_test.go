@@ -0,0 +1,605 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"text-editor/client/editor"
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// resetState gives each test a fresh document and editor.
+func resetState(t *testing.T) {
+	t.Helper()
+
+	doc = crdt.New()
+	e = editor.NewEditor(editor.EditorConfig{})
+	pendingOps = nil
+	resyncing = false
+	readOnly, fileReadOnly = false, false
+	lockHolder, lockHolderID = "", uuid.Nil
+	activePrompt = nil
+	rec = macro{}
+	modal, mode = false, modeInsert
+	opHistory = newHistory(maxHistory)
+	lamport = commons.LamportClock{}
+	detached = false
+	unacked = nil
+	edits = newEditRing(maxEdits)
+	clipboard, sysClip = "", systemClipboard{}
+	dirty, confirmingQuit = false, false
+	unsent, exitNote = false, ""
+	remoteCursors, following, sentCursor = map[string]string{}, "", ""
+	bookmarks = map[string]string{}
+	pendingRun, runFlush = nil, nil
+	accel = accelerator{}
+	opHooks = nil
+	tabWidth, indentWidth, indentToStop = 4, 4, false
+	userSites, guestWrite = nil, map[string]bool{}
+}
+
+func TestPerformOperation_DeferredUntilSiteID(t *testing.T) {
+	resetState(t)
+
+	for _, ch := range "hi" {
+		performOperation(OperationInsert, termbox.Event{Ch: ch}, nil)
+	}
+
+	// Nothing should be applied while the site ID is unassigned.
+	if got := crdt.Content(doc); got != "" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "")
+	}
+	if got := len(pendingOps); got != 2 {
+		t.Errorf("got != want; got = %v, expected = %v\n", got, 2)
+	}
+
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "7"}, nil)
+
+	if got := crdt.Content(doc); got != "hi" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "hi")
+	}
+	if got := len(pendingOps); got != 0 {
+		t.Errorf("got != want; got = %v, expected = %v\n", got, 0)
+	}
+
+	// Deferred edits must be generated with the assigned site ID.
+	for _, c := range doc.Characters {
+		if c.Visible && !strings.HasPrefix(c.ID, "7") {
+			t.Errorf("character %q generated with wrong site: ID = %v\n", c.Value, c.ID)
+		}
+	}
+}
+
+// loadText returns a document loaded from a file containing text, as -file loads one.
+func loadText(t *testing.T, text string) crdt.Document {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "start.txt")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := crdt.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+func TestHandleMsg_SiteIDClaimsLoadedFile(t *testing.T) {
+	resetState(t)
+
+	// Two clients start with different files, loaded before either has a site ID.
+	doc = loadText(t, "ab")
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "7"}, nil)
+	mine := doc
+
+	resetState(t)
+	doc = loadText(t, "xy")
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "9"}, nil)
+
+	if got := crdt.Content(mine); got != "ab" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "ab")
+	}
+	for _, c := range mine.Characters {
+		if c.Visible && !strings.HasPrefix(c.ID, "7.") {
+			t.Errorf("loaded character %q kept site 0: ID = %v\n", c.Value, c.ID)
+		}
+	}
+
+	// Merging keeps both files' characters rather than mistaking one's for the other's.
+	if _, err := doc.Merge(mine); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if got := crdt.Content(doc); len(got) != 4 || !strings.Contains(got, "ab") || !strings.Contains(got, "xy") {
+		t.Errorf("got merged content %q, expected both files\n", got)
+	}
+}
+
+func TestHandleMsg_SiteIDSharesStartFile(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+
+	// One client starts with a file; once it has a site ID, it sends the file for the room to merge.
+	doc = loadText(t, "ab")
+	handleMsg(commons.Message{Type: commons.PermissionMessage, Text: "write"}, conn)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "7"}, conn)
+
+	var shared commons.Message
+	select {
+	case shared = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the file wasn't sent\n")
+	}
+	if shared.Type != commons.DocSyncMessage || shared.Document == nil || crdt.Content(*shared.Document) != "ab" {
+		t.Fatalf("got %+v, expected a DocSync of the file\n", shared)
+	}
+	mine := doc
+
+	// The other client gets the file from the room, so an edit anchored to the file's characters integrates.
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "9"}, nil)
+	handleMsg(shared, nil)
+	char, err := mine.GenerateCharacter(3, "c")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	handleMsg(commons.Message{Type: "operation", Username: "peer", Operation: commons.Operation{Type: "insert", Position: 3, Value: "c", Character: &char}}, nil)
+	if got := crdt.Content(doc); got != "abc" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "abc")
+	}
+}
+
+func TestHandleMsg_SiteIDKeepsStartFileWhenReadOnly(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+
+	doc = loadText(t, "ab")
+	handleMsg(commons.Message{Type: commons.PermissionMessage, Text: "read"}, conn)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "7"}, conn)
+
+	// A guest that can't edit the room shows the file without merging it into the room's document.
+	if !detached {
+		t.Errorf("the file wasn't kept local\n")
+	}
+	if got := crdt.Content(doc); got != "ab" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "ab")
+	}
+	select {
+	case msg := <-received:
+		t.Errorf("got %+v, expected nothing to be sent\n", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestHandleMsg_DocSyncAfterReconnect(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "3"}, nil)
+
+	// Another client's document, generated before this client's offline edit.
+	other := crdt.New()
+	other.SiteID = 1
+	other.GenerateInsert(1, "a")
+	doc.Characters = append([]crdt.Character(nil), other.Characters...)
+
+	// Offline edits on both sides.
+	doc.GenerateInsert(2, "b")
+	other.GenerateInsert(1, "c")
+
+	// The server hands out a new site ID on reconnect, which must not replace ours.
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "9"}, nil)
+	if doc.SiteID != 3 {
+		t.Errorf("got != want; got = %v, expected = %v\n", doc.SiteID, 3)
+	}
+
+	handleMsg(commons.Message{Type: commons.DocSyncMessage, Document: &other}, nil)
+
+	want := "cab"
+	if got := crdt.Content(doc); got != want {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, want)
+	}
+	if got := e.GetText(); string(got) != want {
+		t.Errorf("editor out of sync; got = %q, expected = %q\n", string(got), want)
+	}
+}
+
+func TestRequestResync_ReplacesLocalDocument(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "3"}, nil)
+
+	// The peer's copy is the shared document.
+	peer := crdt.New()
+	peer.SiteID = 1
+	peer.GenerateInsert(1, "o")
+	peer.GenerateInsert(2, "k")
+
+	// The local copy has diverged, with a stray character and the cursor past the peer's text.
+	doc.Characters = append([]crdt.Character(nil), peer.Characters...)
+	doc.GenerateInsert(3, "!")
+	doc.GenerateInsert(4, "?")
+	e.SetText(crdt.Content(doc))
+	e.SetX(4)
+
+	// The stub server answers a DocReq with the peer's document.
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msg commons.Message
+		if err := conn.ReadJSON(&msg); err != nil || msg.Type != commons.DocReqMessage {
+			t.Errorf("expected a DocReq; got = %+v, err = %v\n", msg, err)
+			return
+		}
+		conn.WriteJSON(commons.Message{Type: commons.DocSyncMessage, Document: &peer})
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial error: %v\n", err)
+	}
+	defer conn.Close()
+
+	e.IsConnected = true
+	requestResync(conn)
+
+	var msg commons.Message
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read error: %v\n", err)
+	}
+	handleMsg(msg, conn)
+
+	if got := crdt.Content(doc); got != "ok" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "ok")
+	}
+	if got := string(e.GetText()); got != "ok" {
+		t.Errorf("editor out of sync; got = %q, expected = %q\n", got, "ok")
+	}
+	if e.Cursor != 2 {
+		t.Errorf("cursor not clamped; got = %v, expected = %v\n", e.Cursor, 2)
+	}
+	if doc.SiteID != 3 {
+		t.Errorf("site ID changed; got = %v, expected = %v\n", doc.SiteID, 3)
+	}
+	if resyncing {
+		t.Errorf("still resyncing after the document arrived\n")
+	}
+}
+
+func TestPerformOperation_ReadOnly(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.PermissionMessage, Text: "read"}, nil)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "2"}, nil)
+
+	performOperation(OperationInsert, termbox.Event{Ch: 'x'}, nil)
+	if got := crdt.Content(doc); got != "" {
+		t.Errorf("read-only edit was applied; got = %q\n", got)
+	}
+
+	handleMsg(commons.Message{Type: commons.PermissionMessage, Text: "write"}, nil)
+	performOperation(OperationInsert, termbox.Event{Ch: 'x'}, nil)
+	if got := crdt.Content(doc); got != "x" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "x")
+	}
+}
+
+func TestPerformOperation_TypeAfterDeletingEverything(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+	e.IsConnected = true
+	doc.SiteID = 1
+
+	for _, ch := range "abc" {
+		performOperation(OperationInsert, termbox.Event{Ch: ch}, conn)
+	}
+	// One more Backspace than there are characters: the last has nothing to delete.
+	for range 4 {
+		performOperation(OperationDelete, termbox.Event{}, conn)
+	}
+	if got := crdt.Content(doc); got != "" || e.Cursor != 0 {
+		t.Fatalf("got content %q with the cursor at %d, expected an empty document with the cursor at 0\n", got, e.Cursor)
+	}
+
+	performOperation(OperationInsert, termbox.Event{Ch: 'x'}, conn)
+	if got := crdt.Content(doc); got != "x" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "x")
+	}
+	if got := string(e.GetText()); got != "x" || e.Cursor != 1 {
+		t.Errorf("editor shows %q with the cursor at %d, expected %q with the cursor at 1\n", got, e.Cursor, "x")
+	}
+
+	// A peer applying the operations sent ends up with the same document.
+	peer := crdt.New()
+	peer.SiteID = 2
+	for i := 0; i < 7; i++ {
+		var msg commons.Message
+		select {
+		case msg = <-received:
+		case <-time.After(time.Second):
+			t.Fatalf("got %d operations, expected 7\n", i)
+		}
+		switch op := msg.Operation; op.Type {
+		case "insert":
+			if _, err := peer.IntegrateRemoteInsert(*op.Character); err != nil {
+				t.Fatalf("error: %v\n", err)
+			}
+		case "delete":
+			if op.ID == "" || peer.DeleteByID(op.ID) < 1 {
+				t.Errorf("delete of %q didn't delete a character\n", op.ID)
+			}
+		}
+	}
+	select {
+	case msg := <-received:
+		t.Errorf("unexpected message %+v\n", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := crdt.Content(peer); got != "x" {
+		t.Errorf("peer diverged; got = %q, expected = %q\n", got, "x")
+	}
+}
+
+func TestPerformOperation_TypeAfterPeerDeletedEverything(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+	for _, ch := range "abc" {
+		performOperation(OperationInsert, termbox.Event{Ch: ch}, nil)
+	}
+
+	// A peer deletes everything and syncs its document, which leaves only tombstones here.
+	peer := crdt.Document{Characters: slices.Clone(doc.Characters)}
+	for _, char := range doc.Characters {
+		peer.IntegrateDelete(char)
+	}
+	handleMsg(commons.Message{Type: commons.DocSyncMessage, Document: &peer}, nil)
+	if got := crdt.Content(doc); got != "" {
+		t.Fatalf("got != want; got = %q, expected an empty document\n", got)
+	}
+
+	performOperation(OperationInsert, termbox.Event{Ch: 'x'}, nil)
+	if got := crdt.Content(doc); got != "x" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "x")
+	}
+	if got := string(e.GetText()); got != "x" || e.Cursor != 1 {
+		t.Errorf("editor shows %q with the cursor at %d, expected %q with the cursor at 1\n", got, e.Cursor, "x")
+	}
+}
+
+func TestHandleMsg_DocSyncMissingSentinels(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	performOperation(OperationInsert, termbox.Event{Ch: 'a'}, nil)
+
+	want := append([]crdt.Character(nil), doc.Characters...)
+
+	// A document whose only character lost its sentinels, as a malformed peer might send.
+	malformed := crdt.Document{Characters: []crdt.Character{{ID: "21", Visible: true, Value: "x"}}}
+
+	for _, msg := range []commons.Message{
+		{Type: commons.DocSyncMessage},
+		{Type: commons.DocSyncMessage, Document: &crdt.Document{}},
+		{Type: commons.DocSyncMessage, Document: &malformed},
+	} {
+		handleMsg(msg, nil)
+	}
+
+	// A requested resync must not adopt it either.
+	resyncing = true
+	handleMsg(commons.Message{Type: commons.DocSyncMessage, Document: &malformed}, nil)
+
+	if !cmp.Equal(doc.Characters, want) {
+		t.Errorf("local document changed; diff = %v\n", cmp.Diff(doc.Characters, want))
+	}
+	if got := string(e.GetText()); got != "a" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "a")
+	}
+
+	// Edits still work afterwards.
+	performOperation(OperationInsert, termbox.Event{Ch: 'b'}, nil)
+	if got := crdt.Content(doc); got != "ab" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "ab")
+	}
+}
+
+func TestHandleMsg_RemoteInsertMovesCursor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"x", "xabc"},
+		{"é", "éabc"},
+		{"世", "世abc"},
+	}
+
+	for _, tc := range tests {
+		resetState(t)
+		handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+		typeText(t, "abc")
+		e.SetX(2)
+
+		// A peer inserts before the cursor, which moves past the one character inserted.
+		peer := crdt.New()
+		peer.SiteID = 2
+		peer.Characters = append([]crdt.Character(nil), doc.Characters...)
+		char, err := peer.GenerateCharacter(1, tc.value)
+		if err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+		handleMsg(commons.Message{Type: "operation", Username: "peer", Operation: commons.Operation{Type: "insert", Position: 1, Value: tc.value, Character: &char}}, nil)
+
+		if got := string(e.GetText()); got != tc.want || e.Cursor != 3 {
+			t.Errorf("(%q) editor shows %q with the cursor at %d, expected %q with the cursor at 3\n", tc.value, got, e.Cursor, tc.want)
+		}
+	}
+}
+
+func TestLamportClock_SendReceive(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	var last uint64
+	advanced := func(step string) {
+		t.Helper()
+		if now := lamport.Now(); now <= last {
+			t.Errorf("(%s) clock went from %d to %d", step, last, now)
+		}
+		last = lamport.Now()
+	}
+
+	performOperation(OperationInsert, termbox.Event{Ch: 'a'}, nil)
+	advanced("local insert")
+
+	// A peer that has seen far more operations.
+	handleMsg(commons.Message{Type: "operation", Username: "bob", Operation: commons.Operation{Type: "insert", Position: 1, Value: "b", Clock: 10}}, nil)
+	advanced("remote insert")
+	if last <= 10 {
+		t.Errorf("clock %d should be past the received operation's 10", last)
+	}
+
+	// A stale remote operation still advances the clock.
+	handleMsg(commons.Message{Type: "operation", Username: "bob", Operation: commons.Operation{Type: "delete", Position: 1, Clock: 3}}, nil)
+	advanced("stale remote delete")
+
+	performOperation(OperationInsert, termbox.Event{Ch: 'c'}, nil)
+	advanced("local insert after receiving")
+}
+
+func TestLoadFile_EditArrivesMidLoad(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	// Both clients share "ab".
+	for _, ch := range "ab" {
+		performOperation(OperationInsert, termbox.Event{Ch: ch}, nil)
+	}
+	peer := crdt.Document{Characters: append([]crdt.Character(nil), doc.Characters...), SiteID: 2}
+
+	// The peer types between "a" and "b"; its edit is in flight while this client loads a file.
+	char, err := peer.GenerateCharacter(2, "x")
+	if err != nil {
+		t.Fatalf("error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("new"), 0644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	oldFileName := fileName
+	fileName = path
+	defer func() { fileName = oldFileName }()
+
+	if err := loadFile(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := crdt.Content(doc); got != "new" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "new")
+	}
+
+	handleMsg(commons.Message{Type: "operation", Username: "peer", Operation: commons.Operation{Type: "insert", Position: 2, Value: "x", Character: &char}}, nil)
+
+	// The peer merges the loaded document it's sent.
+	if _, err := peer.Merge(doc); err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+
+	got := crdt.Content(doc)
+	if got != crdt.Content(peer) {
+		t.Errorf("replicas diverged: local = %q, peer = %q\n", got, crdt.Content(peer))
+	}
+	if strings.ReplaceAll(got, "x", "") != "new" || !strings.Contains(got, "x") {
+		t.Errorf("got %q, expected \"new\" plus the peer's \"x\"", got)
+	}
+	if string(e.Text) != got {
+		t.Errorf("editor text %q doesn't match the document %q", string(e.Text), got)
+	}
+}
+
+func TestLoadFile_NoBroadcast(t *testing.T) {
+	// The stub server collects everything the client sends until it disconnects.
+	received := make(chan []commons.Message, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msgs []commons.Message
+		for {
+			var msg commons.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				received <- msgs
+				return
+			}
+			msgs = append(msgs, msg)
+		}
+	}))
+	defer srv.Close()
+
+	for _, noBroadcast := range []bool{false, true} {
+		resetState(t)
+		handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+		path := filepath.Join(t.TempDir(), "file.txt")
+		if err := os.WriteFile(path, []byte("ref"), 0644); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		oldFileName, oldFlags := fileName, flags
+		fileName, flags.NoBroadcastLoad = path, noBroadcast
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("dial error: %v\n", err)
+		}
+		e.IsConnected = true
+
+		if err := loadFile(conn); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		performOperation(OperationInsert, termbox.Event{Ch: '!'}, conn)
+
+		// Remote edits don't apply to the detached document.
+		handleMsg(commons.Message{Type: "operation", Operation: commons.Operation{Type: "insert", Position: 1, Value: "x"}}, conn)
+
+		conn.Close()
+		msgs := <-received
+		fileName, flags = oldFileName, oldFlags
+
+		var docSyncs, ops int
+		for _, msg := range msgs {
+			switch msg.Type {
+			case commons.DocSyncMessage:
+				docSyncs++
+			case "operation":
+				ops++
+			}
+		}
+
+		if noBroadcast {
+			if docSyncs != 0 || ops != 0 {
+				t.Errorf("no-broadcast load: got %d DocSyncs and %d operations sent, expected none", docSyncs, ops)
+			}
+			if got := crdt.Content(doc); got != "!ref" {
+				t.Errorf("no-broadcast load: got %q, expected %q", got, "!ref")
+			}
+		} else if docSyncs != 1 || ops != 1 {
+			t.Errorf("broadcast load: got %d DocSyncs and %d operations sent, expected 1 of each", docSyncs, ops)
+		}
+	}
+}
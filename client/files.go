@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// queuedFiles lists the other files given with -file, as path or path:line[:col], in the order F12 opens them.
+var queuedFiles []string
+
+// fileList is the -file flag. It can be given several times, each time with one file or a comma-separated list,
+// and collects them all in Files; File is the first, the one opened on startup.
+type fileList struct {
+	f *Flags
+
+	// fromConfig is set while the files came from the config file, so the command line replaces rather than adds to them.
+	fromConfig bool
+}
+
+func (l *fileList) String() string {
+	if l.f == nil {
+		return ""
+	}
+	return strings.Join(l.f.Files, ",")
+}
+
+func (l *fileList) Set(value string) error {
+	if l.fromConfig {
+		l.f.Files, l.fromConfig = nil, false
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			l.f.Files = append(l.f.Files, name)
+		}
+	}
+
+	l.f.File = ""
+	if len(l.f.Files) > 0 {
+		l.f.File = l.f.Files[0]
+	}
+	return nil
+}
+
+// markDefault notes that the files set so far are defaults from the config file.
+func (l *fileList) markDefault() {
+	l.fromConfig = true
+}
+
+// existingFiles returns the files in targets, as given to -file, that exist, warning on w about the ones that don't.
+// If none exist, the first is kept, so opening it reports why.
+func existingFiles(targets []string, w io.Writer) []string {
+	var found []string
+	for _, target := range targets {
+		path, _, _ := fileTarget(target)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(w, "Skipping %s: no such file\n", path)
+			continue
+		}
+		found = append(found, target)
+	}
+
+	if len(found) == 0 && len(targets) > 0 {
+		return targets[:1]
+	}
+	return found
+}
+
+// nextFile opens the next file given with -file, asking for confirmation first if there are unsaved changes.
+// The current file goes to the back of the queue, so F12 cycles through them all.
+func nextFile(conn *websocket.Conn) {
+	if len(queuedFiles) == 0 {
+		e.StatusChan <- "No other files: open several with -file a.txt,b.txt"
+		return
+	}
+	if !dirty {
+		switchFile(conn)
+		return
+	}
+
+	openPrompt("Unsaved changes, open the next file anyway? (y/n) ", func(input string, conn *websocket.Conn) {
+		if answer := strings.ToLower(strings.TrimSpace(input)); answer != "y" && answer != "yes" {
+			e.StatusChan <- "cancelled"
+			return
+		}
+		switchFile(conn)
+	})
+}
+
+// switchFile loads the first queued file in place of the current one.
+// A file that fails to load is dropped from the queue, and the current one kept.
+func switchFile(conn *websocket.Conn) {
+	target := queuedFiles[0]
+	queuedFiles = queuedFiles[1:]
+
+	previous := fileName
+	path, line, col := fileTarget(target)
+	fileName = path
+	e.SetFileName(fileName)
+	if err := loadFile(conn); err != nil {
+		fileName = previous
+		e.SetFileName(fileName)
+		return
+	}
+
+	if previous != "" {
+		queuedFiles = append(queuedFiles, previous)
+	}
+	if line > 0 {
+		e.GotoLine(line, col)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestParseArgs_Files(t *testing.T) {
+	var f Flags
+	fs := newJoinFlags(&f)
+	if err := parseArgs(fs, []string{"-config", writeConfig(t, `{}`), "-file", "a.txt, b.txt,", "-file", "c.txt:3"}); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if want := []string{"a.txt", "b.txt", "c.txt:3"}; !slices.Equal(f.Files, want) || f.File != "a.txt" {
+		t.Errorf("got files %q and file %q, expected %q and %q", f.Files, f.File, want, "a.txt")
+	}
+
+	// Files from the config are replaced, not added to, by the command line.
+	path := writeConfig(t, `{"file": "notes.txt,todo.txt"}`)
+	f = Flags{}
+	if err := parseArgs(newJoinFlags(&f), []string{"-config", path, "-file", "x.txt"}); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if want := []string{"x.txt"}; !slices.Equal(f.Files, want) || f.File != "x.txt" {
+		t.Errorf("got files %q and file %q, expected %q", f.Files, f.File, want)
+	}
+
+	f = Flags{}
+	if err := parseArgs(newJoinFlags(&f), []string{"-config", path}); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if want := []string{"notes.txt", "todo.txt"}; !slices.Equal(f.Files, want) {
+		t.Errorf("got files %q, expected %q from the config", f.Files, want)
+	}
+}
+
+func TestExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	for _, name := range []string{a, b} {
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missing := filepath.Join(dir, "missing.txt")
+
+	var warnings strings.Builder
+	got := existingFiles([]string{missing, a + ":2", missing + ":4", b}, &warnings)
+	if want := []string{a + ":2", b}; !slices.Equal(got, want) {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+	if n := strings.Count(warnings.String(), "Skipping "+missing); n != 2 {
+		t.Errorf("got warnings %q, expected two about %s", warnings.String(), missing)
+	}
+
+	// With no file to open, the first is kept so opening it explains the problem.
+	if got := existingFiles([]string{missing}, &warnings); !slices.Equal(got, []string{missing}) {
+		t.Errorf("got %q, expected %q", got, []string{missing})
+	}
+}
+
+func TestNextFile(t *testing.T) {
+	resetState(t)
+	oldFileName, oldQueued := fileName, queuedFiles
+	defer func() { fileName, queuedFiles = oldFileName, oldQueued }()
+	doc.SiteID = 1
+
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(a, []byte("aaa"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("b\nbb"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileName, queuedFiles = a, []string{b + ":2:2", filepath.Join(dir, "gone.txt")}
+
+	// F12 opens the next file at the given position, and queues the current one.
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF12}, nil)
+	if fileName != b || crdt.Content(doc) != "b\nbb" || e.Cursor != 3 {
+		t.Fatalf("got file %s with %q and cursor %d, expected %s with %q and cursor 3", fileName, crdt.Content(doc), e.Cursor, b, "b\nbb")
+	}
+
+	// A file that fails to load is dropped, keeping the current one.
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF12}, nil)
+	if fileName != b || !slices.Equal(queuedFiles, []string{a}) {
+		t.Errorf("got file %s and queue %q, expected %s and %q", fileName, queuedFiles, b, []string{a})
+	}
+
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF12}, nil)
+	if fileName != a || crdt.Content(doc) != "aaa" {
+		t.Errorf("got file %s with %q, expected %s with %q", fileName, crdt.Content(doc), a, "aaa")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// parseFileTarget splits a path:line:col or path:line argument, as compilers print locations, into its parts.
+// Line and column count from 1, and are 0 when not given. Only trailing numbers are split off,
+// so colons elsewhere in the path, e.g. a Windows drive letter or a name like "notes:draft.txt", are kept.
+func parseFileTarget(arg string) (path string, line, col int) {
+	path = arg
+
+	var nums []int
+	for len(nums) < 2 {
+		i := strings.LastIndexByte(path, ':')
+		if i <= 0 {
+			break
+		}
+		n, err := strconv.Atoi(path[i+1:])
+		if err != nil || n <= 0 || strings.ContainsAny(path[i+1:], "+-") {
+			break
+		}
+		nums = append(nums, n)
+		path = path[:i]
+	}
+
+	switch len(nums) {
+	case 1:
+		return path, nums[0], 0
+	case 2:
+		return path, nums[1], nums[0]
+	}
+	return arg, 0, 0
+}
+
+// fileTarget is parseFileTarget for the -file flag: an existing file whose name ends in numbers is opened as named.
+func fileTarget(arg string) (path string, line, col int) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, 0, 0
+	}
+	return parseFileTarget(arg)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFileTarget(t *testing.T) {
+	tests := []struct {
+		arg      string
+		wantPath string
+		wantLine int
+		wantCol  int
+	}{
+		{"file.txt", "file.txt", 0, 0},
+		{"file.txt:42", "file.txt", 42, 0},
+		{"file.txt:42:10", "file.txt", 42, 10},
+		{"dir/file.txt:3:1", "dir/file.txt", 3, 1},
+		{`C:\Users\me\file.txt`, `C:\Users\me\file.txt`, 0, 0},
+		{`C:\Users\me\file.txt:7`, `C:\Users\me\file.txt`, 7, 0},
+		{`C:\Users\me\file.txt:7:2`, `C:\Users\me\file.txt`, 7, 2},
+		{"notes:draft.txt", "notes:draft.txt", 0, 0},
+		{"notes:draft.txt:5", "notes:draft.txt", 5, 0},
+		{"log:1:2:3", "log:1", 2, 3},
+		{"file.txt:", "file.txt:", 0, 0},
+		{"file.txt:0", "file.txt:0", 0, 0},
+		{"file.txt:+4", "file.txt:+4", 0, 0},
+		{"file.txt:-4", "file.txt:-4", 0, 0},
+		{"file.txt:x:4", "file.txt:x", 4, 0},
+		{":42", ":42", 0, 0},
+	}
+
+	for _, tc := range tests {
+		path, line, col := parseFileTarget(tc.arg)
+		if path != tc.wantPath || line != tc.wantLine || col != tc.wantCol {
+			t.Errorf("parseFileTarget(%q) = (%q, %d, %d), expected (%q, %d, %d)", tc.arg, path, line, col, tc.wantPath, tc.wantLine, tc.wantCol)
+		}
+	}
+}
+
+func TestFileTarget_ExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "build:12")
+	if err := os.WriteFile(path, []byte("log"), 0644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	// A file named like a location is opened as named...
+	if got, line, col := fileTarget(path); got != path || line != 0 || col != 0 {
+		t.Errorf("fileTarget(%q) = (%q, %d, %d), expected the file itself", path, got, line, col)
+	}
+
+	// ...unless it's given a location of its own.
+	if got, line, col := fileTarget(path + ":3:4"); got != path || line != 3 || col != 4 {
+		t.Errorf("fileTarget(%q) = (%q, %d, %d), expected (%q, 3, 4)", path+":3:4", got, line, col, path)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// remoteCursors maps collaborators' names to the ID of the character before their cursor, as they last announced it.
+	// Character IDs, unlike positions, stay valid as the text around them changes.
+	remoteCursors = map[string]string{}
+
+	// following names the collaborator whose view this editor follows, or "" if none.
+	following string
+
+	// sentCursor is the character ID last announced as this client's cursor, so an unmoved cursor isn't sent again.
+	sentCursor string
+)
+
+// cursorCharID returns the ID of the character before position cursor of the document, or "start" at its start.
+func cursorCharID(cursor int) string {
+	if cursor <= 0 {
+		return crdt.StartChar.ID
+	}
+	if char := crdt.IthVisible(doc, cursor); char.ID != "-1" {
+		return char.ID
+	}
+	return crdt.StartChar.ID
+}
+
+// cursorPosition returns the position of the cursor after the character with the given ID, or -1 if it was deleted.
+func cursorPosition(charID string) int {
+	if charID == crdt.StartChar.ID {
+		return 0
+	}
+	return doc.VisiblePosition(charID)
+}
+
+// broadcastCursor announces this client's cursor to the room when it has moved, so others can follow it.
+func broadcastCursor(conn *websocket.Conn) {
+	// Peers can't place a cursor after characters of a run they haven't been sent yet.
+	if conn == nil || !e.IsConnected || detached || pendingRun != nil {
+		return
+	}
+
+	id := cursorCharID(e.Cursor)
+	if id == sentCursor {
+		return
+	}
+	if err := conn.WriteJSON(commons.Message{Type: commons.CursorMessage, Text: id}); err != nil {
+		logger.Errorf("failed to send cursor, err: %v\n", err)
+		return
+	}
+	sentCursor = id
+}
+
+// handleCursorMsg records a collaborator's cursor, scrolling to it if they're being followed.
+func handleCursorMsg(msg commons.Message) {
+	remoteCursors[msg.Username] = msg.Text
+	if msg.Username == following {
+		followCursor()
+	}
+}
+
+// followCursor scrolls the view to the followed collaborator's cursor, if it's known and still in the document.
+func followCursor() {
+	charID, ok := remoteCursors[following]
+	if !ok {
+		return
+	}
+	if pos := cursorPosition(charID); pos >= 0 {
+		e.ShowPosition(pos)
+	}
+}
+
+// toggleFollow stops following a collaborator, or prompts for one to follow.
+func toggleFollow() {
+	if following != "" {
+		e.StatusChan <- fmt.Sprintf("Stopped following %s", following)
+		following = ""
+		return
+	}
+	openPrompt("Follow user: ", func(input string, conn *websocket.Conn) {
+		startFollowing(strings.TrimSpace(input))
+	})
+}
+
+// startFollowing follows the named collaborator's view, which must be in the session.
+func startFollowing(name string) {
+	e.StatusMu.Lock()
+	users := e.Users
+	e.StatusMu.Unlock()
+
+	switch {
+	case name == "":
+		e.StatusChan <- "no user given"
+	case name == username:
+		e.StatusChan <- "you can't follow yourself"
+	case !slices.Contains(users, name):
+		e.StatusChan <- fmt.Sprintf("%s isn't in the session", name)
+	default:
+		following = name
+		followCursor()
+		e.StatusChan <- fmt.Sprintf("Following %s (F7 stops)", name)
+	}
+}
+
+// pruneCursors forgets the cursors of collaborators who left, and stops following one who did.
+func pruneCursors(users []string) {
+	for name := range remoteCursors {
+		if !slices.Contains(users, name) {
+			delete(remoteCursors, name)
+		}
+	}
+	if following != "" && !slices.Contains(users, following) {
+		e.StatusChan <- fmt.Sprintf("%s left, stopped following", following)
+		following = ""
+	}
+}
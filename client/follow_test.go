@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestCursorCharID_RoundTrip(t *testing.T) {
+	resetState(t)
+	typeText(t, "abc")
+
+	for cursor := 0; cursor <= 3; cursor++ {
+		if got := cursorPosition(cursorCharID(cursor)); got != cursor {
+			t.Errorf("cursor %d came back as %d", cursor, got)
+		}
+	}
+
+	// A cursor after a deleted character can't be placed.
+	id := cursorCharID(2)
+	doc.DeleteByID(id)
+	if got := cursorPosition(id); got != -1 {
+		t.Errorf("got position %d after a deleted character, expected -1", got)
+	}
+}
+
+func TestFollow_ScrollsToFollowedCursor(t *testing.T) {
+	resetState(t)
+	e.ScrollEnabled = true
+	e.SetSize(10, 5)
+	typeText(t, "1\n2\n3\n4\n5\n6\n7\n8\n9")
+	oldUsername := username
+	username = "me"
+	defer func() { username = oldUsername }()
+	handleMsg(commons.Message{Type: commons.UsersMessage, Text: "me,alice,bob,"}, nil)
+
+	// Cursors of users who aren't followed don't scroll the view.
+	handleMsg(commons.Message{Type: commons.CursorMessage, Username: "alice", Text: crdt.IthVisible(doc, 14).ID}, nil)
+	if e.RowOff != 0 {
+		t.Fatalf("row offset = %d before following, expected 0", e.RowOff)
+	}
+
+	// Following starts at the user's last known cursor, after the 8 on the 8th line.
+	for _, ev := range []termbox.Event{{Key: termbox.KeyF7}, {Ch: 'a'}, {Ch: 'l'}, {Ch: 'i'}, {Ch: 'c'}, {Ch: 'e'}, {Key: termbox.KeyEnter}} {
+		_ = handleTermboxEvent(ev, nil)
+	}
+	if following != "alice" {
+		t.Fatalf("following %q, expected %q", following, "alice")
+	}
+	if e.RowOff != 5 || e.Cursor != 0 {
+		t.Errorf("row offset = %d and cursor = %d, expected 5 and 0", e.RowOff, e.Cursor)
+	}
+
+	// Their cursor moving to the top scrolls back; bob's doesn't.
+	handleMsg(commons.Message{Type: commons.CursorMessage, Username: "bob", Text: crdt.IthVisible(doc, 16).ID}, nil)
+	handleMsg(commons.Message{Type: commons.CursorMessage, Username: "alice", Text: crdt.StartChar.ID}, nil)
+	if e.RowOff != 0 {
+		t.Errorf("row offset = %d, expected 0", e.RowOff)
+	}
+
+	// Following stops when they leave.
+	handleMsg(commons.Message{Type: commons.UsersMessage, Text: "me,bob,"}, nil)
+	if following != "" {
+		t.Errorf("still following %q after they left", following)
+	}
+	if _, ok := remoteCursors["alice"]; ok {
+		t.Errorf("kept the cursor of a user who left")
+	}
+}
+
+func TestFollow_Toggle(t *testing.T) {
+	resetState(t)
+	oldUsername := username
+	username = "me"
+	defer func() { username = oldUsername }()
+	handleMsg(commons.Message{Type: commons.UsersMessage, Text: "me,alice,"}, nil)
+
+	for _, name := range []string{"", "me", "carol"} {
+		startFollowing(name)
+		if following != "" {
+			t.Errorf("following %q after asking to follow %q", following, name)
+		}
+	}
+
+	startFollowing("alice")
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF7}, nil)
+	if following != "" || activePrompt != nil {
+		t.Errorf("F7 while following: got following %q and prompt open = %v, expected neither", following, activePrompt != nil)
+	}
+}
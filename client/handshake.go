@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+// errRejected is returned when the server won't speak this client's protocol.
+var errRejected = errors.New("the server rejected this client")
+
+// protocol is the version of the message protocol agreed with the server.
+var protocol int
+
+// connect connects to the server like createConn, then agrees on the protocol version to speak with it.
+func connect(flags Flags) (*websocket.Conn, *http.Response, error) {
+	conn, resp, err := createConn(flags)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	version, err := handshake(conn, flags.ConnectTimeout)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	protocol = version
+	return conn, nil, nil
+}
+
+// handshake tells the server the newest protocol version this client speaks, and returns the one it picks.
+// A timeout of 0 waits as long as it takes.
+func handshake(conn *websocket.Conn, timeout time.Duration) (int, error) {
+	hello := commons.Message{Type: commons.HelloMessage, Text: strconv.Itoa(commons.ProtocolVersion)}
+	if err := conn.WriteJSON(hello); err != nil {
+		return 0, err
+	}
+
+	if timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	var welcome commons.Message
+	if err := conn.ReadJSON(&welcome); err != nil {
+		var closeErr *websocket.CloseError
+		if errors.As(err, &closeErr) && closeErr.Code == websocket.CloseProtocolError {
+			return 0, fmt.Errorf("%w: %s", errRejected, closeErr.Text)
+		}
+		return 0, err
+	}
+	if welcome.Type != commons.WelcomeMessage {
+		return 0, fmt.Errorf("%w: expected a %q message, got %q: upgrade the server", errRejected, commons.WelcomeMessage, welcome.Type)
+	}
+
+	version, err := strconv.Atoi(welcome.Text)
+	if err != nil || version < commons.MinProtocolVersion || version > commons.ProtocolVersion {
+		return 0, fmt.Errorf("%w: the server picked protocol version %q, this client speaks %d to %d",
+			errRejected, welcome.Text, commons.MinProtocolVersion, commons.ProtocolVersion)
+	}
+	return version, nil
+}
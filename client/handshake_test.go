@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+// newHandshakeServer starts a server that answers a client's hello with reply, or rejects it with reason if reply is nil.
+func newHandshakeServer(t *testing.T, reply *commons.Message, reason string) string {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var hello commons.Message
+		if err := conn.ReadJSON(&hello); err != nil || hello.Type != commons.HelloMessage {
+			return
+		}
+		if reply == nil {
+			msg := websocket.FormatCloseMessage(websocket.CloseProtocolError, reason)
+			_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			return
+		}
+		_ = conn.WriteJSON(reply)
+		_, _, _ = conn.ReadMessage()
+	}))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestConnect_Negotiates(t *testing.T) {
+	addr := newHandshakeServer(t, &commons.Message{Type: commons.WelcomeMessage, Text: "1"}, "")
+
+	protocol = 0
+	conn, _, err := connect(Flags{Server: addr, ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("connect error: %v", err)
+	}
+	conn.Close()
+	if protocol != 1 {
+		t.Errorf("got protocol %d, expected 1", protocol)
+	}
+}
+
+func TestConnect_Rejected(t *testing.T) {
+	tests := []struct {
+		description string
+		reply       *commons.Message
+		reason      string
+		want        string
+	}{
+		{"closed by the server", nil, "protocol version 1 is no longer supported", "no longer supported"},
+		{"unsupported welcome", &commons.Message{Type: commons.WelcomeMessage, Text: "9"}, "", "picked protocol version \"9\""},
+		{"no welcome", &commons.Message{Type: commons.SiteIDMessage, Text: "1"}, "", "upgrade the server"},
+	}
+
+	for _, tc := range tests {
+		addr := newHandshakeServer(t, tc.reply, tc.reason)
+		_, resp, err := connect(Flags{Server: addr, ConnectTimeout: time.Second})
+		if !errors.Is(err, errRejected) {
+			t.Fatalf("(%s) got err = %v, expected a rejection", tc.description, err)
+		}
+		if got := classifyConnError(err, resp); !strings.Contains(got, tc.want) {
+			t.Errorf("(%s) got = %q, expected it to contain %q", tc.description, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// pingInterval is how often the heartbeat pings the server.
+var pingInterval = 5 * time.Second
+
+// startHeartbeat pings the server every pingInterval, showing the round-trip time of each pong in the info bar.
+// Each ping carries its send time, which the server echoes in the pong. The heartbeat stops when the connection closes.
+// It must be called before the connection is read from, so no pong is missed.
+func startHeartbeat(conn *websocket.Conn) {
+	e.SetLatency(0)
+
+	conn.SetPongHandler(func(data string) error {
+		rtt, err := pongRTT(data, time.Now())
+		if err != nil {
+			logger.Errorf("ignoring pong, err: %v\n", err)
+			return nil
+		}
+		e.SetLatency(rtt)
+		return nil
+	})
+
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+
+		for {
+			payload := pingPayload(time.Now())
+			if err := conn.WriteControl(websocket.PingMessage, payload, time.Now().Add(pingInterval)); err != nil {
+				logger.Infof("heartbeat stopped, err: %v\n", err)
+				return
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// pingPayload encodes a ping's send time.
+func pingPayload(sent time.Time) []byte {
+	return []byte(strconv.FormatInt(sent.UnixNano(), 10))
+}
+
+// pongRTT returns the round-trip time of a ping whose pong, carrying data, arrived at now.
+func pongRTT(data string, now time.Time) (time.Duration, error) {
+	sent, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pong payload %q: %w", data, err)
+	}
+
+	rtt := now.Sub(time.Unix(0, sent))
+	if rtt < 0 {
+		return 0, fmt.Errorf("pong from the future: %v", rtt)
+	}
+	return rtt, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPongRTT(t *testing.T) {
+	sent := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	payload := string(pingPayload(sent))
+
+	tests := []struct {
+		description string
+		data        string
+		now         time.Time
+		want        time.Duration
+		wantErr     bool
+	}{
+		{"immediate pong", payload, sent, 0, false},
+		{"typical pong", payload, sent.Add(37 * time.Millisecond), 37 * time.Millisecond, false},
+		{"slow pong", payload, sent.Add(2*time.Second + 5*time.Microsecond), 2*time.Second + 5*time.Microsecond, false},
+		{"pong before its ping", payload, sent.Add(-time.Millisecond), 0, true},
+		{"empty payload", "", sent, 0, true},
+		{"garbage payload", "pong", sent, 0, true},
+	}
+
+	for _, tc := range tests {
+		got, err := pongRTT(tc.data, tc.now)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("(%s) err = %v, expected an error: %v\n", tc.description, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("(%s) got != want; got = %v, expected = %v\n", tc.description, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxHistory bounds how many operations the history keeps.
+const maxHistory = 200
+
+// historyEntry describes an operation applied to the local document.
+type historyEntry struct {
+	Time     time.Time
+	Type     string
+	Position int
+	Value    string
+	Author   string
+}
+
+// String formats the entry as a line of the history panel.
+func (h historyEntry) String() string {
+	return fmt.Sprintf("%s  %-6s %5d  %-8q %s", h.Time.Format("15:04:05"), h.Type, h.Position, h.Value, h.Author)
+}
+
+// history is a ring buffer of the most recently applied operations.
+type history struct {
+	entries []historyEntry
+
+	// start is the index of the oldest entry once the buffer is full.
+	start int
+
+	// limit is the most entries kept.
+	limit int
+}
+
+// newHistory returns a history that keeps the last limit operations.
+func newHistory(limit int) *history {
+	return &history{limit: limit}
+}
+
+// add records an operation, dropping the oldest one if the history is full.
+func (h *history) add(entry historyEntry) {
+	if h.limit <= 0 {
+		return
+	}
+	if len(h.entries) < h.limit {
+		h.entries = append(h.entries, entry)
+		return
+	}
+	h.entries[h.start] = entry
+	h.start = (h.start + 1) % h.limit
+}
+
+// last returns up to n of the most recent operations, newest first.
+func (h *history) last(n int) []historyEntry {
+	n = max(0, min(n, len(h.entries)))
+
+	entries := make([]historyEntry, 0, n)
+	for i := 0; i < n; i++ {
+		idx := (h.start + len(h.entries) - 1 - i) % len(h.entries)
+		entries = append(entries, h.entries[idx])
+	}
+	return entries
+}
+
+// opHistory records operations applied locally and from peers.
+// It's only touched from the main loop, so it needs no locking.
+var opHistory = newHistory(maxHistory)
+
+// recordOp adds an operation, made at the given time, to the history.
+func recordOp(at time.Time, opType string, position int, value, author string) {
+	opHistory.add(historyEntry{Time: at, Type: opType, Position: position, Value: value, Author: author})
+}
+
+// showHistory opens a read-only panel listing the most recent operations; any key dismisses it.
+func showHistory() {
+	// Leave room for the header and the status bar.
+	entries := opHistory.last(max(1, e.GetHeight()-2))
+
+	lines := []string{fmt.Sprintf("Last %d operations (newest first, any key to close)", len(entries))}
+	for _, entry := range entries {
+		lines = append(lines, entry.String())
+	}
+	if len(entries) == 0 {
+		lines = append(lines, "No operations yet.")
+	}
+
+	e.SetPanel(lines)
+}
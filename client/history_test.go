@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"text-editor/commons"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nsf/termbox-go"
+)
+
+// values returns the values of entries, in order.
+func values(entries []historyEntry) []string {
+	var vs []string
+	for _, entry := range entries {
+		vs = append(vs, entry.Value)
+	}
+	return vs
+}
+
+func TestHistory_Bounded(t *testing.T) {
+	h := newHistory(3)
+
+	if got := h.last(10); len(got) != 0 {
+		t.Errorf("empty history: got %v, expected nothing", got)
+	}
+
+	for i := 1; i <= 2; i++ {
+		h.add(historyEntry{Value: fmt.Sprint(i)})
+	}
+	if got, want := values(h.last(10)), []string{"2", "1"}; !cmp.Equal(got, want) {
+		t.Errorf("partly full (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	for i := 3; i <= 7; i++ {
+		h.add(historyEntry{Value: fmt.Sprint(i)})
+	}
+	if got := len(h.entries); got != 3 {
+		t.Errorf("got %d entries stored, expected %d", got, 3)
+	}
+	if got, want := values(h.last(10)), []string{"7", "6", "5"}; !cmp.Equal(got, want) {
+		t.Errorf("wrapped (-want +got)\n%s", cmp.Diff(want, got))
+	}
+	if got, want := values(h.last(2)), []string{"7", "6"}; !cmp.Equal(got, want) {
+		t.Errorf("last 2 (-want +got)\n%s", cmp.Diff(want, got))
+	}
+	if got := h.last(-1); len(got) != 0 {
+		t.Errorf("negative count: got %v, expected nothing", got)
+	}
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	h := newHistory(0)
+	h.add(historyEntry{Value: "a"})
+
+	if got := h.last(1); len(got) != 0 {
+		t.Errorf("got %v, expected nothing", got)
+	}
+}
+
+func TestHistory_RecordsLocalAndRemoteOperations(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	username = "me"
+
+	_ = handleTermboxEvent(termbox.Event{Ch: 'a'}, nil)
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyBackspace2}, nil)
+	handleMsg(commons.Message{Type: "operation", Username: "bob", Operation: commons.Operation{Type: "insert", Position: 1, Value: "b"}}, nil)
+
+	got := h2s(opHistory.last(maxHistory))
+	want := []string{"insert 1 b bob", "delete 1 a me", "insert 1 a me"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("(-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	// The panel opens on Ctrl+E and closes on any key, without editing.
+	e.SetSize(80, 10)
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlE}, nil)
+	if panel := e.GetPanel(); len(panel) != 4 {
+		t.Errorf("got panel %q, expected a header and 3 operations", panel)
+	}
+	_ = handleTermboxEvent(termbox.Event{Ch: 'x'}, nil)
+	if panel := e.GetPanel(); panel != nil {
+		t.Errorf("expected the panel to close, got %q", panel)
+	}
+	if got := string(e.Text); got != "b" {
+		t.Errorf("got text %q, expected %q", got, "b")
+	}
+}
+
+// h2s summarizes entries as "type position value author".
+func h2s(entries []historyEntry) []string {
+	var s []string
+	for _, entry := range entries {
+		s = append(s, fmt.Sprintf("%s %d %s %s", entry.Type, entry.Position, entry.Value, entry.Author))
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+package main
+
+import "text-editor/commons"
+
+// OpHook inspects a local edit before it's applied or sent, and reports whether to allow it.
+// Inserts have Type "insert", the 1-based Position the character goes to, and the character as Value,
+// which the hook may replace with another character. Deletes have Type "delete", the Position, ID and Value
+// of the character before the cursor; changing them has no effect.
+type OpHook func(op *commons.Operation) (allow bool)
+
+// opHooks are the registered hooks, in the order they run.
+var opHooks []OpHook
+
+// RegisterOpHook adds a hook run on every local edit, after those registered before it.
+// Hooks are meant to be registered at startup, e.g. from an init function in a file added to this package,
+// as they're run on the main loop without locking.
+func RegisterOpHook(hook OpHook) {
+	opHooks = append(opHooks, hook)
+}
+
+// runOpHooks runs the registered hooks on op in order, reporting whether they all allowed it.
+// A hook that disallows the edit stops the ones after it from running.
+func runOpHooks(op *commons.Operation) bool {
+	for _, hook := range opHooks {
+		if !hook(op) {
+			return false
+		}
+	}
+	return true
+}
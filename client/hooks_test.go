@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestOpHook_BlocksCharacter(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	var seen []string
+	RegisterOpHook(func(op *commons.Operation) bool {
+		seen = append(seen, op.Type+" "+op.Value)
+		return op.Type != "insert" || op.Value != "x"
+	})
+
+	for _, ch := range "axb" {
+		performOperation(OperationInsert, termbox.Event{Ch: ch}, nil)
+	}
+	if got := crdt.Content(doc); got != "ab" || string(e.Text) != "ab" {
+		t.Errorf("got != want; got = %q (editor %q), expected = %q\n", got, string(e.Text), "ab")
+	}
+	if got, want := strings.Join(seen, ","), "insert a,insert x,insert b"; got != want {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, want)
+	}
+
+	// Deletes go through the hooks too, with the character being deleted.
+	performOperation(OperationDelete, termbox.Event{}, nil)
+	if got := crdt.Content(doc); got != "a" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "a")
+	}
+	if got := seen[len(seen)-1]; got != "delete b" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "delete b")
+	}
+}
+
+func TestOpHook_Order(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	var calls []string
+	RegisterOpHook(func(op *commons.Operation) bool {
+		calls = append(calls, "upper")
+		op.Value = strings.ToUpper(op.Value)
+		return true
+	})
+	RegisterOpHook(func(op *commons.Operation) bool {
+		calls = append(calls, "block")
+		return op.Value != "Q"
+	})
+	RegisterOpHook(func(op *commons.Operation) bool {
+		calls = append(calls, "last")
+		return true
+	})
+
+	performOperation(OperationInsert, termbox.Event{Ch: 'a'}, nil)
+	performOperation(OperationInsert, termbox.Event{Ch: 'q'}, nil)
+
+	// Later hooks see earlier ones' changes, and don't run once a hook blocks the edit.
+	if got := crdt.Content(doc); got != "A" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "A")
+	}
+	if got, want := strings.Join(calls, ","), "upper,block,last,upper,block"; got != want {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, want)
+	}
+}
+
+func TestOpHook_MultipleCharacters(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	RegisterOpHook(func(op *commons.Operation) bool {
+		op.Value = "ab"
+		return true
+	})
+
+	performOperation(OperationInsert, termbox.Event{Ch: 'x'}, nil)
+	if got := crdt.Content(doc); got != "" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/mattn/go-runewidth"
+	"github.com/nsf/termbox-go"
+)
+
+var (
+	// tabWidth is how many columns a tab stop spans; runEditor sets it from -tabstop.
+	tabWidth = 4
+
+	// indentWidth is how many spaces the Tab key inserts; runEditor sets it from -indentwidth.
+	indentWidth = 4
+
+	// indentToStop makes the Tab key insert only enough spaces to reach the next multiple of indentWidth, for -indent-to-stop.
+	indentToStop bool
+)
+
+// indentSpaces returns how many spaces the Tab key inserts with the cursor at column col, counted from 0:
+// width of them or, with toStop, only enough to reach the next multiple of width.
+func indentSpaces(col, width int, toStop bool) int {
+	width = max(1, width)
+	if toStop {
+		return width - col%width
+	}
+	return width
+}
+
+// column returns the column, counted from 0, that position pos of text is at on its line.
+// Tabs reach the next multiple of stop, like when converting them to spaces, and wide characters take two columns.
+func column(text []rune, pos, stop int) int {
+	start := pos
+	for start > 0 && text[start-1] != '\n' {
+		start--
+	}
+
+	col := 0
+	for _, r := range text[start:pos] {
+		if r == '\t' {
+			col += stop - col%stop
+		} else {
+			col += runewidth.RuneWidth(r)
+		}
+	}
+	return col
+}
+
+// insertIndent inserts the spaces the Tab key types at the cursor.
+func insertIndent(ev termbox.Event, conn *websocket.Conn) {
+	n := indentSpaces(column(e.Text, min(e.Cursor, len(e.Text)), tabWidth), indentWidth, indentToStop)
+	ev.Ch = ' '
+	for i := 0; i < n; i++ {
+		performOperation(OperationInsert, ev, conn)
+	}
+}
+
+// minimalEdit returns the smallest edit turning old, found at pos, into new, and false if they're equal.
+func minimalEdit(pos int, old, new []rune) (textEdit, bool) {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+
+	if prefix == len(old) && prefix == len(new) {
+		return textEdit{}, false
+	}
+	return textEdit{pos: pos + prefix, del: len(old) - prefix - suffix, ins: string(new[prefix : len(new)-suffix])}, true
+}
+
+// tabsToSpacesEdits returns the edits that replace every tab in text with spaces up to the next tab stop.
+func tabsToSpacesEdits(text []rune, width int) []textEdit {
+	var edits []textEdit
+
+	col := 0
+	for i, r := range text {
+		switch r {
+		case '\n':
+			col = 0
+		case '\t':
+			n := width - col%width
+			edits = append(edits, textEdit{pos: i, del: 1, ins: strings.Repeat(" ", n)})
+			col += n
+		default:
+			col += runewidth.RuneWidth(r)
+		}
+	}
+
+	return edits
+}
+
+// spacesToTabsEdits returns the edits that re-indent each line of text with tabs, keeping its indentation's width.
+// Only leading indentation changes; spaces that don't fill a tab stop stay as spaces.
+func spacesToTabsEdits(text []rune, width int) []textEdit {
+	var edits []textEdit
+
+	for start := 0; start <= len(text); {
+		// Measure the line's indentation in columns.
+		end, cols := start, 0
+		for end < len(text) && (text[end] == ' ' || text[end] == '\t') {
+			if text[end] == '\t' {
+				cols += width - cols%width
+			} else {
+				cols++
+			}
+			end++
+		}
+
+		indent := strings.Repeat("\t", cols/width) + strings.Repeat(" ", cols%width)
+		if edit, ok := minimalEdit(start, text[start:end], []rune(indent)); ok {
+			edits = append(edits, edit)
+		}
+
+		// Move to the next line.
+		for end < len(text) && text[end] != '\n' {
+			end++
+		}
+		start = end + 1
+	}
+
+	return edits
+}
+
+// convertIndentation converts the document's tabs to spaces, or its leading spaces to tabs, as the user asked.
+func convertIndentation(input string, conn *websocket.Conn) {
+	var edits []textEdit
+	var done string
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "spaces", "s":
+		edits = tabsToSpacesEdits(e.Text, tabWidth)
+		done = fmt.Sprintf("Converted %d tabs to spaces", len(edits))
+	case "tabs", "t":
+		edits = spacesToTabsEdits(e.Text, tabWidth)
+		done = fmt.Sprintf("Indented %d lines with tabs", len(edits))
+	default:
+		e.StatusChan <- fmt.Sprintf("Unknown conversion %q, expected spaces or tabs", input)
+		return
+	}
+
+	if len(edits) == 0 {
+		e.StatusChan <- "Nothing to convert"
+		return
+	}
+	if applyEdits(edits, conn) {
+		e.StatusChan <- done
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nsf/termbox-go"
+)
+
+// typeText puts text in the document and the editor, as if it had been typed.
+func typeText(t *testing.T, text string) {
+	t.Helper()
+
+	for i, ch := range []rune(text) {
+		if _, err := doc.GenerateInsert(i+1, string(ch)); err != nil {
+			t.Fatalf("insert error: %v", err)
+		}
+	}
+	e.SetText(text)
+}
+
+func TestMinimalEdit(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     textEdit
+		ok       bool
+	}{
+		{"    ", "\t", textEdit{pos: 10, del: 4, ins: "\t"}, true},
+		{"\t  ", "\t\t", textEdit{pos: 11, del: 2, ins: "\t"}, true},
+		{"      ", "\t  ", textEdit{pos: 10, del: 4, ins: "\t"}, true},
+		{"\t", "\t", textEdit{}, false},
+		{"", "", textEdit{}, false},
+	}
+
+	for _, tc := range tests {
+		got, ok := minimalEdit(10, []rune(tc.old), []rune(tc.new))
+		if ok != tc.ok || got != tc.want {
+			t.Errorf("%q -> %q: got (%+v, %v), expected (%+v, %v)", tc.old, tc.new, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestConvertIndentation(t *testing.T) {
+	tests := []struct {
+		description string
+		to          string
+		text        string
+		want        string
+	}{
+		{"tabs to spaces", "spaces", "\tif x {\n\t\ty()\n\t}\n", "    if x {\n        y()\n    }\n"},
+		{"mid-line tab to the next stop", "spaces", "a\tb\nabcd\te", "a   b\nabcd    e"},
+		{"mixed indentation to spaces", "spaces", "  \tx", "    x"},
+		{"spaces to tabs", "tabs", "    if x {\n        y()\n    }\n", "\tif x {\n\t\ty()\n\t}\n"},
+		{"partial tab stop kept as spaces", "tabs", "      x\n  y", "\t  x\n  y"},
+		{"mid-line spaces kept", "tabs", "    a    b", "\ta    b"},
+		{"mixed indentation to tabs", "tabs", "  \t  \tx", "\t\tx"},
+		{"whitespace-only line", "tabs", "a\n        \nb", "a\n\t\t\nb"},
+	}
+
+	for _, tc := range tests {
+		resetState(t)
+		handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+		typeText(t, tc.text)
+
+		convertIndentation(tc.to, nil)
+
+		if got := crdt.Content(doc); got != tc.want {
+			t.Errorf("(%s) got %q, expected %q", tc.description, got, tc.want)
+		}
+		if got := string(e.Text); got != tc.want {
+			t.Errorf("(%s) editor text: got %q, expected %q", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestConvertIndentation_RoundTrip(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	text := "\tfunc() {\n\t\treturn\n\t}"
+	typeText(t, text)
+	e.Cursor = len([]rune("\tfunc() {\n\t\tret"))
+
+	convertIndentation("spaces", nil)
+	if got, want := e.Cursor, len([]rune("    func() {\n        ret")); got != want {
+		t.Errorf("cursor after converting to spaces: got %d, expected %d", got, want)
+	}
+
+	convertIndentation("tabs", nil)
+	if got := crdt.Content(doc); !cmp.Equal(got, text) {
+		t.Errorf("round trip (-want +got)\n%s", cmp.Diff(text, got))
+	}
+	if got, want := e.Cursor, len([]rune("\tfunc() {\n\t\tret")); got != want {
+		t.Errorf("cursor after converting back: got %d, expected %d", got, want)
+	}
+}
+
+func TestIndentSpaces(t *testing.T) {
+	tests := []struct {
+		description string
+		col, width  int
+		toStop      bool
+		want        int
+	}{
+		{"fixed width at the start of a line", 0, 4, false, 4},
+		{"fixed width mid-stop", 3, 4, false, 4},
+		{"two-space indentation", 5, 2, false, 2},
+		{"to the next stop from the start", 0, 4, true, 4},
+		{"to the next stop from mid-stop", 1, 4, true, 3},
+		{"to the next stop from just before it", 3, 4, true, 1},
+		{"to the next stop from a stop", 8, 4, true, 4},
+		{"to the next two-space stop", 5, 2, true, 1},
+		{"non-positive width", 3, 0, true, 1},
+	}
+
+	for _, tc := range tests {
+		if got := indentSpaces(tc.col, tc.width, tc.toStop); got != tc.want {
+			t.Errorf("(%s) got %d spaces, expected %d", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestColumn(t *testing.T) {
+	tests := []struct {
+		description string
+		text        string
+		pos, stop   int
+		want        int
+	}{
+		{"start of the text", "abc", 0, 4, 0},
+		{"mid-line", "abc", 2, 4, 2},
+		{"second line", "abcdef\nab", 9, 4, 2},
+		{"after a tab", "\tx", 1, 4, 4},
+		{"after a mid-line tab", "ab\tx", 3, 4, 4},
+		{"after a tab with a wider stop", "ab\tx", 3, 8, 8},
+		{"after a wide character", "世x", 1, 4, 2},
+	}
+
+	for _, tc := range tests {
+		if got := column([]rune(tc.text), tc.pos, tc.stop); got != tc.want {
+			t.Errorf("(%s) got column %d, expected %d", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestInsertIndent(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	typeText(t, "abc")
+	e.Cursor = 3
+
+	indentWidth = 2
+	insertIndent(termbox.Event{}, nil)
+	if got, want := crdt.Content(doc), "abc  "; got != want {
+		t.Errorf("fixed width: got %q, expected %q", got, want)
+	}
+
+	// From column 5, the next stop of 4 is 3 spaces away.
+	indentWidth, indentToStop = 4, true
+	insertIndent(termbox.Event{}, nil)
+	if got, want := crdt.Content(doc), "abc     "; got != want {
+		t.Errorf("to the next stop: got %q, expected %q", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// errInvalidUTF8 is returned for files that aren't UTF-8 text.
+var errInvalidUTF8 = errors.New("not valid UTF-8")
+
+// insertFile inserts the contents of the file at path at the cursor, leaving the cursor after them.
+// Unlike loadFile, the rest of the document is kept: each character is an ordinary insert, sent to peers as it's made.
+// The file is streamed rather than read whole, so large files don't have to fit in memory twice.
+func insertFile(path string, conn *websocket.Conn) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		e.StatusChan <- "no file given"
+		return
+	}
+	if reason := editBlocked(); reason != "" {
+		e.StatusChan <- reason
+		return
+	}
+	if doc.SiteID == 0 {
+		e.StatusChan <- "connecting..."
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		e.StatusChan <- fmt.Sprintf("Failed to open %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	// Check the whole file first, so invalid text isn't left half inserted.
+	if err := checkUTF8(f); err != nil {
+		e.StatusChan <- fmt.Sprintf("Failed to insert %s: %v", path, err)
+		return
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		e.StatusChan <- fmt.Sprintf("Failed to insert %s: %v", path, err)
+		return
+	}
+
+	n, err := insertFrom(f, conn)
+	if err != nil {
+		logger.Errorf("failed to insert %s, err: %v", path, err)
+		e.StatusChan <- fmt.Sprintf("Inserted %d characters of %s before failing: %v", n, path, err)
+		return
+	}
+	e.StatusChan <- fmt.Sprintf("Inserted %s (%d characters)", path, n)
+}
+
+// checkUTF8 reads r to the end, reporting whether it's valid UTF-8.
+func checkUTF8(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for offset := 0; ; {
+		ch, size, err := br.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ch == utf8.RuneError && size == 1 {
+			return fmt.Errorf("%w at byte %d", errInvalidUTF8, offset)
+		}
+		offset += size
+	}
+}
+
+// insertFrom inserts the text read from r at the cursor, one character at a time, returning how many were inserted.
+func insertFrom(r io.Reader, conn *websocket.Conn) (int, error) {
+	br := bufio.NewReader(r)
+	n := 0
+	for {
+		ch, size, err := br.ReadRune()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if ch == utf8.RuneError && size == 1 {
+			return n, errInvalidUTF8
+		}
+
+		performOperation(OperationInsert, termbox.Event{Ch: ch}, conn)
+		n++
+	}
+}
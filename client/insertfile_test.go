@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"text-editor/crdt"
+)
+
+func TestInsertFile(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+	typeText(t, "<>")
+	e.SetX(1)
+
+	path := filepath.Join(t.TempDir(), "snippet.txt")
+	if err := os.WriteFile(path, []byte("héllo\nwörld"), 0600); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	insertFile(path, nil)
+
+	want := "<héllo\nwörld>"
+	if got := crdt.Content(doc); got != want {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, want)
+	}
+	if got := string(e.GetText()); got != want {
+		t.Errorf("editor out of sync; got = %q, expected = %q\n", got, want)
+	}
+	if e.Cursor != 12 {
+		t.Errorf("cursor not after the inserted text; got = %v, expected = %v\n", e.Cursor, 12)
+	}
+	if got := len(opHistory.last(maxHistory)); got != 11 {
+		t.Errorf("got %d inserts, expected one per character (11)\n", got)
+	}
+}
+
+func TestInsertFile_InvalidUTF8(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+	typeText(t, "<>")
+	e.SetX(1)
+
+	path := filepath.Join(t.TempDir(), "binary.bin")
+	if err := os.WriteFile(path, []byte("ok\xff\xfe"), 0600); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	insertFile(path, nil)
+
+	if got := crdt.Content(doc); got != "<>" {
+		t.Errorf("invalid file was inserted; got = %q\n", got)
+	}
+	if e.Cursor != 1 {
+		t.Errorf("cursor moved; got = %v, expected = %v\n", e.Cursor, 1)
+	}
+}
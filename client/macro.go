@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// macro records key events so they can be replayed, like a Vim register.
+type macro struct {
+	// recording is set while key events are being captured.
+	recording bool
+
+	// replaying is set while the recorded events are fed back through handleTermboxEvent.
+	replaying bool
+
+	// events holds the last recording.
+	events []termbox.Event
+}
+
+// rec is the editor's single macro register.
+var rec macro
+
+// isMacroKey reports whether ev controls macros, so it's never recorded itself.
+func isMacroKey(ev termbox.Event) bool {
+	return ev.Type == termbox.EventKey && (ev.Key == termbox.KeyCtrlT || ev.Key == termbox.KeyCtrlY)
+}
+
+// recordEvent captures ev if a recording is in progress.
+// Keys that would end the session aren't recorded, since replaying them would quit the editor.
+func recordEvent(ev termbox.Event) {
+	if !rec.recording || rec.replaying || ev.Type != termbox.EventKey || isMacroKey(ev) || confirmingQuit {
+		return
+	}
+	// Modal editors use Esc to leave insert mode instead.
+	if activePrompt == nil && (ev.Key == termbox.KeyCtrlC || (ev.Key == termbox.KeyEsc && !modal)) {
+		return
+	}
+	rec.events = append(rec.events, ev)
+}
+
+// toggleRecording starts a new recording, or stops the one in progress.
+func toggleRecording() {
+	if rec.recording {
+		rec.recording = false
+		e.StatusChan <- fmt.Sprintf("recorded %d keys", len(rec.events))
+		return
+	}
+
+	rec.recording = true
+	rec.events = nil
+	e.StatusChan <- "recording..."
+}
+
+// replayMacro feeds the recorded events through handleTermboxEvent as if they were typed again.
+// Replay runs on the main loop, so remote edits can't interleave with it; it acts on the
+// document as it is when replay starts, relative to the cursor, like typing would.
+func replayMacro(conn *websocket.Conn) {
+	if rec.recording {
+		e.StatusChan <- "stop recording before replaying"
+		return
+	}
+	if len(rec.events) == 0 {
+		e.StatusChan <- "no macro recorded"
+		return
+	}
+
+	rec.replaying = true
+	defer func() { rec.replaying = false }()
+
+	for _, ev := range rec.events {
+		if err := handleTermboxEvent(ev, conn); err != nil {
+			logger.Errorf("macro replay stopped: %v", err)
+			e.StatusChan <- fmt.Sprintf("macro replay stopped: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestMacro_ReplayInserts(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	// Record "ab", a newline, and a backspace-corrected "c". Declining to quit isn't recorded.
+	events := []termbox.Event{{Key: termbox.KeyCtrlT}}
+	for _, ch := range "abx" {
+		events = append(events, termbox.Event{Ch: ch})
+	}
+	events = append(events,
+		termbox.Event{Key: termbox.KeyBackspace2},
+		termbox.Event{Ch: 'c'},
+		termbox.Event{Key: termbox.KeyEnter},
+		termbox.Event{Key: termbox.KeyEsc},
+		termbox.Event{Ch: 'n'},
+		termbox.Event{Key: termbox.KeyCtrlT},
+	)
+
+	for _, ev := range events {
+		if err := handleTermboxEvent(ev, nil); err != nil && ev.Key != termbox.KeyEsc {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if got := len(rec.events); got != 6 {
+		t.Errorf("got != want; got = %v recorded events, expected = %v\n", got, 6)
+	}
+
+	// Replay twice.
+	for i := 0; i < 2; i++ {
+		if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlY}, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	want := "abc\nabc\nabc\n"
+	if got := crdt.Content(doc); got != want {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, want)
+	}
+	if got := string(e.Text); got != want {
+		t.Errorf("editor text: got = %q, expected = %q\n", got, want)
+	}
+}
+
+func TestMacro_ReplayWhileRecording(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlT}, nil)
+	_ = handleTermboxEvent(termbox.Event{Ch: 'a'}, nil)
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlY}, nil)
+
+	if got := crdt.Content(doc); got != "a" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "a")
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"strings"
 
 	"text-editor/client/editor"
@@ -11,6 +15,7 @@ import (
 	"text-editor/crdt"
 
 	"github.com/Pallinder/go-randomdata"
+	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 )
 
@@ -27,54 +32,156 @@ var (
 	// fileName specifies the file for loading and saving
 	fileName string
 
+	// startLine and startCol, if set, are where -file puts the cursor on startup, counted from 1
+	startLine, startCol int
+
 	// flags contain the parsed command-line arguments
 	flags Flags
+
+	// recent lists recently opened and saved files; nil if it couldn't be set up
+	recent *RecentFiles
+
+	// username is the name announced to other users
+	username string
 )
 
 func main() {
-	// Initialize flags from command-line arguments
-	flags = parseFlags()
+	if err := dispatch(os.Args[1:]); err != nil {
+		// The flag package has already explained parse errors.
+		if !errors.Is(err, errFlagParse) {
+			fmt.Println(err)
+		}
+		os.Exit(1)
+	}
+}
+
+// runJoin joins a collaborative editing session on a server.
+func runJoin(args []string) error {
+	fs := flag.NewFlagSet("join", flag.ContinueOnError)
+	addConnFlags(fs, &flags)
+	addEditorFlags(fs, &flags)
+	addLogFlags(fs, &flags)
+	fs.BoolVar(&flags.Version, "version", false, "Print version information and exit")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
 
-	s := bufio.NewScanner(os.Stdin)
+	// Print build information and exit without connecting
+	if flags.Version {
+		fmt.Println(commons.VersionString())
+		return nil
+	}
 
 	// Generate a random username for the user
-	name := randomdata.SillyName()
+	username = randomdata.SillyName()
 
 	// If login is enabled, prompt for a custom username
 	if flags.Login {
-		fmt.Print("Enter your name: ")
-		s.Scan()
-		name = s.Text()
+		username = readUsername(os.Stdin, os.Stdout, username)
 	}
 
-	conn, _, err := createConn(flags)
+	conn, resp, err := connect(flags)
 	if err != nil {
-		fmt.Printf("Connection error, exiting: %s\n", err)
-		return
+		return fmt.Errorf("%s Exiting.", classifyConnError(err, resp))
 	}
 	defer conn.Close()
 
 	// Notify other users about the new participant
-	msg := commons.Message{Username: name, Text: "has joined the session.", Type: commons.JoinMessage}
+	msg := commons.Message{Username: username, Text: "has joined the session.", Type: commons.JoinMessage}
 	_ = conn.WriteJSON(msg)
 
-	logFile, debugLogFile, err := setupLogger(logger)
+	return runEditor(conn)
+}
+
+// readUsername prompts for a username on w and reads it from r.
+// If r is exhausted, fails, or only has whitespace, e.g. in a scripted run, it warns and returns fallback.
+func readUsername(r io.Reader, w io.Writer, fallback string) string {
+	fmt.Fprint(w, "Enter your name: ")
+
+	s := bufio.NewScanner(r)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			fmt.Fprintf(w, "\nFailed to read a name (%s), using %q.\n", err, fallback)
+		} else {
+			fmt.Fprintf(w, "\nNo name entered (end of input), using %q.\n", fallback)
+		}
+		return fallback
+	}
+
+	name := strings.TrimSpace(s.Text())
+	if name == "" {
+		fmt.Fprintf(w, "No name entered, using %q.\n", fallback)
+		return fallback
+	}
+	return name
+}
+
+// runEditor opens the file given by flags, if any, and runs the editor until the user exits.
+// A nil conn edits offline.
+func runEditor(conn *websocket.Conn) error {
+	logFile, debugLogFile, err := setupLogger(logger, flags)
 	if err != nil {
-		fmt.Printf("Failed to setup logger, exiting: %s\n", err)
-		return
+		return fmt.Errorf("Failed to setup logger, exiting: %s", err)
 	}
 	defer closeLogFiles(logFile, debugLogFile)
 
-	if flags.File != "" {
-		if doc, err = crdt.Load(flags.File); err != nil {
-			fmt.Printf("failed to load document: %s\n", err)
-			return
+	if path, err := recentPath(); err == nil {
+		if recent, err = LoadRecent(path); err != nil {
+			logger.Warnf("failed to load recent files, err: %v", err)
 		}
 	}
 
+	if flags.Snippets != "" {
+		if snippets, err = LoadSnippets(flags.Snippets); err != nil {
+			return fmt.Errorf("failed to load snippets: %s", err)
+		}
+	}
+
+	if len(flags.Files) > 0 {
+		files := existingFiles(flags.Files, os.Stdout)
+		queuedFiles = files[1:]
+
+		var path string
+		path, startLine, startCol = fileTarget(files[0])
+		if doc, err = crdt.Load(path); err != nil {
+			return fmt.Errorf("failed to load document: %s", err)
+		}
+		fileName = path
+		rememberFile(fileName)
+		noteWritable(fileName)
+	}
+
+	if flags.SystemClipboard {
+		if sysClip = detectClipboard(exec.LookPath, os.Getenv); sysClip.paste == nil && sysClip.copy == nil {
+			logger.Warnf("no system clipboard tool found (pbcopy, wl-copy, xclip or xsel), using the editor's own clipboard")
+		}
+	}
+
+	theme, err := LoadTheme(flags.Theme)
+	if err != nil {
+		return fmt.Errorf("failed to load theme: %s", err)
+	}
+	if err := editor.ValidateStatusTemplate(flags.StatusLine); err != nil {
+		return fmt.Errorf("invalid -statusline: %s", err)
+	}
+
+	accel = accelerator{max: flags.Accel}
+	tabWidth, indentWidth, indentToStop = max(1, flags.TabStop), max(1, flags.IndentWidth), flags.IndentToStop
+
+	// Modal editors start out in normal mode.
+	if modal = flags.Modal; modal {
+		mode = modeNormal
+	}
+
 	uiConfig := UIConfig{
 		EditorConfig: editor.EditorConfig{
-			ScrollEnabled: flags.Scroll,
+			ScrollEnabled:    flags.Scroll,
+			ScrollMargin:     flags.ScrollOff,
+			SideScrollMargin: flags.SideScrollOff,
+			LongLineLimit:    flags.LongLine,
+			DebugBar:         flags.DebugBar,
+			Theme:            &theme,
+			StatusTemplate:   flags.StatusLine,
 		},
 	}
 
@@ -84,11 +191,15 @@ func main() {
 		// Check if the error is related to editor events (e.g., exiting)
 		if strings.HasPrefix(err.Error(), "editor") {
 			fmt.Println("exiting session.")
-			return
+			if exitNote != "" {
+				fmt.Println(exitNote)
+			}
+			return nil
 		}
 
 		// Display error message for actual errors
-		fmt.Printf("TUI error, exiting: %s\n", err)
-		return
+		return fmt.Errorf("TUI error, exiting: %s", err)
 	}
+
+	return nil
 }
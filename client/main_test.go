@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadUsername(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        string
+		wantWarning bool
+	}{
+		{"name", "alice\n", "alice", false},
+		{"name without newline", "alice", "alice", false},
+		{"surrounding spaces", "  alice \n", "alice", false},
+		{"eof", "", "fallback", true},
+		{"empty line", "\n", "fallback", true},
+		{"whitespace only", " \t \n", "fallback", true},
+	}
+
+	for _, tc := range tests {
+		var out strings.Builder
+		got := readUsername(strings.NewReader(tc.input), &out, "fallback")
+		if got != tc.want {
+			t.Errorf("(%s) got %q, expected %q", tc.description, got, tc.want)
+		}
+		if warned := strings.Contains(out.String(), `using "fallback"`); warned != tc.wantWarning {
+			t.Errorf("(%s) got output %q, expected a warning: %v", tc.description, out.String(), tc.wantWarning)
+		}
+	}
+}
+
+func TestReadUsername_ReadError(t *testing.T) {
+	var out strings.Builder
+	got := readUsername(iotest.ErrReader(errors.New("boom")), &out, "fallback")
+	if got != "fallback" {
+		t.Errorf("got %q, expected %q", got, "fallback")
+	}
+	if !strings.Contains(out.String(), "boom") {
+		t.Errorf("expected the error in the output, got %q", out.String())
+	}
+}
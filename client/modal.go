@@ -0,0 +1,98 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// editMode is the input mode of a modal editor.
+type editMode int
+
+const (
+	// modeInsert types characters into the document, as the editor does when it isn't modal.
+	modeInsert editMode = iota
+
+	// modeNormal uses letters for movement and commands instead of inserting them.
+	modeNormal
+)
+
+// String returns the label shown in the status bar for the mode.
+func (m editMode) String() string {
+	if m == modeNormal {
+		return "NORMAL"
+	}
+	return "INSERT"
+}
+
+var (
+	// modal enables normal and insert modes, like Vim.
+	modal bool
+
+	// mode is the current input mode; it's always modeInsert unless modal is set.
+	mode = modeInsert
+)
+
+// setMode switches the input mode and shows it in the status bar.
+func setMode(m editMode) {
+	mode = m
+	if modal {
+		e.SetMode(m.String())
+	}
+}
+
+// handleModalEvent handles key events specific to modal editing.
+// It reports whether ev was consumed; other events are handled as usual, so e.g. Ctrl+S still saves.
+func handleModalEvent(ev termbox.Event, conn *websocket.Conn) bool {
+	if !modal || ev.Type != termbox.EventKey {
+		return false
+	}
+
+	// Esc leaves insert mode rather than the session; Ctrl+C still exits.
+	if mode == modeInsert {
+		if ev.Key == termbox.KeyEsc {
+			setMode(modeNormal)
+			return true
+		}
+		return false
+	}
+
+	switch ev.Key {
+	// Keys that would edit the document are ignored in normal mode.
+	case termbox.KeyEsc, termbox.KeyEnter, termbox.KeyTab, termbox.KeySpace, termbox.KeyBackspace, termbox.KeyBackspace2, termbox.KeyDelete:
+		return true
+	}
+
+	if ev.Ch == 0 {
+		return false
+	}
+
+	switch ev.Ch {
+	case 'i':
+		setMode(modeInsert)
+	case 'a':
+		e.MoveCursor(1, 0)
+		setMode(modeInsert)
+	case 'h':
+		moveCursor(-1, 0)
+	case 'l':
+		moveCursor(1, 0)
+	case 'k':
+		moveCursor(0, -1)
+	case 'j':
+		moveCursor(0, 1)
+
+	// % jumps to a percentage through the document.
+	case '%':
+		promptPercent()
+
+	// x deletes the character under the cursor.
+	case 'x':
+		if e.Cursor < len(e.Text) {
+			e.MoveCursor(1, 0)
+			performOperation(OperationDelete, ev, conn)
+		}
+	}
+
+	// Other characters do nothing rather than being inserted.
+	return true
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestModal_ModeTransitions(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	modal = true
+	setMode(modeNormal)
+
+	type step struct {
+		ev       termbox.Event
+		wantMode editMode
+		wantText string
+	}
+	steps := []step{
+		// Letters don't insert in normal mode.
+		{termbox.Event{Ch: 'z'}, modeNormal, ""},
+		{termbox.Event{Key: termbox.KeySpace}, modeNormal, ""},
+		{termbox.Event{Ch: 'i'}, modeInsert, ""},
+		{termbox.Event{Ch: 'a'}, modeInsert, "a"},
+		{termbox.Event{Ch: 'b'}, modeInsert, "ab"},
+		{termbox.Event{Ch: 'c'}, modeInsert, "abc"},
+
+		// Esc returns to normal mode instead of exiting.
+		{termbox.Event{Key: termbox.KeyEsc}, modeNormal, "abc"},
+		{termbox.Event{Key: termbox.KeyEsc}, modeNormal, "abc"},
+		{termbox.Event{Ch: 'h'}, modeNormal, "abc"},
+		{termbox.Event{Ch: 'h'}, modeNormal, "abc"},
+		{termbox.Event{Ch: 'x'}, modeNormal, "ac"},
+		{termbox.Event{Ch: 'l'}, modeNormal, "ac"},
+		{termbox.Event{Ch: 'a'}, modeInsert, "ac"},
+		{termbox.Event{Ch: 'd'}, modeInsert, "acd"},
+	}
+
+	for i, s := range steps {
+		if err := handleTermboxEvent(s.ev, nil); err != nil {
+			t.Fatalf("step %d: unexpected error: %v", i, err)
+		}
+		if mode != s.wantMode {
+			t.Errorf("step %d: got mode %v, expected %v", i, mode, s.wantMode)
+		}
+		if got := crdt.Content(doc); got != s.wantText {
+			t.Errorf("step %d: got text %q, expected %q", i, got, s.wantText)
+		}
+	}
+
+	if got := e.Mode; got != "INSERT" {
+		t.Errorf("got status bar mode %q, expected %q", got, "INSERT")
+	}
+
+	// Ctrl+C still exits, once quitting with unsaved changes is confirmed.
+	if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlC}, nil); err != nil {
+		t.Errorf("expected Ctrl+C to ask for confirmation first, got %v", err)
+	}
+	if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlC}, nil); err == nil {
+		t.Errorf("expected Ctrl+C to exit")
+	}
+}
+
+func TestModal_JKMovement(t *testing.T) {
+	resetState(t)
+	e.Text = []rune("ab\ncd")
+	modal = true
+	setMode(modeNormal)
+
+	_ = handleTermboxEvent(termbox.Event{Ch: 'j'}, nil)
+	if e.Cursor != 3 {
+		t.Errorf("after j: got cursor %d, expected %d", e.Cursor, 3)
+	}
+	_ = handleTermboxEvent(termbox.Event{Ch: 'k'}, nil)
+	if e.Cursor != 0 {
+		t.Errorf("after k: got cursor %d, expected %d", e.Cursor, 0)
+	}
+}
+
+func TestModal_DisabledByDefault(t *testing.T) {
+	resetState(t)
+
+	if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyEsc}, nil); err == nil {
+		t.Errorf("expected Esc to exit when the editor isn't modal")
+	}
+	if e.Mode != "" {
+		t.Errorf("got status bar mode %q, expected none", e.Mode)
+	}
+}
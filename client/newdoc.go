@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+)
+
+// newDocument starts a fresh, empty document, asking for confirmation first if there are unsaved changes.
+func newDocument(conn *websocket.Conn) {
+	if !dirty {
+		clearDocument(conn)
+		return
+	}
+
+	openPrompt("Unsaved changes, start a new document anyway? (y/n) ", func(input string, conn *websocket.Conn) {
+		if answer := strings.ToLower(strings.TrimSpace(input)); answer != "y" && answer != "yes" {
+			e.StatusChan <- "cancelled"
+			return
+		}
+		clearDocument(conn)
+	})
+}
+
+// clearDocument empties the document and forgets its file, so saving asks for a new one.
+//
+// Offline, or detached from the room, the document is simply replaced with a new one.
+// In a shared session, every character is deleted instead and the result is sent to the room,
+// like loadFile does: peers merging a new document would keep their characters, but merging
+// the deletions clears theirs too. Either way, the site ID and clock are kept, so characters
+// inserted afterwards get fresh IDs rather than reusing the old characters'.
+func clearDocument(conn *websocket.Conn) {
+	shared := conn != nil && !detached
+	if shared {
+		if reason := roomEditBlocked(); reason != "" {
+			e.StatusChan <- reason
+			return
+		}
+		if doc.SiteID == 0 {
+			e.StatusChan <- "connecting..."
+			return
+		}
+		if err := doc.ReplaceContent(""); err != nil {
+			logger.Errorf("failed to clear document, err: %v", err)
+			e.StatusChan <- "Failed to clear the document"
+			return
+		}
+	} else {
+		adoptDoc(crdt.New())
+	}
+
+	e.SetText("")
+	e.ClearSelection()
+	e.SetX(0)
+	e.RowOff, e.ColOff = 0, 0
+	edits = newEditRing(maxEdits)
+
+	fileName = ""
+	e.SetFileName("")
+	fileReadOnly = false
+	dirty = false
+
+	if shared {
+		docMsg := commons.Message{Type: commons.DocSyncMessage, Document: &doc}
+		if err := conn.WriteJSON(&docMsg); err != nil {
+			logger.Errorf("failed to send cleared document, err: %v", err)
+		}
+		e.StatusChan <- "New document: cleared for everyone in the room"
+		return
+	}
+	e.StatusChan <- "New document"
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+func TestNewDocument_Offline(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+	oldFileName := fileName
+	fileName = "notes.txt"
+	defer func() { fileName = oldFileName }()
+
+	for _, ch := range "abc" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, nil)
+	}
+	oldIDs := map[string]bool{}
+	for _, c := range doc.Characters {
+		oldIDs[c.ID] = true
+	}
+
+	// With unsaved changes, F5 asks first; declining keeps the document.
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF5}, nil)
+	for _, ev := range []termbox.Event{{Ch: 'n'}, {Key: termbox.KeyEnter}} {
+		_ = handleTermboxEvent(ev, nil)
+	}
+	if got := crdt.Content(doc); got != "abc" {
+		t.Fatalf("got text %q after declining, expected %q", got, "abc")
+	}
+
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF5}, nil)
+	for _, ev := range []termbox.Event{{Ch: 'y'}, {Key: termbox.KeyEnter}} {
+		_ = handleTermboxEvent(ev, nil)
+	}
+	if got := crdt.Content(doc); got != "" || len(e.Text) != 0 || e.Cursor != 0 {
+		t.Fatalf("got text %q, editor text %q and cursor %d, expected an empty document", got, string(e.Text), e.Cursor)
+	}
+	if fileName != "" || dirty {
+		t.Errorf("got file name %q and dirty = %v, expected a new, unsaved document", fileName, dirty)
+	}
+
+	// The new document is still editable, without reusing the old characters' IDs.
+	for _, ch := range "xy" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, nil)
+	}
+	if got := crdt.Content(doc); got != "xy" || string(e.Text) != "xy" {
+		t.Errorf("got text %q and editor text %q, expected %q", got, string(e.Text), "xy")
+	}
+	for _, c := range doc.Characters {
+		if c.ID != "start" && c.ID != "end" && oldIDs[c.ID] {
+			t.Errorf("character %q reuses the ID %q", c.Value, c.ID)
+		}
+	}
+}
+
+func TestNewDocument_ClearsPeers(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 2
+	typeText(t, "shared")
+
+	// A peer has the same document.
+	peer := crdt.New()
+	peer.SiteID = 1
+	if _, err := peer.Merge(doc); err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+
+	// The stub server passes on the document the client sends.
+	sent := make(chan crdt.Document, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var msg commons.Message
+		if err := conn.ReadJSON(&msg); err != nil || msg.Type != commons.DocSyncMessage || msg.Document == nil {
+			t.Errorf("expected a document; got = %+v, err = %v\n", msg, err)
+			close(sent)
+			return
+		}
+		sent <- *msg.Document
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial error: %v\n", err)
+	}
+	defer conn.Close()
+
+	e.IsConnected = true
+	newDocument(conn)
+
+	if got := crdt.Content(doc); got != "" {
+		t.Fatalf("got text %q, expected an empty document", got)
+	}
+	cleared, ok := <-sent
+	if !ok {
+		t.FailNow()
+	}
+	if _, err := peer.Merge(cleared); err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+	if got := crdt.Content(peer); got != "" {
+		t.Errorf("peer has %q after merging, expected an empty document", got)
+	}
+
+	// Both sides keep editing the same document.
+	if _, err := doc.GenerateInsert(1, "n"); err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+	if _, err := peer.Merge(doc); err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+	if got := crdt.Content(peer); got != "n" {
+		t.Errorf("peer has %q, expected %q", got, "n")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// toggleOverwrite switches between inserting typed characters and overwriting the ones under the cursor.
+func toggleOverwrite() {
+	e.SetOverwrite(!e.IsOverwrite())
+	if e.IsOverwrite() {
+		e.StatusChan <- "Overwrite mode: typing replaces characters (Insert toggles)"
+		return
+	}
+	e.StatusChan <- "Insert mode"
+}
+
+// typeChar inserts the typed character ev.Ch at the cursor or, in overwrite mode, replaces the character under it.
+// Replacing deletes the character and inserts the new one, so peers receive both operations, one after the other.
+// At the end of a line, or of the document, there's nothing to replace and the character is inserted.
+func typeChar(ev termbox.Event, conn *websocket.Conn) {
+	if e.IsOverwrite() && doc.SiteID != 0 && editBlocked() == "" && e.Cursor < len(e.Text) && e.Text[e.Cursor] != '\n' {
+		// Deleting removes the character before the cursor, so step over the one to replace first.
+		e.MoveCursor(1, 0)
+		performOperation(OperationDelete, ev, conn)
+	}
+	performOperation(OperationInsert, ev, conn)
+}
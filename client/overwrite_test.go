@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestOverwrite_DeleteInsertPair(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+	e.IsConnected = true
+	doc.SiteID = 1
+
+	for _, ch := range "abc" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	for range 3 {
+		<-received
+	}
+	replaced := crdt.IthVisible(doc, 2)
+
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyInsert}, conn)
+	e.SetX(1)
+	_ = handleTermboxEvent(termbox.Event{Ch: 'x'}, conn)
+
+	if got := crdt.Content(doc); got != "axc" || string(e.Text) != "axc" {
+		t.Errorf("got text %q and editor text %q, expected %q", got, string(e.Text), "axc")
+	}
+	if e.Cursor != 2 {
+		t.Errorf("got cursor %d, expected 2, after the new character", e.Cursor)
+	}
+
+	// Peers delete the replaced character, then insert the new one in its place.
+	del, ins := (<-received).Operation, (<-received).Operation
+	if del.Type != "delete" || del.ID != replaced.ID {
+		t.Errorf("got %+v, expected the delete of %s", del, replaced.ID)
+	}
+	if ins.Type != "insert" || ins.Value != "x" || ins.Position != 2 {
+		t.Errorf("got %+v, expected the insert of x at 2", ins)
+	}
+}
+
+func TestOverwrite_EndOfLine(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+	e.IsConnected = true
+	doc.SiteID = 1
+
+	for _, ch := range "ab\ncd" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	for range 5 {
+		<-received
+	}
+	e.SetOverwrite(true)
+
+	// Over a newline, and at the end of the document, characters are inserted.
+	e.SetX(2)
+	_ = handleTermboxEvent(termbox.Event{Ch: 'y'}, conn)
+	e.SetX(len(e.Text))
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeySpace}, conn)
+
+	if got, want := crdt.Content(doc), "aby\ncd "; got != want {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+	for i := range 2 {
+		if msg := <-received; msg.Type != commons.MessageType("operation") || msg.Operation.Type != "insert" {
+			t.Errorf("operation %d: got %+v, expected only inserts", i, msg.Operation)
+		}
+	}
+	if len(received) > 0 {
+		t.Errorf("got %d unexpected messages", len(received))
+	}
+}
+
+func TestOverwrite_Toggle(t *testing.T) {
+	resetState(t)
+
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyInsert}, nil)
+	if !e.IsOverwrite() {
+		t.Fatalf("Insert didn't switch to overwrite mode")
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyInsert}, nil)
+	if e.IsOverwrite() {
+		t.Errorf("Insert didn't switch back to insert mode")
+	}
+}
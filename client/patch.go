@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+)
+
+// exportPatch writes the operations turning the file on disk into the document to path, as JSON.
+// A file that doesn't exist yet counts as empty, like in the diff panel.
+func exportPatch(path string, conn *websocket.Conn) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		e.StatusChan <- "no file given"
+		return
+	}
+	if fileName == "" {
+		e.StatusChan <- "No file to compare with!"
+		return
+	}
+
+	saved, err := os.ReadFile(fileName)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		e.StatusChan <- fmt.Sprintf("Failed to read %s: %v", fileName, err)
+		return
+	}
+	base := crdt.New()
+	if err := base.ReplaceContent(string(saved)); err != nil {
+		e.StatusChan <- fmt.Sprintf("Failed to read %s: %v", fileName, err)
+		return
+	}
+
+	ops := crdt.OperationsBetween(base, doc)
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		logger.Errorf("failed to export patch to %s, err: %v", path, err)
+		e.StatusChan <- fmt.Sprintf("Failed to export patch to %s: %v", path, err)
+		return
+	}
+	e.StatusChan <- fmt.Sprintf("Exported %d operations since %s was saved to %s", len(ops), fileName, path)
+}
+
+// importPatch applies the operations in the patch file at path to the document and shares the result with the room.
+// A patch that doesn't fit the document isn't applied at all.
+func importPatch(path string, conn *websocket.Conn) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		e.StatusChan <- "no file given"
+		return
+	}
+	if reason := editBlocked(); reason != "" {
+		e.StatusChan <- reason
+		return
+	}
+	shared := conn != nil && !detached
+	if shared && doc.SiteID == 0 {
+		e.StatusChan <- "connecting..."
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		e.StatusChan <- fmt.Sprintf("Failed to read %s: %v", path, err)
+		return
+	}
+	var ops []crdt.Operation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		e.StatusChan <- fmt.Sprintf("Invalid patch %s: %v", path, err)
+		return
+	}
+
+	// Try the patch on a copy first, so a mismatch halfway through leaves the document alone.
+	patched := doc
+	patched.Characters = slices.Clone(doc.Characters)
+	if err := patched.ApplyOperations(ops); err != nil {
+		logger.Errorf("failed to apply patch %s, err: %v", path, err)
+		e.StatusChan <- fmt.Sprintf("Failed to apply %s: %v", path, err)
+		return
+	}
+	doc = patched
+
+	e.SetText(crdt.Content(doc))
+	e.SetX(min(e.Cursor, len(e.Text)))
+	e.ClearSelection()
+	if len(ops) > 0 {
+		dirty = true
+	}
+
+	// Like loading a file, the patched document is merged into the room's.
+	if shared {
+		docMsg := commons.Message{Type: commons.DocSyncMessage, Document: &doc}
+		if err := conn.WriteJSON(&docMsg); err != nil {
+			logger.Errorf("failed to send patched document, err: %v", err)
+		}
+	}
+	e.StatusChan <- fmt.Sprintf("Applied %d operations from %s", len(ops), path)
+}
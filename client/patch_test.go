@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"text-editor/crdt"
+)
+
+func TestPatch_ExportImport(t *testing.T) {
+	resetState(t)
+	oldFileName := fileName
+	defer func() { fileName = oldFileName }()
+
+	dir := t.TempDir()
+	fileName = filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(fileName, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	patch := filepath.Join(dir, "notes.patch")
+
+	// This editor changed the file's text.
+	doc.SiteID = 1
+	if err := doc.ReplaceContent("one\n2\ntwo!\n"); err != nil {
+		t.Fatal(err)
+	}
+	exportPatch(patch, nil)
+	if msg := <-e.StatusChan; !strings.HasPrefix(msg, "Exported 3 operations") {
+		t.Errorf("got status %q, expected 3 operations exported", msg)
+	}
+
+	// Another editor applies the patch to the file as saved.
+	resetState(t)
+	doc.SiteID = 2
+	if err := doc.ReplaceContent("one\ntwo\n"); err != nil {
+		t.Fatal(err)
+	}
+	importPatch(patch, nil)
+	if got, want := crdt.Content(doc), "one\n2\ntwo!\n"; got != want || string(e.Text) != want {
+		t.Errorf("got text %q and editor text %q, expected %q", got, string(e.Text), want)
+	}
+	<-e.StatusChan
+
+	// A patch that doesn't fit leaves the document alone.
+	if err := os.WriteFile(patch, []byte(`[{"type": "insert", "position": 1, "value": "x"}, {"type": "delete", "position": 3, "value": "x"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	importPatch(patch, nil)
+	if msg := <-e.StatusChan; !strings.HasPrefix(msg, "Failed to apply") {
+		t.Errorf("got status %q, expected the patch to be refused", msg)
+	}
+	if got, want := crdt.Content(doc), "one\n2\ntwo!\n"; got != want {
+		t.Errorf("got text %q, expected %q, unchanged", got, want)
+	}
+}
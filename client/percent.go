@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// promptPercent asks how far through the document to jump, as a percentage.
+func promptPercent() {
+	openPrompt("Go to percent: ", func(input string, conn *websocket.Conn) {
+		gotoPercent(input)
+	})
+}
+
+// gotoPercent moves the cursor to the line input percent of the way through the document, e.g. "50" or "50%".
+func gotoPercent(input string) {
+	p, err := parsePercent(input)
+	if err != nil {
+		e.StatusChan <- err.Error()
+		return
+	}
+	e.GotoPercent(p)
+	e.StatusChan <- fmt.Sprintf("Went to %g%% of the document", max(0, min(p, 100)))
+}
+
+// parsePercent parses a percentage such as "50", "12.5" or "75%".
+func parsePercent(s string) (float64, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	p, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || math.IsNaN(p) {
+		return 0, fmt.Errorf("%q is not a percentage", s)
+	}
+	return p, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{"50", 50, false},
+		{" 75% ", 75, false},
+		{"12.5", 12.5, false},
+		{"150", 150, false},
+		{"", 0, true},
+		{"half", 0, true},
+		{"NaN", 0, true},
+	}
+
+	for _, tc := range tests {
+		got, err := parsePercent(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parsePercent(%q) returned error %v, expected an error: %t", tc.input, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parsePercent(%q) = %v, expected %v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestGotoPercent_Keys(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	typeText(t, "a\nb\nc\nd\ne")
+
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF1}, nil)
+	for _, ch := range "50%" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, nil)
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyEnter}, nil)
+
+	// The middle of five lines is the third.
+	if e.Cursor != 4 {
+		t.Errorf("cursor at %d, expected 4", e.Cursor)
+	}
+}
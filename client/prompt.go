@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// prompt collects a line of input in the status bar.
+type prompt struct {
+	// label is shown before the input.
+	label string
+
+	// input is what the user has typed so far.
+	input []rune
+
+	// submit is called with the input when the user presses Enter.
+	submit func(input string, conn *websocket.Conn)
+}
+
+// activePrompt is the prompt currently taking key events, if any.
+var activePrompt *prompt
+
+// openPrompt shows a prompt in the status bar, calling submit with the user's input.
+func openPrompt(label string, submit func(input string, conn *websocket.Conn)) {
+	activePrompt = &prompt{label: label, submit: submit}
+	showPrompt()
+}
+
+// closePrompt removes the prompt from the status bar.
+func closePrompt() {
+	activePrompt = nil
+	showPrompt()
+}
+
+// showPrompt mirrors the active prompt into the editor's status bar.
+func showPrompt() {
+	text := ""
+	if activePrompt != nil {
+		text = activePrompt.label + string(activePrompt.input)
+	}
+	setPromptText(text)
+}
+
+// setPromptText shows text in place of the status bar, or restores the status bar if text is "".
+func setPromptText(text string) {
+	e.StatusMu.Lock()
+	e.Prompt = text
+	e.StatusMu.Unlock()
+}
+
+// handlePromptEvent edits, submits or cancels the active prompt.
+func handlePromptEvent(ev termbox.Event, conn *websocket.Conn) {
+	p := activePrompt
+
+	switch ev.Key {
+	case termbox.KeyEnter:
+		closePrompt()
+		p.submit(string(p.input), conn)
+		return
+	case termbox.KeyEsc, termbox.KeyCtrlC:
+		closePrompt()
+		e.StatusChan <- "cancelled"
+		return
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case termbox.KeySpace:
+		p.input = append(p.input, ' ')
+	default:
+		if ev.Ch != 0 {
+			p.input = append(p.input, ev.Ch)
+		}
+	}
+
+	showPrompt()
+}
+
+// parseCodePoint parses a hex code point such as "1F600", "U+1F600" or "0x1F600" into the rune it names.
+// Surrogates, values beyond U+10FFFF and control characters other than newline and tab are rejected.
+func parseCodePoint(s string) (rune, error) {
+	s = strings.TrimSpace(s)
+	for _, prefix := range []string{"U+", "u+", "0x", "0X"} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	if s == "" {
+		return 0, errors.New("no code point given")
+	}
+
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a hex number", s)
+	}
+
+	r := rune(n)
+	if !utf8.ValidRune(r) {
+		return 0, fmt.Errorf("U+%X is not a valid code point", n)
+	}
+	if unicode.IsControl(r) && r != '\n' && r != '\t' {
+		return 0, fmt.Errorf("U+%04X is a control character", n)
+	}
+
+	return r, nil
+}
+
+// insertCodePoint inserts the character named by a hex code point at the cursor.
+func insertCodePoint(input string, conn *websocket.Conn) {
+	r, err := parseCodePoint(input)
+	if err != nil {
+		e.StatusChan <- fmt.Sprintf("Can't insert: %v", err)
+		return
+	}
+
+	performOperation(OperationInsert, termbox.Event{Ch: r}, conn)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestParseCodePoint(t *testing.T) {
+	tests := []struct {
+		description string
+		input       string
+		want        rune
+		wantErr     bool
+	}{
+		{"emoji", "1F600", '😀', false},
+		{"lowercase", "e9", 'é', false},
+		{"U+ prefix", "U+00E9", 'é', false},
+		{"0x prefix", "0x41", 'A', false},
+		{"surrounding spaces", "  41 ", 'A', false},
+		{"newline", "a", '\n', false},
+		{"largest code point", "10FFFF", '\U0010FFFF', false},
+		{"empty", "", 0, true},
+		{"only prefix", "U+", 0, true},
+		{"not hex", "xyz", 0, true},
+		{"negative", "-41", 0, true},
+		{"beyond unicode", "110000", 0, true},
+		{"surrogate", "D800", 0, true},
+		{"too large for 32 bits", "1FFFFFFFF", 0, true},
+		{"control character", "1B", 0, true},
+		{"nul", "0", 0, true},
+	}
+
+	for _, tc := range tests {
+		got, err := parseCodePoint(tc.input)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("(%s) got err = %v, expected error: %v\n", tc.description, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("(%s) got != want; got = %q, expected = %q\n", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestPrompt_InsertCodePoint(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	// Ctrl+U, then "1F60" with a stray "x" removed by Backspace, then "0" and Enter.
+	events := []termbox.Event{{Key: termbox.KeyCtrlU}}
+	for _, ch := range "1F60x" {
+		events = append(events, termbox.Event{Ch: ch})
+	}
+	events = append(events,
+		termbox.Event{Key: termbox.KeyBackspace2},
+		termbox.Event{Ch: '0'},
+		termbox.Event{Key: termbox.KeyEnter},
+	)
+	for _, ev := range events {
+		ev.Type = termbox.EventKey
+		if err := handleTermboxEvent(ev, nil); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	if got := crdt.Content(doc); got != "😀" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "😀")
+	}
+	if activePrompt != nil || e.Prompt != "" {
+		t.Errorf("prompt still open after Enter\n")
+	}
+
+	// Invalid input leaves the document alone.
+	for _, ev := range []termbox.Event{{Key: termbox.KeyCtrlU}, {Ch: 'z'}, {Key: termbox.KeyEnter}} {
+		ev.Type = termbox.EventKey
+		if err := handleTermboxEvent(ev, nil); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+	if got := crdt.Content(doc); got != "😀" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "😀")
+	}
+}
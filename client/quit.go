@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// errExiting ends the session; main reports it as a normal exit rather than an error.
+var errExiting = errors.New("editor: exiting")
+
+// quitPrompt asks whether to quit with unsaved changes.
+const quitPrompt = "Unsaved changes, quit anyway? (y/n) "
+
+var (
+	// dirty is set when this user has edited the document since it was last saved or loaded.
+	// Peers' edits don't set it: they're theirs to save, and the room keeps them either way.
+	dirty bool
+
+	// confirmingQuit is set while the status bar asks whether to quit with unsaved changes.
+	confirmingQuit bool
+)
+
+// requestQuit ends the session, unless there are unsaved changes; then it asks for confirmation first.
+func requestQuit() error {
+	if !dirty {
+		return errExiting
+	}
+
+	confirmingQuit = true
+	setPromptText(quitPrompt)
+	return nil
+}
+
+// handleQuitConfirmEvent answers the quit confirmation: Esc, Ctrl+C or y quits, and any other key cancels.
+// It reports whether ev was consumed, and returns errExiting to quit.
+func handleQuitConfirmEvent(ev termbox.Event) (bool, error) {
+	if !confirmingQuit || ev.Type != termbox.EventKey {
+		return false, nil
+	}
+
+	confirmingQuit = false
+	setPromptText("")
+
+	if ev.Key == termbox.KeyEsc || ev.Key == termbox.KeyCtrlC || ev.Ch == 'y' || ev.Ch == 'Y' {
+		return true, errExiting
+	}
+	e.StatusChan <- "cancelled"
+	return true, nil
+}
+
+// exitFlushTimeout bounds how long exiting waits on the server to take the last operations.
+const exitFlushTimeout = 2 * time.Second
+
+var (
+	// unsent is set when edits made since joining didn't reach the server, because the connection was down,
+	// until rejoining sends them along with the rest of the document.
+	unsent bool
+
+	// exitNote, if set, is printed after the session ends, e.g. to say where unsent edits were saved.
+	exitNote string
+)
+
+// flushOnExit sends what the session hasn't sent yet before it ends, such as a pending run of inserts,
+// giving up after exitFlushTimeout. If the server can't be reached, or edits made offline never reached it,
+// the document is saved next to the file instead, unless the file already has it, so last-moment edits aren't lost.
+// Offline sessions have nothing to send.
+func flushOnExit(conn *websocket.Conn) {
+	if conn == nil || detached {
+		return
+	}
+
+	if e.IsConnected {
+		if err := conn.SetWriteDeadline(time.Now().Add(exitFlushTimeout)); err != nil {
+			logger.Errorf("failed to set the write deadline, err: %v", err)
+		}
+		if err := flushRun(conn); err != nil {
+			logger.Errorf("failed to send pending operations on exit, err: %v", err)
+			unsent = true
+		}
+	}
+	if !unsent && pendingRun == nil {
+		return
+	}
+	if !dirty && fileName != "" {
+		exitNote = fmt.Sprintf("some edits didn't reach the server; they're saved in %s", fileName)
+		return
+	}
+
+	path := recoveryPath()
+	if err := crdt.Save(path, &doc); err != nil {
+		logger.Errorf("failed to save unsent edits to %s, err: %v", path, err)
+		exitNote = "some edits didn't reach the server, and saving them locally failed: see the log"
+		return
+	}
+	exitNote = fmt.Sprintf("some edits didn't reach the server; the document was saved to %s", path)
+}
+
+// recoveryPath returns where flushOnExit saves a document whose edits didn't reach the server: next to the file, marked unsent.
+func recoveryPath() string {
+	name := fileName
+	if name == "" {
+		name = "editor-content.txt"
+	}
+	return name + ".unsent"
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestQuit_ConfirmUnsavedChanges(t *testing.T) {
+	tests := []struct {
+		description string
+		edit        bool
+		answer      *termbox.Event
+		wantExit    bool
+	}{
+		{"no changes", false, nil, true},
+		{"esc again", true, &termbox.Event{Key: termbox.KeyEsc}, true},
+		{"ctrl+c", true, &termbox.Event{Key: termbox.KeyCtrlC}, true},
+		{"yes", true, &termbox.Event{Ch: 'y'}, true},
+		{"no", true, &termbox.Event{Ch: 'n'}, false},
+		{"other key", true, &termbox.Event{Key: termbox.KeyArrowLeft}, false},
+	}
+
+	for _, tc := range tests {
+		resetState(t)
+		handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+		if tc.edit {
+			_ = handleTermboxEvent(termbox.Event{Ch: 'a'}, nil)
+		}
+
+		err := handleTermboxEvent(termbox.Event{Key: termbox.KeyEsc}, nil)
+		if tc.answer == nil {
+			if !errors.Is(err, errExiting) {
+				t.Errorf("(%s) got %v, expected Esc to exit at once", tc.description, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("(%s) got %v, expected Esc to ask for confirmation", tc.description, err)
+		}
+		if !confirmingQuit || e.Prompt != quitPrompt {
+			t.Errorf("(%s) confirming = %v, prompt %q, expected the quit prompt", tc.description, confirmingQuit, e.Prompt)
+		}
+
+		err = handleTermboxEvent(*tc.answer, nil)
+		if got := errors.Is(err, errExiting); got != tc.wantExit {
+			t.Errorf("(%s) got %v, expected exit = %v", tc.description, err, tc.wantExit)
+		}
+		if confirmingQuit || e.Prompt != "" {
+			t.Errorf("(%s) confirmation still open after answering", tc.description)
+		}
+
+		// The answer doesn't also edit or move.
+		if got := crdt.Content(doc); got != "a" || e.Cursor != 1 {
+			t.Errorf("(%s) got text %q and cursor %d, expected %q and 1", tc.description, got, e.Cursor, "a")
+		}
+	}
+}
+
+func TestQuit_SavingClearsChanges(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+	oldFileName := fileName
+	fileName = filepath.Join(t.TempDir(), "doc.txt")
+	defer func() { fileName = oldFileName }()
+
+	_ = handleTermboxEvent(termbox.Event{Ch: 'a'}, nil)
+	if !dirty {
+		t.Fatalf("expected an edit to leave unsaved changes")
+	}
+
+	if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlS}, nil); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyEsc}, nil); !errors.Is(err, errExiting) {
+		t.Errorf("got %v, expected Esc to exit at once after saving", err)
+	}
+}
+
+func TestFlushOnExit_SendsPendingRun(t *testing.T) {
+	resetState(t)
+	oldCompress := flags.CompressRuns
+	flags.CompressRuns = true
+	defer func() { flags.CompressRuns = oldCompress }()
+
+	conn, received := dialRecorder(t)
+	e.IsConnected = true
+	doc.SiteID = 1
+	fileName = filepath.Join(t.TempDir(), "notes.txt")
+
+	// Quitting right after typing leaves a run of inserts waiting to be sent.
+	for _, ch := range "zzz" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	if pendingRun == nil {
+		t.Fatal("no run pending before exiting")
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyEsc}, conn)
+	if err := handleTermboxEvent(termbox.Event{Ch: 'y'}, conn); !errors.Is(err, errExiting) {
+		t.Fatalf("got %v, expected to exit", err)
+	}
+	flushOnExit(conn)
+
+	select {
+	case msg := <-received:
+		if msg.Operation.Repeat != 3 {
+			t.Errorf("got %+v, expected the run of three z's", msg.Operation)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the pending run wasn't sent on exit")
+	}
+	if pendingRun != nil || exitNote != "" {
+		t.Errorf("after exiting: pending run %v, note %q, expected neither", pendingRun, exitNote)
+	}
+	if _, err := os.Stat(recoveryPath()); !os.IsNotExist(err) {
+		t.Errorf("saved %s though everything was sent, err: %v", recoveryPath(), err)
+	}
+}
+
+func TestFlushOnExit_SavesUnsentEdits(t *testing.T) {
+	resetState(t)
+	conn, _ := dialRecorder(t)
+	doc.SiteID = 1
+	fileName = filepath.Join(t.TempDir(), "notes.txt")
+
+	// The connection dropped, so the edit stays local until rejoining.
+	e.IsConnected = false
+	for _, ch := range "offline" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	if !unsent {
+		t.Fatal("an edit made offline isn't marked unsent")
+	}
+
+	flushOnExit(conn)
+
+	got, err := os.ReadFile(fileName + ".unsent")
+	if err != nil || string(got) != "offline" {
+		t.Errorf("got %q saved, err: %v, expected %q", got, err, "offline")
+	}
+	if !strings.Contains(exitNote, fileName+".unsent") {
+		t.Errorf("exit note %q doesn't say where the edits were saved", exitNote)
+	}
+}
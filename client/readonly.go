@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// errFileReadOnly is reported for files whose permissions don't let anyone write them.
+var errFileReadOnly = errors.New("file is read-only")
+
+// fileReadOnly is set while the open file can't be written, so edits that couldn't be saved are refused.
+var fileReadOnly bool
+
+// checkWritable returns why the file at path can't be saved over, or nil if it can.
+// A missing file is writable, as saving creates it.
+func checkWritable(path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Opening the file catches what the permission bits don't, like ownership, but privileged users can open
+	// any file, so files nobody may write are read-only regardless.
+	if info.Mode().Perm()&0222 == 0 {
+		return errFileReadOnly
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// noteWritable switches the editor to read-only if the file just opened can't be written, and back if it can.
+func noteWritable(path string) {
+	err := checkWritable(path)
+	fileReadOnly = err != nil
+	if fileReadOnly {
+		logger.Warnf("opened %s read-only, err: %v", path, err)
+		e.StatusChan <- fmt.Sprintf("Opened %s read-only, it can't be written (%v): F6 saves a copy", path, err)
+	}
+}
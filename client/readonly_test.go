@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestCheckWritable(t *testing.T) {
+	dir := t.TempDir()
+
+	writable := filepath.Join(dir, "writable.txt")
+	if err := os.WriteFile(writable, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	readOnlyFile := filepath.Join(dir, "read-only.txt")
+	if err := os.WriteFile(readOnlyFile, []byte("a"), 0444); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkWritable(writable); err != nil {
+		t.Errorf("writable file: got err = %v, expected nil", err)
+	}
+	if err := checkWritable(filepath.Join(dir, "new.txt")); err != nil {
+		t.Errorf("missing file: got err = %v, expected nil, as saving creates it", err)
+	}
+	if err := checkWritable(readOnlyFile); !errors.Is(err, errFileReadOnly) {
+		t.Errorf("read-only file: got err = %v, expected %v", err, errFileReadOnly)
+	}
+	if err := checkWritable(dir); err == nil {
+		t.Errorf("directory: got nil, expected an error")
+	}
+}
+
+func TestLoadFile_ReadOnly(t *testing.T) {
+	resetState(t)
+	oldFileName := fileName
+	defer func() { fileName = oldFileName }()
+	doc.SiteID = 1
+
+	fileName = filepath.Join(t.TempDir(), "read-only.txt")
+	if err := os.WriteFile(fileName, []byte("abc"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadFile(nil); err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if !fileReadOnly || string(e.Text) != "abc" {
+		t.Fatalf("got read-only %v and text %q, expected the file loaded read-only", fileReadOnly, string(e.Text))
+	}
+
+	// Typing is refused rather than lost on save.
+	_ = handleTermboxEvent(termbox.Event{Ch: 'x'}, nil)
+	if string(e.Text) != "abc" {
+		t.Errorf("got text %q, expected edits to be refused", string(e.Text))
+	}
+
+	// Saving a copy elsewhere makes the document editable again.
+	saveAsConfirmed(filepath.Join(t.TempDir(), "copy.txt"))
+	_ = handleTermboxEvent(termbox.Event{Ch: 'x'}, nil)
+	if fileReadOnly || string(e.Text) != "xabc" {
+		t.Errorf("got read-only %v and text %q, expected %q after saving a copy", fileReadOnly, string(e.Text), "xabc")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// maxRecent bounds how many recent files are remembered.
+const maxRecent = 10
+
+// RecentFiles is the list of recently opened or saved files, most recent first, persisted as JSON.
+type RecentFiles struct {
+	// path is where the list is persisted.
+	path string
+
+	Files []string
+}
+
+// recentPath returns where the recent files list is kept, alongside the logs in ~/.edito.
+func recentPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".edito", "recent.json"), nil
+}
+
+// LoadRecent reads the recent files list persisted at path, dropping files that no longer exist.
+// A missing list is treated as empty.
+func LoadRecent(path string) (*RecentFiles, error) {
+	r := &RecentFiles{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return r, err
+	}
+
+	if err := json.Unmarshal(data, &r.Files); err != nil {
+		return r, err
+	}
+	r.prune()
+
+	return r, nil
+}
+
+// AddRecent records a file as the most recently used one and persists the list.
+// Paths are made absolute, so the same file opened from different directories appears once.
+func (r *RecentFiles) AddRecent(path string) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	r.Files = slices.DeleteFunc(r.Files, func(file string) bool { return file == path })
+	r.Files = slices.Insert(r.Files, 0, path)
+	r.prune()
+
+	return r.save()
+}
+
+// prune drops files that no longer exist and bounds the list to maxRecent entries.
+func (r *RecentFiles) prune() {
+	r.Files = slices.DeleteFunc(r.Files, func(file string) bool {
+		_, err := os.Stat(file)
+		return errors.Is(err, os.ErrNotExist)
+	})
+	if len(r.Files) > maxRecent {
+		r.Files = r.Files[:maxRecent]
+	}
+}
+
+// save persists the list, creating its directory if needed.
+func (r *RecentFiles) save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r.Files, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
+
+// rememberFile adds a file to the recent files list, if there is one.
+func rememberFile(path string) {
+	if recent == nil {
+		return
+	}
+	if err := recent.AddRecent(path); err != nil {
+		logger.Warnf("failed to update recent files, err: %v", err)
+	}
+}
+
+// openRecentPicker prompts for the number of a recent file to open.
+func openRecentPicker() {
+	if recent == nil || len(recent.Files) == 0 {
+		e.StatusChan <- "No recent files!"
+		return
+	}
+
+	var label strings.Builder
+	label.WriteString("Open recent:")
+	for i, file := range recent.Files {
+		fmt.Fprintf(&label, " %d) %s", i+1, filepath.Base(file))
+	}
+	label.WriteString(" #: ")
+
+	openPrompt(label.String(), openRecent)
+}
+
+// openRecent opens the recent file with the given 1-based number.
+func openRecent(input string, conn *websocket.Conn) {
+	n, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || n < 1 || n > len(recent.Files) {
+		e.StatusChan <- fmt.Sprintf("No recent file %q", input)
+		return
+	}
+
+	fileName = recent.Files[n-1]
+	e.SetFileName(fileName)
+	_ = loadFile(conn)
+}
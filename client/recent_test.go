@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// touchFiles creates empty files with the given names in dir, returning their paths.
+func touchFiles(t *testing.T, dir string, names ...string) []string {
+	t.Helper()
+
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0600); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestRecentFiles_Ordering(t *testing.T) {
+	dir := t.TempDir()
+	files := touchFiles(t, dir, "a.txt", "b.txt", "c.txt")
+	listPath := filepath.Join(dir, "edito", "recent.json")
+
+	r, err := LoadRecent(listPath)
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if len(r.Files) != 0 {
+		t.Errorf("a missing list should be empty; got = %v\n", r.Files)
+	}
+
+	// Reopening a file moves it to the front without duplicating it.
+	for _, file := range []string{files[0], files[1], files[2], files[0]} {
+		if err := r.AddRecent(file); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	want := []string{files[0], files[2], files[1]}
+	if !cmp.Equal(r.Files, want) {
+		t.Errorf("got != want; diff = %v\n", cmp.Diff(r.Files, want))
+	}
+
+	// The list survives a reload.
+	loaded, err := LoadRecent(listPath)
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if !cmp.Equal(loaded.Files, want) {
+		t.Errorf("got != want; diff = %v\n", cmp.Diff(loaded.Files, want))
+	}
+}
+
+func TestRecentFiles_RelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	file := touchFiles(t, dir, "a.txt")[0]
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	defer os.Chdir(oldDir)
+
+	r := &RecentFiles{path: filepath.Join(dir, "recent.json")}
+	for _, path := range []string{"a.txt", file, "./a.txt"} {
+		if err := r.AddRecent(path); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	want := []string{file}
+	if !cmp.Equal(r.Files, want) {
+		t.Errorf("got != want; diff = %v\n", cmp.Diff(r.Files, want))
+	}
+}
+
+func TestRecentFiles_Bounded(t *testing.T) {
+	dir := t.TempDir()
+
+	var names []string
+	for i := 0; i < maxRecent+3; i++ {
+		names = append(names, fmt.Sprintf("%d.txt", i))
+	}
+	files := touchFiles(t, dir, names...)
+
+	r := &RecentFiles{path: filepath.Join(dir, "recent.json")}
+	for _, file := range files {
+		if err := r.AddRecent(file); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	if len(r.Files) != maxRecent {
+		t.Fatalf("got != want; len = %v, expected = %v\n", len(r.Files), maxRecent)
+	}
+	if got, want := r.Files[0], files[len(files)-1]; got != want {
+		t.Errorf("most recent file should come first; got = %v, expected = %v\n", got, want)
+	}
+	if got, want := r.Files[maxRecent-1], files[3]; got != want {
+		t.Errorf("oldest files should be dropped; got = %v, expected = %v\n", got, want)
+	}
+}
+
+func TestRecentFiles_PrunesMissing(t *testing.T) {
+	dir := t.TempDir()
+	files := touchFiles(t, dir, "a.txt", "b.txt", "c.txt")
+	listPath := filepath.Join(dir, "recent.json")
+
+	r := &RecentFiles{path: listPath}
+	for _, file := range files {
+		if err := r.AddRecent(file); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	if err := os.Remove(files[1]); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	loaded, err := LoadRecent(listPath)
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	want := []string{files[2], files[0]}
+	if !cmp.Equal(loaded.Files, want) {
+		t.Errorf("got != want; diff = %v\n", cmp.Diff(loaded.Files, want))
+	}
+}
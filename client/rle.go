@@ -0,0 +1,94 @@
+package main
+
+import (
+	"time"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+)
+
+// runFlushDelay is how long after a run of repeated inserts starts it's sent, however long it has grown.
+// It bounds how far peers lag behind someone holding a key.
+const runFlushDelay = 100 * time.Millisecond
+
+var (
+	// pendingRun is the run of repeated inserts waiting to be sent with -compress-runs, or nil if there's none.
+	pendingRun *commons.Message
+
+	// runFlush fires when the pending run is due to be sent; it's nil without one.
+	runFlush <-chan time.Time
+)
+
+// sendOperation sends a local operation to the room and waits for its echo.
+// With -compress-runs, inserts are held back briefly, so a run of the same character is sent as one operation.
+func sendOperation(msg commons.Message, conn *websocket.Conn) error {
+	if !flags.CompressRuns {
+		return writeOperation(msg, conn)
+	}
+	if extendRun(msg) {
+		return nil
+	}
+	if err := flushRun(conn); err != nil {
+		return err
+	}
+
+	op := msg.Operation
+	if op.Type != "insert" || op.Character == nil {
+		return writeOperation(msg, conn)
+	}
+
+	// Start a run with this insert; the document's clock is still the one its character was generated at.
+	op.Repeat, op.Site, op.SiteClock = 1, doc.SiteID, doc.Clock
+	msg.Operation = op
+	pendingRun = &msg
+	runFlush = time.After(runFlushDelay)
+	return nil
+}
+
+// extendRun adds an insert to the pending run if it's the run's next character, reporting whether it was.
+func extendRun(msg commons.Message) bool {
+	op := msg.Operation
+	if pendingRun == nil || op.Type != "insert" || op.Character == nil {
+		return false
+	}
+
+	run := pendingRun.Operation
+	if op.Value != run.Value {
+		return false
+	}
+	chars := crdt.RunCharacters(*run.Character, run.Site, run.SiteClock, run.Repeat+1)
+	if chars[run.Repeat] != *op.Character {
+		return false
+	}
+
+	// The run is as recent as its last character.
+	pendingRun.Operation.Repeat++
+	pendingRun.Operation.Clock = op.Clock
+	return true
+}
+
+// flushRun sends the pending run, if any.
+func flushRun(conn *websocket.Conn) error {
+	if pendingRun == nil {
+		return nil
+	}
+	msg := *pendingRun
+	pendingRun, runFlush = nil, nil
+
+	// A run of one is an ordinary insert.
+	if msg.Operation.Repeat == 1 {
+		msg.Operation.Repeat, msg.Operation.Site, msg.Operation.SiteClock = 0, 0, 0
+	}
+	return writeOperation(msg, conn)
+}
+
+// writeOperation writes an operation to the connection, noting that its echo is awaited.
+func writeOperation(msg commons.Message, conn *websocket.Conn) error {
+	if err := conn.WriteJSON(msg); err != nil {
+		return err
+	}
+	awaitEcho(msg.Operation)
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// dialRecorder connects to a stub server that passes on the messages it receives.
+func dialRecorder(t *testing.T) (*websocket.Conn, chan commons.Message) {
+	t.Helper()
+
+	received := make(chan commons.Message, 100)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var msg commons.Message
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			received <- msg
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial error: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn, received
+}
+
+func TestCompressRuns_ExpandsToSameContent(t *testing.T) {
+	resetState(t)
+	oldCompress := flags.CompressRuns
+	flags.CompressRuns = true
+	defer func() { flags.CompressRuns = oldCompress }()
+
+	conn, received := dialRecorder(t)
+	e.IsConnected = true
+	doc.SiteID = 1
+
+	// A peer starts from the same empty document.
+	peer := crdt.New()
+	peer.SiteID = 2
+
+	for _, ch := range "aaaaab" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyBackspace2}, conn)
+	for _, ch := range "cc" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	if err := flushRun(conn); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+
+	// The a's are one operation; b is an ordinary insert, sent before the delete.
+	var msgs []commons.Message
+	for len(msgs) < 4 {
+		msgs = append(msgs, <-received)
+	}
+	wantRepeats := []int{5, 0, 0, 2}
+	for i, msg := range msgs {
+		if msg.Operation.Repeat != wantRepeats[i] {
+			t.Errorf("message %d: got %+v, expected a repeat of %d", i, msg.Operation, wantRepeats[i])
+		}
+	}
+
+	for _, msg := range msgs {
+		for _, op := range msg.Operation.Expand() {
+			switch op.Type {
+			case "insert":
+				if _, err := peer.IntegrateRemoteInsert(*op.Character); err != nil {
+					t.Fatalf("integrate error: %v", err)
+				}
+			case "delete":
+				peer.DeleteByID(op.ID)
+			}
+		}
+	}
+	if got, want := crdt.Content(peer), crdt.Content(doc); got != want || want != "aaaaacc" {
+		t.Errorf("peer has %q, expected %q like the sender, %q", got, want, "aaaaacc")
+	}
+}
+
+func TestHandleMsg_Run(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 2
+
+	// A peer typed three x's.
+	sender := crdt.New()
+	sender.SiteID = 1
+	first, err := sender.GenerateCharacter(1, "x")
+	if err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+	op := commons.Operation{Type: "insert", Position: 1, Value: "x", Character: &first, Repeat: 3, Site: 1, SiteClock: sender.Clock}
+	handleMsg(commons.Message{Type: "operation", Username: "peer", Operation: op}, nil)
+
+	if got := crdt.Content(doc); got != "xxx" || string(e.Text) != "xxx" {
+		t.Errorf("got text %q and editor text %q, expected %q", got, string(e.Text), "xxx")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// saveTo saves the document to name, backing up the version it replaces, and reports the result in the status bar.
+func saveTo(name string) error {
+	// Keep the previous versions, but don't let a failed backup (e.g. in a read-only directory) prevent saving.
+	backupErr := rotateBackups(name, flags.Backups)
+	if backupErr != nil {
+		logger.Warnf("failed to back up %s, err: %v", name, backupErr)
+	}
+
+	// Persist the CRDT to a file.
+	err := saveDoc(name)
+	if err != nil {
+		logrus.Errorf("Failed to save to %s: %v", name, err)
+		e.StatusChan <- fmt.Sprintf("Failed to save to %s: %v", name, err)
+		return err
+	}
+
+	dirty = false
+
+	// Update the status bar.
+	if backupErr != nil {
+		e.StatusChan <- fmt.Sprintf("Saved document to %s (backup skipped: %v)", name, backupErr)
+	} else {
+		e.StatusChan <- fmt.Sprintf("Saved document to %s", name)
+	}
+	rememberFile(name)
+	return nil
+}
+
+// saveAs saves the document to path, which becomes the file later saves go to.
+// An existing file other than the current one is only replaced once the user confirms.
+func saveAs(path string, conn *websocket.Conn) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		e.StatusChan <- "no file given"
+		return
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		e.StatusChan <- fmt.Sprintf("Failed to save to %s: is a directory", path)
+		return
+	case err == nil && path != fileName:
+		openPrompt(fmt.Sprintf("%s exists, overwrite? (y/n) ", path), func(input string, conn *websocket.Conn) {
+			if answer := strings.ToLower(strings.TrimSpace(input)); answer != "y" && answer != "yes" {
+				e.StatusChan <- "cancelled"
+				return
+			}
+			saveAsConfirmed(path)
+		})
+		return
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		e.StatusChan <- fmt.Sprintf("Failed to save to %s: %v", path, err)
+		return
+	}
+
+	saveAsConfirmed(path)
+}
+
+// saveAsConfirmed saves the document to path and switches to it, keeping the current file if saving fails.
+func saveAsConfirmed(path string) {
+	// A failed save has already been reported; unlike Ctrl+S, a mistyped path shouldn't end the session.
+	if err := saveTo(path); err != nil {
+		return
+	}
+	fileName = path
+	e.SetFileName(fileName)
+	fileReadOnly = false
+}
+
+// saveDoc writes the document to name, trimming trailing whitespace if -trim-on-save is set.
+// Trimming only changes what's written; the shared document keeps its whitespace.
+func saveDoc(name string) error {
+	if !flags.TrimOnSave {
+		return crdt.Save(name, &doc)
+	}
+	return os.WriteFile(name, []byte(savedText()), 0644)
+}
+
+// savedText returns the text saving writes: the document's, trimmed if -trim-on-save is set.
+func savedText() string {
+	if !flags.TrimOnSave {
+		return crdt.Content(doc)
+	}
+	return trimTrailingWhitespace(crdt.Content(doc))
+}
+
+// saveFile saves the document to the current file for Ctrl+S.
+// With -whitespace-guard, a save that would only change the file's whitespace is confirmed first,
+// so a file isn't reformatted by accident.
+func saveFile() error {
+	if !flags.WhitespaceGuard {
+		return saveTo(fileName)
+	}
+
+	// A file that can't be read, e.g. because it doesn't exist yet, has nothing to reformat.
+	saved, err := os.ReadFile(fileName)
+	if err != nil || !whitespaceOnlyDiff(string(saved), savedText()) {
+		return saveTo(fileName)
+	}
+
+	openPrompt("Only whitespace changed (trailing spaces, tabs or line breaks), save anyway? (y/n) ", func(input string, conn *websocket.Conn) {
+		if answer := strings.ToLower(strings.TrimSpace(input)); answer != "y" && answer != "yes" {
+			e.StatusChan <- "cancelled"
+			return
+		}
+		// A failed save has already been reported.
+		_ = saveTo(fileName)
+	})
+	return nil
+}
+
+// whitespaceOnlyDiff reports whether a and b differ, but only in whitespace:
+// the amount or kind of it, e.g. trailing spaces, tabs instead of spaces, or line endings.
+// Whitespace added inside a word, or removed from between two, changes the content.
+func whitespaceOnlyDiff(a, b string) bool {
+	return a != b && slices.Equal(strings.Fields(a), strings.Fields(b))
+}
+
+// trimTrailingWhitespace removes whitespace from the end of every line and ends non-empty text with exactly one newline.
+func trimTrailingWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(line, unicode.IsSpace)
+	}
+
+	text = strings.TrimRight(strings.Join(lines, "\n"), "\n")
+	if text == "" {
+		return ""
+	}
+	return text + "\n"
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestTrimTrailingWhitespace(t *testing.T) {
+	tests := []struct {
+		description string
+		text        string
+		want        string
+	}{
+		{"empty", "", ""},
+		{"clean", "a\nb\n", "a\nb\n"},
+		{"missing final newline", "a\nb", "a\nb\n"},
+		{"trailing spaces and tabs", "a  \nb\t\n", "a\nb\n"},
+		{"leading whitespace kept", "  a\n\tb \n", "  a\n\tb\n"},
+		{"inner whitespace kept", "a  b \n", "a  b\n"},
+		{"all-whitespace lines", "a\n   \n\t\nb\n", "a\n\n\nb\n"},
+		{"extra final newlines", "a\n\n\n", "a\n"},
+		{"trailing whitespace-only lines", "a\n  \n \t \n", "a\n"},
+		{"only whitespace", "  \n\t\n", ""},
+		{"carriage returns", "a \r\nb\r\n", "a\nb\n"},
+		{"unicode whitespace", "a 　\n", "a\n"},
+	}
+
+	for _, tc := range tests {
+		if got := trimTrailingWhitespace(tc.text); got != tc.want {
+			t.Errorf("(%s) got %q, expected %q", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestSaveDoc_TrimOnSave(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+	for i, ch := range []rune("a \nb") {
+		doc.GenerateInsert(i+1, string(ch))
+	}
+
+	oldFlags := flags
+	defer func() { flags = oldFlags }()
+
+	for _, tc := range []struct {
+		trim bool
+		want string
+	}{{false, "a \nb"}, {true, "a\nb\n"}} {
+		flags.TrimOnSave = tc.trim
+		path := filepath.Join(t.TempDir(), "file.txt")
+		if err := saveDoc(path); err != nil {
+			t.Fatalf("save error: %v", err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("trim = %v: got %q, expected %q", tc.trim, got, tc.want)
+		}
+	}
+
+	// The shared document keeps its whitespace.
+	if got := crdt.Content(doc); got != "a \nb" {
+		t.Errorf("document changed: got %q", got)
+	}
+}
+
+// saveAsKeys presses F6 and answers the prompts with answers, each followed by Enter.
+func saveAsKeys(t *testing.T, answers ...string) {
+	t.Helper()
+
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyF6}, nil)
+	for _, answer := range answers {
+		for _, ch := range answer {
+			_ = handleTermboxEvent(termbox.Event{Ch: ch}, nil)
+		}
+		if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyEnter}, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestSaveAs(t *testing.T) {
+	resetState(t)
+	dir := t.TempDir()
+	oldFileName, oldBackups := fileName, flags.Backups
+	fileName, flags.Backups = filepath.Join(dir, "old.txt"), 0
+	defer func() { fileName, flags.Backups = oldFileName, oldBackups }()
+
+	doc.SiteID = 1
+	_ = handleTermboxEvent(termbox.Event{Ch: 'a'}, nil)
+
+	readFile := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		return string(data)
+	}
+
+	// Saving to a new file switches to it, so Ctrl+S saves there too.
+	newPath := filepath.Join(dir, "new.txt")
+	saveAsKeys(t, newPath)
+	if fileName != newPath || e.FileName != newPath {
+		t.Fatalf("got file name %q (shown as %q), expected %q", fileName, e.FileName, newPath)
+	}
+	if got := readFile(newPath); got != "a" {
+		t.Errorf("got %q in %s, expected %q", got, newPath, "a")
+	}
+	if dirty {
+		t.Errorf("expected saving to clear unsaved changes")
+	}
+
+	_ = handleTermboxEvent(termbox.Event{Ch: 'b'}, nil)
+	if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlS}, nil); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if got := readFile(newPath); got != "ab" {
+		t.Errorf("got %q in %s after Ctrl+S, expected %q", got, newPath, "ab")
+	}
+
+	// An existing file is only replaced once confirmed.
+	existing := filepath.Join(dir, "existing.txt")
+	if err := os.WriteFile(existing, []byte("keep"), 0644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	saveAsKeys(t, existing, "n")
+	if got := readFile(existing); got != "keep" || fileName != newPath {
+		t.Errorf("declined overwrite: got %q in the file and file name %q, expected %q and %q", got, fileName, "keep", newPath)
+	}
+	saveAsKeys(t, existing, "y")
+	if got := readFile(existing); got != "ab" || fileName != existing {
+		t.Errorf("confirmed overwrite: got %q in the file and file name %q, expected %q and %q", got, fileName, "ab", existing)
+	}
+
+	// Saving again to the current file needs no confirmation.
+	saveAsKeys(t, existing)
+	if activePrompt != nil {
+		t.Errorf("expected no confirmation to save to the current file, got %q", activePrompt.label)
+	}
+
+	// Invalid paths are reported, keeping the current file.
+	for len(e.StatusChan) > 0 {
+		<-e.StatusChan
+	}
+	for _, path := range []string{dir, filepath.Join(dir, "missing", "file.txt")} {
+		saveAsKeys(t, path)
+		if fileName != existing {
+			t.Errorf("saving to %s: got file name %q, expected %q", path, fileName, existing)
+		}
+		if msg := <-e.StatusChan; !strings.HasPrefix(msg, "Failed to save to "+path+": ") {
+			t.Errorf("saving to %s: got status %q, expected an error", path, msg)
+		}
+	}
+}
+
+func TestWhitespaceOnlyDiff(t *testing.T) {
+	tests := []struct {
+		description string
+		a, b        string
+		want        bool
+	}{
+		{"identical", "a b\nc\n", "a b\nc\n", false},
+		{"both empty", "", "", false},
+		{"trailing spaces added", "a\nb\n", "a  \nb\n", true},
+		{"trailing spaces removed", "a \t\nb\n", "a\nb\n", true},
+		{"tabs to spaces", "\tif x {\n\t\ty()\n", "    if x {\n        y()\n", true},
+		{"spaces to tabs", "    x", "\tx", true},
+		{"line endings", "a\r\nb\r\n", "a\nb\n", true},
+		{"final newline added", "a", "a\n", true},
+		{"blank line added", "a\nb", "a\n\nb", true},
+		{"whitespace only to empty", "  \n", "", true},
+		{"word changed", "a b\n", "a c\n", false},
+		{"word changed along with whitespace", "a b \n", "\ta c\n", false},
+		{"line added", "a\n", "a\nb\n", false},
+		{"word split", "ab", "a b", false},
+		{"words joined", "a b", "ab", false},
+	}
+
+	for _, tc := range tests {
+		if got := whitespaceOnlyDiff(tc.a, tc.b); got != tc.want {
+			t.Errorf("(%s) %q vs %q: got %v, expected %v", tc.description, tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestSaveFile_WhitespaceGuard(t *testing.T) {
+	resetState(t)
+	oldFileName, oldFlags := fileName, flags
+	defer func() { fileName, flags = oldFileName, oldFlags }()
+	fileName = filepath.Join(t.TempDir(), "file.txt")
+	flags.WhitespaceGuard, flags.Backups = true, 0
+
+	if err := os.WriteFile(fileName, []byte("a\n"), 0644); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	readFile := func() string {
+		t.Helper()
+		data, err := os.ReadFile(fileName)
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		return string(data)
+	}
+	save := func(answer string) {
+		t.Helper()
+		if err := handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlS}, nil); err != nil {
+			t.Fatalf("save failed: %v", err)
+		}
+		if answer == "" {
+			return
+		}
+		if activePrompt == nil {
+			t.Fatalf("expected a confirmation prompt")
+		}
+		for _, ch := range answer {
+			_ = handleTermboxEvent(termbox.Event{Ch: ch}, nil)
+		}
+		_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyEnter}, nil)
+	}
+
+	doc.SiteID = 1
+	typeText(t, "a  \n")
+
+	// Only trailing spaces were added, so saving asks first.
+	save("n")
+	if got := readFile(); got != "a\n" {
+		t.Errorf("declined: got %q in the file, expected it unchanged", got)
+	}
+	save("y")
+	if got := readFile(); got != "a  \n" {
+		t.Errorf("confirmed: got %q in the file, expected %q", got, "a  \n")
+	}
+
+	// Real changes, even alongside whitespace ones, save without asking.
+	e.Cursor = len(e.Text)
+	_ = handleTermboxEvent(termbox.Event{Ch: 'b'}, nil)
+	save("")
+	if activePrompt != nil {
+		t.Errorf("expected no prompt for a content change")
+	}
+	if got := readFile(); got != "a  \nb" {
+		t.Errorf("got %q in the file, expected %q", got, "a  \nb")
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/websocket"
+	"github.com/nsf/termbox-go"
+)
+
+// Terminals don't report Shift with Home, End or the arrow keys to termbox, so a selection is made
+// the way Emacs sets a mark: Ctrl+Space starts selecting at the cursor, and the selection keys below
+// then extend it to wherever they move the cursor. Any other key ends the selection.
+
+// selectionKeys are the keys that extend a selection being made, by moving its end to the cursor:
+// the arrows and Ctrl+B/F/P/N move it by a character or line, and Home and End to the start or end of the document.
+var selectionKeys = map[termbox.Key]bool{
+	termbox.KeyArrowLeft:  true,
+	termbox.KeyArrowRight: true,
+	termbox.KeyArrowUp:    true,
+	termbox.KeyArrowDown:  true,
+	termbox.KeyCtrlB:      true,
+	termbox.KeyCtrlF:      true,
+	termbox.KeyCtrlP:      true,
+	termbox.KeyCtrlN:      true,
+	termbox.KeyHome:       true,
+	termbox.KeyEnd:        true,
+}
+
+// clipboard holds the text last copied with Ctrl+X, for pasting with Ctrl+V.
+// With -system-clipboard, the system clipboard is used instead where a tool for it was found.
+var clipboard string
+
+// isSelectionKey reports whether ev extends a selection being made.
+// In normal mode, the movement letters do too.
+func isSelectionKey(ev termbox.Event) bool {
+	if ev.Ch == 0 {
+		return selectionKeys[ev.Key]
+	}
+	return modal && mode == modeNormal && strings.ContainsRune("hjkl", ev.Ch)
+}
+
+// handleSelectionEvent handles the keys that start, copy and paste selections.
+// It reports whether ev was consumed.
+func handleSelectionEvent(ev termbox.Event, conn *websocket.Conn) bool {
+	if ev.Type != termbox.EventKey {
+		return false
+	}
+
+	switch {
+	// Ctrl+Space starts a selection at the cursor, or cancels the one being made.
+	// Character events have no key either, so only one without a character is Ctrl+Space.
+	case ev.Key == termbox.KeyCtrlSpace && ev.Ch == 0:
+		if e.IsSelecting() {
+			e.ClearSelection()
+			e.StatusChan <- "Selection cancelled"
+		} else {
+			e.StartSelection()
+			e.StatusChan <- "Selecting: move to extend, Ctrl+X to copy"
+		}
+
+	// Esc cancels a selection rather than exiting.
+	case ev.Key == termbox.KeyEsc && e.IsSelecting():
+		e.ClearSelection()
+		e.StatusChan <- "Selection cancelled"
+
+	// Ctrl+X copies the selection; Ctrl+C already exits.
+	case ev.Key == termbox.KeyCtrlX:
+		copySelection()
+
+	// Ctrl+V pastes what was copied at the cursor.
+	case ev.Key == termbox.KeyCtrlV:
+		pasteClipboard(conn)
+
+	default:
+		return false
+	}
+	return true
+}
+
+// updateSelection extends the selection being made to the cursor after a selection key, and ends it after any other.
+func updateSelection(ev termbox.Event) {
+	if ev.Type != termbox.EventKey || !e.IsSelecting() {
+		return
+	}
+	if isSelectionKey(ev) {
+		e.ExtendSelection()
+		return
+	}
+	e.ClearSelection()
+}
+
+// copySelection copies the selected text to the clipboard and ends the selection.
+func copySelection() {
+	text := e.SelectedText()
+	if text == "" {
+		e.StatusChan <- "Nothing selected: Ctrl+Space starts a selection"
+		return
+	}
+
+	clipboard = text
+	e.ClearSelection()
+
+	if sysClip.copy == nil {
+		e.StatusChan <- fmt.Sprintf("Copied %d characters", len([]rune(text)))
+		return
+	}
+	// The text is still in the editor's own clipboard if the system's can't take it.
+	if err := sysClip.write(text); err != nil {
+		logger.Warnf("failed to copy to the system clipboard with %s, err: %v", sysClip.copy[0], err)
+		e.StatusChan <- fmt.Sprintf("Copied %d characters, but not to the system clipboard: %v", len([]rune(text)), err)
+		return
+	}
+	e.StatusChan <- fmt.Sprintf("Copied %d characters to the system clipboard", len([]rune(text)))
+}
+
+// clipboardText returns the text to paste: the system clipboard's, if there's a tool to read it,
+// or else, or if reading it fails, what was last copied in the editor.
+func clipboardText() string {
+	if sysClip.paste == nil {
+		return clipboard
+	}
+	text, err := sysClip.read()
+	if err != nil {
+		logger.Warnf("failed to paste from the system clipboard with %s, err: %v", sysClip.paste[0], err)
+		return clipboard
+	}
+	return text
+}
+
+// pasteClipboard inserts the clipboard's text at the cursor, leaving the cursor after it.
+func pasteClipboard(conn *websocket.Conn) {
+	text := clipboardText()
+	if text == "" {
+		e.StatusChan <- "Nothing to paste"
+		return
+	}
+	if !utf8.ValidString(text) {
+		e.StatusChan <- "Can't paste: the clipboard isn't valid UTF-8 text"
+		return
+	}
+	if reason := editBlocked(); reason != "" {
+		e.StatusChan <- reason
+		return
+	}
+	if doc.SiteID == 0 {
+		e.StatusChan <- "connecting..."
+		return
+	}
+
+	n, _ := insertFrom(strings.NewReader(text), conn)
+	e.StatusChan <- fmt.Sprintf("Pasted %d characters", n)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestSelection_KeysExtendSelection(t *testing.T) {
+	resetState(t)
+	e.Text = []rune("abc\ndef\nghi")
+	e.SetX(5)
+
+	type step struct {
+		description        string
+		ev                 termbox.Event
+		wantStart, wantEnd int
+		wantSelecting      bool
+	}
+	steps := []step{
+		{"start", termbox.Event{Key: termbox.KeyCtrlSpace}, 5, 5, true},
+		{"right", termbox.Event{Key: termbox.KeyArrowRight}, 5, 6, true},
+		{"ctrl+f", termbox.Event{Key: termbox.KeyCtrlF}, 5, 7, true},
+		{"down", termbox.Event{Key: termbox.KeyArrowDown}, 5, 11, true},
+		{"left", termbox.Event{Key: termbox.KeyArrowLeft}, 5, 10, true},
+		{"ctrl+b", termbox.Event{Key: termbox.KeyCtrlB}, 5, 9, true},
+
+		// Moving back past the start selects backwards from it.
+		{"up", termbox.Event{Key: termbox.KeyArrowUp}, 5, 5, true},
+		{"ctrl+p", termbox.Event{Key: termbox.KeyCtrlP}, 1, 5, true},
+		{"ctrl+n", termbox.Event{Key: termbox.KeyCtrlN}, 5, 5, true},
+		{"home", termbox.Event{Key: termbox.KeyHome}, 0, 5, true},
+		{"end", termbox.Event{Key: termbox.KeyEnd}, 5, 11, true},
+
+		// Other keys end the selection.
+		{"f3", termbox.Event{Key: termbox.KeyF3}, 0, 0, false},
+		{"right after selecting", termbox.Event{Key: termbox.KeyArrowRight}, 0, 0, false},
+		{"restart", termbox.Event{Key: termbox.KeyCtrlSpace}, 11, 11, true},
+		{"cancel", termbox.Event{Key: termbox.KeyCtrlSpace}, 0, 0, false},
+		{"start again", termbox.Event{Key: termbox.KeyCtrlSpace}, 11, 11, true},
+		{"left again", termbox.Event{Key: termbox.KeyArrowLeft}, 10, 11, true},
+		{"esc", termbox.Event{Key: termbox.KeyEsc}, 0, 0, false},
+	}
+
+	for _, s := range steps {
+		s.ev.Type = termbox.EventKey
+		if err := handleTermboxEvent(s.ev, nil); err != nil {
+			t.Fatalf("(%s) unexpected error: %v", s.description, err)
+		}
+		if start, end := e.Selection(); start != s.wantStart || end != s.wantEnd {
+			t.Errorf("(%s) selection = [%d, %d), expected [%d, %d)", s.description, start, end, s.wantStart, s.wantEnd)
+		}
+		if got := e.IsSelecting(); got != s.wantSelecting {
+			t.Errorf("(%s) selecting = %v, expected %v", s.description, got, s.wantSelecting)
+		}
+	}
+}
+
+func TestSelection_ModalMovement(t *testing.T) {
+	resetState(t)
+	e.Text = []rune("ab\ncd")
+	modal = true
+	setMode(modeNormal)
+
+	for _, ev := range []termbox.Event{{Key: termbox.KeyCtrlSpace}, {Ch: 'l'}, {Ch: 'j'}} {
+		ev.Type = termbox.EventKey
+		_ = handleTermboxEvent(ev, nil)
+	}
+	if start, end := e.Selection(); start != 0 || end != 4 {
+		t.Errorf("selection = [%d, %d), expected [0, 4)", start, end)
+	}
+}
+
+func TestSelection_CopyAndPaste(t *testing.T) {
+	resetState(t)
+	handleMsg(commons.Message{Type: commons.SiteIDMessage, Text: "1"}, nil)
+
+	keys := func(evs ...termbox.Event) {
+		for _, ev := range evs {
+			ev.Type = termbox.EventKey
+			if err := handleTermboxEvent(ev, nil); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+	for _, ch := range "hello" {
+		keys(termbox.Event{Ch: ch})
+	}
+
+	// Copying with nothing selected leaves the clipboard alone.
+	keys(termbox.Event{Key: termbox.KeyCtrlX})
+	if clipboard != "" {
+		t.Fatalf("copied %q with nothing selected", clipboard)
+	}
+
+	keys(termbox.Event{Key: termbox.KeyCtrlSpace}, termbox.Event{Key: termbox.KeyArrowLeft}, termbox.Event{Key: termbox.KeyArrowLeft},
+		termbox.Event{Key: termbox.KeyArrowLeft}, termbox.Event{Key: termbox.KeyCtrlX})
+	if clipboard != "llo" {
+		t.Errorf("copied %q, expected %q", clipboard, "llo")
+	}
+	if e.IsSelecting() {
+		t.Errorf("expected copying to end the selection")
+	}
+
+	keys(termbox.Event{Key: termbox.KeyEnd}, termbox.Event{Key: termbox.KeyCtrlV})
+	if got := crdt.Content(doc); got != "hellollo" {
+		t.Errorf("got text %q after pasting, expected %q", got, "hellollo")
+	}
+	if e.Cursor != 8 {
+		t.Errorf("got cursor %d after pasting, expected 8", e.Cursor)
+	}
+}
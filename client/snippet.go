@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// snippetCursor marks where the cursor goes after a snippet is inserted; by default it ends up after the snippet.
+const snippetCursor = "$0"
+
+// Snippets maps snippet names to the text they insert.
+type Snippets map[string]string
+
+// snippets holds the snippets loaded with -snippets, if any.
+var snippets Snippets
+
+// LoadSnippets reads a JSON file mapping snippet names to their text.
+func LoadSnippets(path string) (Snippets, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := Snippets{}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid snippets file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Names returns the snippets' names, sorted.
+func (s Snippets) Names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// insertTimestamp inserts the current date and time, formatted with -time-format, at the cursor.
+func insertTimestamp(conn *websocket.Conn) {
+	insertSnippet(time.Now().Format(flags.TimeFormat), conn)
+}
+
+// promptSnippet asks for the name of a snippet to insert at the cursor.
+func promptSnippet() {
+	if len(snippets) == 0 {
+		e.StatusChan <- "No snippets: load some with -snippets"
+		return
+	}
+	openPrompt(fmt.Sprintf("Snippet (%s): ", strings.Join(snippets.Names(), ", ")), func(input string, conn *websocket.Conn) {
+		name := strings.TrimSpace(input)
+		text, ok := snippets[name]
+		if !ok {
+			e.StatusChan <- fmt.Sprintf("no snippet named %q", name)
+			return
+		}
+		insertSnippet(text, conn)
+	})
+}
+
+// insertSnippet inserts text at the cursor, one character at a time like typing it, so peers receive ordinary inserts.
+// The cursor is left at the snippet's first $0, which isn't inserted, or after the snippet without one.
+func insertSnippet(text string, conn *websocket.Conn) {
+	if reason := editBlocked(); reason != "" {
+		e.StatusChan <- reason
+		return
+	}
+	if doc.SiteID == 0 {
+		e.StatusChan <- "connecting..."
+		return
+	}
+
+	before, after, marked := strings.Cut(text, snippetCursor)
+	if !marked {
+		before, after = text, ""
+	}
+
+	// Snippets come from a JSON file or the clock, so they're valid UTF-8.
+	_, _ = insertFrom(strings.NewReader(before+after), conn)
+	if marked {
+		e.SetX(e.Cursor - len([]rune(after)))
+	}
+}
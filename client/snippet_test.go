@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"text-editor/crdt"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestInsertSnippet(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+	e.IsConnected = true
+	doc.SiteID = 1
+
+	for _, ch := range "ab" {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyArrowLeft}, conn)
+	<-received
+	<-received
+
+	// The snippet spans lines, and puts the cursor at its $0.
+	insertSnippet("if x {\n\t$0\n}", conn)
+
+	want := "aif x {\n\t\n}b"
+	if got := crdt.Content(doc); got != want || string(e.Text) != want {
+		t.Errorf("got text %q and editor text %q, expected %q", got, string(e.Text), want)
+	}
+	if e.Cursor != len("aif x {\n\t") {
+		t.Errorf("got cursor %d, expected %d", e.Cursor, len("aif x {\n\t"))
+	}
+
+	// Each character is sent as an ordinary insert, in order.
+	for i, ch := range "if x {\n\t\n}" {
+		msg := <-received
+		op := msg.Operation
+		if op.Type != "insert" || op.Value != string(ch) || op.Position != i+2 || op.Repeat != 0 {
+			t.Errorf("message %d: got %+v, expected an insert of %q at %d", i, op, ch, i+2)
+		}
+	}
+}
+
+func TestInsertTimestamp(t *testing.T) {
+	resetState(t)
+	oldFormat := flags.TimeFormat
+	flags.TimeFormat = "2006"
+	defer func() { flags.TimeFormat = oldFormat }()
+	doc.SiteID = 1
+
+	before := time.Now().Year()
+	insertTimestamp(nil)
+	if got := string(e.Text); got != time.Now().Format("2006") && got != time.Date(before, 1, 1, 0, 0, 0, 0, time.Local).Format("2006") {
+		t.Errorf("got %q, expected the current year", got)
+	}
+	if e.Cursor != 4 {
+		t.Errorf("got cursor %d, expected it after the timestamp", e.Cursor)
+	}
+}
+
+func TestLoadSnippets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snippets.json")
+	if err := os.WriteFile(path, []byte(`{"sig": "Regards", "todo": "TODO($0): "}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSnippets(path)
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if s["sig"] != "Regards" || len(s.Names()) != 2 || s.Names()[0] != "sig" {
+		t.Errorf("got snippets %v, expected sig and todo", s)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"sig": 3}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnippets(path); err == nil {
+		t.Errorf("loaded a snippet that isn't text")
+	}
+}
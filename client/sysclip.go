@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// clipboardTimeout is how long a clipboard tool may take before it's given up on, as the editor waits for it.
+const clipboardTimeout = 2 * time.Second
+
+// clipboardTool is an external command for the system clipboard, and when it can be used.
+type clipboardTool struct {
+	// args is the command and its arguments.
+	args []string
+
+	// env, if set, is an environment variable that must be set for the tool to work, e.g. DISPLAY for X11 tools.
+	env string
+}
+
+// pasteTools and copyTools are the tools tried for reading and writing the system clipboard, in order of preference.
+var (
+	pasteTools = []clipboardTool{
+		{args: []string{"pbpaste"}},
+		{args: []string{"wl-paste", "--no-newline"}, env: "WAYLAND_DISPLAY"},
+		{args: []string{"xclip", "-selection", "clipboard", "-out"}, env: "DISPLAY"},
+		{args: []string{"xsel", "--clipboard", "--output"}, env: "DISPLAY"},
+	}
+	copyTools = []clipboardTool{
+		{args: []string{"pbcopy"}},
+		{args: []string{"wl-copy"}, env: "WAYLAND_DISPLAY"},
+		{args: []string{"xclip", "-selection", "clipboard", "-in"}, env: "DISPLAY"},
+		{args: []string{"xsel", "--clipboard", "--input"}, env: "DISPLAY"},
+	}
+)
+
+// systemClipboard holds the commands reading and writing the system clipboard; either is nil if no tool was found.
+type systemClipboard struct {
+	paste, copy []string
+}
+
+// sysClip is the system clipboard Ctrl+V and Ctrl+X use with -system-clipboard; runEditor detects its tools.
+// Without them, the editor's own clipboard is used.
+var sysClip systemClipboard
+
+// detectClipboard finds the first usable tool for pasting and for copying, with lookPath finding commands,
+// like exec.LookPath, and getenv reading the environment, like os.Getenv.
+func detectClipboard(lookPath func(string) (string, error), getenv func(string) string) systemClipboard {
+	find := func(tools []clipboardTool) []string {
+		for _, tool := range tools {
+			if tool.env != "" && getenv(tool.env) == "" {
+				continue
+			}
+			if _, err := lookPath(tool.args[0]); err == nil {
+				return tool.args
+			}
+		}
+		return nil
+	}
+	return systemClipboard{paste: find(pasteTools), copy: find(copyTools)}
+}
+
+// clipboardCommand returns the command running args, given up on after clipboardTimeout, and the function releasing it.
+func clipboardCommand(args []string) (*exec.Cmd, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), clipboardTimeout)
+	return exec.CommandContext(ctx, args[0], args[1:]...), cancel
+}
+
+// read returns the system clipboard's text.
+func (c systemClipboard) read() (string, error) {
+	cmd, cancel := clipboardCommand(c.paste)
+	defer cancel()
+
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// write replaces the system clipboard's text with text.
+func (c systemClipboard) write(text string) error {
+	cmd, cancel := clipboardCommand(c.copy)
+	defer cancel()
+
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/nsf/termbox-go"
+)
+
+func TestDetectClipboard(t *testing.T) {
+	tests := []struct {
+		description string
+		installed   []string
+		env         map[string]string
+		wantPaste   []string
+		wantCopy    []string
+	}{
+		{"nothing installed", nil, map[string]string{"DISPLAY": ":0"}, nil, nil},
+		{"macOS", []string{"pbcopy", "pbpaste"}, nil, []string{"pbpaste"}, []string{"pbcopy"}},
+		{
+			"Wayland", []string{"wl-copy", "wl-paste", "xclip"}, map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			[]string{"wl-paste", "--no-newline"}, []string{"wl-copy"},
+		},
+		{
+			"Wayland tools without a Wayland session", []string{"wl-copy", "wl-paste", "xclip"}, map[string]string{"DISPLAY": ":0"},
+			[]string{"xclip", "-selection", "clipboard", "-out"}, []string{"xclip", "-selection", "clipboard", "-in"},
+		},
+		{
+			"xsel only", []string{"xsel"}, map[string]string{"DISPLAY": ":0"},
+			[]string{"xsel", "--clipboard", "--output"}, []string{"xsel", "--clipboard", "--input"},
+		},
+		{"X11 tools without a display", []string{"xclip", "xsel"}, nil, nil, nil},
+		{"paste tool only", []string{"wl-paste"}, map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, []string{"wl-paste", "--no-newline"}, nil},
+	}
+
+	for _, tc := range tests {
+		lookPath := func(name string) (string, error) {
+			for _, installed := range tc.installed {
+				if name == installed {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", exec.ErrNotFound
+		}
+		getenv := func(key string) string { return tc.env[key] }
+
+		got := detectClipboard(lookPath, getenv)
+		if !cmp.Equal(got.paste, tc.wantPaste) || !cmp.Equal(got.copy, tc.wantCopy) {
+			t.Errorf("(%s) got paste %q and copy %q, expected %q and %q", tc.description, got.paste, got.copy, tc.wantPaste, tc.wantCopy)
+		}
+	}
+}
+
+func TestClipboardCommand(t *testing.T) {
+	cmd, cancel := clipboardCommand([]string{"xclip", "-selection", "clipboard", "-in"})
+	defer cancel()
+
+	if got, want := cmd.Args, []string{"xclip", "-selection", "clipboard", "-in"}; !cmp.Equal(got, want) {
+		t.Errorf("got args %q, expected %q", got, want)
+	}
+	if !strings.HasSuffix(cmd.Path, "xclip") {
+		t.Errorf("got path %q, expected it to run xclip", cmd.Path)
+	}
+}
+
+func TestPasteClipboard_SystemClipboardFallback(t *testing.T) {
+	resetState(t)
+	doc.SiteID = 1
+
+	// Without tools, Ctrl+X and Ctrl+V use the editor's own clipboard, as before.
+	typeText(t, "ab")
+	e.Cursor = 0
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlSpace}, nil)
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyArrowRight}, nil)
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlX}, nil)
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlV}, nil)
+	if got := string(e.Text); got != "aab" {
+		t.Errorf("got %q, expected %q", got, "aab")
+	}
+
+	// A tool that fails falls back to it too.
+	sysClip = systemClipboard{paste: []string{"/nonexistent/clipboard-tool"}}
+	if got := clipboardText(); got != "a" {
+		t.Errorf("got %q after a failed paste tool, expected %q", got, "a")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"text-editor/client/editor"
+
+	"github.com/nsf/termbox-go"
+)
+
+// themeFile is a theme as written in a JSON file: colors like "bold red", on top of a built-in base theme.
+type themeFile struct {
+	// Base names the built-in theme the file changes; it defaults to "default".
+	Base string `json:"base"`
+
+	// Colors maps the names in themeColors to colors; colors not given keep the base's.
+	Colors map[string]string `json:"colors"`
+
+	// Users, if given, replaces the colors users are named in.
+	Users []string `json:"users"`
+}
+
+// themeColors maps the color names a theme file may set to the theme's attributes.
+func themeColors(t *editor.Theme) map[string]*termbox.Attribute {
+	return map[string]*termbox.Attribute{
+		"text":         &t.Text.Fg,
+		"background":   &t.Text.Bg,
+		"selection":    &t.Selection.Fg,
+		"selection-bg": &t.Selection.Bg,
+		"long-line":    &t.LongLine.Fg,
+		"long-line-bg": &t.LongLine.Bg,
+		"status":       &t.Status.Fg,
+		"status-bg":    &t.Status.Bg,
+		"mode":         &t.Mode,
+		"title":        &t.Title,
+		"lock":         &t.Lock,
+		"warning":      &t.Warning,
+		"connected":    &t.Connected,
+		"disconnected": &t.Disconnected,
+		"minimap":      &t.Minimap.Fg,
+		"minimap-bg":   &t.Minimap.Bg,
+		"minimap-view": &t.MinimapView,
+	}
+}
+
+// themeNames returns the built-in themes' names, sorted.
+func themeNames() []string {
+	names := make([]string, 0, len(editor.Themes))
+	for name := range editor.Themes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// LoadTheme returns the built-in theme called name, or else reads a theme from the JSON file at name.
+func LoadTheme(name string) (editor.Theme, error) {
+	if theme, ok := editor.Themes[name]; ok {
+		return theme, nil
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return editor.Theme{}, fmt.Errorf("%q is neither a built-in theme (%s) nor a readable file: %w", name, strings.Join(themeNames(), ", "), err)
+	}
+	theme, err := parseTheme(data)
+	if err != nil {
+		return editor.Theme{}, fmt.Errorf("invalid theme file %s: %w", name, err)
+	}
+	return theme, nil
+}
+
+// parseTheme reads a theme file's JSON.
+func parseTheme(data []byte) (editor.Theme, error) {
+	var f themeFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return editor.Theme{}, err
+	}
+
+	if f.Base == "" {
+		f.Base = "default"
+	}
+	theme, ok := editor.Themes[f.Base]
+	if !ok {
+		return editor.Theme{}, fmt.Errorf("unknown base theme %q", f.Base)
+	}
+	// Don't share the built-in theme's user colors.
+	theme.Users = slices.Clone(theme.Users)
+
+	fields := themeColors(&theme)
+	for name, value := range f.Colors {
+		field, ok := fields[name]
+		if !ok {
+			return editor.Theme{}, fmt.Errorf("unknown color %q", name)
+		}
+		attr, err := editor.ParseAttribute(value)
+		if err != nil {
+			return editor.Theme{}, fmt.Errorf("color %q: %w", name, err)
+		}
+		*field = attr
+	}
+
+	if f.Users != nil {
+		theme.Users = nil
+		for _, value := range f.Users {
+			attr, err := editor.ParseAttribute(value)
+			if err != nil {
+				return editor.Theme{}, fmt.Errorf("user color: %w", err)
+			}
+			theme.Users = append(theme.Users, attr)
+		}
+	}
+	return theme, nil
+}
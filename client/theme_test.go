@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"text-editor/client/editor"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestLoadTheme_BuiltIn(t *testing.T) {
+	theme, err := LoadTheme("light")
+	if err != nil {
+		t.Fatalf("failed to load the light theme: %v", err)
+	}
+	if theme.Text != editor.Themes["light"].Text {
+		t.Errorf("got text colors %+v, want the light theme's", theme.Text)
+	}
+}
+
+func TestLoadTheme_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.json")
+	data := `{"base": "dark", "colors": {"selection-bg": "magenta", "title": "bold yellow"}, "users": ["green", "cyan"]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := LoadTheme(path)
+	if err != nil {
+		t.Fatalf("failed to load the theme: %v", err)
+	}
+
+	dark := editor.Themes["dark"]
+	if theme.Selection.Bg != termbox.ColorMagenta || theme.Selection.Fg != dark.Selection.Fg {
+		t.Errorf("got selection colors %+v, want the dark theme's on magenta", theme.Selection)
+	}
+	if theme.Title != termbox.ColorYellow|termbox.AttrBold {
+		t.Errorf("got title color %v, want bold yellow", theme.Title)
+	}
+	if theme.Text != dark.Text {
+		t.Errorf("got text colors %+v, want the dark theme's %+v", theme.Text, dark.Text)
+	}
+	if len(theme.Users) != 2 || theme.Users[1] != termbox.ColorCyan {
+		t.Errorf("got user colors %v, want green and cyan", theme.Users)
+	}
+}
+
+func TestParseTheme_Errors(t *testing.T) {
+	tests := []struct {
+		description string
+		data        string
+		want        string
+	}{
+		{"unknown base", `{"base": "solarized"}`, `unknown base theme "solarized"`},
+		{"unknown color name", `{"colors": {"cursor": "red"}}`, `unknown color "cursor"`},
+		{"bad color", `{"colors": {"text": "purple"}}`, `color "text"`},
+		{"bad user color", `{"users": ["red", "plaid"]}`, "user color"},
+		{"unknown field", `{"colours": {}}`, "unknown field"},
+	}
+	for _, tc := range tests {
+		_, err := parseTheme([]byte(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("(%s) got err %v, want one mentioning %q", tc.description, err, tc.want)
+		}
+	}
+}
+
+func TestLoadTheme_Missing(t *testing.T) {
+	_, err := LoadTheme(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil || !strings.Contains(err.Error(), "dark, default, light") {
+		t.Errorf("got err %v, want one listing the built-in themes", err)
+	}
+}
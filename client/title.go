@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+// promptTitle asks the room's owner for a new title for the room, shown to everyone in it.
+func promptTitle(conn *websocket.Conn) {
+	if conn == nil {
+		e.StatusChan <- "Not in a room: connect to a server to set a title"
+		return
+	}
+	if !isOwner {
+		e.StatusChan <- "only the room owner can set its title"
+		return
+	}
+	openPrompt("Room title (empty clears it): ", setTitle)
+}
+
+// setTitle asks the server to change the room's title; everyone's info bar shows it once the server announces it.
+func setTitle(input string, conn *websocket.Conn) {
+	msg := commons.Message{Type: commons.TitleMessage, Text: strings.TrimSpace(input)}
+	if err := conn.WriteJSON(&msg); err != nil {
+		logger.Errorf("failed to set the room title, err: %v", err)
+		e.StatusChan <- "failed to set the room title!"
+	}
+}
+
+// handleTitleMsg shows the room's title, as set by its owner, in the info bar.
+func handleTitleMsg(msg commons.Message) {
+	e.SetTitle(msg.Text)
+
+	switch {
+	case msg.Text == "":
+		e.StatusChan <- "The room's title was cleared"
+	case msg.Username != "":
+		e.StatusChan <- fmt.Sprintf("Room title: %s (set by %s)", msg.Text, msg.Username)
+	default:
+		e.StatusChan <- fmt.Sprintf("Room title: %s", msg.Text)
+	}
+}
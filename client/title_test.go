@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"text-editor/commons"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestHandleMsg_Title(t *testing.T) {
+	resetState(t)
+
+	handleMsg(commons.Message{Type: commons.TitleMessage, Text: "Release notes", Username: "alice"}, nil)
+	if e.Title != "Release notes" {
+		t.Errorf("got title %q, expected %q", e.Title, "Release notes")
+	}
+	if got, want := <-e.StatusChan, "Room title: Release notes (set by alice)"; got != want {
+		t.Errorf("got status %q, expected %q", got, want)
+	}
+
+	handleMsg(commons.Message{Type: commons.TitleMessage}, nil)
+	if e.Title != "" {
+		t.Errorf("got title %q after it was cleared, expected none", e.Title)
+	}
+}
+
+func TestSetTitle(t *testing.T) {
+	resetState(t)
+	conn, received := dialRecorder(t)
+	oldOwner := isOwner
+	defer func() { isOwner = oldOwner }()
+
+	// Guests can't set the title.
+	isOwner = false
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlJ}, conn)
+	if activePrompt != nil {
+		t.Fatalf("expected no prompt for a guest")
+	}
+
+	isOwner = true
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyCtrlJ}, conn)
+	for _, ch := range " Notes " {
+		_ = handleTermboxEvent(termbox.Event{Ch: ch}, conn)
+	}
+	_ = handleTermboxEvent(termbox.Event{Key: termbox.KeyEnter}, conn)
+
+	msg := <-received
+	if msg.Type != commons.TitleMessage || msg.Text != "Notes" {
+		t.Errorf("sent %+v, expected a title message setting %q", msg, "Notes")
+	}
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"time"
+
 	"text-editor/client/editor"
+	"text-editor/commons"
 	"text-editor/crdt"
 
 	"github.com/gorilla/websocket"
@@ -16,24 +21,31 @@ type UIConfig struct {
 // termbox enables us to assign content to individual cells, making the cell the fundamental unit of the editor.
 
 // initUI establishes a new editor view and initiates the primary loop.
+// Terminals termbox can't use get the line mode instead.
 func initUI(conn *websocket.Conn, conf UIConfig) error {
-	err := termbox.Init()
-	if err != nil {
-		return err
+	full, reason := chooseUI(flags.Dumb, os.Getenv("TERM"), termbox.Init)
+	if !full {
+		logger.Warnf("using line mode: %s", reason)
+		return dumbUI(conn, conf, reason, os.Stdin, os.Stdout)
 	}
 	defer termbox.Close()
 
 	e = editor.NewEditor(conf.EditorConfig)
 	e.SetSize(termbox.Size())
 	e.SetText(crdt.Content(doc))
+	positionAtStart()
+	e.SetFileName(fileName)
+	setMode(mode)
 	e.SendDraw()
-	e.IsConnected = true
+	e.IsConnected = conn != nil
 
 	go handleStatusMsg()
 
+	go typingLoop()
+
 	go drawLoop()
 
-	err = mainLoop(conn)
+	err := mainLoop(conn)
 	if err != nil {
 		return err
 	}
@@ -46,18 +58,70 @@ func mainLoop(conn *websocket.Conn) error {
 	// termboxChan facilitates the transmission and reception of termbox events.
 	termboxChan := getTermboxChan()
 
-	// msgChan enables the sending and receiving of messages.
-	msgChan := getMsgChan(conn)
+	// msgChan enables the sending and receiving of messages. It stays nil when editing offline.
+	var msgChan chan commons.Message
+	if conn != nil {
+		startHeartbeat(conn)
+		msgChan = getMsgChan(conn)
+	}
+
+	// reconnectChan delivers a new connection after the current one drops.
+	reconnectChan := make(chan *websocket.Conn)
+
+	// checksumTicker paces sending the document's checksum, so the server can spot a desync.
+	checksumTicker := time.NewTicker(checksumInterval)
+	defer checksumTicker.Stop()
 
 	for {
 		select {
 		case termboxEvent := <-termboxChan:
 			err := handleTermboxEvent(termboxEvent, conn)
 			if err != nil {
+				// Don't leave the last edits unsent.
+				flushOnExit(conn)
 				return err
 			}
-		case msg := <-msgChan:
+			if flags.Debug {
+				checkDrift("a key event")
+			}
+			broadcastCursor(conn)
+		case <-runFlush:
+			if err := flushRun(conn); err != nil {
+				e.IsConnected = false
+				unsent = true
+				e.StatusChan <- "lost connection!"
+				continue
+			}
+			broadcastCursor(conn)
+		case <-checksumTicker.C:
+			sendChecksum(conn)
+		case msg, ok := <-msgChan:
+			if !ok {
+				// Keep editing offline while reconnecting in the background.
+				msgChan = nil
+				go reconnect(reconnectChan)
+				continue
+			}
 			handleMsg(msg, conn)
+			if flags.Debug {
+				checkDrift(fmt.Sprintf("a %q message", msg.Type))
+			}
+		case newConn := <-reconnectChan:
+			conn.Close()
+			conn = newConn
+			startHeartbeat(conn)
+			msgChan = getMsgChan(conn)
+
+			// Peers may have forgotten this client's cursor while it was gone.
+			sentCursor = ""
+			if err := rejoin(conn); err != nil {
+				logger.Errorf("failed to rejoin, err: %v\n", err)
+				continue
+			}
+			e.IsConnected = true
+			unsent = false
+			e.StatusChan <- "reconnected!"
+			e.SendDraw()
 		}
 	}
 }
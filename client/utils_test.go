@@ -0,0 +1,338 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSetupLogger_LevelFilters(t *testing.T) {
+	tests := []struct {
+		description string
+		level       string
+		logged      []string
+		filtered    []string
+	}{
+		{"info", "info", []string{"info-msg", "warn-msg"}, []string{"debug-msg"}},
+		{"warn", "warn", []string{"warn-msg"}, []string{"debug-msg", "info-msg"}},
+		{"debug", "debug", []string{"debug-msg", "info-msg", "warn-msg"}, []string{}},
+	}
+
+	for _, tc := range tests {
+		path := filepath.Join(t.TempDir(), "editor.log")
+		logger := logrus.New()
+
+		logFile, debugLogFile, err := setupLogger(logger, Flags{LogFile: path, LogLevel: tc.level})
+		if err != nil {
+			t.Fatalf("(%s) error: %v\n", tc.description, err)
+		}
+
+		logger.Debug("debug-msg")
+		logger.Info("info-msg")
+		logger.Warn("warn-msg")
+		closeLogFiles(logFile, debugLogFile)
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("(%s) error: %v\n", tc.description, err)
+		}
+
+		for _, msg := range tc.logged {
+			if !strings.Contains(string(content), msg) {
+				t.Errorf("(%s) expected %q to be logged\n", tc.description, msg)
+			}
+		}
+		for _, msg := range tc.filtered {
+			if strings.Contains(string(content), msg) {
+				t.Errorf("(%s) expected %q to be filtered\n", tc.description, msg)
+			}
+		}
+	}
+}
+
+func TestSetupLogger_InvalidLevel(t *testing.T) {
+	_, _, err := setupLogger(logrus.New(), Flags{LogStderr: true, LogLevel: "loud"})
+	if err == nil {
+		t.Errorf("expected an error for an invalid level\n")
+	}
+}
+
+// blockedDir returns a path that can't be created as a directory, even by root,
+// because its parent is a regular file.
+func blockedDir(t *testing.T) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	return filepath.Join(file, ".edito")
+}
+
+func TestOpenLogFiles_FallsBack(t *testing.T) {
+	fallback := t.TempDir()
+
+	logFile, debugLogFile, err := openLogFiles([]string{blockedDir(t), fallback})
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	defer closeLogFiles(logFile, debugLogFile)
+
+	got := filepath.Dir(logFile.Name())
+	if got != fallback {
+		t.Errorf("got != want; got = %v, expected = %v\n", got, fallback)
+	}
+}
+
+func TestSetupLogger_UnwritableDirectory(t *testing.T) {
+	blocked := blockedDir(t)
+	t.Setenv("HOME", blocked)
+	t.Setenv("TMPDIR", blocked)
+
+	logger := logrus.New()
+	logFile, debugLogFile, err := setupLogger(logger, Flags{LogLevel: "info"})
+	if err != nil {
+		t.Fatalf("expected logging failure not to be fatal, got: %v\n", err)
+	}
+	defer closeLogFiles(logFile, debugLogFile)
+
+	// The logger must still be usable.
+	logger.Info("still running")
+}
+
+// newTestWSServer starts a server that accepts WebSocket connections and discards them.
+func newTestWSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestCreateConn_Proxy(t *testing.T) {
+	target := newTestWSServer(t)
+
+	// The stub proxy records CONNECT requests and tunnels them to their destination.
+	var gotHost, gotAuth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "expected CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		gotHost = r.Host
+		gotAuth = r.Header.Get("Proxy-Authorization")
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go func() { _, _ = io.Copy(upstream, buf) }()
+		_, _ = io.Copy(conn, upstream)
+	}))
+	defer proxy.Close()
+
+	targetHost := strings.TrimPrefix(target.URL, "http://")
+	proxyURL := "http://user:pass@" + strings.TrimPrefix(proxy.URL, "http://")
+
+	conn, _, err := createConn(Flags{Server: targetHost, Proxy: proxyURL})
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	conn.Close()
+
+	if gotHost != targetHost {
+		t.Errorf("got != want; got = %v, expected = %v\n", gotHost, targetHost)
+	}
+
+	wantAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	if gotAuth != wantAuth {
+		t.Errorf("got != want; got = %v, expected = %v\n", gotAuth, wantAuth)
+	}
+}
+
+func TestCreateConn_InvalidProxy(t *testing.T) {
+	_, _, err := createConn(Flags{Server: "localhost:1", Proxy: "://bad"})
+	if err == nil {
+		t.Errorf("expected an error for an invalid proxy URL\n")
+	}
+}
+
+func TestCreateConn_RetriesUntilServerUp(t *testing.T) {
+	oldBackoff := connectBackoff
+	connectBackoff = 50 * time.Millisecond
+	defer func() { connectBackoff = oldBackoff }()
+
+	// Reserve a free port, then release it so the first attempt is refused.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	// Bring the server up after the first attempt has failed.
+	srv := newTestWSServer(t)
+	srv.Close()
+	go func() {
+		time.Sleep(75 * time.Millisecond)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { listener.Close() })
+		_ = http.Serve(listener, srv.Config.Handler)
+	}()
+
+	conn, _, err := createConn(Flags{Server: addr, ConnectTimeout: time.Second, ConnectRetries: 5})
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	conn.Close()
+}
+
+func TestCreateConn_GivesUp(t *testing.T) {
+	oldBackoff := connectBackoff
+	connectBackoff = time.Millisecond
+	defer func() { connectBackoff = oldBackoff }()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	_, _, err = createConn(Flags{Server: addr, ConnectTimeout: time.Second, ConnectRetries: 2})
+	if err == nil {
+		t.Errorf("expected an error when the server never comes up\n")
+	}
+}
+
+// timeoutError is a network error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyConnError(t *testing.T) {
+	tests := []struct {
+		description string
+		err         error
+		status      int
+		want        string
+	}{
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, 0, "Connection refused"},
+		{"timeout", &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}, 0, "Connection timed out"},
+		{"dns", &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "nowhere"}}, 0, "Couldn't resolve"},
+		{"tls record", tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, 0, "TLS handshake failed"},
+		{"tls certificate", &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}, 0, "TLS handshake failed"},
+		{"unauthorized", websocket.ErrBadHandshake, http.StatusUnauthorized, "Check your access token"},
+		{"forbidden", websocket.ErrBadHandshake, http.StatusForbidden, "Check your access token"},
+		{"not found", websocket.ErrBadHandshake, http.StatusNotFound, "Check the -server address"},
+		{"bad request", websocket.ErrBadHandshake, http.StatusBadRequest, "Check the -room name"},
+		{"server error", websocket.ErrBadHandshake, http.StatusInternalServerError, "Try again later"},
+		{"unknown", errors.New("boom"), 0, "Connection error: boom"},
+	}
+
+	for _, tc := range tests {
+		var resp *http.Response
+		if tc.status != 0 {
+			resp = &http.Response{StatusCode: tc.status, Status: http.StatusText(tc.status)}
+		}
+
+		got := classifyConnError(tc.err, resp)
+		if !strings.Contains(got, tc.want) {
+			t.Errorf("(%s) got = %q, expected it to contain %q\n", tc.description, got, tc.want)
+		}
+	}
+}
+
+// Verify that a real dial against a closed port is reported as refused.
+func TestClassifyConnError_Dial(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error: %v\n", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	_, resp, err := createConn(Flags{Server: addr, ConnectTimeout: time.Second})
+	if err == nil {
+		t.Fatalf("expected an error dialing a closed port\n")
+	}
+
+	if got := classifyConnError(err, resp); !strings.HasPrefix(got, "Connection refused") {
+		t.Errorf("got = %q, expected a refused connection\n", got)
+	}
+}
+
+func TestCreateConn_Token(t *testing.T) {
+	var gotAuth string
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		if gotAuth != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	conn, resp, err := createConn(Flags{Server: addr, Token: "secret", ConnectTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("dial error: %v\n", err)
+	}
+	conn.Close()
+
+	// A mismatch is rejected without retrying and explained to the user.
+	_, resp, err = createConn(Flags{Server: addr, Token: "wrong", ConnectTimeout: time.Second, ConnectRetries: 3})
+	if err == nil {
+		t.Fatalf("expected an error with the wrong token\n")
+	}
+	if got := classifyConnError(err, resp); !strings.Contains(got, "-token") {
+		t.Errorf("got = %q, expected it to mention -token\n", got)
+	}
+	if gotAuth != "Bearer wrong" {
+		t.Errorf("got != want; got = %q, expected = %q\n", gotAuth, "Bearer wrong")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"text-editor/client/editor"
+
+	"github.com/nsf/termbox-go"
+)
+
+// viewMargin is how many lines beyond the view are loaded on either side, so scrolling a little doesn't read the file.
+const viewMargin = 200
+
+// fileWindow is the part of a file that's loaded for viewing, with an index of where the file's lines start,
+// so any part of it can be read without reading what comes before.
+type fileWindow struct {
+	r io.ReaderAt
+
+	// offsets holds the byte offset each line starts at, followed by the file's size.
+	offsets []int64
+
+	// start and end are the range [start, end) of the lines loaded, and text is their content,
+	// without the newline ending the last of them.
+	start, end int
+	text       string
+}
+
+// indexLines reads r to the end, returning the byte offset each line starts at, followed by its size.
+// Only the offsets are kept, not the text. Like crdt.Load, a trailing newline starts an empty last line.
+func indexLines(r io.Reader) ([]int64, error) {
+	offsets := []int64{0}
+	br := bufio.NewReaderSize(r, 64*1024)
+	var pos int64
+	for {
+		chunk, err := br.ReadSlice('\n')
+		pos += int64(len(chunk))
+		switch {
+		case err == nil:
+			offsets = append(offsets, pos)
+		case errors.Is(err, bufio.ErrBufferFull):
+			// A long line; keep reading it.
+		case errors.Is(err, io.EOF):
+			return append(offsets, pos), nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// newFileWindow indexes the lines of r, which is size bytes long, loading none of them yet.
+func newFileWindow(r io.ReaderAt, size int64) (*fileWindow, error) {
+	offsets, err := indexLines(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	return &fileWindow{r: r, offsets: offsets}, nil
+}
+
+// lines returns how many lines the file has.
+func (w *fileWindow) lines() int {
+	return len(w.offsets) - 1
+}
+
+// windowRange returns the range [start, end) of lines to load for a view of rows lines starting at line top,
+// with margin lines more on either side, in a file of the given number of lines.
+func windowRange(top, rows, margin, lines int) (int, int) {
+	start := max(0, top-margin)
+	end := min(lines, top+rows+margin)
+	return start, max(start, end)
+}
+
+// covers reports whether the lines loaded include all of a view of rows lines starting at line top.
+func (w *fileWindow) covers(top, rows int) bool {
+	return top >= w.start && min(top+rows, w.lines()) <= w.end
+}
+
+// fetch loads the lines around a view of rows lines starting at line top, unless they're loaded already,
+// and reports whether it did.
+func (w *fileWindow) fetch(top, rows int) (bool, error) {
+	if w.end > w.start && w.covers(top, rows) {
+		return false, nil
+	}
+	return true, w.load(windowRange(top, rows, viewMargin, w.lines()))
+}
+
+// load reads the range [start, end) of lines from the file.
+func (w *fileWindow) load(start, end int) error {
+	buf := make([]byte, w.offsets[end]-w.offsets[start])
+	if _, err := w.r.ReadAt(buf, w.offsets[start]); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	w.start, w.end = start, end
+	w.text = string(buf)
+	if end < w.lines() {
+		// The newline belongs between this line and the next, which isn't loaded.
+		w.text = strings.TrimSuffix(w.text, "\n")
+	}
+	return nil
+}
+
+// lineOffset returns where line, which must be loaded, starts in the window's text, in runes.
+func (w *fileWindow) lineOffset(line int) int {
+	return utf8.RuneCountInString(w.text[:w.offsets[line]-w.offsets[w.start]])
+}
+
+// clampTop returns the first line of a view of rows lines scrolled to top, kept within a file of the given number of lines.
+func clampTop(top, rows, lines int) int {
+	return max(0, min(top, lines-rows))
+}
+
+// runView shows a file read-only, loading only the lines in view, so files too big to edit can still be read.
+func runView(args []string) error {
+	fs := flag.NewFlagSet("view", flag.ContinueOnError)
+	fs.StringVar(&flags.File, "file", "", "The file to view")
+	if err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if flags.File == "" {
+		return errors.New("view: -file is required")
+	}
+
+	f, err := os.Open(flags.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	w, err := newFileWindow(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to index %s: %w", flags.File, err)
+	}
+
+	if err := termbox.Init(); err != nil {
+		return err
+	}
+	defer termbox.Close()
+	return viewLoop(w, flags.File)
+}
+
+// viewLoop shows w until the user quits, scrolling with the arrow, page and Home/End keys.
+func viewLoop(w *fileWindow, name string) error {
+	e = editor.NewEditor(editor.EditorConfig{ScrollEnabled: true})
+	e.SetFileName(name)
+	e.SetSize(termbox.Size())
+
+	top := 0
+	for {
+		rows := max(1, e.GetHeight()-1)
+		top = clampTop(top, rows, w.lines())
+		if err := showWindow(w, top, rows); err != nil {
+			return err
+		}
+		e.Draw()
+
+		ev := termbox.PollEvent()
+		switch ev.Type {
+		case termbox.EventResize:
+			e.SetSize(ev.Width, ev.Height)
+		case termbox.EventError:
+			return ev.Err
+		case termbox.EventKey:
+			switch {
+			case ev.Key == termbox.KeyEsc, ev.Key == termbox.KeyCtrlC, ev.Ch == 'q':
+				return nil
+			case ev.Key == termbox.KeyArrowUp:
+				top--
+			case ev.Key == termbox.KeyArrowDown:
+				top++
+			case ev.Key == termbox.KeyPgup:
+				top -= rows
+			case ev.Key == termbox.KeyPgdn, ev.Key == termbox.KeySpace:
+				top += rows
+			case ev.Key == termbox.KeyHome:
+				top = 0
+			case ev.Key == termbox.KeyEnd:
+				top = w.lines()
+			case ev.Key == termbox.KeyArrowLeft:
+				e.ColOff = max(0, e.ColOff-1)
+			case ev.Key == termbox.KeyArrowRight:
+				e.ColOff++
+			}
+		}
+	}
+}
+
+// showWindow puts the lines in view, starting at line top, in the editor, fetching them from the file if needed.
+func showWindow(w *fileWindow, top, rows int) error {
+	fetched, err := w.fetch(top, rows)
+	if err != nil {
+		return fmt.Errorf("failed to read lines %d-%d: %w", top+1, top+rows, err)
+	}
+	if fetched {
+		e.SetText(w.text)
+	}
+
+	// The cursor is kept on the first line in view, which the editor shows at the top.
+	e.SetX(w.lineOffset(top))
+	e.RowOff = top - w.start
+
+	e.StatusMu.Lock()
+	e.ShowMsg = true
+	e.StatusMsg = fmt.Sprintf("%s: lines %d-%d of %d, read-only. Arrows, PgUp/PgDn and Home/End scroll; q quits",
+		e.FileName, top+1, min(top+rows, w.lines()), w.lines())
+	e.StatusMu.Unlock()
+	return nil
+}
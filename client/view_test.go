@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// numberedLines returns n lines reading "line 0" to "line n-1", each ended by a newline.
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+// newTestWindow returns a window over text.
+func newTestWindow(t *testing.T, text string) *fileWindow {
+	t.Helper()
+	w, err := newFileWindow(strings.NewReader(text), int64(len(text)))
+	if err != nil {
+		t.Fatalf("failed to index the text: %v", err)
+	}
+	return w
+}
+
+func TestIndexLines(t *testing.T) {
+	tests := []struct {
+		description string
+		text        string
+		want        []int64
+	}{
+		{"empty", "", []int64{0, 0}},
+		{"no trailing newline", "ab\ncd", []int64{0, 3, 5}},
+		{"trailing newline", "ab\n", []int64{0, 3, 3}},
+		{"empty lines", "\n\n", []int64{0, 1, 2, 2}},
+		{"line longer than the buffer", strings.Repeat("x", 100000) + "\ny", []int64{0, 100001, 100002}},
+	}
+	for _, tc := range tests {
+		got, err := indexLines(strings.NewReader(tc.text))
+		if err != nil {
+			t.Errorf("(%s) unexpected error: %v", tc.description, err)
+			continue
+		}
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("(%s) got != want, diff: %v", tc.description, cmp.Diff(got, tc.want))
+		}
+	}
+}
+
+func TestWindowRange(t *testing.T) {
+	tests := []struct {
+		description          string
+		top, rows, margin, n int
+		wantStart, wantEnd   int
+	}{
+		{"start of the file", 0, 10, 5, 100, 0, 15},
+		{"middle", 50, 10, 5, 100, 45, 65},
+		{"end of the file", 95, 10, 5, 100, 90, 100},
+		{"file shorter than the view", 0, 10, 5, 3, 0, 3},
+		{"no margin", 20, 10, 0, 100, 20, 30},
+	}
+	for _, tc := range tests {
+		start, end := windowRange(tc.top, tc.rows, tc.margin, tc.n)
+		if start != tc.wantStart || end != tc.wantEnd {
+			t.Errorf("(%s) got [%d, %d), want [%d, %d)", tc.description, start, end, tc.wantStart, tc.wantEnd)
+		}
+	}
+}
+
+func TestClampTop(t *testing.T) {
+	tests := []struct {
+		top, rows, lines, want int
+	}{
+		{-5, 10, 100, 0},
+		{50, 10, 100, 50},
+		{95, 10, 100, 90},
+		{100, 10, 100, 90},
+		{3, 10, 5, 0},
+	}
+	for _, tc := range tests {
+		if got := clampTop(tc.top, tc.rows, tc.lines); got != tc.want {
+			t.Errorf("clampTop(%d, %d, %d) = %d, want %d", tc.top, tc.rows, tc.lines, got, tc.want)
+		}
+	}
+}
+
+func TestFileWindow_Fetch(t *testing.T) {
+	lines := 2000
+	w := newTestWindow(t, numberedLines(lines))
+	rows := 20
+
+	// The first fetch loads the view and the margin after it.
+	fetched, err := w.fetch(0, rows)
+	if err != nil || !fetched {
+		t.Fatalf("first fetch: got fetched=%t, err %v, want a fetch", fetched, err)
+	}
+	if w.start != 0 || w.end != rows+viewMargin {
+		t.Errorf("got window [%d, %d), want [0, %d)", w.start, w.end, rows+viewMargin)
+	}
+	if !strings.HasPrefix(w.text, "line 0\n") || !strings.HasSuffix(w.text, fmt.Sprintf("line %d", w.end-1)) {
+		t.Errorf("window text doesn't hold exactly its lines: starts %q, ends %q", w.text[:10], w.text[len(w.text)-10:])
+	}
+
+	// Views inside the window don't read the file, up to its last line.
+	for _, top := range []int{1, viewMargin} {
+		if fetched, _ := w.fetch(top, rows); fetched {
+			t.Errorf("view at %d in window [%d, %d): fetched again", top, w.start, w.end)
+		}
+	}
+
+	// A view one line past the window's end fetches, centering the window on it.
+	top := viewMargin + 1
+	if fetched, _ := w.fetch(top, rows); !fetched {
+		t.Fatalf("view at %d past window end %d: not fetched", top, w.end)
+	}
+	if w.start != top-viewMargin || w.end != top+rows+viewMargin {
+		t.Errorf("got window [%d, %d), want [%d, %d)", w.start, w.end, top-viewMargin, top+rows+viewMargin)
+	}
+	if got, want := string([]rune(w.text)[w.lineOffset(top):][:len("line 201")]), "line 201"; got != want {
+		t.Errorf("line at the top of the view: got %q, want %q", got, want)
+	}
+
+	// A view before the window's start fetches too.
+	if fetched, _ := w.fetch(w.start-1, rows); !fetched {
+		t.Errorf("view before window start %d: not fetched", w.start)
+	}
+
+	// The window ends at the last line, the empty one after the trailing newline.
+	if _, err := w.fetch(clampTop(lines, rows, w.lines()), rows); err != nil {
+		t.Fatal(err)
+	}
+	if w.end != w.lines() || !strings.HasSuffix(w.text, fmt.Sprintf("line %d\n", lines-1)) {
+		t.Errorf("got window [%d, %d) of %d lines ending %q, want it to end with the file", w.start, w.end, w.lines(), w.text[len(w.text)-10:])
+	}
+}
@@ -0,0 +1,37 @@
+package commons
+
+import "sync"
+
+// LamportClock is a logical clock that orders operations across clients.
+// It advances on every send and jumps past the time of every message received,
+// so an operation's clock is always greater than that of any operation its sender had seen.
+type LamportClock struct {
+	mu   sync.Mutex
+	time uint64
+}
+
+// Tick advances the clock for an outgoing operation and returns the operation's time.
+func (c *LamportClock) Tick() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.time++
+	return c.time
+}
+
+// Observe advances the clock past the time of a received operation and returns the new time.
+func (c *LamportClock) Observe(t uint64) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.time = max(c.time, t) + 1
+	return c.time
+}
+
+// Now returns the clock's current time without advancing it.
+func (c *LamportClock) Now() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.time
+}
@@ -0,0 +1,43 @@
+package commons
+
+import "testing"
+
+func TestLamportClock_Monotonic(t *testing.T) {
+	var alice, bob LamportClock
+
+	// Each step returns the clock's new time, which must always increase.
+	steps := []struct {
+		description string
+		step        func() uint64
+	}{
+		{"alice sends", alice.Tick},
+		{"alice sends again", alice.Tick},
+		{"bob receives alice's second op", func() uint64 { return bob.Observe(2) }},
+		{"bob sends", bob.Tick},
+		{"alice receives bob's op", func() uint64 { return alice.Observe(4) }},
+		{"alice receives an old op", func() uint64 { return alice.Observe(1) }},
+		{"alice sends after receiving", alice.Tick},
+	}
+
+	last := map[*LamportClock]uint64{}
+	clocks := []*LamportClock{&alice, &alice, &bob, &bob, &alice, &alice, &alice}
+	for i, s := range steps {
+		c := clocks[i]
+		got := s.step()
+		if got <= last[c] {
+			t.Errorf("(%s) clock went from %d to %d", s.description, last[c], got)
+		}
+		if now := c.Now(); now != got {
+			t.Errorf("(%s) Now() = %d, expected %d", s.description, now, got)
+		}
+		last[c] = got
+	}
+
+	// Causality: bob's op was sent after seeing alice's, so its time is greater.
+	if bob.Now() <= 2 {
+		t.Errorf("bob's clock %d should be past alice's op at 2", bob.Now())
+	}
+	if alice.Now() != 7 {
+		t.Errorf("got alice's clock %d, expected %d", alice.Now(), 7)
+	}
+}
@@ -1,15 +1,17 @@
 package commons
 
 import (
+	"encoding/json"
+
 	"text-editor/crdt"
 
 	"github.com/google/uuid"
 )
 
 type Message struct {
-	Username string `json:"username"`
+	Username string `json:"username,omitempty"`
 
-	Text string `json:"text"`
+	Text string `json:"text,omitempty"`
 
 	Type MessageType `json:"type"`
 
@@ -17,7 +19,35 @@ type Message struct {
 
 	Operation Operation `json:"operation"`
 
-	Document crdt.Document `json:"document"`
+	// Document is only set on DocSync messages.
+	Document *crdt.Document `json:"document,omitempty"`
+
+	// Site names a client by its site ID, e.g. the guest a room owner's PermissionMessage is for.
+	// Unlike usernames, site IDs are unique. A UsersMessage lists the users' site IDs, in the same order as Text.
+	Site string `json:"site,omitempty"`
+}
+
+// MarshalJSON leaves out the fields a message doesn't use, keeping frames small.
+// Decoding is unchanged, so frames that include every field are still accepted.
+func (m Message) MarshalJSON() ([]byte, error) {
+	// message has Message's fields without this method, avoiding infinite recursion.
+	type message Message
+
+	// The outer ID and Operation shadow the embedded ones, so unset values can be omitted.
+	wire := struct {
+		message
+		ID        *uuid.UUID `json:"ID,omitempty"`
+		Operation *Operation `json:"operation,omitempty"`
+	}{message: message(m)}
+
+	if m.ID != uuid.Nil {
+		wire.ID = &m.ID
+	}
+	if m.Operation != (Operation{}) {
+		wire.Operation = &m.Operation
+	}
+
+	return json.Marshal(wire)
 }
 
 type MessageType string
@@ -28,4 +58,31 @@ const (
 	SiteIDMessage  MessageType = "SiteID"
 	JoinMessage    MessageType = "join"
 	UsersMessage   MessageType = "users"
+
+	// PermissionMessage tells a client its permission ("owner", "write" or "read").
+	// Sent by a room owner, it sets the permission of the guest whose site ID is in Site, or all guests if empty.
+	PermissionMessage MessageType = "permission"
+
+	// LockMessage announces the write lock holder's name in Text (empty if free) and ID.
+	// Sent by a client, Text is "acquire" or "release".
+	LockMessage MessageType = "lock"
+
+	// CursorMessage announces where a client's cursor is: Text holds the ID of the character before it,
+	// or "start" at the start of the document. The server sets Username to the sender's name.
+	CursorMessage MessageType = "cursor"
+
+	// ChecksumMessage carries a document checksum in Text. Clients send theirs periodically;
+	// the server answers with the room document's when a client's has stayed different from it.
+	ChecksumMessage MessageType = "checksum"
+
+	// HelloMessage is the first message a client sends, with the newest protocol version it speaks in Text.
+	HelloMessage MessageType = "hello"
+
+	// WelcomeMessage answers a HelloMessage with the negotiated protocol version in Text.
+	// A server that can't speak the client's version closes the connection instead, giving the reason.
+	WelcomeMessage MessageType = "welcome"
+
+	// TitleMessage announces the room's title in Text, and who set it in Username; an empty Text means it has none.
+	// Sent by a room owner, it sets the title.
+	TitleMessage MessageType = "title"
 )
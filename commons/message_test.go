@@ -0,0 +1,76 @@
+package commons
+
+import (
+	"encoding/json"
+	"testing"
+
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+)
+
+func TestMessage_MarshalSize(t *testing.T) {
+	char := crdt.Character{ID: "12", Visible: true, Value: "a", IDPrevious: "start", IDNext: "end", OriginPrevious: "start", OriginNext: "end"}
+	doc := crdt.New()
+
+	tests := []struct {
+		description string
+		msg         Message
+		omitted     []string
+		maxSize     int
+	}{
+		{"insert", Message{Type: "operation", Operation: Operation{Type: "insert", Position: 1, Value: "a", Character: &char}}, []string{"document", "username", "ID"}, 250},
+		{"delete", Message{Type: "operation", Operation: Operation{Type: "delete", Position: 1, ID: "12"}}, []string{"document", "username", "ID"}, 100},
+		{"join", Message{Type: JoinMessage, Username: "alice", Text: "has joined the session."}, []string{"document", "operation", "ID"}, 100},
+		{"users", Message{Type: UsersMessage, Text: "alice,bob,"}, []string{"document", "operation", "ID", "username"}, 60},
+		{"site ID", Message{Type: SiteIDMessage, Text: "3", ID: uuid.New()}, []string{"document", "operation", "username"}, 100},
+		{"doc sync", Message{Type: DocSyncMessage, Document: &doc}, []string{"operation", "ID", "username", "text"}, 250},
+	}
+
+	for _, tc := range tests {
+		data, err := json.Marshal(tc.msg)
+		if err != nil {
+			t.Fatalf("(%s) marshal error: %v\n", tc.description, err)
+		}
+		t.Logf("%s: %d bytes", tc.description, len(data))
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatalf("(%s) unmarshal error: %v\n", tc.description, err)
+		}
+		for _, field := range tc.omitted {
+			if _, ok := fields[field]; ok {
+				t.Errorf("(%s) unused field %q was serialized: %s\n", tc.description, field, data)
+			}
+		}
+		if len(data) > tc.maxSize {
+			t.Errorf("(%s) got %d bytes, expected at most %d: %s\n", tc.description, len(data), tc.maxSize, data)
+		}
+
+		// Omitted fields decode to their zero values.
+		var got Message
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("(%s) unmarshal error: %v\n", tc.description, err)
+		}
+		if !cmp.Equal(got, tc.msg) {
+			t.Errorf("(%s) round trip changed the message; diff = %v\n", tc.description, cmp.Diff(got, tc.msg))
+		}
+	}
+}
+
+// Verify that frames with every field set, as older peers send them, still decode.
+func TestMessage_UnmarshalFullFrame(t *testing.T) {
+	data := `{"username":"","text":"","type":"operation","ID":"00000000-0000-0000-0000-000000000000",` +
+		`"operation":{"type":"insert","position":1,"value":"a"},"document":{"Characters":null}}`
+
+	var got Message
+	if err := json.Unmarshal([]byte(data), &got); err != nil {
+		t.Fatalf("unmarshal error: %v\n", err)
+	}
+
+	want := Operation{Type: "insert", Position: 1, Value: "a"}
+	if got.Type != "operation" || !cmp.Equal(got.Operation, want) {
+		t.Errorf("got != want; got = %+v, expected operation = %+v\n", got, want)
+	}
+}
@@ -1,9 +1,50 @@
 package commons
 
+import "text-editor/crdt"
+
 type Operation struct {
 	Type string `json:"type"`
 
 	Position int `json:"position"`
 
 	Value string `json:"value"`
+
+	// ID identifies the target character of a delete, so peers remove exactly that character.
+	ID string `json:"id,omitempty"`
+
+	// Character is the character generated by an insert, so peers integrate it between its exact neighbors.
+	Character *crdt.Character `json:"character,omitempty"`
+
+	// Clock is the originating client's Lamport time when it sent the operation.
+	Clock uint64 `json:"clock,omitempty"`
+
+	// Timestamp is when the operation was made, in Unix milliseconds on the originating client.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// Repeat, if above 1, makes an insert a run of Repeat copies of Value typed one after another, e.g. by holding a key.
+	// Character is the first of them, generated by Site at SiteClock; Expand recreates the rest.
+	Repeat    int `json:"repeat,omitempty"`
+	Site      int `json:"site,omitempty"`
+	SiteClock int `json:"siteClock,omitempty"`
+}
+
+// Expand returns the inserts a run stands for, each with its own character, or the operation itself if it isn't a run.
+func (op Operation) Expand() []Operation {
+	if op.Type != "insert" || op.Repeat <= 1 || op.Character == nil {
+		return []Operation{op}
+	}
+
+	chars := crdt.RunCharacters(*op.Character, op.Site, op.SiteClock, op.Repeat)
+	ops := make([]Operation, len(chars))
+	for i := range chars {
+		ops[i] = Operation{
+			Type:      "insert",
+			Position:  op.Position + i,
+			Value:     op.Value,
+			Character: &chars[i],
+			Clock:     op.Clock,
+			Timestamp: op.Timestamp,
+		}
+	}
+	return ops
 }
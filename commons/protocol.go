@@ -0,0 +1,30 @@
+package commons
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// ProtocolVersion is the newest version of the message protocol this build speaks.
+	// Raise it when a change to messages would confuse peers built before it.
+	ProtocolVersion = 1
+
+	// MinProtocolVersion is the oldest version of the message protocol this build still speaks.
+	MinProtocolVersion = 1
+)
+
+// NegotiateProtocol returns the protocol version to speak with a peer whose newest version is given in text,
+// as sent in a HelloMessage: the newest version both sides speak.
+func NegotiateProtocol(text string) (int, error) {
+	theirs, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid protocol version %q", text)
+	}
+
+	version := min(theirs, ProtocolVersion)
+	if version < MinProtocolVersion {
+		return 0, fmt.Errorf("protocol version %d is no longer supported, the oldest supported is %d: upgrade the client", theirs, MinProtocolVersion)
+	}
+	return version, nil
+}
@@ -0,0 +1,25 @@
+package commons
+
+import "testing"
+
+func TestNegotiateProtocol(t *testing.T) {
+	tests := []struct {
+		description string
+		text        string
+		want        int
+		wantErr     bool
+	}{
+		{"same version", "1", ProtocolVersion, false},
+		{"newer peer", "99", ProtocolVersion, false},
+		{"older peer", "0", 0, true},
+		{"not a number", "v1", 0, true},
+		{"missing", "", 0, true},
+	}
+
+	for _, tc := range tests {
+		got, err := NegotiateProtocol(tc.text)
+		if got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("(%s) got = %v, %v; expected = %v, error %v\n", tc.description, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package commons
+
+import "fmt"
+
+// Build information, injected at build time via -ldflags, e.g.:
+//
+//	go build -ldflags "-X text-editor/commons.Version=v1.0.0 -X text-editor/commons.Commit=$(git rev-parse --short HEAD) -X text-editor/commons.Date=$(date -u +%Y-%m-%d)" ./client
+var (
+	// Version is the release version of the binary.
+	Version = "dev"
+
+	// Commit is the git commit the binary was built from.
+	Commit = "unknown"
+
+	// Date is the date the binary was built.
+	Date = "unknown"
+)
+
+// VersionString returns a human-readable description of the build.
+func VersionString() string {
+	return fmt.Sprintf("%s (commit: %s, built: %s)", Version, Commit, Date)
+}
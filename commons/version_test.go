@@ -0,0 +1,17 @@
+package commons
+
+import "testing"
+
+func TestVersionString(t *testing.T) {
+	oldVersion, oldCommit, oldDate := Version, Commit, Date
+	defer func() { Version, Commit, Date = oldVersion, oldCommit, oldDate }()
+
+	Version, Commit, Date = "v1.2.3", "abc1234", "2024-10-18"
+
+	got := VersionString()
+	want := "v1.2.3 (commit: abc1234, built: 2024-10-18)"
+
+	if got != want {
+		t.Errorf("got != want; got = %v, expected = %v\n", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package crdt
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchSizes are the document sizes benchmarks are run against.
+var benchSizes = []int{100, 1000, 10000}
+
+// benchAlphabet is what random documents are made of. The newline gives them realistic lines.
+const benchAlphabet = "abcdefghijklmnopqrstuvwxyz     \n"
+
+// contentConcat is the previous string-concatenation implementation of Content, kept for comparison.
+func contentConcat(doc Document) string {
+	value := ""
+	for _, char := range doc.Characters {
+		if char.Visible {
+			value += char.Value
+		}
+	}
+	return value
+}
+
+// newBenchDocument builds a document of n random characters directly, without going through Insert.
+// The seed is fixed so runs are comparable.
+func newBenchDocument(n int) Document {
+	r := rand.New(rand.NewSource(int64(n)))
+
+	chars := make([]Character, 0, n+2)
+	chars = append(chars, StartChar)
+	for i := 0; i < n; i++ {
+		value := string(benchAlphabet[r.Intn(len(benchAlphabet))])
+		chars = append(chars, Character{ID: fmt.Sprint("b", i), Visible: true, Value: value})
+	}
+	chars = append(chars, EndChar)
+
+	// Link the characters, as if each had been typed after the previous one.
+	for i := 1; i < len(chars)-1; i++ {
+		chars[i].IDPrevious, chars[i].IDNext = chars[i-1].ID, chars[i+1].ID
+		chars[i].OriginPrevious, chars[i].OriginNext = chars[i-1].ID, chars[i+1].ID
+	}
+	chars[0].IDNext = chars[1].ID
+	chars[len(chars)-1].IDPrevious = chars[len(chars)-2].ID
+
+	return Document{Characters: chars, SiteID: 1}
+}
+
+// cloneDocument copies a document so benchmarks can start over from it.
+func cloneDocument(doc Document) Document {
+	doc.Characters = append([]Character(nil), doc.Characters...)
+	return doc
+}
+
+func BenchmarkInsert(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			base := newBenchDocument(n)
+			doc := cloneDocument(base)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Start over periodically, so the document stays close to its nominal size.
+				if i%n == 0 {
+					b.StopTimer()
+					doc = cloneDocument(base)
+					b.StartTimer()
+				}
+				if _, err := doc.Insert(n/2, "x"); err != nil {
+					b.Fatalf("error: %v\n", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDelete(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			base := newBenchDocument(n)
+			doc := cloneDocument(base)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Start over before the middle of the document runs out of visible characters.
+				if i%(n/2) == 0 {
+					b.StopTimer()
+					doc = cloneDocument(base)
+					b.StartTimer()
+				}
+				doc.Delete(n / 4)
+			}
+		})
+	}
+}
+
+func BenchmarkContent(b *testing.B) {
+	doc := newBenchDocument(100000)
+
+	b.Run("builder", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = Content(doc)
+		}
+	})
+
+	b.Run("concat", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = contentConcat(doc)
+		}
+	})
+
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			doc := newBenchDocument(n)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = Content(doc)
+			}
+		})
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	for _, n := range benchSizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			path := filepath.Join(b.TempDir(), "doc.txt")
+			if err := os.WriteFile(path, []byte(Content(newBenchDocument(n))), 0o600); err != nil {
+				b.Fatalf("error: %v\n", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Load(path); err != nil {
+					b.Fatalf("error: %v\n", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSaveStream(b *testing.B) {
+	doc := newBenchDocument(100000)
+
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := SaveStream(io.Discard, &doc); err != nil {
+				b.Fatalf("error: %v\n", err)
+			}
+		}
+	})
+
+	b.Run("content", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := io.WriteString(io.Discard, Content(doc)); err != nil {
+				b.Fatalf("error: %v\n", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,24 @@
+package crdt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Checksum returns a hash of the document's visible characters, their IDs as well as their values,
+// so replicas that converged have the same checksum and ones that diverged almost certainly don't.
+// Deleted characters aren't included, since replicas may not have all the same tombstones.
+func (doc *Document) Checksum() string {
+	h := sha256.New()
+	for _, char := range doc.Characters {
+		if !char.Visible {
+			continue
+		}
+		// Each field ends in a zero byte, so different splits of the same bytes hash differently.
+		h.Write([]byte(char.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(char.Value))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
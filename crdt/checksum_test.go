@@ -0,0 +1,55 @@
+package crdt
+
+import "testing"
+
+func TestChecksum(t *testing.T) {
+	a := New()
+	a.SiteID = 1
+	for i, v := range "hello" {
+		if _, err := a.GenerateCharacter(i+1, string(v)); err != nil {
+			t.Fatalf("insert error: %v", err)
+		}
+	}
+
+	// A replica that merged the same characters matches, whatever tombstones it has.
+	b := New()
+	if _, err := b.Merge(a); err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+	if a.Checksum() != b.Checksum() {
+		t.Errorf("identical documents have different checksums: %s and %s", a.Checksum(), b.Checksum())
+	}
+
+	// Diverging by an edit changes it.
+	b.SiteID = 2
+	if _, err := b.GenerateCharacter(6, "!"); err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+	if a.Checksum() == b.Checksum() {
+		t.Errorf("diverged documents have the same checksum %s", a.Checksum())
+	}
+
+	// So does the same text made of different characters.
+	c := New()
+	c.SiteID = 3
+	for i, v := range "hello" {
+		if _, err := c.GenerateCharacter(i+1, string(v)); err != nil {
+			t.Fatalf("insert error: %v", err)
+		}
+	}
+	if Content(a) != Content(c) || a.Checksum() == c.Checksum() {
+		t.Errorf("documents with the same text but different characters have the same checksum %s", a.Checksum())
+	}
+
+	// Converging again matches.
+	a.Merge(b)
+	b.DeleteByID(b.Characters[1].ID)
+	a.DeleteByID(b.Characters[1].ID)
+	if a.Checksum() != b.Checksum() {
+		t.Errorf("converged documents have different checksums: %s and %s", a.Checksum(), b.Checksum())
+	}
+
+	if empty := New(); empty.Checksum() != (&Document{}).Checksum() {
+		t.Errorf("checksums of empty documents differ")
+	}
+}
@@ -0,0 +1,98 @@
+package crdt
+
+import "strings"
+
+// DiffOp says how the lines of a hunk differ between two texts.
+type DiffOp int
+
+const (
+	// DiffEqual lines are in both texts.
+	DiffEqual DiffOp = iota
+
+	// DiffDelete lines are only in the first text.
+	DiffDelete
+
+	// DiffInsert lines are only in the second text.
+	DiffInsert
+)
+
+// DiffHunk is a run of consecutive lines that are kept, deleted or inserted.
+type DiffHunk struct {
+	Op    DiffOp
+	Lines []string
+}
+
+// DiffLines returns the line diff turning a into b, based on their longest common subsequence of lines.
+// Where lines are both deleted and inserted, the deletions come first.
+// A trailing newline doesn't start another line, and an empty text has no lines.
+func DiffLines(a, b string) []DiffHunk {
+	x, y := splitLines(a), splitLines(b)
+
+	// Lines shared at the start and end needn't go through the quadratic table.
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var hunks []DiffHunk
+	add := func(op DiffOp, line string) {
+		if n := len(hunks); n > 0 && hunks[n-1].Op == op {
+			hunks[n-1].Lines = append(hunks[n-1].Lines, line)
+			return
+		}
+		hunks = append(hunks, DiffHunk{Op: op, Lines: []string{line}})
+	}
+
+	for _, line := range x[:prefix] {
+		add(DiffEqual, line)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of mx[i:] and my[j:].
+	mx, my := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+	lcs := make([][]int, len(mx)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			if mx[i] == my[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(mx) || j < len(my) {
+		switch {
+		case i < len(mx) && j < len(my) && mx[i] == my[j]:
+			add(DiffEqual, mx[i])
+			i, j = i+1, j+1
+		case j == len(my) || (i < len(mx) && lcs[i+1][j] >= lcs[i][j+1]):
+			add(DiffDelete, mx[i])
+			i++
+		default:
+			add(DiffInsert, my[j])
+			j++
+		}
+	}
+
+	for _, line := range x[len(x)-suffix:] {
+		add(DiffEqual, line)
+	}
+
+	return hunks
+}
+
+// splitLines splits text into lines, without an empty line after a trailing newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
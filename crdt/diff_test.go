@@ -0,0 +1,90 @@
+package crdt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		description string
+		a, b        string
+		want        []DiffHunk
+	}{
+		{"both empty", "", "", nil},
+		{"identical", "a\nb\n", "a\nb\n", []DiffHunk{{DiffEqual, []string{"a", "b"}}}},
+		{"everything added", "", "a\nb", []DiffHunk{{DiffInsert, []string{"a", "b"}}}},
+		{"everything removed", "a\nb\n", "", []DiffHunk{{DiffDelete, []string{"a", "b"}}}},
+		{"trailing newline ignored", "a\nb", "a\nb\n", []DiffHunk{{DiffEqual, []string{"a", "b"}}}},
+		{"line changed", "a\nb\nc", "a\nB\nc", []DiffHunk{
+			{DiffEqual, []string{"a"}},
+			{DiffDelete, []string{"b"}},
+			{DiffInsert, []string{"B"}},
+			{DiffEqual, []string{"c"}},
+		}},
+		{"line inserted", "a\nc", "a\nb\nc", []DiffHunk{
+			{DiffEqual, []string{"a"}},
+			{DiffInsert, []string{"b"}},
+			{DiffEqual, []string{"c"}},
+		}},
+		{"lines moved", "a\nb\nc\nd", "b\nc\na\nd", []DiffHunk{
+			{DiffDelete, []string{"a"}},
+			{DiffEqual, []string{"b", "c"}},
+			{DiffInsert, []string{"a"}},
+			{DiffEqual, []string{"d"}},
+		}},
+		{"interleaved", "x\na\ny\nb\nz", "a\n1\nb\n2", []DiffHunk{
+			{DiffDelete, []string{"x"}},
+			{DiffEqual, []string{"a"}},
+			{DiffDelete, []string{"y"}},
+			{DiffInsert, []string{"1"}},
+			{DiffEqual, []string{"b"}},
+			{DiffDelete, []string{"z"}},
+			{DiffInsert, []string{"2"}},
+		}},
+		{"blank lines", "a\n\n\nb", "a\n\nb", []DiffHunk{
+			{DiffEqual, []string{"a", ""}},
+			{DiffDelete, []string{""}},
+			{DiffEqual, []string{"b"}},
+		}},
+	}
+
+	for _, tc := range tests {
+		got := DiffLines(tc.a, tc.b)
+		if !cmp.Equal(got, tc.want) {
+			t.Errorf("(%s) (-want +got)\n%s", tc.description, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+// Applying a diff's equal and inserted lines must rebuild the second text, and its equal and deleted lines the first.
+func TestDiffLines_Reconstructs(t *testing.T) {
+	a := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+	b := "package main\n\nimport (\n\t\"fmt\"\n)\n\nfunc main() {\n\tfmt.Println(\"hi\")\n\tfmt.Println(\"bye\")\n}\n"
+
+	var gotA, gotB []string
+	common := 0
+	for _, h := range DiffLines(a, b) {
+		if h.Op != DiffInsert {
+			gotA = append(gotA, h.Lines...)
+		}
+		if h.Op != DiffDelete {
+			gotB = append(gotB, h.Lines...)
+		}
+		if h.Op == DiffEqual {
+			common += len(h.Lines)
+		}
+	}
+
+	if got, want := strings.Join(gotA, "\n")+"\n", a; got != want {
+		t.Errorf("first text (-want +got)\n%s", cmp.Diff(want, got))
+	}
+	if got, want := strings.Join(gotB, "\n")+"\n", b; got != want {
+		t.Errorf("second text (-want +got)\n%s", cmp.Diff(want, got))
+	}
+	if common != 6 {
+		t.Errorf("got %d common lines, expected the longest common subsequence of 6\n", common)
+	}
+}
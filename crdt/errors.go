@@ -0,0 +1,31 @@
+package crdt
+
+import "fmt"
+
+// InsertError reports a failed insert along with where it was attempted.
+// It wraps one of the Err variables, so callers can tell kinds of failure apart with errors.Is.
+type InsertError struct {
+	// Position is the position the character was to be inserted at, or -1 if it was placed between its neighbors.
+	Position int
+
+	// ID is the character's ID, or "" if it didn't have one.
+	ID string
+
+	// Err is the reason the insert failed.
+	Err error
+}
+
+func (e *InsertError) Error() string {
+	switch {
+	case e.Position < 0:
+		return fmt.Sprintf("insert of %q: %v", e.ID, e.Err)
+	case e.ID == "":
+		return fmt.Sprintf("insert at position %d: %v", e.Position, e.Err)
+	default:
+		return fmt.Sprintf("insert of %q at position %d: %v", e.ID, e.Position, e.Err)
+	}
+}
+
+func (e *InsertError) Unwrap() error {
+	return e.Err
+}
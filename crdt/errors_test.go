@@ -0,0 +1,48 @@
+package crdt
+
+import (
+	"errors"
+	"testing"
+)
+
+// Verify that callers can tell failed inserts apart by kind, and learn where they were attempted.
+func TestInsertError(t *testing.T) {
+	doc := New()
+
+	tests := []struct {
+		description string
+		char        Character
+		position    int
+		want        error
+		notWant     error
+	}{
+		{"out of bounds", Character{ID: "11", Value: "a"}, 5, ErrPositionOutOfBounds, ErrEmptyWCharacter},
+		{"empty ID", Character{Value: "a"}, 1, ErrEmptyWCharacter, ErrPositionOutOfBounds},
+	}
+
+	for _, tc := range tests {
+		_, err := doc.LocalInsert(tc.char, tc.position)
+		if !errors.Is(err, tc.want) || errors.Is(err, tc.notWant) {
+			t.Errorf("(%s) got err = %v, expected one wrapping %v", tc.description, err, tc.want)
+		}
+
+		var insertErr *InsertError
+		if !errors.As(err, &insertErr) {
+			t.Fatalf("(%s) got err = %T, expected an *InsertError", tc.description, err)
+		}
+		if insertErr.Position != tc.position {
+			t.Errorf("(%s) got position %d, expected %d", tc.description, insertErr.Position, tc.position)
+		}
+	}
+
+	// Characters whose neighbors are missing report their ID instead of a position.
+	orphan := Character{ID: "21", Value: "b", IDPrevious: "11", IDNext: "end"}
+	_, err := doc.IntegrateRemoteInsert(orphan)
+	var insertErr *InsertError
+	if !errors.Is(err, ErrBoundsNotPresent) || !errors.As(err, &insertErr) || insertErr.ID != "21" || insertErr.Position != -1 {
+		t.Errorf("got err = %#v, expected an *InsertError for 21 wrapping %v", err, ErrBoundsNotPresent)
+	}
+	if got := Content(doc); got != "" {
+		t.Errorf("failed inserts changed the document to %q", got)
+	}
+}
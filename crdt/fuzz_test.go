@@ -0,0 +1,148 @@
+package crdt
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// simOp is an operation generated by a simulated site: an inserted character or a deleted character's ID.
+type simOp struct {
+	insert   *Character
+	deleteID string
+}
+
+// deliverable reports whether a site has everything an operation depends on.
+func (op simOp) deliverable(doc *Document) bool {
+	if op.insert == nil {
+		return doc.Contains(op.deleteID)
+	}
+	originPrev, originNext := op.insert.origins()
+	return doc.Contains(originPrev) && doc.Contains(originNext)
+}
+
+// simulate runs steps random local edits and deliveries across sites, then delivers everything outstanding.
+// It returns the sites' final documents and a log of what happened.
+func simulate(seed int64, sites, steps int) ([]Document, []string) {
+	r := rand.New(rand.NewSource(seed))
+
+	docs := make([]Document, sites)
+	for i := range docs {
+		docs[i] = New()
+		docs[i].SiteID = i + 1
+	}
+
+	// inbox[to][from] holds operations generated by site from, not yet delivered to site to.
+	inbox := make([][][]simOp, sites)
+	for i := range inbox {
+		inbox[i] = make([][]simOp, sites)
+	}
+
+	var log []string
+
+	// deliver applies the next operation from one site to another, if its dependencies have arrived.
+	deliver := func(to, from int) bool {
+		queue := inbox[to][from]
+		if len(queue) == 0 || !queue[0].deliverable(&docs[to]) {
+			return false
+		}
+		op := queue[0]
+		inbox[to][from] = queue[1:]
+
+		if op.insert != nil {
+			docs[to].IntegrateRemoteInsert(*op.insert)
+			log = append(log, fmt.Sprintf("site %d integrates insert %s from site %d", to+1, op.insert.ID, from+1))
+		} else {
+			docs[to].DeleteByID(op.deleteID)
+			log = append(log, fmt.Sprintf("site %d integrates delete %s from site %d", to+1, op.deleteID, from+1))
+		}
+		return true
+	}
+
+	for step := 0; step < steps; step++ {
+		site := r.Intn(sites)
+		doc := &docs[site]
+		visible := doc.visibleCount()
+
+		var op simOp
+		switch n := r.Intn(10); {
+		case n < 5:
+			// Insert locally.
+			position := 1 + r.Intn(visible+1)
+			value := string(rune('a' + r.Intn(26)))
+			char, err := doc.GenerateCharacter(position, value)
+			if err != nil {
+				log = append(log, fmt.Sprintf("site %d failed to insert %q at %d: %v", site+1, value, position, err))
+				continue
+			}
+			op.insert = &char
+			log = append(log, fmt.Sprintf("site %d inserts %q at %d as %s", site+1, value, position, char.ID))
+		case n < 7 && visible > 0:
+			// Delete locally.
+			char := IthVisible(*doc, 1+r.Intn(visible))
+			doc.DeleteByID(char.ID)
+			op.deleteID = char.ID
+			log = append(log, fmt.Sprintf("site %d deletes %s", site+1, char.ID))
+		default:
+			// Receive an operation from another site.
+			deliver(site, r.Intn(sites))
+			continue
+		}
+
+		for to := range docs {
+			if to != site {
+				inbox[to][site] = append(inbox[to][site], op)
+			}
+		}
+	}
+
+	// Drain every inbox, in a random order, until nothing more can be delivered.
+	for progress := true; progress; {
+		progress = false
+		for _, to := range r.Perm(sites) {
+			for _, from := range r.Perm(sites) {
+				for deliver(to, from) {
+					progress = true
+				}
+			}
+		}
+	}
+
+	return docs, log
+}
+
+// diverged reports why the sites' documents differ, or an empty string if they converged.
+func diverged(docs []Document) string {
+	want := Content(docs[0])
+	for i := 1; i < len(docs); i++ {
+		if got := Content(docs[i]); got != want {
+			return fmt.Sprintf("site %d has %q, site 1 has %q", i+1, got, want)
+		}
+	}
+	return ""
+}
+
+// FuzzConvergence checks that sites applying the same operations in different orders end up with the same content.
+func FuzzConvergence(f *testing.F) {
+	for seed := int64(0); seed < 20; seed++ {
+		f.Add(seed, uint8(seed%3), uint16(50+seed*10))
+	}
+
+	f.Fuzz(func(t *testing.T, seed int64, sites uint8, steps uint16) {
+		numSites, numSteps := 2+int(sites%3), int(steps%300)
+
+		docs, _ := simulate(seed, numSites, numSteps)
+		if diverged(docs) == "" {
+			return
+		}
+
+		// Report the shortest run with this seed that still diverges, with its log as a reproducer.
+		for n := 1; n <= numSteps; n++ {
+			docs, log := simulate(seed, numSites, n)
+			if reason := diverged(docs); reason != "" {
+				t.Fatalf("sites diverged: %s\nreproduce with simulate(%d, %d, %d):\n%s\n", reason, seed, numSites, n, strings.Join(log, "\n"))
+			}
+		}
+	})
+}
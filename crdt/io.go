@@ -0,0 +1,89 @@
+package crdt
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// Reader returns a reader of the document's visible content.
+// It streams the characters one at a time rather than building the content up front,
+// so the document mustn't be changed until reading is done.
+func (doc *Document) Reader() io.Reader {
+	return &docReader{doc: doc}
+}
+
+// docReader reads a document's visible characters in order.
+type docReader struct {
+	doc *Document
+
+	// next is the index of the next character to read.
+	next int
+
+	// pending holds the part of a character's value that didn't fit in the last read.
+	pending string
+}
+
+// Read implements io.Reader.
+func (r *docReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if r.pending == "" {
+			if r.next >= len(r.doc.Characters) {
+				break
+			}
+			if char := r.doc.Characters[r.next]; char.Visible {
+				r.pending = char.Value
+			}
+			r.next++
+			continue
+		}
+
+		copied := copy(p[n:], r.pending)
+		r.pending = r.pending[copied:]
+		n += copied
+	}
+
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Writer returns a writer that appends to the document, inserting each character written at the end.
+// A character split between writes is inserted once the rest of it arrives.
+// Bytes that aren't valid UTF-8 are inserted on their own, so the content reads back as it was written.
+// Each insert scans the document, like any other, so writing n characters takes O(n²) time.
+func (doc *Document) Writer() io.Writer {
+	return &docWriter{doc: doc}
+}
+
+// docWriter appends the characters written to it to a document.
+type docWriter struct {
+	doc *Document
+
+	// partial holds the start of a character split between writes.
+	partial []byte
+}
+
+// Write implements io.Writer.
+func (w *docWriter) Write(p []byte) (int, error) {
+	buf := append(w.partial, p...)
+	w.partial = nil
+
+	position := w.doc.visibleCount() + 1
+	for len(buf) > 0 {
+		if !utf8.FullRune(buf) {
+			w.partial = append([]byte(nil), buf...)
+			break
+		}
+
+		_, size := utf8.DecodeRune(buf)
+		if _, err := w.doc.GenerateCharacter(position, string(buf[:size])); err != nil {
+			return 0, err
+		}
+		position++
+		buf = buf[size:]
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,180 @@
+package crdt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDocument_Writer(t *testing.T) {
+	text := "héllo, 世界\nline two 🎉\xff"
+
+	tests := []struct {
+		description string
+		src         io.Reader
+	}{
+		{"one write", strings.NewReader(text)},
+
+		// Characters split between writes are still inserted whole.
+		{"byte at a time", iotest.OneByteReader(strings.NewReader(text))},
+		{"half at a time", iotest.HalfReader(strings.NewReader(text))},
+	}
+
+	for _, tc := range tests {
+		doc := New()
+		doc.SiteID = 1
+
+		n, err := io.Copy(doc.Writer(), tc.src)
+		if err != nil {
+			t.Fatalf("(%s) copy error: %v\n", tc.description, err)
+		}
+		if n != int64(len(text)) {
+			t.Errorf("(%s) copied %d bytes, expected %d\n", tc.description, n, len(text))
+		}
+		if got := Content(doc); got != text {
+			t.Errorf("(%s) got != want; got = %q, expected = %q\n", tc.description, got, text)
+		}
+		if got, want := doc.visibleCount(), len([]rune(text)); got != want {
+			t.Errorf("(%s) got %d characters, expected one per rune (%d)\n", tc.description, got, want)
+		}
+	}
+}
+
+func TestDocument_WriterAppends(t *testing.T) {
+	doc := New()
+	doc.SiteID = 1
+	if _, err := doc.Insert(1, "a"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	w := doc.Writer()
+	for _, s := range []string{"b", "cd"} {
+		if _, err := io.WriteString(w, s); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+
+	if got := Content(doc); got != "abcd" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "abcd")
+	}
+}
+
+func TestDocument_Reader(t *testing.T) {
+	doc := New()
+	doc.SiteID = 1
+	if _, err := io.Copy(doc.Writer(), strings.NewReader("xhéllo, 世界!")); err != nil {
+		t.Fatalf("copy error: %v\n", err)
+	}
+
+	// Deleted characters aren't read.
+	doc.Delete(1)
+	want := "héllo, 世界!"
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, doc.Reader()); err != nil {
+		t.Fatalf("copy error: %v\n", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, want)
+	}
+
+	// Reads of any size, including ones that split characters, yield the same bytes.
+	if err := iotest.TestReader(doc.Reader(), []byte(want)); err != nil {
+		t.Errorf("reader misbehaves: %v\n", err)
+	}
+
+	empty := New()
+	if got, err := io.ReadAll(empty.Reader()); err != nil || len(got) != 0 {
+		t.Errorf("empty document: got (%q, %v), expected nothing\n", got, err)
+	}
+}
+
+// Verify that streaming a large document, with tombstones, writes exactly what saving its content did.
+func TestSaveStream_MatchesContent(t *testing.T) {
+	doc := newBenchDocument(200000)
+	for i := 1; i < len(doc.Characters)-1; i += 7 {
+		doc.Characters[i].Visible = false
+	}
+	want := Content(doc)
+
+	var buf bytes.Buffer
+	if err := SaveStream(&buf, &doc); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if buf.String() != want {
+		t.Errorf("got != want; streamed %d bytes, expected %d\n", buf.Len(), len(want))
+	}
+
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := Save(path, &doc); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if string(saved) != want {
+		t.Errorf("got != want; saved %d bytes, expected %d\n", len(saved), len(want))
+	}
+}
+
+// Verify that a failing writer's error is returned.
+func TestSaveStream_WriteError(t *testing.T) {
+	doc := newBenchDocument(10000)
+	errWrite := errors.New("disk full")
+	if err := SaveStream(errWriter{errWrite}, &doc); !errors.Is(err, errWrite) {
+		t.Errorf("got != want; err = %v, expected = %v\n", err, errWrite)
+	}
+}
+
+// errWriter fails every write with err.
+type errWriter struct{ err error }
+
+func (w errWriter) Write(p []byte) (int, error) { return 0, w.err }
+
+// Verify that saving replaces the file whole, keeping its permissions and leaving no temporary files,
+// and that a file that can't be written is left alone.
+func TestSave_Replaces(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "doc.txt")
+	if err := os.WriteFile(path, []byte("old content that's longer"), 0o600); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	doc := New()
+	if _, err := doc.Insert(1, "n"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if err := Save(path, &doc); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != "n" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "n")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("got != want; mode = %v, expected = %v\n", info.Mode().Perm(), os.FileMode(0o600))
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("got %d files, expected only the saved one\n", len(entries))
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root can write any file")
+	}
+	if err := os.Chmod(path, 0o400); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	empty := New()
+	if err := Save(path, &empty); err == nil {
+		t.Errorf("saved over a read-only file\n")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "n" {
+		t.Errorf("read-only file changed; got = %q, expected = %q\n", got, "n")
+	}
+}
@@ -0,0 +1,132 @@
+package crdt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrPatchMismatch is returned when a patch deletes a character other than the one it was made against.
+var ErrPatchMismatch = errors.New("patch doesn't match the document")
+
+// Operation is one edit of a document's visible text, as exchanged in patches.
+// Unlike the operations relayed between peers, it refers to characters by position rather than ID,
+// so it applies to any document with the same text, e.g. one loaded from the same file.
+type Operation struct {
+	// Type is "insert" or "delete".
+	Type string `json:"type"`
+
+	// Position is the visible position, from 1, the value is inserted at or the deleted character is at,
+	// counted after the operations before it have been applied.
+	Position int `json:"position"`
+
+	// Value is the inserted character, or the deleted one, which is checked before deleting it.
+	Value string `json:"value"`
+}
+
+// OperationsBetween returns the operations turning base's visible text into current's.
+// Characters that are in both documents, e.g. when current was edited from base, are matched by ID,
+// so the operations are the edits that were made; other characters are matched by value.
+// Where characters are both deleted and inserted, the deletions come first.
+func OperationsBetween(base, current Document) []Operation {
+	// IDs in both documents are the same character, even if it's only visible in one of them.
+	inBase := make(map[string]bool, len(base.Characters))
+	for _, char := range base.Characters {
+		inBase[char.ID] = true
+	}
+	shared := make(map[string]bool)
+	for _, char := range current.Characters {
+		if inBase[char.ID] {
+			shared[char.ID] = true
+		}
+	}
+	same := func(a, b Character) bool {
+		if shared[a.ID] || shared[b.ID] {
+			return a.ID == b.ID
+		}
+		return a.Value == b.Value
+	}
+
+	x, y := visibleCharacters(base), visibleCharacters(current)
+
+	// Characters kept at the start and end needn't go through the quadratic table.
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && same(x[prefix], y[prefix]) {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && same(x[len(x)-1-suffix], y[len(y)-1-suffix]) {
+		suffix++
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of mx[i:] and my[j:].
+	mx, my := x[prefix:len(x)-suffix], y[prefix:len(y)-suffix]
+	lcs := make([][]int, len(mx)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(my)+1)
+	}
+	for i := len(mx) - 1; i >= 0; i-- {
+		for j := len(my) - 1; j >= 0; j-- {
+			if same(mx[i], my[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// position is where the next kept character is, in the text as edited so far.
+	var ops []Operation
+	position := prefix + 1
+	i, j := 0, 0
+	for i < len(mx) || j < len(my) {
+		switch {
+		case i < len(mx) && j < len(my) && same(mx[i], my[j]):
+			position++
+			i, j = i+1, j+1
+		case j == len(my) || (i < len(mx) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, Operation{Type: "delete", Position: position, Value: mx[i].Value})
+			i++
+		default:
+			ops = append(ops, Operation{Type: "insert", Position: position, Value: my[j].Value})
+			position++
+			j++
+		}
+	}
+	return ops
+}
+
+// visibleCharacters returns the document's visible characters, in order.
+func visibleCharacters(doc Document) []Character {
+	var chars []Character
+	for _, char := range doc.Characters {
+		if char.Visible {
+			chars = append(chars, char)
+		}
+	}
+	return chars
+}
+
+// ApplyOperations applies ops to the document in order, as local edits.
+// It stops at the first operation that doesn't fit the document, leaving the ones before it applied.
+func (doc *Document) ApplyOperations(ops []Operation) error {
+	for i, op := range ops {
+		switch op.Type {
+		case "insert":
+			if _, err := doc.GenerateCharacter(op.Position, op.Value); err != nil {
+				return fmt.Errorf("operation %d: %w", i+1, err)
+			}
+		case "delete":
+			char := IthVisible(*doc, op.Position)
+			if char.ID == "-1" {
+				return fmt.Errorf("operation %d: delete at position %d: %w", i+1, op.Position, ErrPositionOutOfBounds)
+			}
+			if char.Value != op.Value {
+				return fmt.Errorf("operation %d: delete of %q at position %d found %q: %w", i+1, op.Value, op.Position, char.Value, ErrPatchMismatch)
+			}
+			doc.IntegrateDelete(char)
+		default:
+			return fmt.Errorf("operation %d: unknown type %q", i+1, op.Type)
+		}
+	}
+	return nil
+}
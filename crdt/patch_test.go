@@ -0,0 +1,118 @@
+package crdt
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newTextDocument returns a document holding text, generated by the given site.
+func newTextDocument(t *testing.T, site int, text string) Document {
+	t.Helper()
+
+	doc := New()
+	doc.SiteID = site
+	if err := doc.ReplaceContent(text); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	return doc
+}
+
+// Verify that the operations between documents with unrelated characters turn one's text into the other's.
+func TestOperationsBetween(t *testing.T) {
+	tests := []struct {
+		description string
+		base, want  string
+		ops         []Operation
+	}{
+		{"unchanged", "abc", "abc", nil},
+		{"insert", "ac", "abc", []Operation{{"insert", 2, "b"}}},
+		{"delete", "abc", "ac", []Operation{{"delete", 2, "b"}}},
+		{"replace", "abc", "axc", []Operation{{"delete", 2, "b"}, {"insert", 2, "x"}}},
+		{"from empty", "", "hi", []Operation{{"insert", 1, "h"}, {"insert", 2, "i"}}},
+		{"to empty", "hi", "", []Operation{{"delete", 1, "h"}, {"delete", 1, "i"}}},
+		{"lines", "one\ntwo\n", "one\n2\ntwo!\n", nil},
+	}
+
+	for _, tc := range tests {
+		base, current := newTextDocument(t, 1, tc.base), newTextDocument(t, 2, tc.want)
+		ops := OperationsBetween(base, current)
+		if tc.ops != nil || tc.base == tc.want {
+			if diff := cmp.Diff(tc.ops, ops); diff != "" {
+				t.Errorf("(%s) operations mismatch (-want +got):\n%s", tc.description, diff)
+			}
+		}
+
+		if err := base.ApplyOperations(ops); err != nil {
+			t.Fatalf("(%s) apply error: %v\n", tc.description, err)
+		}
+		if got := Content(base); got != tc.want {
+			t.Errorf("(%s) got = %q, expected = %q\n", tc.description, got, tc.want)
+		}
+	}
+}
+
+// Verify that a document edited from the base yields the edits made, and applying them to the base reproduces it.
+func TestOperationsBetween_Edited(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 50; round++ {
+		base := newTextDocument(t, 1, "the quick brown fox")
+
+		current := Document{SiteID: 2}
+		current.SetText(base)
+		for edit := 0; edit < 10; edit++ {
+			length := len(Content(current))
+			if length > 0 && rng.Intn(2) == 0 {
+				current.GenerateDelete(rng.Intn(length) + 1)
+				continue
+			}
+			if _, err := current.GenerateCharacter(rng.Intn(length+1)+1, string(rune('a'+rng.Intn(3)))); err != nil {
+				t.Fatalf("error: %v\n", err)
+			}
+		}
+
+		ops := OperationsBetween(base, current)
+		if err := base.ApplyOperations(ops); err != nil {
+			t.Fatalf("(round %d) apply error: %v\n", round, err)
+		}
+		if got, want := Content(base), Content(current); got != want {
+			t.Errorf("(round %d) got = %q, expected = %q\n", round, got, want)
+		}
+	}
+}
+
+// Verify that shared characters are matched by ID, so the operations are the edits made even where the text repeats.
+func TestOperationsBetween_MatchesByID(t *testing.T) {
+	base := newTextDocument(t, 1, "aaa")
+	current := Document{SiteID: 2}
+	current.SetText(base)
+	current.GenerateDelete(3)
+	if _, err := current.GenerateCharacter(1, "a"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	want := []Operation{{"insert", 1, "a"}, {"delete", 4, "a"}}
+	if diff := cmp.Diff(want, OperationsBetween(base, current)); diff != "" {
+		t.Errorf("operations mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// Verify that deleting the wrong character, or outside the document, is refused.
+func TestApplyOperations_Mismatch(t *testing.T) {
+	doc := newTextDocument(t, 1, "abc")
+
+	if err := doc.ApplyOperations([]Operation{{"delete", 2, "x"}}); !errors.Is(err, ErrPatchMismatch) {
+		t.Errorf("got err = %v, expected %v\n", err, ErrPatchMismatch)
+	}
+	if err := doc.ApplyOperations([]Operation{{"delete", 4, "c"}}); !errors.Is(err, ErrPositionOutOfBounds) {
+		t.Errorf("got err = %v, expected %v\n", err, ErrPositionOutOfBounds)
+	}
+	if err := doc.ApplyOperations([]Operation{{"insert", 9, "c"}}); !errors.Is(err, ErrPositionOutOfBounds) {
+		t.Errorf("got err = %v, expected %v\n", err, ErrPositionOutOfBounds)
+	}
+	if got := Content(doc); got != "abc" {
+		t.Errorf("got = %q, expected the document unchanged\n", got)
+	}
+}
@@ -0,0 +1,37 @@
+package crdt
+
+import "fmt"
+
+// CharacterID returns the ID of the character generated by site at the given clock.
+// The separator keeps IDs unique: without it, site 1 at clock 11 and site 11 at clock 1 would both be "111".
+func CharacterID(site, clock int) string {
+	return fmt.Sprintf("%d.%d", site, clock)
+}
+
+// RunCharacters returns the characters of a run of count copies of first's value typed one after another,
+// e.g. by holding a key, given the first of them and the site and clock it was generated with.
+// Each later character was generated at the next clock, right after the one before it and before
+// the same character as the first, so the run can be sent as its first character and a count.
+func RunCharacters(first Character, site, clock, count int) []Character {
+	if count <= 0 {
+		return nil
+	}
+
+	_, next := first.origins()
+	chars := make([]Character, count)
+	chars[0] = first
+	for i := 1; i < count; i++ {
+		prev := chars[i-1].ID
+		chars[i] = Character{
+			ID:         CharacterID(site, clock+i),
+			Visible:    true,
+			Value:      first.Value,
+			IDPrevious: prev,
+			IDNext:     next,
+
+			OriginPrevious: prev,
+			OriginNext:     next,
+		}
+	}
+	return chars
+}
@@ -0,0 +1,53 @@
+package crdt
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRunCharacters(t *testing.T) {
+	doc := New()
+	doc.SiteID = 3
+	for i, v := range "ab" {
+		if _, err := doc.GenerateCharacter(i+1, string(v)); err != nil {
+			t.Fatalf("insert error: %v", err)
+		}
+	}
+	base := doc
+	base.Characters = append([]Character(nil), doc.Characters...)
+
+	// Type five x's between a and b, one after another.
+	var typed []Character
+	for i := 0; i < 5; i++ {
+		char, err := doc.GenerateCharacter(2+i, "x")
+		if err != nil {
+			t.Fatalf("insert error: %v", err)
+		}
+		typed = append(typed, char)
+	}
+
+	got := RunCharacters(typed[0], 3, 3, 5)
+	if !cmp.Equal(got, typed) {
+		t.Fatalf("(-want +got)\n%s", cmp.Diff(typed, got))
+	}
+
+	// Integrating the run elsewhere gives the same content as the inserts did.
+	peer := New()
+	peer.SiteID = 4
+	if _, err := peer.Merge(base); err != nil {
+		t.Fatalf("merge error: %v", err)
+	}
+	for _, char := range got {
+		if _, err := peer.IntegrateRemoteInsert(char); err != nil {
+			t.Fatalf("integrate error: %v", err)
+		}
+	}
+	if got, want := Content(peer), Content(doc); got != want {
+		t.Errorf("got %q, expected %q", got, want)
+	}
+
+	if got := RunCharacters(typed[0], 3, 3, 0); got != nil {
+		t.Errorf("got %v for an empty run, expected none", got)
+	}
+}
@@ -1,17 +1,27 @@
 package crdt
 
 import (
+	"bufio"
 	"errors"
-	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
-	"sync"
+	"unicode/utf8"
 )
 
 // DONE
-// Document is a slice of characters
+// Document is a slice of characters.
+// A Document is not safe for concurrent use; callers must synchronize access.
 type Document struct {
 	Characters []Character
+
+	// Unique per user, used to generate identifiers for characters inserted locally.
+	// It is never transmitted, so a synced document doesn't carry the sender's identity.
+	SiteID int `json:"-"`
+
+	// Incremented whenever an insert operation takes place. Used to generate unique IDs for characters.
+	Clock int `json:"-"`
 }
 
 type Character struct {
@@ -20,26 +30,34 @@ type Character struct {
 	Value      string
 	IDPrevious string
 	IDNext     string
-}
 
-var (
-	mu sync.Mutex
-
-	// Unique variable per user to generate identifiers for characters in the document.
-	SiteID = 0
+	// OriginPrevious and OriginNext are the neighbors the character was generated between.
+	// Unlike IDPrevious and IDNext they never change, which remote integration relies on.
+	OriginPrevious string `json:",omitempty"`
+	OriginNext     string `json:",omitempty"`
+}
 
-	// Incremented whenever an insert operation takes place. Used to generate unique IDs for characters.
-	LocalClock = 0
+// origins returns the neighbors the character was generated between.
+// Characters without recorded origins fall back to their current neighbors.
+func (char Character) origins() (string, string) {
+	if char.OriginPrevious == "" || char.OriginNext == "" {
+		return char.IDPrevious, char.IDNext
+	}
+	return char.OriginPrevious, char.OriginNext
+}
 
-	// StartChar is placed at the start.
+var (
+	// StartChar is placed at the start. Each document holds its own copy.
 	StartChar = Character{ID: "start", Visible: false, Value: "", IDPrevious: "", IDNext: "end"}
 
-	// EndChar is placed at the end.
+	// EndChar is placed at the end. Each document holds its own copy.
 	EndChar = Character{ID: "end", Visible: false, Value: "", IDPrevious: "start", IDNext: ""}
 
+	// Failed inserts return an *InsertError wrapping one of these; check for them with errors.Is.
 	ErrPositionOutOfBounds = errors.New("position out of bounds")
 	ErrEmptyWCharacter     = errors.New("empty char ID provided")
 	ErrBoundsNotPresent    = errors.New("subsequence bound(s) not present")
+	ErrMissingSentinels    = errors.New("document is missing its start or end character")
 )
 
 // New returns a new document with the start and end characters.
@@ -48,36 +66,75 @@ func New() Document {
 }
 
 // Load creates a new CRDTdocument from a file.
+// Each character is inserted whole, as the editor counts them; bytes that aren't valid UTF-8 are inserted on
+// their own, as Writer does, so the content reads back as it was.
 func Load(fileName string) (Document, error) {
 	doc := New()
 	content, err := os.ReadFile(fileName)
 	if err != nil {
 		return doc, err
 	}
-	lines := strings.Split(string(content), "\n")
-	pos := 1
-	for i := 0; i < len(lines); i++ {
-		for j := 0; j < len(lines[i]); j++ {
-			_, err := doc.Insert(pos, string(lines[i][j]))
-			if err != nil {
-				return doc, err
-			}
-			pos++
-		}
-		if i < len(lines)-1 { // don't insert '\n' on last line
-			_, err := doc.Insert(pos, "\n")
-			if err != nil {
-				return doc, err
-			}
-			pos++
+	text := string(content)
+	for pos := 1; len(text) > 0; pos++ {
+		_, size := utf8.DecodeRuneInString(text)
+		if _, err := doc.Insert(pos, text[:size]); err != nil {
+			return doc, err
 		}
+		text = text[size:]
 	}
-	return doc, err
+	return doc, nil
 }
 
 // Save writes the document to a file. Overwrites the file if it exists.
+// The content is streamed to a temporary file that's then renamed over fileName,
+// so a failed save never leaves a truncated file behind, and large documents aren't copied into one string first.
+// An existing file keeps its permissions, and one that can't be written is left alone.
 func Save(fileName string, doc *Document) error {
-	return os.WriteFile(fileName, []byte(Content(*doc)), 0644)
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
+
+		// Renaming would replace a file its permissions don't let us write.
+		f, err := os.OpenFile(fileName, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		f.Close()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := SaveStream(tmp, doc); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
+
+// SaveStream writes the document's visible content to w through a buffer, a character at a time,
+// without building the content in memory.
+func SaveStream(w io.Writer, doc *Document) error {
+	bw := bufio.NewWriter(w)
+	for _, char := range doc.Characters {
+		if !char.Visible {
+			continue
+		}
+		if _, err := bw.WriteString(char.Value); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
 }
 
 // Utility functions
@@ -90,15 +147,34 @@ func (doc *Document) SetText(newDoc Document) {
 	}
 }
 
+// Validate checks that the document is bounded by the start and end characters,
+// which every operation relies on. Received documents should be validated before use.
+func (doc *Document) Validate() error {
+	n := len(doc.Characters)
+	if n < 2 || doc.Characters[0].ID != StartChar.ID || doc.Characters[n-1].ID != EndChar.ID {
+		return ErrMissingSentinels
+	}
+	return nil
+}
+
 // Content returns the content of the document.
 func Content(doc Document) string {
-	value := ""
+	// Size the buffer up front so the content is built with a single allocation.
+	size := 0
+	for _, char := range doc.Characters {
+		if char.Visible {
+			size += len(char.Value)
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(size)
 	for _, char := range doc.Characters {
 		if char.Visible {
-			value += char.Value
+			b.WriteString(char.Value)
 		}
 	}
-	return value
+	return b.String()
 }
 
 // IthVisible returns the ith visible character in the document.
@@ -117,6 +193,17 @@ func IthVisible(doc Document, position int) Character {
 	return Character{ID: "-1"}
 }
 
+// visibleCount returns the number of visible characters in the document.
+func (doc *Document) visibleCount() int {
+	count := 0
+	for _, char := range doc.Characters {
+		if char.Visible {
+			count++
+		}
+	}
+	return count
+}
+
 // Length returns the length of the document.
 func (doc *Document) Length() int {
 	return len(doc.Characters)
@@ -160,6 +247,23 @@ func (doc *Document) Right(charID string) string {
 	return doc.Characters[i+1].ID
 }
 
+// VisiblePosition returns the visible position (1-based) of the given character,
+// or -1 if it is absent or deleted.
+func (doc *Document) VisiblePosition(charID string) int {
+	visible := 0
+	for _, char := range doc.Characters {
+		if !char.Visible {
+			continue
+		}
+		visible++
+		if char.ID == charID {
+			return visible
+		}
+	}
+
+	return -1
+}
+
 // Contains checks if a character is present in the document.
 func (doc *Document) Contains(charID string) bool {
 	position := doc.Position(charID)
@@ -202,11 +306,11 @@ func (doc *Document) Subsequence(wcharacterStart, wcharacterEnd Character) ([]Ch
 // LocalInsert inserts the character into the document.
 func (doc *Document) LocalInsert(char Character, position int) (*Document, error) {
 	if position <= 0 || position >= doc.Length() {
-		return doc, ErrPositionOutOfBounds
+		return doc, &InsertError{Position: position, ID: char.ID, Err: ErrPositionOutOfBounds}
 	}
 
 	if char.ID == "" {
-		return doc, ErrEmptyWCharacter
+		return doc, &InsertError{Position: position, Err: ErrEmptyWCharacter}
 	}
 
 	doc.Characters = append(doc.Characters[:position],
@@ -216,6 +320,8 @@ func (doc *Document) LocalInsert(char Character, position int) (*Document, error
 	// Update next and previous pointers.
 	doc.Characters[position-1].IDNext = char.ID
 	doc.Characters[position+1].IDPrevious = char.ID
+	doc.Characters[position].IDPrevious = doc.Characters[position-1].ID
+	doc.Characters[position].IDNext = doc.Characters[position+1].ID
 
 	return doc, nil
 }
@@ -228,7 +334,7 @@ func (doc *Document) IntegrateInsert(char, charPrev, charNext Character) (*Docum
 	// Handle invalid subsequence.
 	subsequence, err := doc.Subsequence(charPrev, charNext)
 	if err != nil {
-		return doc, err
+		return doc, &InsertError{Position: -1, ID: char.ID, Err: err}
 	}
 
 	// Get the position of the next character.
@@ -240,48 +346,131 @@ func (doc *Document) IntegrateInsert(char, charPrev, charNext Character) (*Docum
 		return doc.LocalInsert(char, position)
 	}
 
-	// Handle single character subsequence (Insert at previous position)
-	if len(subsequence) == 1 {
-		return doc.LocalInsert(char, position-1)
+	// Only characters generated between the same neighbors compete for the slot.
+	// The rest were generated inside one of their gaps and are skipped over.
+	prevPosition := doc.Position(charPrev.ID)
+	nextPosition := position + 1
+	candidates := []Character{charPrev}
+	for _, c := range subsequence {
+		originPrev, originNext := c.origins()
+		if doc.Position(originPrev) <= prevPosition && nextPosition <= doc.Position(originNext) {
+			candidates = append(candidates, c)
+		}
+	}
+	candidates = append(candidates, charNext)
+
+	// Handle no competing characters (Insert at current position)
+	if len(candidates) == 2 {
+		return doc.LocalInsert(char, position)
 	}
 
-	// Find the correct position to insert the character.
+	// Find the correct position to insert the character, ordering concurrent inserts by ID.
 	i := 1
-	for i < len(subsequence)-1 && subsequence[i].ID < char.ID {
+	for i < len(candidates)-1 && candidates[i].ID < char.ID {
 		i++
 	}
 	// Insert the character at the correct position.
-	return doc.IntegrateInsert(char, subsequence[i-1], subsequence[i])
+	return doc.IntegrateInsert(char, candidates[i-1], candidates[i])
+}
+
+// IntegrateRemoteInsert integrates a character generated by another site,
+// placing it between the neighbors it was generated with. Characters already present are ignored.
+func (doc *Document) IntegrateRemoteInsert(char Character) (*Document, error) {
+	if doc.Contains(char.ID) {
+		return doc, nil
+	}
+
+	originPrev, originNext := char.origins()
+	return doc.IntegrateInsert(char, doc.Find(originPrev), doc.Find(originNext))
+}
+
+// Merge integrates the characters of other into the document, matching characters by ID.
+// Missing characters are placed between the neighbors they were generated with,
+// and a character deleted in either document stays deleted. Pointers are relinked as characters
+// are integrated. The result doesn't depend on merge order, and merging the same document again is a no-op.
+func (doc *Document) Merge(other Document) (*Document, error) {
+	present := make(map[string]bool, len(doc.Characters))
+	for _, char := range doc.Characters {
+		present[char.ID] = true
+	}
+
+	var pending []Character
+	for _, char := range other.Characters {
+		if !present[char.ID] {
+			pending = append(pending, char)
+			continue
+		}
+		if !char.Visible {
+			doc.IntegrateDelete(char)
+		}
+	}
+
+	// Characters can only be integrated once both of their origins are present,
+	// so keep making passes until everything is in place.
+	for len(pending) > 0 {
+		var deferred []Character
+		for _, char := range pending {
+			originPrev, originNext := char.origins()
+			if !present[originPrev] || !present[originNext] {
+				deferred = append(deferred, char)
+				continue
+			}
+
+			if _, err := doc.IntegrateRemoteInsert(char); err != nil {
+				return doc, err
+			}
+			present[char.ID] = true
+		}
+
+		if len(deferred) == len(pending) {
+			return doc, &InsertError{Position: -1, ID: deferred[0].ID, Err: ErrBoundsNotPresent}
+		}
+		pending = deferred
+	}
+
+	return doc, nil
 }
 
 // GenerateInsert generates an insert operation for the given position and value.
 func (doc *Document) GenerateInsert(position int, value string) (*Document, error) {
-	// Increment local clock.
-	mu.Lock()
-	LocalClock++
-	mu.Unlock()
+	_, err := doc.GenerateCharacter(position, value)
+	return doc, err
+}
 
-	// Get previous and next characters.
-	charPrev := IthVisible(*doc, position-1)
-	charNext := IthVisible(*doc, position)
+// GenerateCharacter inserts the value at the given position and returns the generated character,
+// which peers integrate with IntegrateRemoteInsert.
+func (doc *Document) GenerateCharacter(position int, value string) (Character, error) {
+	// Valid positions run from 1 (before the first visible character) to one past the last.
+	visible := doc.visibleCount()
+	if position < 1 || position > visible+1 {
+		return Character{}, &InsertError{Position: position, Err: ErrPositionOutOfBounds}
+	}
+
+	// Increment local clock.
+	doc.Clock++
 
-	// Use defaults.
-	if charPrev.ID == "-1" {
-		charPrev = doc.Find("start")
+	// Get previous and next characters, using the sentinels at the document's boundaries.
+	charPrev, charNext := doc.Find("start"), doc.Find("end")
+	if position > 1 {
+		charPrev = IthVisible(*doc, position-1)
 	}
-	if charNext.ID == "-1" {
-		charNext = doc.Find("end")
+	if position <= visible {
+		charNext = IthVisible(*doc, position)
 	}
 
 	char := Character{
-		ID:         fmt.Sprint(SiteID) + fmt.Sprint(LocalClock),
+		ID:         CharacterID(doc.SiteID, doc.Clock),
 		Visible:    true,
 		Value:      value,
 		IDPrevious: charPrev.ID,
 		IDNext:     charNext.ID,
+
+		OriginPrevious: charPrev.ID,
+		OriginNext:     charNext.ID,
 	}
 
-	return doc.IntegrateInsert(char, charPrev, charNext)
+	_, err := doc.IntegrateInsert(char, charPrev, charNext)
+	return char, err
 }
 
 // IntegrateDelete marks the given character for deletion.
@@ -297,12 +486,54 @@ func (doc *Document) IntegrateDelete(char Character) *Document {
 	return doc
 }
 
+// DeleteByID marks the character with the given ID for deletion, regardless of its current position.
+// It returns the visible position (1-based) the character occupied, or -1 if it was absent or already deleted.
+func (doc *Document) DeleteByID(id string) int {
+	visible := 0
+	for i, char := range doc.Characters {
+		if char.Visible {
+			visible++
+		}
+		if char.ID != id {
+			continue
+		}
+		if !char.Visible {
+			return -1
+		}
+		doc.Characters[i].Visible = false
+		return visible
+	}
+
+	return -1
+}
+
 // GenerateDelete generates a delete operation for the given position.
 func (doc *Document) GenerateDelete(position int) *Document {
 	char := IthVisible(*doc, position)
 	return doc.IntegrateDelete(char)
 }
 
+// ReplaceContent makes text the document's visible content by deleting every visible character
+// and inserting text's characters in their place.
+// Unlike replacing the document, the old characters stay as tombstones, so merging the result
+// into a peer's copy replaces the text there too, and concurrent edits anchored to them still integrate.
+func (doc *Document) ReplaceContent(text string) error {
+	for i := range doc.Characters {
+		if id := doc.Characters[i].ID; id != "start" && id != "end" {
+			doc.Characters[i].Visible = false
+		}
+	}
+
+	position := 1
+	for _, r := range text {
+		if _, err := doc.GenerateCharacter(position, string(r)); err != nil {
+			return err
+		}
+		position++
+	}
+	return nil
+}
+
 // Implement the CRDT interface
 
 func (doc *Document) Insert(position int, value string) (string, error) {
@@ -1,7 +1,9 @@
 package crdt
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -19,6 +21,37 @@ func TestDocument(t *testing.T) {
 	}
 }
 
+// Verify that documents keep their own site ID and clock.
+func TestDocument_IndependentClocks(t *testing.T) {
+	docA := New()
+	docA.SiteID = 1
+	docB := New()
+	docB.SiteID = 2
+
+	for i, value := range []string{"a", "b", "c"} {
+		if _, err := docA.Insert(i+1, value); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	if _, err := docB.Insert(1, "x"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	got := []int{docA.Clock, docB.Clock}
+	want := []int{3, 1}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got != want; diff = %v\n", cmp.Diff(got, want))
+	}
+
+	// docB's first character must use its own site and clock, unaffected by docA's inserts.
+	gotID := docB.Find(CharacterID(2, 1)).Value
+	if gotID != "x" {
+		t.Errorf("got != want; got = %v, expected = %v\n", gotID, "x")
+	}
+}
+
 // Verify that inserting a character results in the correct document.
 func TestInsert(t *testing.T) {
 	doc := New()
@@ -179,3 +212,489 @@ func TestLoad(t *testing.T) {
 		t.Errorf("got != want; diff = %v\n", cmp.Diff(got, want))
 	}
 }
+
+// Verify that DeleteByID removes exactly the target character and reports its visible position.
+func TestDeleteByID(t *testing.T) {
+	doc := New()
+	doc.SiteID = 1
+	for i, value := range []string{"a", "b", "c"} {
+		if _, err := doc.Insert(i+1, value); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	tests := []struct {
+		description  string
+		id           string
+		wantPosition int
+		wantContent  string
+	}{
+		{"delete middle", CharacterID(1, 2), 2, "ac"},
+		{"delete already deleted", CharacterID(1, 2), -1, "ac"},
+		{"delete unknown", CharacterID(9, 9), -1, "ac"},
+		{"delete last", CharacterID(1, 3), 2, "a"},
+	}
+
+	for _, tc := range tests {
+		gotPosition := doc.DeleteByID(tc.id)
+		if gotPosition != tc.wantPosition {
+			t.Errorf("(%s) got != want; got = %v, expected = %v\n", tc.description, gotPosition, tc.wantPosition)
+		}
+
+		gotContent := Content(doc)
+		if gotContent != tc.wantContent {
+			t.Errorf("(%s) got != want; got = %v, expected = %v\n", tc.description, gotContent, tc.wantContent)
+		}
+	}
+}
+
+// Verify that characters of sites whose site and clock digits run together keep distinct IDs:
+// site 1 at clock 11 and site 11 at clock 1 once both got the ID "111".
+func TestCharacterID_SitesDontCollide(t *testing.T) {
+	siteA := New()
+	siteA.SiteID = 1
+	siteB := New()
+	siteB.SiteID = 11
+
+	for i := 0; i < 11; i++ {
+		char, err := siteA.GenerateCharacter(i+1, "a")
+		if err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+		if _, err := siteB.IntegrateRemoteInsert(char); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	char, err := siteB.GenerateCharacter(12, "b")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if last := IthVisible(siteA, 11).ID; char.ID == last {
+		t.Fatalf("site 11's first character has the ID %q of site 1's eleventh", char.ID)
+	}
+	if _, err := siteA.IntegrateRemoteInsert(char); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if gotA, gotB := Content(siteA), Content(siteB); gotA != gotB || gotA != strings.Repeat("a", 11)+"b" {
+		t.Errorf("sites diverged: got %q and %q, expected both %q", gotA, gotB, strings.Repeat("a", 11)+"b")
+	}
+
+	// Deleting site 11's character by ID leaves site 1's alone.
+	if got := siteA.DeleteByID(char.ID); got != 12 {
+		t.Errorf("deleted position %d, expected 12", got)
+	}
+	if got := Content(siteA); got != strings.Repeat("a", 11) {
+		t.Errorf("got %q after the delete, expected only site 1's characters", got)
+	}
+}
+
+// Verify that concurrent deletes and inserts converge when deletes target character IDs.
+func TestDeleteByID_Convergence(t *testing.T) {
+	siteA := New()
+	siteA.SiteID = 1
+	for i, value := range []string{"a", "b", "c"} {
+		if _, err := siteA.Insert(i+1, value); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	// Site B starts from a synced copy of site A's document.
+	siteB := Document{Characters: append([]Character(nil), siteA.Characters...), SiteID: 2}
+
+	// Concurrently: B inserts "x" at the start while A deletes "b".
+	if _, err := siteB.Insert(1, "x"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	inserted := IthVisible(siteB, 1)
+
+	deleted := IthVisible(siteA, 2)
+	siteA.DeleteByID(deleted.ID)
+
+	// Exchange operations. Deleting position 2 on B would remove "a" instead of "b".
+	siteB.DeleteByID(deleted.ID)
+	if _, err := siteA.IntegrateInsert(inserted, siteA.Find(inserted.IDPrevious), siteA.Find(inserted.IDNext)); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	got := []string{Content(siteA), Content(siteB)}
+	want := []string{"xac", "xac"}
+
+	if !cmp.Equal(got, want) {
+		t.Errorf("got != want; diff = %v\n", cmp.Diff(got, want))
+	}
+}
+
+// Verify that interleaved concurrent inserts converge identically on all sites,
+// whatever order the remote characters are integrated in.
+func TestIntegrateRemoteInsert_Convergence(t *testing.T) {
+	base := New()
+	base.SiteID = 9
+	for i, value := range []string{"a", "b"} {
+		if _, err := base.Insert(i+1, value); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	// Every site starts from the same synced document.
+	sites := make([]Document, 3)
+	for i := range sites {
+		sites[i] = Document{Characters: append([]Character(nil), base.Characters...), SiteID: i + 1}
+	}
+
+	// Concurrent edits: each site types at the same spot between "a" and "b",
+	// and the last site also types at the start.
+	edits := [][]struct {
+		position int
+		value    string
+	}{
+		{{2, "x"}, {3, "y"}},
+		{{2, "1"}, {3, "2"}, {4, "3"}},
+		{{2, "p"}, {1, "q"}},
+	}
+
+	generated := make([][]Character, len(sites))
+	for i, siteEdits := range edits {
+		for _, edit := range siteEdits {
+			char, err := sites[i].GenerateCharacter(edit.position, edit.value)
+			if err != nil {
+				t.Fatalf("error: %v\n", err)
+			}
+			generated[i] = append(generated[i], char)
+		}
+	}
+
+	// Deliver remote characters in a different site order on each site,
+	// keeping each site's own characters in the order they were generated.
+	orders := [][]int{{1, 2}, {2, 0}, {0, 1}}
+	for i, order := range orders {
+		for _, from := range order {
+			for _, char := range generated[from] {
+				if _, err := sites[i].IntegrateRemoteInsert(char); err != nil {
+					t.Fatalf("error: %v\n", err)
+				}
+			}
+		}
+	}
+
+	want := Content(sites[0])
+	for i := 1; i < len(sites); i++ {
+		if got := Content(sites[i]); got != want {
+			t.Errorf("site %d diverged; got = %q, expected = %q\n", i+1, got, want)
+		}
+	}
+
+	// Each site's own run of characters must stay contiguous and in order.
+	for _, run := range []string{"xy", "123"} {
+		if !strings.Contains(want, run) {
+			t.Errorf("content %q lost the order of %q\n", want, run)
+		}
+	}
+
+	// Integrating the same character again must be a no-op.
+	if _, err := sites[0].IntegrateRemoteInsert(generated[1][0]); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if got := Content(sites[0]); got != want {
+		t.Errorf("re-integration changed content; got = %q, expected = %q\n", got, want)
+	}
+}
+
+// mergeReplicas returns two replicas of a shared document, each with concurrent offline edits.
+func mergeReplicas(t *testing.T) (Document, Document) {
+	t.Helper()
+
+	base := New()
+	base.SiteID = 9
+	for i, value := range []string{"a", "b", "c"} {
+		if _, err := base.Insert(i+1, value); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	docA := Document{Characters: append([]Character(nil), base.Characters...), SiteID: 1}
+	docB := Document{Characters: append([]Character(nil), base.Characters...), SiteID: 2}
+
+	// Both sites type between "a" and "b", and B also types at the start.
+	for _, edit := range []struct {
+		doc      *Document
+		position int
+		value    string
+	}{
+		{&docA, 2, "x"}, {&docA, 3, "y"}, {&docB, 2, "1"}, {&docB, 3, "2"}, {&docB, 1, "0"},
+	} {
+		if _, err := edit.doc.Insert(edit.position, edit.value); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	// Each site deletes a different shared character: "c" on A ("axybc"), "b" on B ("0a12bc").
+	docA.Delete(5)
+	docB.Delete(5)
+
+	return docA, docB
+}
+
+// Verify that merging in either order produces identical documents.
+func TestMerge_Commutative(t *testing.T) {
+	docA, docB := mergeReplicas(t)
+	otherA, otherB := docA, docB
+	otherA.Characters = append([]Character(nil), docA.Characters...)
+	otherB.Characters = append([]Character(nil), docB.Characters...)
+
+	if _, err := docA.Merge(otherB); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if _, err := docB.Merge(otherA); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	if !cmp.Equal(docA.Characters, docB.Characters) {
+		t.Errorf("merge order changed the result; diff = %v\n", cmp.Diff(docA.Characters, docB.Characters))
+	}
+
+	// Every insert survives and both deletes win.
+	got := Content(docA)
+	if len(got) != 6 || !strings.HasPrefix(got, "0a") || strings.ContainsAny(got, "bc") {
+		t.Errorf("unexpected merged content %q\n", got)
+	}
+}
+
+// Verify that merging a document with itself, or merging the same document twice, changes nothing.
+func TestMerge_Idempotent(t *testing.T) {
+	docA, docB := mergeReplicas(t)
+
+	self := Document{Characters: append([]Character(nil), docA.Characters...)}
+	want := append([]Character(nil), docA.Characters...)
+	if _, err := docA.Merge(self); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if !cmp.Equal(docA.Characters, want) {
+		t.Errorf("merging with itself changed the document; diff = %v\n", cmp.Diff(docA.Characters, want))
+	}
+
+	if _, err := docA.Merge(docB); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	want = append([]Character(nil), docA.Characters...)
+	if _, err := docA.Merge(docB); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if !cmp.Equal(docA.Characters, want) {
+		t.Errorf("merging twice changed the document; diff = %v\n", cmp.Diff(docA.Characters, want))
+	}
+}
+
+// Verify that inserts at the document's boundaries land in the right place and use the sentinels as neighbors.
+func TestGenerateCharacter_Boundaries(t *testing.T) {
+	// loaded returns a document loaded from a file containing text.
+	loaded := func(text string) Document {
+		t.Helper()
+
+		path := t.TempDir() + "/doc.txt"
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+		doc, err := Load(path)
+		if err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+		return doc
+	}
+
+	// withDeletedTail returns "abc" with "c" deleted, so the last visible character isn't the last character.
+	withDeletedTail := func() Document {
+		doc := New()
+		for i, value := range []string{"a", "b", "c"} {
+			if _, err := doc.Insert(i+1, value); err != nil {
+				t.Fatalf("error: %v\n", err)
+			}
+		}
+		doc.Delete(3)
+		return doc
+	}
+
+	// allDeleted returns "ab" with both characters deleted, leaving only tombstones between the sentinels.
+	allDeleted := func() Document {
+		doc := loaded("ab")
+		doc.Delete(1)
+		doc.Delete(1)
+		return doc
+	}
+
+	tests := []struct {
+		description string
+		doc         Document
+		position    int
+		want        string
+		wantPrev    string
+		wantNext    string
+	}{
+		{"first character of an empty document", New(), 1, "x", "start", "end"},
+		{"start of a document", loaded("ab"), 1, "xab", "start", ""},
+		{"end of a document", loaded("ab"), 3, "abx", "", "end"},
+		{"end of a loaded multi-line document", loaded("ab\ncd"), 6, "ab\ncdx", "", "end"},
+		{"end of a loaded non-ASCII document", loaded("héllo"), 6, "héllox", "", "end"},
+		{"middle of a loaded non-ASCII document", loaded("日本\n語"), 2, "日x本\n語", "", ""},
+		{"end of a document with deleted characters", withDeletedTail(), 3, "abx", "", "end"},
+		{"document with only deleted characters", allDeleted(), 1, "x", "start", "end"},
+	}
+
+	for _, tc := range tests {
+		tc.doc.SiteID = 5
+		char, err := tc.doc.GenerateCharacter(tc.position, "x")
+		if err != nil {
+			t.Fatalf("(%s) error: %v\n", tc.description, err)
+		}
+
+		if got := Content(tc.doc); got != tc.want {
+			t.Errorf("(%s) got != want; got = %q, expected = %q\n", tc.description, got, tc.want)
+		}
+		if tc.wantPrev != "" && char.OriginPrevious != tc.wantPrev {
+			t.Errorf("(%s) got != want; OriginPrevious = %q, expected = %q\n", tc.description, char.OriginPrevious, tc.wantPrev)
+		}
+		if tc.wantNext != "" && char.OriginNext != tc.wantNext {
+			t.Errorf("(%s) got != want; OriginNext = %q, expected = %q\n", tc.description, char.OriginNext, tc.wantNext)
+		}
+	}
+}
+
+// Verify that sites typing into a document whose characters were all deleted converge,
+// and that lookups in it find nothing rather than a tombstone.
+func TestGenerateCharacter_AfterDeletingEverything(t *testing.T) {
+	a, b := New(), New()
+	a.SiteID, b.SiteID = 1, 2
+	for i, value := range []string{"a", "b", "c"} {
+		char, err := a.GenerateCharacter(i+1, value)
+		if err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+		if _, err := b.IntegrateRemoteInsert(char); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+	for range 3 {
+		char := IthVisible(a, 1)
+		a.IntegrateDelete(char)
+		b.DeleteByID(char.ID)
+	}
+
+	if got := IthVisible(a, 1); got.ID != "-1" {
+		t.Errorf("got != want; IthVisible = %q, expected no character\n", got.ID)
+	}
+	if _, err := a.GenerateCharacter(2, "x"); !errors.Is(err, ErrPositionOutOfBounds) {
+		t.Errorf("got != want; err = %v, expected = %v\n", err, ErrPositionOutOfBounds)
+	}
+
+	// Both sites type the first character of the emptied document at once.
+	x, err := a.GenerateCharacter(1, "x")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	y, err := b.GenerateCharacter(1, "y")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if _, err := a.IntegrateRemoteInsert(y); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if _, err := b.IntegrateRemoteInsert(x); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	if got, want := Content(a), Content(b); got != want || len(got) != 2 {
+		t.Errorf("got != want; a = %q, b = %q, expected both characters on both sites\n", got, want)
+	}
+	if err := a.Validate(); err != nil {
+		t.Errorf("error: %v\n", err)
+	}
+}
+
+// Verify that inserts outside the document are rejected without changing it or its clock.
+func TestGenerateCharacter_OutOfBounds(t *testing.T) {
+	doc := New()
+	if _, err := doc.Insert(1, "a"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	for _, position := range []int{-1, 0, 3} {
+		if _, err := doc.GenerateCharacter(position, "x"); !errors.Is(err, ErrPositionOutOfBounds) {
+			t.Errorf("(position %d) got != want; err = %v, expected = %v\n", position, err, ErrPositionOutOfBounds)
+		}
+	}
+
+	if got := Content(doc); got != "a" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "a")
+	}
+	if doc.Clock != 1 {
+		t.Errorf("got != want; Clock = %v, expected = %v\n", doc.Clock, 1)
+	}
+}
+
+// Verify that Validate requires the start and end characters at the document's bounds.
+func TestValidate(t *testing.T) {
+	char := Character{ID: "11", Visible: true, Value: "a"}
+
+	tests := []struct {
+		description string
+		chars       []Character
+		want        error
+	}{
+		{"new document", New().Characters, nil},
+		{"with characters", []Character{StartChar, char, EndChar}, nil},
+		{"nil characters", nil, ErrMissingSentinels},
+		{"only characters", []Character{char}, ErrMissingSentinels},
+		{"missing start", []Character{char, EndChar}, ErrMissingSentinels},
+		{"missing end", []Character{StartChar, char}, ErrMissingSentinels},
+		{"swapped sentinels", []Character{EndChar, char, StartChar}, ErrMissingSentinels},
+	}
+
+	for _, tc := range tests {
+		doc := Document{Characters: tc.chars}
+		if got := doc.Validate(); got != tc.want {
+			t.Errorf("(%s) got != want; got = %v, expected = %v\n", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestReplaceContent(t *testing.T) {
+	base := New()
+	base.SiteID = 9
+	for i, value := range []string{"a", "b"} {
+		if _, err := base.Insert(i+1, value); err != nil {
+			t.Fatalf("error: %v\n", err)
+		}
+	}
+
+	loader := Document{Characters: append([]Character(nil), base.Characters...), SiteID: 1}
+	peer := Document{Characters: append([]Character(nil), base.Characters...), SiteID: 2}
+
+	// The peer types between "a" and "b" while the loader replaces the text.
+	char, err := peer.GenerateCharacter(2, "x")
+	if err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if err := loader.ReplaceContent("new"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if got := Content(loader); got != "new" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "new")
+	}
+
+	// The peer's edit still integrates after the load, and merging the loaded document
+	// replaces the old text on the peer too.
+	if _, err := loader.IntegrateRemoteInsert(char); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	if _, err := peer.Merge(loader); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+
+	got, want := Content(loader), Content(peer)
+	if got != want {
+		t.Errorf("replicas diverged: loader = %q, peer = %q\n", got, want)
+	}
+	if strings.ReplaceAll(got, "x", "") != "new" || !strings.Contains(got, "x") {
+		t.Errorf("got %q, expected \"new\" plus the concurrent \"x\"", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// docResponse is the JSON representation of a room's document.
+type docResponse struct {
+	Room    string `json:"room"`
+	Content string `json:"content"`
+	Length  int    `json:"length"`
+}
+
+// roomStatus describes a room in the admin status report.
+type roomStatus struct {
+	Name    string   `json:"name"`
+	Clients int      `json:"clients"`
+	Users   []string `json:"users"`
+}
+
+// statusResponse is the JSON representation of the server's state.
+type statusResponse struct {
+	Uptime        string       `json:"uptime"`
+	UptimeSeconds int64        `json:"uptimeSeconds"`
+	Rooms         []roomStatus `json:"rooms"`
+}
+
+// handleDoc serves the current content of a room's document.
+// The content is returned as plain text, or as JSON when format=json is requested.
+func handleDoc(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	room, ok := rooms.lookup(roomName(r))
+	if !ok {
+		http.Error(w, "room not found", http.StatusNotFound)
+		return
+	}
+
+	content := room.content()
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		resp := docResponse{Room: room.name, Content: content, Length: len([]rune(content))}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte(content))
+}
+
+// authorized checks the request's token against apiToken.
+func authorized(r *http.Request) bool {
+	return hasToken(r, apiToken)
+}
+
+// hasToken reports whether the request presents the wanted token; an empty want accepts any request.
+// The token may be given as a bearer token or via the "token" query parameter.
+func hasToken(r *http.Request, want string) bool {
+	if want == "" {
+		return true
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// handleStatus reports the active rooms and their clients for operators.
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	uptime := time.Since(startTime)
+	resp := statusResponse{
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Rooms:         []roomStatus{},
+	}
+
+	for _, room := range rooms.snapshot() {
+		users := room.clients.snapshot()
+		resp.Rooms = append(resp.Rooms, roomStatus{Name: room.name, Clients: len(users), Users: users})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
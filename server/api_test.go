@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// dialTestClient connects a WebSocket client to the test server in the given room.
+func dialTestClient(t *testing.T, srv *httptest.Server, room string) *websocket.Conn {
+	t.Helper()
+	return dialTestClientQuery(t, srv, url.Values{"room": {room}})
+}
+
+// dialTestClientQuery connects a WebSocket client to the test server with the given query parameters.
+func dialTestClientQuery(t *testing.T, srv *httptest.Server, query url.Values) *websocket.Conn {
+	t.Helper()
+
+	u := url.URL{Scheme: "ws", Host: strings.TrimPrefix(srv.URL, "http://"), Path: "/", RawQuery: query.Encode()}
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		t.Fatalf("dial error: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err := conn.WriteJSON(commons.Message{Type: commons.HelloMessage, Text: fmt.Sprint(commons.ProtocolVersion)}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	readUntil(t, conn, commons.WelcomeMessage)
+
+	return conn
+}
+
+// testRoom returns a room name unique to this test run, since rooms outlive individual tests.
+func testRoom(t *testing.T) string {
+	return t.Name() + "-" + uuid.NewString()
+}
+
+// getDoc fetches a room's document through the HTTP API.
+func getDoc(t *testing.T, srv *httptest.Server, query string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + "/doc?" + query)
+	if err != nil {
+		t.Fatalf("request error: %v\n", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read error: %v\n", err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestHandleDoc_ReflectsOperations(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	conn := dialTestClient(t, srv, room)
+
+	ops := []commons.Operation{
+		{Type: "insert", Position: 1, Value: "h"},
+		{Type: "insert", Position: 2, Value: "i"},
+		{Type: "insert", Position: 3, Value: "!"},
+		{Type: "delete", Position: 3},
+	}
+	for _, op := range ops {
+		if err := conn.WriteJSON(commons.Message{Type: "operation", Operation: op}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+
+	want := "hi"
+
+	// Operations are applied asynchronously, so poll until they show up.
+	var got string
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var status int
+		status, got = getDoc(t, srv, "room="+room)
+		if status == http.StatusOK && got == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("got != want; got = %q, expected = %q\n", got, want)
+}
+
+func TestHandleDoc_Errors(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	_ = dialTestClient(t, srv, room)
+
+	oldToken := apiToken
+	apiToken = "secret"
+	defer func() { apiToken = oldToken }()
+
+	tests := []struct {
+		description string
+		query       string
+		want        int
+	}{
+		{"missing token", "room=" + room, http.StatusUnauthorized},
+		{"wrong token", "room=" + room + "&token=nope", http.StatusUnauthorized},
+		{"correct token", "room=" + room + "&token=secret", http.StatusOK},
+		{"unknown room", "room=missing&token=secret", http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		got, _ := getDoc(t, srv, tc.query)
+		if got != tc.want {
+			t.Errorf("(%s) got != want; got = %v, expected = %v\n", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestHandleStatus_CountsClients(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	for _, name := range []string{"bob", "alice"} {
+		conn := dialTestClient(t, srv, room)
+		if err := conn.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: name}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+
+	want := roomStatus{Name: room, Clients: 2, Users: []string{"alice", "bob"}}
+
+	// Joins are processed asynchronously, so poll until they show up.
+	var got roomStatus
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := http.Get(srv.URL + "/admin/status")
+		if err != nil {
+			t.Fatalf("request error: %v\n", err)
+		}
+
+		var status statusResponse
+		err = json.NewDecoder(resp.Body).Decode(&status)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("decode error: %v\n", err)
+		}
+
+		for _, r := range status.Rooms {
+			if r.Name == room {
+				got = r
+			}
+		}
+		if cmp.Equal(got, want) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Errorf("got != want; diff = %v\n", cmp.Diff(got, want))
+}
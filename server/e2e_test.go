@@ -0,0 +1,244 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/gorilla/websocket"
+)
+
+// e2eClient is a headless editor client for end-to-end tests.
+// It keeps its own replica of the document and applies what the server relays the way the editor does.
+type e2eClient struct {
+	t    *testing.T
+	name string
+	conn *websocket.Conn
+	doc  crdt.Document
+}
+
+// startTestServer starts the server on a random port.
+func startTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(newMux())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// connectTestClient joins a room as name and waits for the server to assign a site ID.
+func connectTestClient(t *testing.T, srv *httptest.Server, room, name string) *e2eClient {
+	t.Helper()
+
+	c := &e2eClient{t: t, name: name, conn: dialTestClient(t, srv, room), doc: crdt.New()}
+	if err := c.conn.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: name, Text: "has joined the session."}); err != nil {
+		t.Fatalf("%s: write error: %v\n", name, err)
+	}
+
+	siteID, err := strconv.Atoi(readUntil(t, c.conn, commons.SiteIDMessage).Text)
+	if err != nil {
+		t.Fatalf("%s: invalid site ID: %v\n", name, err)
+	}
+	c.doc.SiteID = siteID
+
+	return c
+}
+
+// insert types value at the given position and sends the operation.
+func (c *e2eClient) insert(position int, value string) {
+	c.t.Helper()
+
+	char, err := c.doc.GenerateCharacter(position, value)
+	if err != nil {
+		c.t.Fatalf("%s: insert error: %v\n", c.name, err)
+	}
+	c.send(commons.Message{Type: "operation", Operation: commons.Operation{Type: "insert", Position: position, Value: value, Character: &char}})
+}
+
+// typeText types text starting at the given position.
+func (c *e2eClient) typeText(position int, text string) {
+	c.t.Helper()
+
+	for i, r := range []rune(text) {
+		c.insert(position+i, string(r))
+	}
+}
+
+// delete removes the character at the given position and sends the operation.
+func (c *e2eClient) delete(position int) {
+	c.t.Helper()
+
+	char := crdt.IthVisible(c.doc, position)
+	if char.ID == "-1" {
+		c.t.Fatalf("%s: no character at position %d\n", c.name, position)
+	}
+	c.doc.IntegrateDelete(char)
+	c.send(commons.Message{Type: "operation", Operation: commons.Operation{Type: "delete", Position: position, ID: char.ID}})
+}
+
+// send writes a message to the server.
+func (c *e2eClient) send(msg commons.Message) {
+	c.t.Helper()
+
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("%s: write error: %v\n", c.name, err)
+	}
+}
+
+// read reads the next message from the server, failing the test if none arrives in time.
+func (c *e2eClient) read() commons.Message {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	var msg commons.Message
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		c.t.Fatalf("%s: read error: %v\n", c.name, err)
+	}
+	return msg
+}
+
+// apply updates the client's replica with a message from the server.
+func (c *e2eClient) apply(msg commons.Message) {
+	c.t.Helper()
+
+	switch msg.Type {
+	case commons.DocReqMessage:
+		// Answer a joining peer's request for the document.
+		c.send(commons.Message{Type: commons.DocSyncMessage, Document: &c.doc, ID: msg.ID})
+	case commons.DocSyncMessage:
+		if msg.Document != nil {
+			if _, err := c.doc.Merge(*msg.Document); err != nil {
+				c.t.Errorf("%s: merge error: %v\n", c.name, err)
+			}
+		}
+	case "operation":
+		op := msg.Operation
+		switch op.Type {
+		case "insert":
+			if op.Character == nil || c.doc.Contains(op.Character.ID) {
+				return
+			}
+			if _, err := c.doc.IntegrateRemoteInsert(*op.Character); err != nil {
+				c.t.Errorf("%s: failed to integrate %q: %v\n", c.name, op.Value, err)
+			}
+		case "delete":
+			c.doc.DeleteByID(op.ID)
+		}
+	}
+}
+
+// waitForContent applies messages from the server until the replica's content is want.
+func (c *e2eClient) waitForContent(want string) {
+	c.t.Helper()
+
+	for got := crdt.Content(c.doc); got != want; got = crdt.Content(c.doc) {
+		c.apply(c.read())
+	}
+}
+
+func TestE2E_OperationsReachPeers(t *testing.T) {
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	alice := connectTestClient(t, srv, room, "alice")
+	bob := connectTestClient(t, srv, room, "bob")
+
+	alice.typeText(1, "hello")
+	bob.waitForContent("hello")
+
+	// Deletes find their character by ID, wherever it has moved to.
+	bob.insert(1, ">")
+	bob.delete(3)
+	alice.waitForContent(">hllo")
+
+	alice.typeText(6, "!")
+	bob.waitForContent(">hllo!")
+	waitForDoc(t, srv, room, ">hllo!")
+}
+
+func TestE2E_ConcurrentEditsConverge(t *testing.T) {
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	alice := connectTestClient(t, srv, room, "alice")
+	bob := connectTestClient(t, srv, room, "bob")
+
+	alice.typeText(1, "ac")
+	bob.waitForContent("ac")
+
+	// Both type between "a" and "c" before seeing each other's edits, and bob deletes "a".
+	alice.typeText(2, "xy")
+	bob.typeText(2, "12")
+	bob.delete(1)
+
+	// Wait until both replicas have all six characters, with "a" deleted.
+	for _, c := range []*e2eClient{alice, bob} {
+		for len(c.doc.Characters) != 8 || len([]rune(crdt.Content(c.doc))) != 5 {
+			c.apply(c.read())
+		}
+	}
+
+	if a, b := crdt.Content(alice.doc), crdt.Content(bob.doc); a != b {
+		t.Fatalf("replicas diverged: alice = %q, bob = %q\n", a, b)
+	}
+	waitForDoc(t, srv, room, crdt.Content(alice.doc))
+}
+
+func TestE2E_LateJoinerGetsDocument(t *testing.T) {
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	alice := connectTestClient(t, srv, room, "alice")
+	alice.typeText(1, "shared")
+	waitForDoc(t, srv, room, "shared")
+
+	carol := connectTestClient(t, srv, room, "carol")
+	carol.waitForContent("shared")
+
+	carol.typeText(7, "!")
+	alice.waitForContent("shared!")
+}
+
+func TestE2E_JoinWithUnresponsivePeer(t *testing.T) {
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	alice := connectTestClient(t, srv, room, "alice")
+	alice.typeText(1, "shared")
+	waitForDoc(t, srv, room, "shared")
+
+	// Alice stops reading, so she could never answer for the document; the server does.
+	bob := connectTestClient(t, srv, room, "bob")
+	bob.waitForContent("shared")
+
+	// So does a resync.
+	bob.doc = crdt.New()
+	bob.send(commons.Message{Type: commons.DocReqMessage})
+	bob.waitForContent("shared")
+}
+
+func TestE2E_StartFileReachesPeers(t *testing.T) {
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	bob := connectTestClient(t, srv, room, "bob")
+
+	// Alice starts with a file: once she has a site ID, its characters are hers, and she sends them to the room.
+	alice := connectTestClient(t, srv, room, "alice")
+	if err := alice.doc.ReplaceContent("ab"); err != nil {
+		t.Fatalf("error: %v\n", err)
+	}
+	alice.send(commons.Message{Type: commons.DocSyncMessage, Document: &alice.doc})
+	bob.waitForContent("ab")
+
+	// Her edits anchored to the file's characters then integrate everywhere.
+	alice.insert(3, "c")
+	bob.waitForContent("abc")
+	waitForDoc(t, srv, room, "abc")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+// handshakeTimeout bounds how long a new connection may take to say hello.
+var handshakeTimeout = 10 * time.Second
+
+// handshake waits for a client's hello and answers with the protocol version to speak, returning it.
+// Clients that don't say hello first, or only speak unsupported versions, are told why and disconnected.
+func handshake(conn *websocket.Conn) (int, error) {
+	conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var hello commons.Message
+	if err := conn.ReadJSON(&hello); err != nil {
+		return 0, fmt.Errorf("reading hello: %w", err)
+	}
+	if hello.Type != commons.HelloMessage {
+		return 0, rejectConn(conn, fmt.Sprintf("expected a %q message first: upgrade the client", commons.HelloMessage))
+	}
+
+	version, err := commons.NegotiateProtocol(hello.Text)
+	if err != nil {
+		return 0, rejectConn(conn, err.Error())
+	}
+
+	welcome := commons.Message{Type: commons.WelcomeMessage, Text: fmt.Sprint(version)}
+	if err := conn.WriteJSON(welcome); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// rejectConn closes the connection with reason, which the client can show, and returns it as an error.
+func rejectConn(conn *websocket.Conn, reason string) error {
+	msg := websocket.FormatCloseMessage(websocket.CloseProtocolError, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); err != nil {
+		logger.Errorf("failed to send rejection: %s", err)
+	}
+	return errors.New(reason)
+}
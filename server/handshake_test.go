@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHandshake(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	tests := []struct {
+		description string
+		hello       commons.Message
+		wantReason  string
+	}{
+		{"negotiated", commons.Message{Type: commons.HelloMessage, Text: "1"}, ""},
+		{"newer client", commons.Message{Type: commons.HelloMessage, Text: "7"}, ""},
+		{"unsupported version", commons.Message{Type: commons.HelloMessage, Text: "0"}, "no longer supported"},
+		{"no hello", commons.Message{Type: commons.JoinMessage, Username: "old", Text: "has joined the session."}, "expected a \"hello\" message first"},
+	}
+
+	for _, tc := range tests {
+		u := url.URL{Scheme: "ws", Host: strings.TrimPrefix(srv.URL, "http://"), Path: "/", RawQuery: url.Values{"room": {testRoom(t)}}.Encode()}
+		conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+		if err != nil {
+			t.Fatalf("(%s) dial error: %v\n", tc.description, err)
+		}
+		if err := conn.WriteJSON(tc.hello); err != nil {
+			t.Fatalf("(%s) write error: %v\n", tc.description, err)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var msg commons.Message
+		err = conn.ReadJSON(&msg)
+		conn.Close()
+
+		if tc.wantReason == "" {
+			if err != nil || msg.Type != commons.WelcomeMessage || msg.Text != "1" {
+				t.Errorf("(%s) got %+v (err %v), expected a welcome to version 1\n", tc.description, msg, err)
+			}
+			continue
+		}
+
+		// Rejected clients are disconnected with the reason.
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseProtocolError || !strings.Contains(closeErr.Text, tc.wantReason) {
+			t.Errorf("(%s) got %+v (err %v), expected to be closed with %q\n", tc.description, msg, err, tc.wantReason)
+		}
+	}
+}
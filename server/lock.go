@@ -0,0 +1,94 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/google/uuid"
+)
+
+// lockIdleTimeout is how long the write lock holder may stay idle before another client can take the lock.
+var lockIdleTimeout = 30 * time.Second
+
+// writeLock tracks which client may edit a room in single-writer mode.
+type writeLock struct {
+	// Client holding the lock, or uuid.Nil if it's free.
+	holder uuid.UUID
+
+	// Username of the holder, announced to clients.
+	name string
+
+	// Time of the holder's last edit or acquisition.
+	lastActive time.Time
+
+	// Guards the lock.
+	mu sync.Mutex
+}
+
+// acquireLock gives the write lock to a client if it's free, already theirs, or its holder has gone idle.
+// It reports whether the client holds the lock, and whether the holder changed.
+func (r *Room) acquireLock(id uuid.UUID) (acquired, changed bool) {
+	r.lock.mu.Lock()
+	defer r.lock.mu.Unlock()
+
+	now := time.Now()
+	if r.lock.holder != uuid.Nil && r.lock.holder != id && now.Sub(r.lock.lastActive) < lockIdleTimeout {
+		return false, false
+	}
+
+	changed = r.lock.holder != id
+	if changed {
+		r.lock.holder = id
+		r.lock.name = r.clients.username(id)
+		logger.Infof("Room %s: write lock taken by %s", r.name, r.lock.name)
+	}
+	r.lock.lastActive = now
+
+	return true, changed
+}
+
+// releaseLock frees the write lock if the client holds it, reporting whether it did.
+func (r *Room) releaseLock(id uuid.UUID) bool {
+	r.lock.mu.Lock()
+	defer r.lock.mu.Unlock()
+
+	if r.lock.holder == uuid.Nil || r.lock.holder != id {
+		return false
+	}
+
+	logger.Infof("Room %s: write lock released by %s", r.name, r.lock.name)
+	r.lock.holder = uuid.Nil
+	r.lock.name = ""
+	return true
+}
+
+// lockMsg announces the current write lock holder.
+func (r *Room) lockMsg() commons.Message {
+	r.lock.mu.Lock()
+	defer r.lock.mu.Unlock()
+
+	return commons.Message{Type: commons.LockMessage, Text: r.lock.name, ID: r.lock.holder}
+}
+
+// handleLock processes a client's request to acquire or release the write lock.
+func (r *Room) handleLock(msg commons.Message) {
+	if !singleWriter {
+		return
+	}
+
+	switch msg.Text {
+	case "acquire":
+		acquired, changed := r.acquireLock(msg.ID)
+		if changed {
+			r.clients.broadcastAll(r.lockMsg())
+		} else if !acquired {
+			r.clients.broadcastOne(r.lockMsg(), msg.ID)
+		}
+	case "release":
+		if r.releaseLock(msg.ID) {
+			r.clients.broadcastAll(r.lockMsg())
+		}
+	}
+}
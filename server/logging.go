@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fatih/color"
+	"github.com/sirupsen/logrus"
+)
+
+// logger is the server's log; setupLogger configures its level, format and output.
+var logger = newLogger()
+
+// LogConfig configures the server's logging.
+type LogConfig struct {
+	// Level is the minimum logrus level that gets logged.
+	Level string
+
+	// Format is "text", colored on terminals, or "json", one object per line.
+	Format string
+
+	// File, if set, receives the logs instead of stdout.
+	File string
+
+	// MaxSize is how many bytes File may grow to before it's rotated; 0 disables rotation.
+	MaxSize int64
+
+	// Backups is how many rotated files to keep, as File.1 (newest) to File.N.
+	Backups int
+}
+
+// newLogger returns a logger printing colored text at info level to stdout, until setupLogger says otherwise.
+func newLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(os.Stdout)
+	l.SetFormatter(&colorFormatter{})
+	return l
+}
+
+// setupLogger configures l from conf. The returned writer, if any, is the log file and should be closed on exit.
+func setupLogger(l *logrus.Logger, conf LogConfig) (io.Closer, error) {
+	level, err := logrus.ParseLevel(conf.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	var formatter logrus.Formatter
+	switch conf.Format {
+	case "text":
+		formatter = &colorFormatter{}
+	case "json":
+		formatter = &logrus.JSONFormatter{}
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected text or json", conf.Format)
+	}
+
+	var out io.Writer = os.Stdout
+	var file *rotatingFile
+	if conf.File != "" {
+		if file, err = openRotatingFile(conf.File, conf.MaxSize, conf.Backups); err != nil {
+			return nil, err
+		}
+		out = file
+	}
+
+	l.SetLevel(level)
+	l.SetFormatter(formatter)
+	l.SetOutput(out)
+	if file == nil {
+		return nil, nil
+	}
+	return file, nil
+}
+
+// colorFormatter prints just the message, colored by its level when color is enabled, i.e. on terminals.
+type colorFormatter struct{}
+
+// levelColors are the colors messages are printed in, by level.
+var levelColors = map[logrus.Level]color.Attribute{
+	logrus.PanicLevel: color.FgRed,
+	logrus.FatalLevel: color.FgRed,
+	logrus.ErrorLevel: color.FgRed,
+	logrus.WarnLevel:  color.FgYellow,
+	logrus.InfoLevel:  color.FgGreen,
+	logrus.DebugLevel: color.FgBlue,
+	logrus.TraceLevel: color.FgBlue,
+}
+
+func (f *colorFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	msg := strings.TrimSuffix(entry.Message, "\n")
+	return []byte(color.New(levelColors[entry.Level]).Sprint(msg) + "\n"), nil
+}
+
+// rotatingFile is a log file that's moved aside once it would grow past maxSize, keeping up to backups old ones.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+
+	// Guards file and size; logrus locks around writes too, but rotation must not race a write.
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// openRotatingFile opens path for appending, rotating it when it would exceed maxSize bytes.
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the log file for appending, noting its size.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) // skipcq: GSC-G302
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// An empty file is written to whatever the size, so an oversized entry still gets logged.
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts path.1 to path.2 and so on, dropping the oldest, moves the current file to path.1 and starts a new one.
+// Without backups, the current file is simply truncated.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	if r.backups > 0 {
+		for i := r.backups - 1; i > 0; i-- {
+			if err := os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSetupLogger_JSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	l := logrus.New()
+	file, err := setupLogger(l, LogConfig{Level: "warn", Format: "json", File: path})
+	if err != nil {
+		t.Fatalf("setup error: %v", err)
+	}
+	l.Infof("Room %s: loaded", "a")
+	l.Warnf("Room %s: out of sync", "b")
+	l.Errorf("Room %s: failed to save", "c")
+	file.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Each line is an object; messages below the level are left out.
+	var got []map[string]any
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(s.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid JSON %q: %v", s.Text(), err)
+		}
+		got = append(got, entry)
+	}
+	want := []struct{ level, msg string }{{"warning", "Room b: out of sync"}, {"error", "Room c: failed to save"}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, expected %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i]["level"] != w.level || got[i]["msg"] != w.msg || got[i]["time"] == nil {
+			t.Errorf("entry %d: got %v, expected %s %q with a time", i, got[i], w.level, w.msg)
+		}
+	}
+}
+
+func TestSetupLogger_Invalid(t *testing.T) {
+	if _, err := setupLogger(logrus.New(), LogConfig{Level: "loud", Format: "json"}); err == nil {
+		t.Errorf("accepted an unknown level")
+	}
+	if _, err := setupLogger(logrus.New(), LogConfig{Level: "info", Format: "xml"}); err == nil {
+		t.Errorf("accepted an unknown format")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.log")
+	r, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+	}
+	r.Close()
+
+	// Each line would overflow the one before it, and only two old files are kept.
+	want := map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"}
+	for name, content := range want {
+		data, err := os.ReadFile(name)
+		if err != nil || string(data) != content {
+			t.Errorf("%s: got %q (err %v), expected %q", filepath.Base(name), data, err, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("kept more than two old files")
+	}
+}
+
+func TestColorFormatter(t *testing.T) {
+	entry := &logrus.Entry{Level: logrus.InfoLevel, Message: "usernames: a,b\n"}
+	out, err := (&colorFormatter{}).Format(entry)
+	if err != nil || !strings.Contains(string(out), "usernames: a,b") || strings.Count(string(out), "\n") != 1 {
+		t.Errorf("got %q (err %v), expected the message on one line", out, err)
+	}
+}
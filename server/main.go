@@ -1,16 +1,19 @@
 package main
 
 import (
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"math/rand/v2"
 	"net/http"
+	"os"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
 
 	"text-editor/commons"
 
-	"github.com/fatih/color"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -30,21 +33,29 @@ type Clients struct {
 	readRequests chan readRequest
 
 	// Channel for adding new clients.
-	addRequests chan *client
+	addRequests chan addRequest
 
 	// Channel for updating client usernames.
 	nameUpdateRequests chan nameUpdate
+
+	// Channel the user list is published on.
+	syncChan chan commons.Message
+
+	// Closed to stop handle once the room is unloaded.
+	done chan struct{}
 }
 
-// NewClients initializes and returns a Clients instance.
-func NewClients() *Clients {
+// NewClients initializes and returns a Clients instance that publishes user lists on syncChan.
+func NewClients(syncChan chan commons.Message) *Clients {
 	return &Clients{
 		list:               make(map[uuid.UUID]*client),
 		mu:                 sync.RWMutex{},
 		deleteRequests:     make(chan deleteRequest),
 		readRequests:       make(chan readRequest, 10000),
-		addRequests:        make(chan *client),
+		addRequests:        make(chan addRequest),
 		nameUpdateRequests: make(chan nameUpdate),
+		syncChan:           syncChan,
+		done:               make(chan struct{}),
 	}
 }
 
@@ -54,113 +65,183 @@ type client struct {
 	SiteID string
 	id     uuid.UUID
 
-	// Protects against concurrent WebSocket writes.
-	writeMu sync.Mutex
+	// Room the client is editing in.
+	room *Room
+
+	// outbox queues the messages writeLoop writes to Conn, so a slow connection doesn't hold up broadcasts to others.
+	outbox chan interface{}
+
+	// closed is closed once the client is removed, ending writeLoop.
+	closed chan struct{}
+
+	// stalled is signalled by send when outbox is full, for writeLoop to drop the client.
+	// Dropping it there keeps broadcasters, such as handleSync, from waiting on delete.
+	stalled chan struct{}
 
 	// Guards client data modifications.
 	mu sync.Mutex
 
+	// Whether the client owns its room, and may change other clients' permissions.
+	owner bool
+
+	// Whether the client's operations are accepted.
+	canWrite bool
+
+	// Whether the client gets its own operations back, acknowledging them.
+	echo bool
+
+	// Version of the message protocol agreed with the client.
+	protocol int
+
 	Username string
 }
 
 var (
-	// Unique identifier for each client, increments monotonically.
-	siteID = 0
-
-	// Protects siteID increments.
-	mu sync.Mutex
-
 	// Converts HTTP connections to WebSocket.
 	upgrader = websocket.Upgrader{}
 
-	// Buffers client messages.
-	messageChan = make(chan commons.Message)
+	// Manages all active rooms.
+	rooms = NewRooms()
+
+	// Token required for HTTP API requests; empty disables the check.
+	apiToken string
+
+	// Token required to connect as an editor client; empty disables the check.
+	connToken string
+
+	// Token that makes a client an owner of its room; empty disables it.
+	ownerToken string
+
+	// Whether clients other than room owners join read-only.
+	readOnlyGuests bool
 
-	// Buffers document synchronization messages.
-	syncChan = make(chan commons.Message)
+	// Whether only the holder of a room's write lock may edit.
+	singleWriter bool
 
-	// Manages all connected clients.
-	clients = NewClients()
+	// Time the server started, reported as uptime.
+	startTime = time.Now()
 )
 
 func main() {
 	addr := flag.String("addr", ":8080", "Server's network address")
+	showVersion := flag.Bool("version", false, "Print version information and exit")
+	flag.StringVar(&apiToken, "api-token", "", "Token required for HTTP API requests (disabled if empty)")
+	flag.StringVar(&connToken, "token", "", "Token clients must present to connect (disabled if empty)")
+	flag.StringVar(&ownerToken, "owner-token", "", "Token that makes a client a room owner (disabled if empty)")
+	flag.BoolVar(&singleWriter, "single-writer", false, "Let only one client at a time edit a room, holding its write lock")
+	flag.StringVar(&roomDir, "room-dir", "", "Directory to save idle rooms to, unloading them from memory (disabled if empty)")
+	flag.DurationVar(&roomIdleTimeout, "room-idle", roomIdleTimeout, "How long a room may have no clients before it's saved and unloaded")
+	flag.BoolVar(&readOnlyGuests, "read-only-guests", true, "Make clients read-only unless they own the room or are granted write access; false lets everyone edit")
+
+	var logConf LogConfig
+	var logMaxSize int64
+	flag.StringVar(&logConf.Level, "log-level", "info", "The minimum level to log (trace, debug, info, warn, error)")
+	flag.StringVar(&logConf.Format, "log-format", "text", "Log as text, colored on terminals, or as json, one object per line")
+	flag.StringVar(&logConf.File, "log-file", "", "The file to write logs to instead of stdout")
+	flag.Int64Var(&logMaxSize, "log-max-size", 10, "Megabytes the log file may grow to before it's rotated (0 disables rotation)")
+	flag.IntVar(&logConf.Backups, "log-backups", 3, "How many rotated log files to keep, as file.1 (newest) to file.N")
 	flag.Parse()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", handleConn)
-
-	// Manages client state.
-	go clients.handle()
-
-	// Processes incoming messages.
-	go handleMsg()
+	if *showVersion {
+		fmt.Println(commons.VersionString())
+		return
+	}
 
-	// Manages document synchronization.
-	go handleSync()
+	logConf.MaxSize = logMaxSize << 20
+	logFile, err := setupLogger(logger, logConf)
+	if err != nil {
+		fmt.Printf("Failed to set up logging: %s\n", err)
+		os.Exit(1)
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
 
 	// Initializes the server.
-	log.Printf("Starting server on %s", *addr)
+	logger.Infof("Starting server on %s", *addr)
 
 	server := &http.Server{
 		Addr:         *addr,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
-		Handler:      mux,
+		Handler:      newMux(),
 	}
 
-	err := server.ListenAndServe()
-	if err != nil {
-		log.Fatal("Server startup failed, terminating.", err)
+	if err := server.ListenAndServe(); err != nil {
+		logger.Fatalf("Server startup failed, terminating: %s", err)
 	}
 }
 
+// newMux registers the WebSocket and HTTP API handlers.
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleConn)
+	mux.HandleFunc("/doc", handleDoc)
+	mux.HandleFunc("/admin/status", handleStatus)
+	return mux
+}
+
 // handleConn manages new WebSocket connections and message reading.
 func handleConn(w http.ResponseWriter, r *http.Request) {
+	// Reject clients without the shared token before upgrading.
+	if !hasToken(r, connToken) {
+		logger.Errorf("Rejected connection from %s: missing or incorrect token", r.RemoteAddr)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	room := rooms.join(roomName(r))
+	defer rooms.leave(room)
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		color.Red("WebSocket upgrade failed: %v\n", err)
+		logger.Errorf("WebSocket upgrade failed: %v", err)
 		conn.Close()
 		return
 	}
 	defer conn.Close()
 
+	// Agree on the protocol before the client joins the room.
+	protocol, err := handshake(conn)
+	if err != nil {
+		logger.Warnf("Rejected connection from %s: %s", r.RemoteAddr, err)
+		return
+	}
+
 	clientID := uuid.New()
 
-	// Safely increment and assign siteID.
-	mu.Lock()
-	siteID++
+	site := room.newSiteID()
 
 	client := &client{
-		Conn:    conn,
-		SiteID:  strconv.Itoa(siteID),
-		id:      clientID,
-		writeMu: sync.Mutex{},
-		mu:      sync.Mutex{},
+		Conn:     conn,
+		SiteID:   strconv.Itoa(site),
+		id:       clientID,
+		room:     room,
+		outbox:   make(chan interface{}, outboxSize),
+		closed:   make(chan struct{}),
+		stalled:  make(chan struct{}, 1),
+		mu:       sync.Mutex{},
+		owner:    ownerToken != "" && r.Header.Get("X-Owner-Token") == ownerToken,
+		canWrite: !readOnlyGuests,
+		echo:     wantsEcho(r),
+		protocol: protocol,
 	}
-	mu.Unlock()
-
-	clients.add(client)
-
-	siteIDMsg := commons.Message{Type: commons.SiteIDMessage, Text: client.SiteID, ID: clientID}
-	clients.broadcastOne(siteIDMsg, clientID)
 
-	docReq := commons.Message{Type: commons.DocReqMessage, ID: clientID}
-	clients.broadcastOneExcept(docReq, clientID)
-
-	clients.sendUsernames()
+	go room.clients.writeLoop(client)
+	room.join(client)
+	room.clients.sendUsernames()
 
 	// Continuously read and process messages from the client.
 	for {
 		var msg commons.Message
 		if err := client.read(&msg); err != nil {
-			color.Red("Message read failed. Closing %s's connection. Error: %s", client.Username, err)
+			logger.Errorf("Message read failed. Closing %s's connection. Error: %s", client.Username, err)
 			return
 		}
 
 		// Route document sync messages separately.
 		if msg.Type == commons.DocSyncMessage {
-			syncChan <- msg
+			room.syncChan <- msg
 			continue
 		}
 
@@ -168,45 +249,7 @@ func handleConn(w http.ResponseWriter, r *http.Request) {
 		msg.ID = clientID
 
 		// Queue message for processing.
-		messageChan <- msg
-	}
-}
-
-// handleMsg processes and broadcasts messages from clients.
-func handleMsg() {
-	for {
-		// Retrieve next message.
-		msg := <-messageChan
-
-		// Log message details.
-		t := time.Now().Format(time.ANSIC)
-		if msg.Type == commons.JoinMessage {
-			clients.updateName(msg.ID, msg.Username)
-			color.Green("%s >> %s %s (ID: %s)\n", t, msg.Username, msg.Text, msg.ID)
-			clients.sendUsernames()
-		} else if msg.Type == "operation" {
-			color.Green("operation >> %+v from ID=%s\n", msg.Operation, msg.ID)
-		} else {
-			color.Green("%s >> unrecognized message type:  %v\n", t, msg)
-			clients.sendUsernames()
-			continue
-		}
-
-		clients.broadcastAllExcept(msg, msg.ID)
-	}
-}
-
-// handleSync manages document synchronization messages.
-func handleSync() {
-	for {
-		syncMsg := <-syncChan
-		switch syncMsg.Type {
-		case commons.DocSyncMessage:
-			clients.broadcastOne(syncMsg, syncMsg.ID)
-		case commons.UsersMessage:
-			color.Blue("usernames: %s", syncMsg.Text)
-			clients.broadcastAll(syncMsg)
-		}
+		room.messageChan <- msg
 	}
 }
 
@@ -214,6 +257,8 @@ func handleSync() {
 func (c *Clients) handle() {
 	for {
 		select {
+		case <-c.done:
+			return
 		case req := <-c.deleteRequests:
 			c.close(req.id)
 			req.done <- 1
@@ -228,18 +273,52 @@ func (c *Clients) handle() {
 				req.resp <- c.list[req.id]
 				close(req.resp)
 			}
-		case client := <-c.addRequests:
+		case req := <-c.addRequests:
+			client := req.client
 			c.mu.Lock()
+			// The first client in a room owns it.
+			client.mu.Lock()
+			if len(c.list) == 0 {
+				client.owner = true
+			}
+			client.canWrite = client.canWrite || client.owner
+			client.mu.Unlock()
+
+			// Queue the client's first messages before listing it, so nothing broadcast can overtake them.
+			if len(req.first) > 0 {
+				for _, msg := range append([]commons.Message{client.permissionMsg()}, req.first...) {
+					if err := client.send(msg); err != nil {
+						logger.Errorf("ERROR: %s", err)
+					}
+				}
+			}
+
 			c.list[client.id] = client
 			c.mu.Unlock()
+			close(req.done)
 		case n := <-c.nameUpdateRequests:
-			c.list[n.id].mu.Lock()
-			c.list[n.id].Username = n.newName
-			c.list[n.id].mu.Unlock()
+			// The client may have left before its name arrived.
+			if client, ok := c.list[n.id]; ok {
+				client.mu.Lock()
+				client.Username = n.newName
+				client.mu.Unlock()
+			}
 		}
 	}
 }
 
+// addRequest facilitates adding a client to the client list.
+type addRequest struct {
+	// Client to be added.
+	client *client
+
+	// Messages queued for the client after its permission, before it's listed; nothing is queued without them.
+	first []commons.Message
+
+	// Closed once the client is listed.
+	done chan struct{}
+}
+
 // deleteRequest facilitates client removal from the client list.
 type deleteRequest struct {
 	// Client to be removed.
@@ -278,9 +357,17 @@ func (c *Clients) get(id uuid.UUID) chan *client {
 	return resp
 }
 
-// add introduces a new client to the list.
-func (c *Clients) add(client *client) {
-	c.addRequests <- client
+// add introduces a new client to the list, returning once it's listed.
+// If first is given, the client is told its permission and then sent first before anything else.
+func (c *Clients) add(client *client, first ...commons.Message) {
+	req := addRequest{client, first, make(chan struct{})}
+	select {
+	case c.addRequests <- req:
+	case <-c.done:
+		// The room was unloaded, and its clients with it.
+		return
+	}
+	<-req.done
 }
 
 // nameUpdate facilitates client username changes.
@@ -289,26 +376,37 @@ type nameUpdate struct {
 	newName string
 }
 
-// updateName modifies a client's username.
-func (c *Clients) updateName(id uuid.UUID, newName string) {
+// updateName sanitizes and stores a client's username, returning the name as stored.
+func (c *Clients) updateName(id uuid.UUID, newName string) string {
+	newName = sanitizeUsername(newName)
 	c.nameUpdateRequests <- nameUpdate{id, newName}
+	return newName
+}
+
+// stop ends handle, once the clients' room is unloaded.
+func (c *Clients) stop() {
+	close(c.done)
 }
 
 // delete removes a client from the active list.
 func (c *Clients) delete(id uuid.UUID) {
 	req := deleteRequest{id, make(chan int)}
-	c.deleteRequests <- req
+	select {
+	case c.deleteRequests <- req:
+	case <-c.done:
+		// The room was unloaded, and its clients with it.
+		return
+	}
 	<-req.done
 	c.sendUsernames()
 }
 
 // broadcastAll sends a message to every active client.
 func (c *Clients) broadcastAll(msg commons.Message) {
-	color.Blue("Broadcasting to all users. Text: %s", msg.Text)
+	logger.Debugf("Broadcasting to all users. Text: %s", msg.Text)
 	for client := range c.getAll() {
 		if err := client.send(msg); err != nil {
-			color.Red("ERROR: %s", err)
-			c.delete(client.id)
+			logger.Errorf("ERROR: %s", err)
 		}
 	}
 }
@@ -320,8 +418,7 @@ func (c *Clients) broadcastAllExcept(msg commons.Message, except uuid.UUID) {
 			continue
 		}
 		if err := client.send(msg); err != nil {
-			color.Red("ERROR: %s", err)
-			c.delete(client.id)
+			logger.Errorf("ERROR: %s", err)
 		}
 	}
 }
@@ -329,24 +426,32 @@ func (c *Clients) broadcastAllExcept(msg commons.Message, except uuid.UUID) {
 // broadcastOne sends a message to a specific client.
 func (c *Clients) broadcastOne(msg commons.Message, dst uuid.UUID) {
 	client := <-c.get(dst)
+	if client == nil {
+		logger.Errorf("ERROR: client %s not found", dst)
+		return
+	}
 	if err := client.send(msg); err != nil {
-		color.Red("ERROR: %s", err)
-		c.delete(client.id)
+		logger.Errorf("ERROR: %s", err)
 	}
 }
 
-// broadcastOneExcept sends a message to any client except one.
-func (c *Clients) broadcastOneExcept(msg commons.Message, except uuid.UUID) {
-	for client := range c.getAll() {
-		if client.id == except {
-			continue
-		}
-		if err := client.send(msg); err != nil {
-			color.Red("ERROR: %s", err)
-			c.delete(client.id)
-			continue
+// maxSiteID bounds site IDs, so they fit in 32 bits on any client.
+const maxSiteID = 1<<31 - 1
+
+// newSiteID returns a site ID no other connection to the room got while it's been loaded.
+// IDs are random rather than counted from 1, so they stay unique after the room is reloaded or the server
+// restarts too: clients keep their site when reconnecting, and a counter starting over would give it to a
+// newcomer as well, whose characters would then reuse the IDs of the ones the reconnecting client made.
+func (r *Room) newSiteID() int {
+	r.sitesMu.Lock()
+	defer r.sitesMu.Unlock()
+
+	for {
+		id := rand.IntN(maxSiteID) + 1
+		if !r.sites[id] {
+			r.sites[id] = true
+			return id
 		}
-		break
 	}
 }
 
@@ -355,14 +460,18 @@ func (c *Clients) close(id uuid.UUID) {
 	c.mu.RLock()
 	client, ok := c.list[id]
 	if ok {
+		if client.closed != nil {
+			close(client.closed)
+		}
 		if err := client.Conn.Close(); err != nil {
-			color.Red("Connection closure failed: %s\n", err)
+			logger.Errorf("Connection closure failed: %s", err)
 		}
 	} else {
-		color.Red("Connection closure failed: client not found")
+		logger.Errorf("Connection closure failed: client not found")
+		c.mu.RUnlock()
 		return
 	}
-	color.Red("Removing %v from client list.\n", c.list[id].Username)
+	logger.Errorf("Removing %v from client list.", c.list[id].Username)
 	c.mu.RUnlock()
 
 	c.mu.Lock()
@@ -371,6 +480,52 @@ func (c *Clients) close(id uuid.UUID) {
 
 }
 
+// snapshot returns the usernames of all active clients, sorted.
+// It reads the list directly rather than through handle, so it never blocks the message path.
+func (c *Clients) snapshot() []string {
+	c.mu.RLock()
+	users := make([]string, 0, len(c.list))
+	for _, client := range c.list {
+		client.mu.Lock()
+		users = append(users, client.Username)
+		client.mu.Unlock()
+	}
+	c.mu.RUnlock()
+
+	sort.Strings(users)
+	return users
+}
+
+// username returns a client's username, or an empty string if it's not in the list.
+func (c *Clients) username(id uuid.UUID) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	client, ok := c.list[id]
+	if !ok {
+		return ""
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.Username
+}
+
+// echoes reports whether a client asked for its own operations to be echoed back.
+func (c *Clients) echoes(id uuid.UUID) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	client, ok := c.list[id]
+	return ok && client.echo
+}
+
+// wantsEcho reports whether a connection asked, with the echo query parameter, for its operations to be echoed back.
+func wantsEcho(r *http.Request) bool {
+	echo, _ := strconv.ParseBool(r.URL.Query().Get("echo"))
+	return echo
+}
+
 // read retrieves a message from the client's connection.
 func (c *client) read(msg *commons.Message) error {
 	err := c.Conn.ReadJSON(msg)
@@ -381,29 +536,81 @@ func (c *client) read(msg *commons.Message) error {
 
 	if err != nil {
 		if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-			color.Red("Message read from %s failed: %v", name, err)
+			logger.Errorf("Message read from %s failed: %v", name, err)
+		}
+		logger.Errorf("Client %v disconnected", name)
+		c.room.clients.delete(c.id)
+
+		// Hand the write lock back so others aren't locked out.
+		if c.room.releaseLock(c.id) {
+			c.room.clients.broadcastAll(c.room.lockMsg())
 		}
-		color.Red("Client %v disconnected", name)
-		clients.delete(c.id)
 		return err
 	}
 	return nil
 }
 
-// send transmits a message over the client's connection.
+const (
+	// outboxSize is how many messages can wait for a client's connection before the client is dropped as too slow.
+	outboxSize = 256
+
+	// writeTimeout is how long a single write may take before the client is dropped.
+	writeTimeout = 10 * time.Second
+)
+
+// errSlowClient is returned by send when a client's outbox is full.
+var errSlowClient = errors.New("client too slow: outbox full")
+
+// send queues a message for writeLoop to write to the client's connection.
+// A slow write just delays the messages queued after it, so a flaky network doesn't cost the client its session,
+// but a client so far behind that its outbox is full won't catch up, so that fails and writeLoop drops it.
 func (c *client) send(v interface{}) error {
-	c.writeMu.Lock()
-	err := c.Conn.WriteJSON(v)
-	c.writeMu.Unlock()
-	return err
+	select {
+	case c.outbox <- v:
+		return nil
+	default:
+	}
+
+	select {
+	case c.stalled <- struct{}{}:
+	default:
+	}
+	return errSlowClient
+}
+
+// writeLoop writes the messages queued for client to its connection, in order, until the client is removed.
+// It's the only writer to the connection. A failed write removes the client: gorilla/websocket keeps returning
+// the first write error, so later writes can't succeed. So does a full outbox, once the write in progress is done.
+func (c *Clients) writeLoop(client *client) {
+	for {
+		select {
+		case <-client.closed:
+			return
+		case <-client.stalled:
+			logger.Errorf("%s's outbox is full, dropping them", client.SiteID)
+			c.delete(client.id)
+			return
+		case v := <-client.outbox:
+			if err := client.Conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+				logger.Errorf("Setting the write deadline for %s failed: %s", client.SiteID, err)
+			}
+			if err := client.Conn.WriteJSON(v); err != nil {
+				logger.Errorf("Write to %s failed, dropping them: %s", client.SiteID, err)
+				c.delete(client.id)
+				return
+			}
+		}
+	}
 }
 
-// sendUsernames broadcasts the list of active users to all clients.
+// sendUsernames broadcasts the list of active users to all clients, with their site IDs in the same order,
+// so a room owner can pick out one guest.
 func (c *Clients) sendUsernames() {
-	var users string
+	var users, sites string
 	for client := range c.getAll() {
 		users += client.Username + ","
+		sites += client.SiteID + ","
 	}
 
-	syncChan <- commons.Message{Text: users, Type: commons.UsersMessage}
+	c.syncChan <- commons.Message{Text: users, Site: sites, Type: commons.UsersMessage}
 }
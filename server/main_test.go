@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"text-editor/commons"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+func TestHandleConn_Token(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	oldToken := connToken
+	connToken = "secret"
+	defer func() { connToken = oldToken }()
+
+	tests := []struct {
+		description string
+		query       url.Values
+		header      http.Header
+		want        int
+	}{
+		{"missing token", url.Values{}, nil, http.StatusUnauthorized},
+		{"wrong token", url.Values{"token": {"nope"}}, nil, http.StatusUnauthorized},
+		{"wrong bearer token", url.Values{}, http.Header{"Authorization": {"Bearer nope"}}, http.StatusUnauthorized},
+		{"correct token", url.Values{"token": {"secret"}}, nil, http.StatusSwitchingProtocols},
+		{"correct bearer token", url.Values{}, http.Header{"Authorization": {"Bearer secret"}}, http.StatusSwitchingProtocols},
+	}
+
+	for _, tc := range tests {
+		tc.query.Set("room", testRoom(t))
+		u := url.URL{Scheme: "ws", Host: strings.TrimPrefix(srv.URL, "http://"), Path: "/", RawQuery: tc.query.Encode()}
+
+		conn, resp, err := websocket.DefaultDialer.Dial(u.String(), tc.header)
+		if resp == nil {
+			t.Fatalf("(%s) dial error: %v\n", tc.description, err)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+
+		if resp.StatusCode != tc.want {
+			t.Errorf("(%s) got != want; got = %v, expected = %v\n", tc.description, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+// readUntil reads messages from conn until one of the given type arrives.
+func readUntil(t *testing.T, conn *websocket.Conn, msgType commons.MessageType) commons.Message {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+
+	for {
+		var msg commons.Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("never received a %q message: %v\n", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestHandleMsg_GuestWriteAccess(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	oldReadOnly := readOnlyGuests
+	readOnlyGuests = true
+	defer func() { readOnlyGuests = oldReadOnly }()
+
+	room := testRoom(t)
+	owner := dialTestClient(t, srv, room)
+	if got := readUntil(t, owner, commons.PermissionMessage).Text; got != "owner" {
+		t.Fatalf("got != want; got = %q, expected = %q\n", got, "owner")
+	}
+
+	// Two guests share a name; only their site IDs tell them apart.
+	guest := dialTestClient(t, srv, room)
+	namesake := dialTestClient(t, srv, room)
+	var guestSite string
+	for _, conn := range []*websocket.Conn{guest, namesake} {
+		if got := readUntil(t, conn, commons.PermissionMessage).Text; got != "read" {
+			t.Fatalf("got != want; got = %q, expected = %q\n", got, "read")
+		}
+		site := readUntil(t, conn, commons.SiteIDMessage).Text
+		if guestSite == "" {
+			guestSite = site
+		}
+		if err := conn.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: "guest"}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+
+	insert := commons.Message{Type: "operation", Operation: commons.Operation{Type: "insert", Position: 1, Value: "g"}}
+
+	// The guest's operation is dropped and the guest is reminded it is read-only.
+	if err := guest.WriteJSON(insert); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	if got := readUntil(t, guest, commons.PermissionMessage).Text; got != "read" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "read")
+	}
+	if _, got := getDoc(t, srv, "room="+room); got != "" {
+		t.Errorf("guest's operation was applied; got = %q\n", got)
+	}
+
+	// Guests can't grant themselves access.
+	if err := guest.WriteJSON(commons.Message{Type: commons.PermissionMessage, Site: guestSite, Text: "write"}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	if err := guest.WriteJSON(insert); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	if got := readUntil(t, guest, commons.PermissionMessage).Text; got != "read" {
+		t.Errorf("guest granted itself access; got = %q, expected = %q\n", got, "read")
+	}
+
+	// Once the owner grants access, the guest's operations are applied.
+	if err := owner.WriteJSON(commons.Message{Type: commons.PermissionMessage, Site: guestSite, Text: "write"}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	if got := readUntil(t, guest, commons.PermissionMessage).Text; got != "write" {
+		t.Fatalf("got != want; got = %q, expected = %q\n", got, "write")
+	}
+
+	if err := guest.WriteJSON(insert); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	waitForDoc(t, srv, room, "g")
+
+	// The guest sharing its name wasn't granted access with it.
+	if err := namesake.WriteJSON(insert); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	if got := readUntil(t, namesake, commons.PermissionMessage).Text; got != "read" {
+		t.Errorf("guest with the same name was granted access too; got = %q, expected = %q\n", got, "read")
+	}
+	if _, got := getDoc(t, srv, "room="+room); got != "g" {
+		t.Errorf("namesake's operation was applied; got = %q\n", got)
+	}
+}
+
+func TestHandleMsg_WriteLock(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	oldSingleWriter := singleWriter
+	singleWriter = true
+	defer func() { singleWriter = oldSingleWriter }()
+
+	room := testRoom(t)
+	conns := map[string]*websocket.Conn{}
+	for _, name := range []string{"alice", "bob"} {
+		conn := dialTestClient(t, srv, room)
+		if got := readUntil(t, conn, commons.LockMessage).Text; got != "" {
+			t.Fatalf("(%s) lock should start free; got holder = %q\n", name, got)
+		}
+		if err := conn.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: name}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+		conns[name] = conn
+	}
+	alice, bob := conns["alice"], conns["bob"]
+
+	insert := func(conn *websocket.Conn, position int, value string) {
+		t.Helper()
+		msg := commons.Message{Type: "operation", Operation: commons.Operation{Type: "insert", Position: position, Value: value}}
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+
+	// Alice's edit takes the free lock, and everyone is told.
+	insert(alice, 1, "a")
+	for name, conn := range conns {
+		if got := readUntil(t, conn, commons.LockMessage).Text; got != "alice" {
+			t.Errorf("(%s) got != want; got = %q, expected = %q\n", name, got, "alice")
+		}
+	}
+	waitForDoc(t, srv, room, "a")
+
+	// Bob's edit is rejected while Alice holds the lock.
+	insert(bob, 1, "b")
+	if got := readUntil(t, bob, commons.LockMessage).Text; got != "alice" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "alice")
+	}
+	if _, got := getDoc(t, srv, "room="+room); got != "a" {
+		t.Errorf("bob's operation was applied; got = %q\n", got)
+	}
+
+	// Alice leaving releases the lock, so Bob can edit.
+	alice.Close()
+	if got := readUntil(t, bob, commons.LockMessage).Text; got != "" {
+		t.Errorf("lock not released on disconnect; got holder = %q\n", got)
+	}
+	insert(bob, 2, "b")
+	if got := readUntil(t, bob, commons.LockMessage).Text; got != "bob" {
+		t.Errorf("got != want; got = %q, expected = %q\n", got, "bob")
+	}
+	waitForDoc(t, srv, room, "ab")
+}
+
+// serverConn returns the server's end of a WebSocket connection, and the client's end.
+func serverConn(t *testing.T) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade error: %v\n", err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(srv.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial error: %v\n", err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	conn := <-conns
+	t.Cleanup(func() { conn.Close() })
+	return conn, peer
+}
+
+// newTestClient returns a client on conn with its outbox, added to c; writing starts if write is set.
+func newTestClient(c *Clients, conn *websocket.Conn, write bool) *client {
+	cl := &client{Conn: conn, id: uuid.New(), SiteID: "1", outbox: make(chan interface{}, outboxSize), closed: make(chan struct{}), stalled: make(chan struct{}, 1)}
+	if write {
+		go c.writeLoop(cl)
+	}
+	c.add(cl)
+	return cl
+}
+
+// waitRemoved fails the test unless the client with the given ID leaves c within a few seconds.
+func waitRemoved(t *testing.T, c *Clients, id uuid.UUID) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if <-c.get(id) == nil {
+			return
+		}
+	}
+	t.Fatalf("client %v wasn't removed\n", id)
+}
+
+func TestBroadcastAll_SlowClient(t *testing.T) {
+	c := NewClients(make(chan commons.Message, 10))
+	go c.handle()
+	defer c.stop()
+
+	// The slow client's writes are stuck, as if its network stalled; the fast one's go through.
+	slowConn, _ := serverConn(t)
+	slow := newTestClient(c, slowConn, false)
+	fastConn, fastPeer := serverConn(t)
+	fast := newTestClient(c, fastConn, true)
+
+	received := make(chan string, outboxSize+1)
+	go func() {
+		for {
+			var msg commons.Message
+			if err := fastPeer.ReadJSON(&msg); err != nil {
+				return
+			}
+			received <- msg.Text
+		}
+	}()
+
+	// Broadcasts queue for the slow client without holding up the fast one.
+	c.broadcastAll(commons.Message{Type: commons.UsersMessage, Text: "0"})
+	select {
+	case got := <-received:
+		if got != "0" {
+			t.Errorf("got %q, expected the broadcast\n", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the fast client didn't get the broadcast\n")
+	}
+	if <-c.get(slow.id) == nil {
+		t.Fatalf("the slow client was dropped while it could still catch up\n")
+	}
+
+	// Once its outbox is full, the slow client is dropped when its stuck write returns, and the fast one
+	// still gets everything, in order.
+	for i := 1; i <= outboxSize; i++ {
+		c.broadcastAll(commons.Message{Type: commons.UsersMessage, Text: fmt.Sprint(i)})
+	}
+	go c.writeLoop(slow)
+	waitRemoved(t, c, slow.id)
+	for i := 1; i <= outboxSize; i++ {
+		if got := <-received; got != fmt.Sprint(i) {
+			t.Fatalf("got %q, expected %q\n", got, fmt.Sprint(i))
+		}
+	}
+	if <-c.get(fast.id) == nil {
+		t.Errorf("the fast client was dropped\n")
+	}
+}
+
+func TestWriteLoop_FailedWriteDropsClient(t *testing.T) {
+	c := NewClients(make(chan commons.Message, 10))
+	go c.handle()
+	defer c.stop()
+
+	conn, _ := serverConn(t)
+	cl := newTestClient(c, conn, true)
+
+	// The network connection fails under the WebSocket one, which then fails every write.
+	_ = conn.UnderlyingConn().Close()
+	c.broadcastAll(commons.Message{Type: commons.UsersMessage, Text: "a,"})
+	waitRemoved(t, c, cl.id)
+}
+
+func TestNewSiteID_UniqueAcrossReloads(t *testing.T) {
+	// Sites from before a reload are still in the room's document, and kept by clients that reconnect.
+	room := &Room{sites: map[int]bool{}}
+	before := map[int]bool{}
+	for range 100 {
+		id := room.newSiteID()
+		if id < 1 || id > maxSiteID || before[id] {
+			t.Fatalf("got site ID %d, expected a new one in [1, %d]", id, maxSiteID)
+		}
+		before[id] = true
+	}
+
+	// A reloaded room, or one on a restarted server, forgets the IDs it gave out, but doesn't give them out again.
+	room = &Room{sites: map[int]bool{}}
+	for range 100 {
+		if id := room.newSiteID(); before[id] {
+			t.Errorf("after a reload: got site ID %d again", id)
+		}
+	}
+}
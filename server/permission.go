@@ -0,0 +1,73 @@
+package main
+
+import (
+	"text-editor/commons"
+
+	"github.com/google/uuid"
+)
+
+// permissionMsg tells the client its current permission.
+func (c *client) permissionMsg() commons.Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	text := "read"
+	switch {
+	case c.owner:
+		text = "owner"
+	case c.canWrite:
+		text = "write"
+	}
+
+	return commons.Message{Type: commons.PermissionMessage, Text: text, ID: c.id}
+}
+
+// permission reports whether a client owns its room and whether it may edit.
+// Like snapshot, it reads the list directly so it never blocks the message path.
+func (c *Clients) permission(id uuid.UUID) (owner, canWrite bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	client, ok := c.list[id]
+	if !ok {
+		return false, false
+	}
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	return client.owner, client.canWrite
+}
+
+// setWrite grants or revokes write access for the guest with the given site ID, or all guests if it's empty.
+// Guests are picked by site rather than username, as several clients can share a name.
+// Owners always keep write access. It returns the clients whose permission was set.
+func (c *Clients) setWrite(site string, canWrite bool) []*client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var changed []*client
+	for _, client := range c.list {
+		client.mu.Lock()
+		if !client.owner && (site == "" || client.SiteID == site) {
+			client.canWrite = canWrite
+			changed = append(changed, client)
+		}
+		client.mu.Unlock()
+	}
+
+	return changed
+}
+
+// changePermission applies a room owner's request to change guests' write access.
+func (r *Room) changePermission(msg commons.Message) {
+	if owner, _ := r.clients.permission(msg.ID); !owner {
+		logger.Errorf("Room %s: ignoring permission change from non-owner ID=%s", r.name, msg.ID)
+		return
+	}
+
+	canWrite := msg.Text == "write"
+	for _, client := range r.clients.setWrite(msg.Site, canWrite) {
+		logger.Infof("Room %s: %s can write: %v", r.name, client.Username, canWrite)
+		r.clients.broadcastOne(client.permissionMsg(), client.id)
+	}
+}
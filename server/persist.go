@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"text-editor/crdt"
+)
+
+var (
+	// Directory idle rooms are saved to and reloaded from; empty keeps every room in memory.
+	roomDir string
+
+	// How long a room may go without clients before it's saved and unloaded.
+	roomIdleTimeout = 5 * time.Minute
+)
+
+// roomPath returns the file a room is saved to. Room names are escaped, so any name is a single file in roomDir.
+func roomPath(name string) string {
+	return filepath.Join(roomDir, url.PathEscape(name)+".json")
+}
+
+// roomSaved reports whether the named room was saved to roomDir.
+func roomSaved(name string) bool {
+	if roomDir == "" {
+		return false
+	}
+	_, err := os.Stat(roomPath(name))
+	return err == nil
+}
+
+// loadRoom creates the named room, restoring its document from roomDir if it was saved there.
+// The whole document is saved, deleted characters included, so clients' characters keep their IDs across reloads.
+func loadRoom(name string) *Room {
+	room := newRoom(name)
+	if roomDir == "" {
+		return room
+	}
+
+	data, err := os.ReadFile(roomPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return room
+	}
+	if err != nil {
+		logger.Errorf("Room %s: failed to read saved document: %s", name, err)
+		return room
+	}
+
+	var doc crdt.Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		logger.Errorf("Room %s: failed to parse saved document: %s", name, err)
+		return room
+	}
+	if err := doc.Validate(); err != nil {
+		logger.Errorf("Room %s: dropping invalid saved document: %s", name, err)
+		return room
+	}
+
+	room.doc = doc
+	logger.Infof("Room %s: loaded from %s", name, roomPath(name))
+	return room
+}
+
+// save writes the room's document to roomDir.
+// It's written to a temporary file first, so a failed save never leaves a truncated document behind.
+func (r *Room) save() error {
+	doc := r.snapshot()
+	data, err := json.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(roomDir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(roomDir, ".room-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), roomPath(r.name))
+}
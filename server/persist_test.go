@@ -0,0 +1,171 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// setRoomDir saves idle rooms to a temporary directory after the given grace period, for the rest of the test.
+func setRoomDir(t *testing.T, idle time.Duration) {
+	t.Helper()
+
+	oldDir, oldIdle := roomDir, roomIdleTimeout
+	roomDir, roomIdleTimeout = t.TempDir(), idle
+
+	// Connections outlive the test briefly, so wait for them to leave their rooms, and cancel the unloads they scheduled.
+	t.Cleanup(func() {
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			rooms.mu.Lock()
+			busy := false
+			for _, room := range rooms.list {
+				busy = busy || (strings.HasPrefix(room.name, t.Name()+"-") && room.refs > 0)
+			}
+			if !busy || time.Now().After(deadline) {
+				for _, room := range rooms.list {
+					if room.idleTimer != nil {
+						room.idleTimer.Stop()
+						room.idleTimer = nil
+					}
+				}
+				roomDir, roomIdleTimeout = oldDir, oldIdle
+				rooms.mu.Unlock()
+				return
+			}
+			rooms.mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+}
+
+// loadedRoom returns the named room if it's loaded, without loading it.
+func loadedRoom(name string) (*Room, bool) {
+	rooms.mu.Lock()
+	defer rooms.mu.Unlock()
+
+	room, ok := rooms.list[name]
+	return room, ok
+}
+
+func TestRooms_UnloadIdleRoom(t *testing.T) {
+	setRoomDir(t, 50*time.Millisecond)
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	alice := connectTestClient(t, srv, room, "alice")
+	alice.typeText(1, "persisted")
+	alice.delete(1)
+	waitForDoc(t, srv, room, "ersisted")
+
+	loaded, _ := loadedRoom(room)
+	want := loaded.snapshot()
+	alice.conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for _, ok := loadedRoom(room); ok; _, ok = loadedRoom(room) {
+		if time.Now().After(deadline) {
+			t.Fatalf("room still loaded after the grace period\n")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(roomPath(room)); err != nil {
+		t.Fatalf("room wasn't saved: %v\n", err)
+	}
+
+	// Rejoining reloads the room with the same characters, deleted ones included.
+	bob := connectTestClient(t, srv, room, "bob")
+	bob.waitForContent("ersisted")
+
+	reloaded, ok := loadedRoom(room)
+	if !ok {
+		t.Fatalf("room not loaded after rejoining\n")
+	}
+	if got := reloaded.snapshot(); !cmp.Equal(got, want) {
+		t.Errorf("reloaded document differs (-want +got)\n%s", cmp.Diff(want, got))
+	}
+
+	bob.typeText(9, "!")
+	waitForDoc(t, srv, room, "ersisted!")
+}
+
+func TestRooms_JoinDuringGracePeriod(t *testing.T) {
+	setRoomDir(t, 200*time.Millisecond)
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	alice := connectTestClient(t, srv, room, "alice")
+	alice.typeText(1, "hi")
+	waitForDoc(t, srv, room, "hi")
+	before, _ := loadedRoom(room)
+	alice.conn.Close()
+
+	// Wait until the server has noticed alice leaving, then rejoin before the room is unloaded.
+	deadline := time.Now().Add(time.Second)
+	for {
+		rooms.mu.Lock()
+		refs := before.refs
+		rooms.mu.Unlock()
+		if refs == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server never noticed the client leaving\n")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	bob := connectTestClient(t, srv, room, "bob")
+	bob.waitForContent("hi")
+
+	// The unload was cancelled, so the room stays loaded well past the grace period.
+	time.Sleep(400 * time.Millisecond)
+	if after, ok := loadedRoom(room); !ok || after != before {
+		t.Errorf("room was unloaded while a client was connected\n")
+	}
+	if _, err := os.Stat(roomPath(room)); err == nil {
+		t.Errorf("room was saved while a client was connected\n")
+	}
+}
+
+func TestRooms_KeptWithoutRoomDir(t *testing.T) {
+	setRoomDir(t, time.Millisecond)
+	roomDir = ""
+
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	alice := connectTestClient(t, srv, room, "alice")
+	alice.typeText(1, "kept")
+	waitForDoc(t, srv, room, "kept")
+	alice.conn.Close()
+
+	// Without anywhere to save it, an idle room stays in memory.
+	time.Sleep(50 * time.Millisecond)
+	waitForDoc(t, srv, room, "kept")
+	if _, ok := loadedRoom(room); !ok {
+		t.Errorf("room was unloaded without a room directory\n")
+	}
+}
+
+func TestRoomPath_EscapesNames(t *testing.T) {
+	setRoomDir(t, time.Minute)
+
+	for _, name := range []string{"../escape", "a/b", "notes"} {
+		if got, want := roomPath(name), roomDir; len(got) <= len(want) || got[:len(want)+1] != want+string(os.PathSeparator) || containsSeparator(got[len(want)+1:]) {
+			t.Errorf("(%s) got %q, expected a file directly in %q\n", name, got, want)
+		}
+	}
+}
+
+// containsSeparator reports whether a file name has a path separator in it.
+func containsSeparator(name string) bool {
+	for _, r := range name {
+		if r == os.PathSeparator {
+			return true
+		}
+	}
+	return false
+}
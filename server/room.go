@@ -0,0 +1,443 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/google/uuid"
+)
+
+// defaultRoom is used when a connection doesn't specify a room.
+const defaultRoom = "default"
+
+// Room is a single collaborative editing session with its own clients and document.
+type Room struct {
+	// Identifies the room in connection URLs and API requests.
+	name string
+
+	// Manages the clients connected to this room.
+	clients *Clients
+
+	// Buffers client messages.
+	messageChan chan commons.Message
+
+	// Buffers document synchronization messages.
+	syncChan chan commons.Message
+
+	// Guards doc.
+	docMu sync.RWMutex
+
+	// Server-side replica of the room's document, kept up to date with relayed operations.
+	doc crdt.Document
+
+	// Counts changes to doc, so a checksum can be compared against an unchanged document; guarded by docMu.
+	version uint64
+
+	// Maps clients whose last checksum didn't match to the version it was compared against.
+	// Only handleMsg uses it.
+	mismatches map[uuid.UUID]uint64
+
+	// Single-writer lock, used if singleWriter is set.
+	lock writeLock
+
+	// Title set by the room's owner.
+	title roomTitle
+
+	// Site IDs handed out to the room's clients, so none is given twice. Site IDs only need to be unique
+	// within a room, like the documents whose characters they name, so they're dropped when it's unloaded.
+	sites map[int]bool
+
+	// Guards sites.
+	sitesMu sync.Mutex
+
+	// Number of connections using the room, guarded by the Rooms' mutex.
+	refs int
+
+	// Pending unload of the idle room, if any, guarded by the Rooms' mutex.
+	idleTimer *time.Timer
+
+	// Counts scheduled unloads, so a timer that fired after being cancelled can tell; guarded by the Rooms' mutex.
+	idleGen int
+
+	// Closed to stop the room's message handlers once it's unloaded.
+	done chan struct{}
+}
+
+// newRoom initializes a room and starts its message handlers.
+func newRoom(name string) *Room {
+	syncChan := make(chan commons.Message)
+
+	r := &Room{
+		name:        name,
+		clients:     NewClients(syncChan),
+		messageChan: make(chan commons.Message),
+		syncChan:    syncChan,
+		doc:         crdt.New(),
+		mismatches:  map[uuid.UUID]uint64{},
+		sites:       map[int]bool{},
+		done:        make(chan struct{}),
+	}
+
+	// Manages client state.
+	go r.clients.handle()
+
+	// Processes incoming messages.
+	go r.handleMsg()
+
+	// Manages document synchronization.
+	go r.handleSync()
+
+	return r
+}
+
+// handleMsg processes and broadcasts messages from the room's clients.
+func (r *Room) handleMsg() {
+	for {
+		// Retrieve next message.
+		var msg commons.Message
+		select {
+		case msg = <-r.messageChan:
+		case <-r.done:
+			return
+		}
+
+		// Log message details.
+		t := time.Now().Format(time.ANSIC)
+		if msg.Type == commons.JoinMessage {
+			msg.Username = r.clients.updateName(msg.ID, msg.Username)
+			logger.Infof("%s >> %s %s (ID: %s)", t, msg.Username, msg.Text, msg.ID)
+			r.clients.sendUsernames()
+		} else if msg.Type == "operation" {
+			logger.Infof("operation >> %+v from ID=%s", msg.Operation, msg.ID)
+
+			// Drop operations from read-only clients, reminding them of their permission.
+			if _, canWrite := r.clients.permission(msg.ID); !canWrite {
+				logger.Warnf("%s >> dropped operation from read-only ID=%s", t, msg.ID)
+				r.clients.broadcastOne(commons.Message{Type: commons.PermissionMessage, Text: "read"}, msg.ID)
+				continue
+			}
+
+			// In single-writer mode, editing takes the lock if it's free.
+			if singleWriter {
+				acquired, changed := r.acquireLock(msg.ID)
+				if !acquired {
+					logger.Warnf("%s >> dropped operation from ID=%s, lock is held", t, msg.ID)
+					r.clients.broadcastOne(r.lockMsg(), msg.ID)
+					continue
+				}
+				if changed {
+					r.clients.broadcastAll(r.lockMsg())
+				}
+			}
+			r.apply(msg.Operation)
+
+			// Attribute the operation to its sender, overriding whatever the client claimed.
+			msg.Username = r.clients.username(msg.ID)
+		} else if msg.Type == commons.CursorMessage {
+			// Cursors are relayed as they are, so peers can follow each other, but attributed like operations.
+			msg.Username = r.clients.username(msg.ID)
+		} else if msg.Type == commons.ChecksumMessage {
+			r.verifyChecksum(msg)
+			continue
+		} else if msg.Type == commons.PermissionMessage {
+			r.changePermission(msg)
+			continue
+		} else if msg.Type == commons.LockMessage {
+			r.handleLock(msg)
+			continue
+		} else if msg.Type == commons.TitleMessage {
+			r.setTitle(msg)
+			continue
+		} else if msg.Type == commons.DocReqMessage {
+			// A client asked to resync; the room's document is authoritative, so no peer is involved.
+			logger.Infof("%s >> resync requested by ID=%s", t, msg.ID)
+			r.sendDoc(msg.ID)
+			continue
+		} else {
+			logger.Infof("%s >> unrecognized message type:  %v", t, msg)
+			r.clients.sendUsernames()
+			continue
+		}
+
+		r.clients.broadcastAllExcept(msg, msg.ID)
+
+		// Clients in echo mode get their operations back once they've been relayed, confirming delivery.
+		if msg.Type == "operation" && r.clients.echoes(msg.ID) {
+			r.clients.broadcastOne(msg, msg.ID)
+		}
+	}
+}
+
+// handleSync manages document synchronization messages.
+func (r *Room) handleSync() {
+	for {
+		var syncMsg commons.Message
+		select {
+		case syncMsg = <-r.syncChan:
+		case <-r.done:
+			return
+		}
+		switch syncMsg.Type {
+		case commons.DocSyncMessage:
+			if syncMsg.Document == nil {
+				logger.Errorf("Room %s: dropping DocSync without a document", r.name)
+				continue
+			}
+			if err := syncMsg.Document.Validate(); err != nil {
+				logger.Errorf("Room %s: dropping invalid document: %s", r.name, err)
+				continue
+			}
+
+			merged := r.mergeDoc(*syncMsg.Document)
+			syncMsg.Document = &merged
+
+			// Addressed syncs answer a joining client's request. Unaddressed ones come from a
+			// reconnecting client, whose offline edits everyone needs.
+			if syncMsg.ID == uuid.Nil {
+				r.clients.broadcastAll(syncMsg)
+			} else {
+				r.clients.broadcastOne(syncMsg, syncMsg.ID)
+			}
+		case commons.UsersMessage:
+			logger.Debugf("usernames: %s", syncMsg.Text)
+			r.clients.broadcastAll(syncMsg)
+		}
+	}
+}
+
+// apply mirrors a relayed operation onto the room's document, a run of inserts one insert at a time.
+func (r *Room) apply(op commons.Operation) {
+	r.docMu.Lock()
+	defer r.docMu.Unlock()
+
+	for _, one := range op.Expand() {
+		r.applyOne(one)
+	}
+	r.version++
+}
+
+// applyOne applies a single operation to the room's document; the caller must hold docMu.
+func (r *Room) applyOne(op commons.Operation) {
+	switch op.Type {
+	case "insert":
+		var err error
+		if op.Character != nil {
+			_, err = r.doc.IntegrateRemoteInsert(*op.Character)
+		} else {
+			_, err = r.doc.Insert(op.Position, op.Value)
+		}
+		if err != nil {
+			logger.Errorf("Room %s: failed to apply insert: %s", r.name, err)
+		}
+	case "delete":
+		if op.ID != "" && r.doc.Contains(op.ID) {
+			r.doc.DeleteByID(op.ID)
+		} else {
+			r.doc.Delete(op.Position)
+		}
+	}
+}
+
+// mergeDoc merges a client's document into the room's document and returns a copy of the result.
+// Merging rather than replacing means no client's edits are lost, e.g. after a server restart.
+func (r *Room) mergeDoc(doc crdt.Document) crdt.Document {
+	r.docMu.Lock()
+	defer r.docMu.Unlock()
+
+	if _, err := r.doc.Merge(doc); err != nil {
+		logger.Errorf("Room %s: failed to merge document: %s", r.name, err)
+	}
+	r.version++
+	return r.docCopy()
+}
+
+// verifyChecksum compares a client's document checksum with the room document's, telling the client if they differ.
+// Operations relayed to the client may not have reached it when it computed its checksum, so a mismatch only
+// counts once the client has sent another checksum that differs from the same, unchanged, document.
+func (r *Room) verifyChecksum(msg commons.Message) {
+	r.docMu.RLock()
+	sum, version := r.doc.Checksum(), r.version
+	r.docMu.RUnlock()
+
+	if msg.Text == sum {
+		delete(r.mismatches, msg.ID)
+		return
+	}
+	if v, ok := r.mismatches[msg.ID]; !ok || v != version {
+		r.mismatches[msg.ID] = version
+		return
+	}
+
+	delete(r.mismatches, msg.ID)
+	logger.Warnf("Room %s: ID=%s is out of sync (checksum %s, room's %s)", r.name, msg.ID, msg.Text, sum)
+	r.clients.broadcastOne(commons.Message{Type: commons.ChecksumMessage, Text: sum}, msg.ID)
+}
+
+// snapshot returns a copy of the room's document.
+func (r *Room) snapshot() crdt.Document {
+	r.docMu.RLock()
+	defer r.docMu.RUnlock()
+	return r.docCopy()
+}
+
+// docCopy returns a copy of the room's document; the caller must hold docMu.
+func (r *Room) docCopy() crdt.Document {
+	return crdt.Document{Characters: append([]crdt.Character(nil), r.doc.Characters...)}
+}
+
+// join adds a client to the room, telling it its permission, the room's state and its site ID, then syncing
+// it from the room's document rather than asking a peer, which may be slow or absent.
+// No operation is applied while the client is added, so each one is either in the document it's sent or
+// relayed to it afterwards.
+func (r *Room) join(c *client) {
+	var first []commons.Message
+	if singleWriter {
+		first = append(first, r.lockMsg())
+	}
+	if r.hasTitle() {
+		first = append(first, r.titleMsg())
+	}
+	first = append(first, commons.Message{Type: commons.SiteIDMessage, Text: c.SiteID, ID: c.id})
+
+	r.docMu.RLock()
+	defer r.docMu.RUnlock()
+	doc := r.docCopy()
+	r.clients.add(c, append(first, commons.Message{Type: commons.DocSyncMessage, Document: &doc, ID: c.id})...)
+}
+
+// sendDoc sends the room's document to a client, addressed to it as a DocSync.
+func (r *Room) sendDoc(dst uuid.UUID) {
+	doc := r.snapshot()
+	r.clients.broadcastOne(commons.Message{Type: commons.DocSyncMessage, Document: &doc, ID: dst}, dst)
+}
+
+// content returns the visible content of the room's document.
+func (r *Room) content() string {
+	r.docMu.RLock()
+	defer r.docMu.RUnlock()
+	return crdt.Content(r.doc)
+}
+
+// Rooms tracks the active rooms by name.
+type Rooms struct {
+	list map[string]*Room
+
+	// Guards against concurrent map access.
+	mu sync.Mutex
+}
+
+// NewRooms initializes and returns a Rooms instance.
+func NewRooms() *Rooms {
+	return &Rooms{
+		list: make(map[string]*Room),
+	}
+}
+
+// join returns the named room for a new connection, loading or creating it if needed.
+// It cancels a pending unload, so a client joining during the grace period keeps the room loaded.
+func (r *Rooms) join(name string) *Room {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room, ok := r.list[name]
+	if !ok {
+		room = loadRoom(name)
+		r.list[name] = room
+	}
+
+	room.refs++
+	if room.idleTimer != nil {
+		room.idleTimer.Stop()
+		room.idleTimer = nil
+	}
+	return room
+}
+
+// leave releases a connection's hold on a room, scheduling its unload once no connection uses it.
+func (r *Rooms) leave(room *Room) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room.refs--
+	if room.refs == 0 {
+		r.scheduleUnload(room)
+	}
+}
+
+// lookup returns the named room if it exists, loading it from roomDir if it was unloaded.
+func (r *Rooms) lookup(name string) (*Room, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room, ok := r.list[name]
+	if ok || !roomSaved(name) {
+		return room, ok
+	}
+
+	// Nobody's using the room, so it's unloaded again after the grace period.
+	room = loadRoom(name)
+	r.list[name] = room
+	r.scheduleUnload(room)
+	return room, true
+}
+
+// scheduleUnload unloads an idle room after roomIdleTimeout, unless a connection joins it first.
+// Rooms are only unloaded with a roomDir to save them to. Callers must hold r.mu.
+func (r *Rooms) scheduleUnload(room *Room) {
+	if roomDir == "" {
+		return
+	}
+
+	room.idleGen++
+	gen, idle := room.idleGen, roomIdleTimeout
+	room.idleTimer = time.AfterFunc(idle, func() { r.unload(room, gen, idle) })
+}
+
+// unload saves an idle room to roomDir and frees it, unless the unload numbered gen was cancelled meanwhile.
+func (r *Rooms) unload(room *Room, gen int, idle time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if room.idleTimer == nil || room.idleGen != gen || room.refs > 0 || r.list[room.name] != room {
+		return
+	}
+	room.idleTimer = nil
+
+	if err := room.save(); err != nil {
+		logger.Errorf("Room %s: keeping idle room loaded, failed to save it: %s", room.name, err)
+		r.scheduleUnload(room)
+		return
+	}
+
+	delete(r.list, room.name)
+	close(room.done)
+	room.clients.stop()
+	logger.Infof("Room %s: unloaded after %s idle", room.name, idle)
+}
+
+// snapshot returns the rooms sorted by name.
+func (r *Rooms) snapshot() []*Room {
+	r.mu.Lock()
+	list := make([]*Room, 0, len(r.list))
+	for _, room := range r.list {
+		list = append(list, room)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list
+}
+
+// roomName extracts the requested room from the URL, falling back to the default room.
+func roomName(req *http.Request) string {
+	name := req.URL.Query().Get("room")
+	if name == "" {
+		return defaultRoom
+	}
+	return name
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"text-editor/commons"
+	"text-editor/crdt"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+)
+
+// insert generates a character in doc and sends it to the server as an operation.
+func insert(t *testing.T, conn *websocket.Conn, doc *crdt.Document, position int, value string) {
+	t.Helper()
+
+	char, err := doc.GenerateCharacter(position, value)
+	if err != nil {
+		t.Fatalf("generate error: %v\n", err)
+	}
+	if conn == nil {
+		return
+	}
+
+	op := commons.Operation{Type: "insert", Position: position, Value: value, Character: &char}
+	if err := conn.WriteJSON(commons.Message{Type: "operation", Operation: op}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+}
+
+// waitForDoc polls the HTTP API until the room's document matches want.
+func waitForDoc(t *testing.T, srv *httptest.Server, room, want string) {
+	t.Helper()
+
+	var got string
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		var status int
+		status, got = getDoc(t, srv, "room="+room)
+		if status == http.StatusOK && got == want {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("got != want; got = %q, expected = %q\n", got, want)
+}
+
+func TestHandleSync_ReconcilesAfterRestart(t *testing.T) {
+	room := testRoom(t)
+
+	// Two clients with their own copies of the document.
+	docA, docB := crdt.New(), crdt.New()
+	docA.SiteID, docB.SiteID = 1, 2
+
+	srv := httptest.NewServer(newMux())
+	connA := dialTestClient(t, srv, room)
+	insert(t, connA, &docA, 1, "a")
+	insert(t, connA, &docA, 2, "b")
+	waitForDoc(t, srv, room, "ab")
+
+	// B has seen A's edits.
+	docB.Characters = append([]crdt.Character(nil), docA.Characters...)
+
+	// The server restarts and forgets every room.
+	srv.Close()
+	connA.Close()
+	rooms = NewRooms()
+
+	// Both clients keep editing while disconnected.
+	insert(t, nil, &docA, 3, "x")
+	insert(t, nil, &docB, 1, "y")
+
+	srv = httptest.NewServer(newMux())
+	defer srv.Close()
+
+	connA = dialTestClient(t, srv, room)
+	connB := dialTestClient(t, srv, room)
+	for _, sync := range []struct {
+		conn *websocket.Conn
+		doc  crdt.Document
+	}{{connA, docA}, {connB, docB}} {
+		if err := sync.conn.WriteJSON(commons.Message{Type: commons.DocSyncMessage, Document: &sync.doc}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+
+	want := "yabx"
+	waitForDoc(t, srv, room, want)
+
+	// Clients receive the merged document.
+	connA.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg commons.Message
+		if err := connA.ReadJSON(&msg); err != nil {
+			t.Fatalf("never received merged document: %v\n", err)
+		}
+		if msg.Type == commons.DocSyncMessage && msg.Document != nil && crdt.Content(*msg.Document) == want {
+			break
+		}
+	}
+}
+
+func TestHandleSync_SlowClient(t *testing.T) {
+	r := newRoom(testRoom(t))
+	defer func() {
+		close(r.done)
+		r.clients.stop()
+	}()
+
+	// The slow client's outbox is full, and its write is stuck.
+	slowConn, _ := serverConn(t)
+	slow := newTestClient(r.clients, slowConn, false)
+	for range outboxSize {
+		slow.outbox <- commons.Message{}
+	}
+	fastConn, _ := serverConn(t)
+	newTestClient(r.clients, fastConn, true)
+
+	// Broadcasting a merged document overflows the slow client's outbox; the sync loop must keep going
+	// while the client is dropped, which publishes the user list on the room's unbuffered syncChan.
+	doc := crdt.New()
+	for range 2 {
+		select {
+		case r.syncChan <- commons.Message{Type: commons.DocSyncMessage, Document: &doc}:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the sync loop is stuck\n")
+		}
+	}
+	go r.clients.writeLoop(slow)
+	waitRemoved(t, r.clients, slow.id)
+
+	select {
+	case r.syncChan <- commons.Message{Type: commons.DocSyncMessage, Document: &doc}:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the sync loop is stuck after dropping the slow client\n")
+	}
+}
+
+func TestHandleMsg_AttributesOperations(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	alice := dialTestClient(t, srv, room)
+	bob := dialTestClient(t, srv, room)
+	for conn, name := range map[*websocket.Conn]string{alice: "alice", bob: "bob"} {
+		if err := conn.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: name}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+
+	// A client can't claim to be someone else.
+	op := commons.Operation{Type: "insert", Position: 1, Value: "a"}
+	if err := alice.WriteJSON(commons.Message{Type: "operation", Username: "bob", Operation: op}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+
+	msg := readUntil(t, bob, "operation")
+	if msg.Username != "alice" {
+		t.Errorf("got != want; got = %q, expected = %q\n", msg.Username, "alice")
+	}
+}
+
+func TestHandleMsg_Echo(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	echoing := dialTestClientQuery(t, srv, url.Values{"room": {room}, "echo": {"1"}})
+	quiet := dialTestClient(t, srv, room)
+	readUntil(t, echoing, commons.SiteIDMessage)
+	readUntil(t, quiet, commons.SiteIDMessage)
+
+	// The sender gets its operation back, as does everyone else.
+	op := commons.Operation{Type: "insert", Position: 1, Value: "a", Clock: 7}
+	if err := echoing.WriteJSON(commons.Message{Type: "operation", Operation: op}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	for _, conn := range []*websocket.Conn{echoing, quiet} {
+		if got := readUntil(t, conn, "operation").Operation; got.Value != "a" || got.Clock != 7 {
+			t.Errorf("got = %+v, expected the sent operation\n", got)
+		}
+	}
+
+	// Without echo mode, the sender only sees others' operations.
+	op = commons.Operation{Type: "insert", Position: 2, Value: "b"}
+	if err := quiet.WriteJSON(commons.Message{Type: "operation", Operation: op}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	readUntil(t, echoing, "operation")
+
+	op = commons.Operation{Type: "insert", Position: 3, Value: "c"}
+	if err := echoing.WriteJSON(commons.Message{Type: "operation", Operation: op}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	if got := readUntil(t, quiet, "operation").Operation; got.Value != "c" {
+		t.Errorf("got = %+v, expected the other client's operation, not an echo\n", got)
+	}
+}
+
+func TestHandleMsg_CursorRelayed(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	alice := dialTestClient(t, srv, room)
+	bob := dialTestClient(t, srv, room)
+	readUntil(t, alice, commons.SiteIDMessage)
+	readUntil(t, bob, commons.SiteIDMessage)
+
+	if err := alice.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: "alice", Text: "has joined the session."}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+
+	// The cursor reaches peers attributed to its sender, whatever name it claims.
+	if err := alice.WriteJSON(commons.Message{Type: commons.CursorMessage, Username: "mallory", Text: "1.3"}); err != nil {
+		t.Fatalf("write error: %v\n", err)
+	}
+	got := readUntil(t, bob, commons.CursorMessage)
+	if got.Username != "alice" || got.Text != "1.3" {
+		t.Errorf("got cursor %q from %q, expected %q from %q\n", got.Text, got.Username, "1.3", "alice")
+	}
+}
+
+func TestHandleMsg_ChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	alice := dialTestClient(t, srv, room)
+	readUntil(t, alice, commons.SiteIDMessage)
+
+	// A single differing checksum may just be an operation in flight; it takes two against the same document.
+	empty := crdt.New()
+	want := empty.Checksum()
+	for _, sum := range []string{"stale", "stale"} {
+		if err := alice.WriteJSON(commons.Message{Type: commons.ChecksumMessage, Text: sum}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+	got := readUntil(t, alice, commons.ChecksumMessage)
+	if got.Text != want {
+		t.Errorf("got room checksum %q, expected %q\n", got.Text, want)
+	}
+
+	// A matching checksum isn't answered, and clears an earlier mismatch.
+	for _, sum := range []string{"stale", want, "stale", "stale"} {
+		if err := alice.WriteJSON(commons.Message{Type: commons.ChecksumMessage, Text: sum}); err != nil {
+			t.Fatalf("write error: %v\n", err)
+		}
+	}
+	if got := readUntil(t, alice, commons.ChecksumMessage); got.Text != want {
+		t.Errorf("got room checksum %q, expected %q\n", got.Text, want)
+	}
+}
+
+func TestJoin_DocBeforeOperations(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	alice := dialTestClient(t, srv, room)
+	readUntil(t, alice, commons.SiteIDMessage)
+
+	// Clients join while alice types; none may be relayed an operation before the document it builds on.
+	go func() {
+		for i := range 5000 {
+			op := commons.Operation{Type: "insert", Position: i + 1, Value: "a"}
+			if err := alice.WriteJSON(commons.Message{Type: "operation", Operation: op}); err != nil {
+				return
+			}
+		}
+	}()
+	for range 50 {
+		bob := dialTestClient(t, srv, room)
+
+		var got []commons.MessageType
+		for len(got) == 0 || got[len(got)-1] != commons.DocSyncMessage {
+			bob.SetReadDeadline(time.Now().Add(2 * time.Second))
+			var msg commons.Message
+			if err := bob.ReadJSON(&msg); err != nil {
+				t.Fatalf("never received the document: %v\n", err)
+			}
+			got = append(got, msg.Type)
+		}
+		want := []commons.MessageType{commons.PermissionMessage, commons.SiteIDMessage, commons.DocSyncMessage}
+		if !cmp.Equal(got, want) {
+			t.Fatalf("got messages %v before the document, expected %v\n", got, want)
+		}
+	}
+}
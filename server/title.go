@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"text-editor/commons"
+)
+
+// maxTitleLength caps room titles, in runes, so they leave room for the rest of the status bar.
+const maxTitleLength = 64
+
+// roomTitle is a room's title, set by its owner and shown to every client.
+type roomTitle struct {
+	// Guards the fields below.
+	mu sync.Mutex
+
+	// The title, empty if none was set.
+	text string
+
+	// Name of the client that set it.
+	setBy string
+}
+
+// sanitizeTitle makes an owner-supplied title safe to show to other users.
+// Control and formatting characters are stripped; an empty result clears the title.
+func sanitizeTitle(title string) string {
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, title)
+	title = strings.TrimSpace(title)
+
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = strings.TrimSpace(string(runes[:maxTitleLength]))
+	}
+	return title
+}
+
+// titleMsg announces the room's title.
+func (r *Room) titleMsg() commons.Message {
+	r.title.mu.Lock()
+	defer r.title.mu.Unlock()
+
+	return commons.Message{Type: commons.TitleMessage, Text: r.title.text, Username: r.title.setBy}
+}
+
+// hasTitle reports whether the room's title was set.
+func (r *Room) hasTitle() bool {
+	r.title.mu.Lock()
+	defer r.title.mu.Unlock()
+	return r.title.text != ""
+}
+
+// setTitle applies a room owner's request to change the room's title, announcing it to every client.
+func (r *Room) setTitle(msg commons.Message) {
+	if owner, _ := r.clients.permission(msg.ID); !owner {
+		logger.Errorf("Room %s: ignoring title change from non-owner ID=%s", r.name, msg.ID)
+		return
+	}
+
+	title, name := sanitizeTitle(msg.Text), r.clients.username(msg.ID)
+	r.title.mu.Lock()
+	r.title.text, r.title.setBy = title, name
+	r.title.mu.Unlock()
+
+	logger.Infof("Room %s: title set to %q by %s", r.name, title, name)
+	r.clients.broadcastAll(r.titleMsg())
+}
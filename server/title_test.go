@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"text-editor/commons"
+)
+
+func TestSanitizeTitle(t *testing.T) {
+	tests := []struct {
+		description string
+		title       string
+		want        string
+	}{
+		{"plain", "Meeting notes", "Meeting notes"},
+		{"surrounding space", "  Draft \n", "Draft"},
+		{"control characters", "Q3\x1b[31m plan\x07", "Q3[31m plan"},
+		{"formatting characters", "a\u200bb", "ab"},
+		{"empty clears", "   ", ""},
+		{"too long", strings.Repeat("x", maxTitleLength+10), strings.Repeat("x", maxTitleLength)},
+	}
+
+	for _, tc := range tests {
+		if got := sanitizeTitle(tc.title); got != tc.want {
+			t.Errorf("(%s) got = %q, expected = %q\n", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestTitle_LateJoiner(t *testing.T) {
+	srv := startTestServer(t)
+	room := testRoom(t)
+
+	// Only the owner, the first client, may set the title.
+	alice := connectTestClient(t, srv, room, "alice")
+	bob := connectTestClient(t, srv, room, "bob")
+	bob.send(commons.Message{Type: commons.TitleMessage, Text: "Bob's title"})
+
+	alice.send(commons.Message{Type: commons.TitleMessage, Text: "Release notes"})
+	for _, c := range []*e2eClient{alice, bob} {
+		msg := readUntil(t, c.conn, commons.TitleMessage)
+		if msg.Text != "Release notes" || msg.Username != "alice" {
+			t.Errorf("%s got title %q set by %q, expected %q set by %q\n", c.name, msg.Text, msg.Username, "Release notes", "alice")
+		}
+	}
+
+	// A client joining later is told the current title when it connects.
+	carol := dialTestClient(t, srv, room)
+	if msg := readUntil(t, carol, commons.TitleMessage); msg.Text != "Release notes" {
+		t.Errorf("late joiner got title %q, expected %q\n", msg.Text, "Release notes")
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/Pallinder/go-randomdata"
+)
+
+// maxUsernameLength caps usernames, in runes, so they can't crowd the status bar.
+const maxUsernameLength = 32
+
+// sanitizeUsername makes a client-supplied name safe to store and show to other users.
+// Control and formatting characters are stripped, as are commas, which separate names in the user list.
+// An empty result falls back to a generated name.
+func sanitizeUsername(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) || r == ',' || r == unicode.ReplacementChar {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if runes := []rune(name); len(runes) > maxUsernameLength {
+		name = strings.TrimSpace(string(runes[:maxUsernameLength]))
+	}
+
+	if name == "" {
+		return randomdata.SillyName()
+	}
+	return name
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"text-editor/commons"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSanitizeUsername(t *testing.T) {
+	tests := []struct {
+		description string
+		name        string
+		want        string
+	}{
+		{"plain", "alice", "alice"},
+		{"surrounding whitespace", "  alice\t\n", "alice"},
+		{"inner spaces kept", "alice smith", "alice smith"},
+		{"escape sequence", "\x1b[31malice\x1b[0m", "[31malice[0m"},
+		{"control characters", "al\x00i\rce\x7f", "alice"},
+		{"bidi override", "alice‮evil", "aliceevil"},
+		{"zero-width space", "al​ice", "alice"},
+		{"comma", "alice,bob", "alicebob"},
+		{"unicode", "zoë 世界", "zoë 世界"},
+		{"overlong", strings.Repeat("a", 100), strings.Repeat("a", maxUsernameLength)},
+		{"overlong wide runes", strings.Repeat("世", 100), strings.Repeat("世", maxUsernameLength)},
+		{"trailing space after truncation", strings.Repeat("a", maxUsernameLength-1) + " b", strings.Repeat("a", maxUsernameLength-1)},
+	}
+
+	for _, tc := range tests {
+		if got := sanitizeUsername(tc.name); got != tc.want {
+			t.Errorf("(%s) got %q, expected %q", tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestSanitizeUsername_EmptyFallsBack(t *testing.T) {
+	for _, name := range []string{"", "   ", "\x1b\x00", ",,,", "​"} {
+		got := sanitizeUsername(name)
+		if got == "" || utf8.RuneCountInString(got) > maxUsernameLength || sanitizeUsername(got) != got {
+			t.Errorf("%q: got %q, expected a generated name", name, got)
+		}
+	}
+}
+
+func TestHandleMsg_SanitizesJoinName(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	conn := dialTestClient(t, srv, testRoom(t))
+	readUntil(t, conn, commons.SiteIDMessage)
+
+	name := " \x1b[2Jmal,lory‮" + strings.Repeat("x", 100) + "\n"
+	if err := conn.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: name}); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+
+	want := "[2Jmallory" + strings.Repeat("x", maxUsernameLength-len("[2Jmallory"))
+	for {
+		msg := readUntil(t, conn, commons.UsersMessage)
+		if msg.Text == want+"," {
+			break
+		}
+	}
+}
+
+func TestSendUsernames_SiteIDs(t *testing.T) {
+	srv := httptest.NewServer(newMux())
+	defer srv.Close()
+
+	room := testRoom(t)
+	sites := map[string]string{}
+	var alice *websocket.Conn
+	for _, name := range []string{"alice", "bob"} {
+		conn := dialTestClient(t, srv, room)
+		sites[name] = readUntil(t, conn, commons.SiteIDMessage).Text
+		if err := conn.WriteJSON(commons.Message{Type: commons.JoinMessage, Username: name}); err != nil {
+			t.Fatalf("write error: %v", err)
+		}
+		if alice == nil {
+			alice = conn
+		}
+	}
+
+	// The site IDs are listed in the same order as the names.
+	for {
+		msg := readUntil(t, alice, commons.UsersMessage)
+		names, got := strings.Split(msg.Text, ","), strings.Split(msg.Site, ",")
+		if len(names) != 3 || !strings.Contains(msg.Text, "alice") || !strings.Contains(msg.Text, "bob") {
+			continue
+		}
+		if len(got) != len(names) {
+			t.Fatalf("got sites %q for users %q\n", msg.Site, msg.Text)
+		}
+		for i, name := range names[:2] {
+			if got[i] != sites[name] {
+				t.Errorf("got site %q for %s, expected %q\n", got[i], name, sites[name])
+			}
+		}
+		break
+	}
+}